@@ -1,19 +1,18 @@
 package api
 
 import (
-	"database/sql"
-	"errors"
 	"net/http"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
-	"github.com/lib/pq"
+	"github.com/samirprakash/go-bank/api/apierror"
 	db "github.com/samirprakash/go-bank/db/sqlc"
 	"github.com/samirprakash/go-bank/token"
 )
 
 type createAccountRequest struct {
-	Currency string `json:"currency" binding:"required,customCurrencyValidator"`
+	Currency string `json:"currency" binding:"required,currency"`
+	// Type defaults to a checking account, savings accounts earn interest
+	Type string `json:"type" binding:"omitempty,oneof=checking savings"`
 }
 
 func (server *Server) createAccount(ctx *gin.Context) {
@@ -21,10 +20,14 @@ func (server *Server) createAccount(ctx *gin.Context) {
 
 	// validate request params
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		abortWithError(ctx, apierror.FromBinding(err))
 		return
 	}
 
+	if req.Type == "" {
+		req.Type = db.AccountChecking
+	}
+
 	// add auth to create account
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 	// create db params
@@ -32,20 +35,13 @@ func (server *Server) createAccount(ctx *gin.Context) {
 		Owner:    authPayload.Username,
 		Balance:  0,
 		Currency: req.Currency,
+		Type:     req.Type,
 	}
 
 	// save to db
-	account, err := server.store.CreateAccount(ctx, arg)
+	account, err := server.store.CreateAccountTx(ctx, arg)
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok {
-			switch pqErr.Code.Name() {
-			case "foreign_key_violation", "unique_violation":
-				ctx.JSON(http.StatusForbidden, errorResponse(err))
-				return
-			}
-		}
-
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		abortWithError(ctx, err)
 		return
 	}
 
@@ -62,29 +58,21 @@ func (server *Server) getAccount(ctx *gin.Context) {
 
 	// validate request
 	if err := ctx.ShouldBindUri(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		abortWithError(ctx, apierror.FromBinding(err))
 		return
 	}
 
 	// get account from db
 	account, err := server.store.GetAccount(ctx, req.ID)
 	if err != nil {
-		// if account does not exist
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
-			return
-		}
-
-		// if there is an issue on the server
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		abortWithError(ctx, err)
 		return
 	}
 
 	// add auth middleware
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 	if account.Owner != authPayload.Username {
-		err = errors.New("account does not belong to the authenticated user")
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		abortWithError(ctx, apierror.PermissionDenied("account does not belong to the authenticated user"))
 		return
 	}
 
@@ -103,7 +91,7 @@ func (server *Server) listAccounts(ctx *gin.Context) {
 	// validate query params
 	if err := ctx.ShouldBindQuery(&req); err != nil {
 		// return 400 if bad request
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		abortWithError(ctx, apierror.FromBinding(err))
 		return
 	}
 
@@ -118,8 +106,7 @@ func (server *Server) listAccounts(ctx *gin.Context) {
 	// get all accounts as per limit and offset
 	accounts, err := server.store.ListAccounts(ctx, arg)
 	if err != nil {
-		// return 500 if internal error
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		abortWithError(ctx, err)
 		return
 	}
 
@@ -127,86 +114,105 @@ func (server *Server) listAccounts(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, accounts)
 }
 
+type updateAccountBalanceURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
 type updateAccountBalanceRequest struct {
-	Amount int64 `json:"amount" binding:"required,min=1,max=10000"`
+	Amount int64 `json:"amount" binding:"required,min=1"`
 }
 
 func (server *Server) updateAccountBalance(ctx *gin.Context) {
+	var uri updateAccountBalanceURI
 	var req updateAccountBalanceRequest
 
 	// validate path param id
-	id, err := strconv.Atoi(ctx.Param("id"))
-	if err != nil {
-		// return 400 if bad request
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		abortWithError(ctx, apierror.FromBinding(err))
 		return
 	}
 
-	// Account ID must not be less than 1
-	if id < 1 {
-		// return 400 if bad request
-		ctx.JSON(http.StatusBadRequest, "Invalid ID")
+	// validate requets body
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		abortWithError(ctx, apierror.FromBinding(err))
 		return
 	}
 
-	// validate requets body
-	if err := ctx.ShouldBindJSON(&req); err != nil {
-		// return 400 if bad request
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+	account, err := server.store.GetAccount(ctx, uri.ID)
+	if err != nil {
+		abortWithError(ctx, err)
 		return
 	}
 
-	arg := db.UpdateAccountBalanceParams{
-		Amount: req.Amount,
-		ID:     int64(id),
+	// frozen and closed accounts cannot receive money
+	if err := db.AccountStatusError(account.Status); err != nil {
+		abortWithError(ctx, err)
+		return
 	}
 
-	// update account balance in db
-	account, err := server.store.UpdateAccountBalance(ctx, arg)
+	// a deposit is bounded by the maximum transfer amount of the account
+	limits, err := server.store.GetAccountLimits(ctx, account.ID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			// reeturn 404 if account not found
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
-			return
-		}
+		abortWithError(ctx, err)
+		return
+	}
 
-		// return 500 if internal error
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+	if err := db.CheckTransferAmount(limits, req.Amount); err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	arg := db.DepositTxParams{
+		AccountID: uri.ID,
+		Amount:    req.Amount,
+	}
+
+	// update account balance in db along with its entry
+	result, err := server.store.DepositTx(ctx, arg)
+	if err != nil {
+		abortWithError(ctx, err)
 		return
 	}
 
 	// return the account with updated balance
-	ctx.JSON(http.StatusOK, account)
+	ctx.JSON(http.StatusOK, result.Account)
 }
 
-type deleteAccountRequest struct {
+type closeAccountRequest struct {
 	ID int64 `uri:"id" binding:"required,min=1"`
 }
 
-func (server *Server) deleteAccount(ctx *gin.Context) {
-	var req deleteAccountRequest
+// closeAccount closes an account of the authenticated user.
+// Accounts are never deleted as their entries and transfers must be kept, and only an empty account can be closed.
+func (server *Server) closeAccount(ctx *gin.Context) {
+	var req closeAccountRequest
 
 	if err := ctx.ShouldBindUri(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		abortWithError(ctx, apierror.FromBinding(err))
 		return
 	}
 
-	_, err := server.store.GetAccount(ctx, req.ID)
+	account, err := server.store.GetAccount(ctx, req.ID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
-			return
-		}
+		abortWithError(ctx, err)
+		return
+	}
 
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if account.Owner != authPayload.Username {
+		abortWithError(ctx, apierror.PermissionDenied("account does not belong to the authenticated user"))
 		return
 	}
 
-	err = server.store.DeleteAccount(ctx, req.ID)
+	result, err := server.store.ChangeAccountStatusTx(ctx, db.ChangeAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    db.AccountClosed,
+		ChangedBy: authPayload.Username,
+	})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		abortWithError(ctx, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, "Accoun deleted!")
+	ctx.JSON(http.StatusOK, result.Account)
 }
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/samirprakash/go-bank/api/apierror"
+	db "github.com/samirprakash/go-bank/db/sqlc"
+	"github.com/samirprakash/go-bank/token"
+)
+
+type accountLimitsResponse struct {
+	AccountID            int64  `json:"account_id"`
+	Tier                 string `json:"tier"`
+	MaxTransferAmount    *int64 `json:"max_transfer_amount"`
+	DailyOutgoingLimit   *int64 `json:"daily_outgoing_limit"`
+	MonthlyOutgoingLimit *int64 `json:"monthly_outgoing_limit"`
+}
+
+func newAccountLimitsResponse(limits db.GetAccountLimitsRow) accountLimitsResponse {
+	return accountLimitsResponse{
+		AccountID:            limits.AccountID,
+		Tier:                 limits.Tier,
+		MaxTransferAmount:    nullInt64(limits.MaxTransferAmount),
+		DailyOutgoingLimit:   nullInt64(limits.DailyOutgoingLimit),
+		MonthlyOutgoingLimit: nullInt64(limits.MonthlyOutgoingLimit),
+	}
+}
+
+// nullInt64 returns nil for a null number so that it is rendered as null in responses
+func nullInt64(n pgtype.Int8) *int64 {
+	if !n.Valid {
+		return nil
+	}
+	return &n.Int64
+}
+
+// toNullInt64 stores an optional number of a request, nil being stored as null
+func toNullInt64(n *int64) pgtype.Int8 {
+	if n == nil {
+		return pgtype.Int8{}
+	}
+	return pgtype.Int8{Int64: *n, Valid: true}
+}
+
+type accountLimitsURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// getAccountLimits returns the limits in force on an account to its owner or an admin.
+// A null limit means that the account has no such limit.
+func (server *Server) getAccountLimits(ctx *gin.Context) {
+	var uri accountLimitsURI
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		abortWithError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	account, err := server.store.GetAccount(ctx, uri.ID)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if account.Owner != authPayload.Username && !isAdmin(server.config.AdminUsernames, authPayload.Username) {
+		abortWithError(ctx, apierror.PermissionDenied("account does not belong to the authenticated user"))
+		return
+	}
+
+	limits, err := server.store.GetAccountLimits(ctx, account.ID)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newAccountLimitsResponse(limits))
+}
+
+type updateAccountLimitsRequest struct {
+	MaxTransferAmount    *int64 `json:"max_transfer_amount" binding:"omitempty,gt=0"`
+	DailyOutgoingLimit   *int64 `json:"daily_outgoing_limit" binding:"omitempty,gt=0"`
+	MonthlyOutgoingLimit *int64 `json:"monthly_outgoing_limit" binding:"omitempty,gt=0"`
+}
+
+// updateAccountLimits overrides the limits of the tier of the owner for one account.
+// A limit left out falls back to the tier, so an empty body resets the account to its tier.
+func (server *Server) updateAccountLimits(ctx *gin.Context) {
+	var uri accountLimitsURI
+	var req updateAccountLimitsRequest
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		abortWithError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		abortWithError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	_, err := server.store.UpsertAccountLimits(ctx, db.UpsertAccountLimitsParams{
+		AccountID:            uri.ID,
+		MaxTransferAmount:    toNullInt64(req.MaxTransferAmount),
+		DailyOutgoingLimit:   toNullInt64(req.DailyOutgoingLimit),
+		MonthlyOutgoingLimit: toNullInt64(req.MonthlyOutgoingLimit),
+		UpdatedBy:            authPayload.Username,
+	})
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	limits, err := server.store.GetAccountLimits(ctx, uri.ID)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newAccountLimitsResponse(limits))
+}
+
+type updateUserTierURI struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+type updateUserTierRequest struct {
+	Tier string `json:"tier" binding:"required"`
+}
+
+// updateUserTier moves a user to another limit tier, which applies to all of their accounts
+func (server *Server) updateUserTier(ctx *gin.Context) {
+	var uri updateUserTierURI
+	var req updateUserTierRequest
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		abortWithError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		abortWithError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	user, err := server.store.UpdateUserTier(ctx, db.UpdateUserTierParams{
+		Username: uri.Username,
+		Tier:     req.Tier,
+	})
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
@@ -0,0 +1,274 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/samirprakash/go-bank/api/apierror"
+	mockdb "github.com/samirprakash/go-bank/db/mock"
+	db "github.com/samirprakash/go-bank/db/sqlc"
+	"github.com/samirprakash/go-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func randomAccountLimits(accountID int64) db.GetAccountLimitsRow {
+	return db.GetAccountLimitsRow{
+		AccountID:            accountID,
+		Tier:                 "standard",
+		MaxTransferAmount:    pgtype.Int8{Int64: 10000, Valid: true},
+		DailyOutgoingLimit:   pgtype.Int8{Int64: 50000, Valid: true},
+		MonthlyOutgoingLimit: pgtype.Int8{},
+	}
+}
+
+func TestAccountLimitsAPI(t *testing.T) {
+	admin := "admin"
+	user, _ := randomUser(t)
+	other, _ := randomUser(t)
+	account := randomAccount(user.Username)
+	limits := randomAccountLimits(account.ID)
+
+	testCases := []struct {
+		name          string
+		username      string
+		method        string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "GetByOwner",
+			username: user.Username,
+			method:   http.MethodGet,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().GetAccountLimits(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(limits, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp accountLimitsResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, "standard", rsp.Tier)
+				require.Equal(t, int64(10000), *rsp.MaxTransferAmount)
+				require.Nil(t, rsp.MonthlyOutgoingLimit)
+			},
+		},
+		{
+			name:     "GetByOtherUser",
+			username: other.Username,
+			method:   http.MethodGet,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().GetAccountLimits(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireBodyMatchError(t, recorder, apierror.CodePermissionDenied)
+			},
+		},
+		{
+			name:     "Update",
+			username: admin,
+			method:   http.MethodPut,
+			body:     gin.H{"daily_outgoing_limit": 2000},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.UpsertAccountLimitsParams{
+					AccountID:          account.ID,
+					DailyOutgoingLimit: pgtype.Int8{Int64: 2000, Valid: true},
+					UpdatedBy:          admin,
+				}
+				store.EXPECT().UpsertAccountLimits(gomock.Any(), gomock.Eq(arg)).Times(1)
+
+				updated := limits
+				updated.DailyOutgoingLimit.Int64 = 2000
+				store.EXPECT().GetAccountLimits(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(updated, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp accountLimitsResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, int64(2000), *rsp.DailyOutgoingLimit)
+			},
+		},
+		{
+			name:     "UpdateByOwner",
+			username: user.Username,
+			method:   http.MethodPut,
+			body:     gin.H{"daily_outgoing_limit": 2000000},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertAccountLimits(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:     "UpdateInvalidLimit",
+			username: admin,
+			method:   http.MethodPut,
+			body:     gin.H{"max_transfer_amount": 0},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertAccountLimits(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireBodyMatchError(t, recorder, apierror.CodeInvalidArgument)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			config := util.Config{
+				TokenSymmetricKey:   util.RandomString(32),
+				AccessTokenDuration: time.Minute,
+				AdminUsernames:      []string{admin},
+			}
+
+			server, err := NewServer(config, store, nil, nil)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/accounts/%d/limits", account.ID)
+			request, err := http.NewRequest(tc.method, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestUpdateUserTierAPI(t *testing.T) {
+	admin := "admin"
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		tier          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			tier: "premium",
+			buildStubs: func(store *mockdb.MockStore) {
+				updated := user
+				updated.Tier = "premium"
+
+				arg := db.UpdateUserTierParams{Username: user.Username, Tier: "premium"}
+				store.EXPECT().UpdateUserTier(gomock.Any(), gomock.Eq(arg)).Times(1).Return(updated, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp userResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, "premium", rsp.Tier)
+			},
+		},
+		{
+			name: "UnknownTier",
+			tier: "gold",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateUserTier(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, &pgconn.PgError{Code: "23503", ConstraintName: "users_tier_fkey"})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireBodyMatchError(t, recorder, apierror.CodeInvalidArgument)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			config := util.Config{
+				TokenSymmetricKey:   util.RandomString(32),
+				AccessTokenDuration: time.Minute,
+				AdminUsernames:      []string{admin},
+			}
+
+			server, err := NewServer(config, store, nil, nil)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(gin.H{"tier": tc.tier})
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/users/%s/tier", user.Username)
+			request, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, admin, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestDepositLimitAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+	store.EXPECT().GetAccountLimits(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(randomAccountLimits(account.ID), nil)
+	store.EXPECT().DepositTx(gomock.Any(), gomock.Any()).Times(0)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	data, err := json.Marshal(gin.H{"amount": 10001})
+	require.NoError(t, err)
+
+	url := fmt.Sprintf("/accounts/%d", account.ID)
+	request, err := http.NewRequest(http.MethodPatch, url, bytes.NewReader(data))
+	require.NoError(t, err)
+
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	apiErr := requireBodyMatchError(t, recorder, apierror.CodeLimitExceeded)
+	require.Equal(t, "max_transfer_amount", apiErr.Details[0].Rule)
+}
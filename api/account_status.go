@@ -0,0 +1,92 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samirprakash/go-bank/api/apierror"
+	db "github.com/samirprakash/go-bank/db/sqlc"
+	"github.com/samirprakash/go-bank/token"
+)
+
+type accountStatusURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type changeAccountStatusRequest struct {
+	Reason string `json:"reason" binding:"required,max=255"`
+}
+
+// freezeAccount blocks every movement of money on an account, e.g. when it has been compromised
+func (server *Server) freezeAccount(ctx *gin.Context) {
+	server.changeAccountStatus(ctx, db.AccountFrozen)
+}
+
+// unfreezeAccount makes a frozen account active again
+func (server *Server) unfreezeAccount(ctx *gin.Context) {
+	server.changeAccountStatus(ctx, db.AccountActive)
+}
+
+// changeAccountStatus moves the account of the URI to the status on behalf of the authenticated admin.
+// The reason is required as it is kept in the audit trail of the account.
+func (server *Server) changeAccountStatus(ctx *gin.Context, status string) {
+	var uri accountStatusURI
+	var req changeAccountStatusRequest
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		abortWithError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		abortWithError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	result, err := server.store.ChangeAccountStatusTx(ctx, db.ChangeAccountStatusTxParams{
+		AccountID: uri.ID,
+		Status:    status,
+		ChangedBy: authPayload.Username,
+		Reason:    req.Reason,
+	})
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+type listAccountStatusChangesRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+// listAccountStatusChanges returns the audit trail of the status of an account, most recent first
+func (server *Server) listAccountStatusChanges(ctx *gin.Context) {
+	var uri accountStatusURI
+	var req listAccountStatusChangesRequest
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		abortWithError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		abortWithError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	changes, err := server.store.ListAccountStatusChanges(ctx, db.ListAccountStatusChangesParams{
+		AccountID: uri.ID,
+		Limit:     req.PageSize,
+		Offset:    (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, changes)
+}
@@ -0,0 +1,263 @@
+// Package apierror defines the errors returned by the HTTP API.
+// Every error response has the same shape and carries a machine readable code,
+// so that clients never have to parse messages and internal errors are never leaked.
+package apierror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	db "github.com/samirprakash/go-bank/db/sqlc"
+	"github.com/samirprakash/go-bank/token"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Code is a machine readable error code
+type Code string
+
+// Error codes returned by the API
+const (
+	CodeInvalidArgument    Code = "invalid_argument"
+	CodeUnauthenticated    Code = "unauthenticated"
+	CodeInvalidToken       Code = "invalid_token"
+	CodeExpiredToken       Code = "expired_token"
+	CodeInvalidCredentials Code = "invalid_credentials"
+	CodePermissionDenied   Code = "permission_denied"
+	CodeNotFound           Code = "not_found"
+	CodeAlreadyExists      Code = "already_exists"
+	CodeFailedPrecondition Code = "failed_precondition"
+	CodeInsufficientFunds  Code = "insufficient_funds"
+	CodeLimitExceeded      Code = "limit_exceeded"
+	CodeAborted            Code = "aborted"
+	CodeUnavailable        Code = "unavailable"
+	CodeInternal           Code = "internal"
+)
+
+// Error is an error returned to API clients
+type Error struct {
+	// Status is the HTTP status code of the response
+	Status int `json:"-"`
+	// Code is the machine readable error code
+	Code Code `json:"code"`
+	// Message is a human readable description of the error, safe to return to clients
+	Message string `json:"message"`
+	// Details holds additional information such as the fields that failed validation
+	Details []FieldViolation `json:"details,omitempty"`
+	// RequestID identifies the request in the logs
+	RequestID string `json:"request_id,omitempty"`
+	// Err is the underlying cause, it is logged but never returned to clients
+	Err error `json:"-"`
+}
+
+// FieldViolation describes why a field of the request is invalid
+type FieldViolation struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Response is the body of an error response
+type Response struct {
+	Error *Error `json:"error"`
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New creates a new API error
+func New(status int, code Code, message string) *Error {
+	return &Error{
+		Status:  status,
+		Code:    code,
+		Message: message,
+	}
+}
+
+// Wrap creates a new API error caused by err
+func Wrap(err error, status int, code Code, message string) *Error {
+	apiErr := New(status, code, message)
+	apiErr.Err = err
+	return apiErr
+}
+
+// InvalidArgument returns a 400 error
+func InvalidArgument(message string) *Error {
+	return New(http.StatusBadRequest, CodeInvalidArgument, message)
+}
+
+// Unauthenticated returns a 401 error
+func Unauthenticated(message string) *Error {
+	return New(http.StatusUnauthorized, CodeUnauthenticated, message)
+}
+
+// PermissionDenied returns a 403 error
+func PermissionDenied(message string) *Error {
+	return New(http.StatusForbidden, CodePermissionDenied, message)
+}
+
+// NotFound returns a 404 error
+func NotFound(message string) *Error {
+	return New(http.StatusNotFound, CodeNotFound, message)
+}
+
+// FailedPrecondition returns a 422 error for requests that are valid but cannot be applied to the current state
+func FailedPrecondition(message string) *Error {
+	return New(http.StatusUnprocessableEntity, CodeFailedPrecondition, message)
+}
+
+// Unavailable returns a 503 error
+func Unavailable(message string) *Error {
+	return New(http.StatusServiceUnavailable, CodeUnavailable, message)
+}
+
+// Internal returns a 500 error hiding the cause from clients
+func Internal(err error) *Error {
+	return Wrap(err, http.StatusInternalServerError, CodeInternal, "internal server error")
+}
+
+// FromError converts any error into an API error.
+// This is the one place where database, token and password errors are mapped to HTTP statuses.
+// Unknown errors become internal errors so that their message is not leaked.
+func FromError(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	var legErr *db.BatchTransferError
+	if errors.As(err, &legErr) {
+		return fromBatchTransferError(legErr)
+	}
+
+	switch {
+	case errors.Is(err, db.ErrInsufficientFunds):
+		return Wrap(err, http.StatusUnprocessableEntity, CodeInsufficientFunds, "insufficient funds")
+	case errors.Is(err, db.ErrTransferLimitExceeded):
+		return limitExceeded(err, "max_transfer_amount")
+	case errors.Is(err, db.ErrDailyLimitExceeded):
+		return limitExceeded(err, "daily_outgoing_limit")
+	case errors.Is(err, db.ErrMonthlyLimitExceeded):
+		return limitExceeded(err, "monthly_outgoing_limit")
+	case errors.Is(err, db.ErrCurrencyMismatch):
+		return Wrap(err, http.StatusBadRequest, CodeInvalidArgument, "account currency mismatch")
+	case errors.Is(err, db.ErrTransferAlreadyReversed),
+		errors.Is(err, db.ErrReversalExceedsTransfer),
+		errors.Is(err, db.ErrReversalOfReversal),
+		errors.Is(err, db.ErrHoldNotActive),
+		errors.Is(err, db.ErrHoldExpired),
+		errors.Is(err, db.ErrCaptureExceedsHold),
+		errors.Is(err, db.ErrAccountFrozen),
+		errors.Is(err, db.ErrAccountClosed),
+		errors.Is(err, db.ErrAccountNotEmpty),
+		errors.Is(err, db.ErrInvalidStatusTransition):
+		return Wrap(err, http.StatusUnprocessableEntity, CodeFailedPrecondition, err.Error())
+	case errors.Is(err, db.ErrRecordNotFound):
+		return Wrap(err, http.StatusNotFound, CodeNotFound, "resource not found")
+	case errors.Is(err, token.ErrExpiredToken):
+		return Wrap(err, http.StatusUnauthorized, CodeExpiredToken, token.ErrExpiredToken.Error())
+	case errors.Is(err, token.ErrInvalidToken):
+		return Wrap(err, http.StatusUnauthorized, CodeInvalidToken, token.ErrInvalidToken.Error())
+	case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+		return Wrap(err, http.StatusUnauthorized, CodeInvalidCredentials, "invalid username or password")
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return fromPgError(pgErr)
+	}
+
+	return Internal(err)
+}
+
+// limitExceeded reports the limit of the account that the amount went over
+func limitExceeded(err error, limit string) *Error {
+	apiErr := Wrap(err, http.StatusUnprocessableEntity, CodeLimitExceeded, err.Error())
+	apiErr.Details = []FieldViolation{{
+		Field:   "amount",
+		Rule:    limit,
+		Message: err.Error(),
+	}}
+	return apiErr
+}
+
+// fromBatchTransferError maps the cause of a failed batch transfer and points at the failing leg
+func fromBatchTransferError(err *db.BatchTransferError) *Error {
+	cause := *FromError(err.Err)
+	if cause.Code == CodeInternal {
+		return Internal(err)
+	}
+
+	cause.Err = err
+	cause.Details = append(cause.Details, FieldViolation{
+		Field:   fmt.Sprintf("legs[%d]", err.Leg),
+		Rule:    string(cause.Code),
+		Message: cause.Message,
+	})
+	return &cause
+}
+
+// constraintErrors maps the named constraints of the schema to the error returned when they are violated
+var constraintErrors = map[string]struct {
+	status  int
+	code    Code
+	message string
+}{
+	"accounts_balance_check":            {http.StatusUnprocessableEntity, CodeInsufficientFunds, "insufficient funds"},
+	"accounts_currency_fkey":            {http.StatusBadRequest, CodeInvalidArgument, "unsupported currency"},
+	"accounts_closed_check":             {http.StatusUnprocessableEntity, CodeFailedPrecondition, db.ErrAccountNotEmpty.Error()},
+	"transfers_amount_check":            {http.StatusBadRequest, CodeInvalidArgument, "amount must be positive"},
+	"transfers_distinct_accounts_check": {http.StatusBadRequest, CodeInvalidArgument, "cannot transfer to the same account"},
+
+	"scheduled_transfers_amount_check":            {http.StatusBadRequest, CodeInvalidArgument, "amount must be positive"},
+	"scheduled_transfers_distinct_accounts_check": {http.StatusBadRequest, CodeInvalidArgument, "cannot transfer to the same account"},
+
+	"account_limits_account_id_fkey": {http.StatusNotFound, CodeNotFound, "account not found"},
+	"account_limits_positive_check":  {http.StatusBadRequest, CodeInvalidArgument, "limits must be positive"},
+	"users_tier_fkey":                {http.StatusBadRequest, CodeInvalidArgument, "unknown tier"},
+	"fee_schedules_currency_fkey":    {http.StatusBadRequest, CodeInvalidArgument, "unknown currency"},
+	"fee_schedules_tier_fkey":        {http.StatusBadRequest, CodeInvalidArgument, "unknown tier"},
+	"fee_schedules_amounts_check":    {http.StatusBadRequest, CodeInvalidArgument, "fee amounts must not be negative"},
+	"fee_schedules_percentage_check": {http.StatusBadRequest, CodeInvalidArgument, "percentage must be between 0 and 10000 basis points"},
+	"fee_schedules_max_fee_check":    {http.StatusBadRequest, CodeInvalidArgument, "max fee must not be below min fee"},
+	"fee_schedules_band_key":         {http.StatusForbidden, CodeAlreadyExists, "a fee schedule already exists for this band"},
+
+	"accounts_type_check":            {http.StatusBadRequest, CodeInvalidArgument, "unknown account type"},
+	"interest_rates_rate_check":      {http.StatusBadRequest, CodeInvalidArgument, "rate must be between 0 and 10000 basis points"},
+	"interest_rates_day_count_check": {http.StatusBadRequest, CodeInvalidArgument, "unknown day-count convention"},
+	"interest_postings_pkey":         {http.StatusForbidden, CodeAlreadyExists, "interest has already been posted for this month"},
+
+	"holds_amount_check":            {http.StatusBadRequest, CodeInvalidArgument, "amount must be positive"},
+	"holds_distinct_accounts_check": {http.StatusBadRequest, CodeInvalidArgument, "cannot hold funds for the same account"},
+
+	"webhook_subscriptions_event_types_check": {http.StatusBadRequest, CodeInvalidArgument, "unknown event type"},
+}
+
+// fromPgError maps postgres error codes to API errors
+func fromPgError(err *pgconn.PgError) *Error {
+	if c, ok := constraintErrors[err.ConstraintName]; ok {
+		return Wrap(err, c.status, c.code, c.message)
+	}
+
+	switch err.Code {
+	case db.UniqueViolation:
+		return Wrap(err, http.StatusForbidden, CodeAlreadyExists, "resource already exists")
+	case db.ForeignKeyViolation:
+		return Wrap(err, http.StatusForbidden, CodeFailedPrecondition, "referenced resource does not exist")
+	case db.CheckViolation:
+		return Wrap(err, http.StatusUnprocessableEntity, CodeFailedPrecondition, "request violates a data integrity rule")
+	case db.SerializationFailure, db.DeadlockDetected:
+		return Wrap(err, http.StatusConflict, CodeAborted, "request conflicted with a concurrent request, please retry")
+	}
+
+	return Internal(err)
+}
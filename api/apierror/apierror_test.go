@@ -0,0 +1,218 @@
+package apierror
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/jackc/pgx/v5/pgconn"
+	db "github.com/samirprakash/go-bank/db/sqlc"
+	"github.com/samirprakash/go-bank/token"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestFromError(t *testing.T) {
+	testCases := []struct {
+		name   string
+		err    error
+		status int
+		code   Code
+	}{
+		{
+			name:   "APIError",
+			err:    fmt.Errorf("wrapped : %w", NotFound("account not found")),
+			status: http.StatusNotFound,
+			code:   CodeNotFound,
+		},
+		{
+			name:   "NoRows",
+			err:    fmt.Errorf("wrapped : %w", db.ErrRecordNotFound),
+			status: http.StatusNotFound,
+			code:   CodeNotFound,
+		},
+		{
+			name:   "UniqueViolation",
+			err:    &pgconn.PgError{Code: "23505"},
+			status: http.StatusForbidden,
+			code:   CodeAlreadyExists,
+		},
+		{
+			name:   "ForeignKeyViolation",
+			err:    &pgconn.PgError{Code: "23503"},
+			status: http.StatusForbidden,
+			code:   CodeFailedPrecondition,
+		},
+		{
+			name:   "InsufficientFunds",
+			err:    &pgconn.PgError{Code: "23514", ConstraintName: "accounts_balance_check"},
+			status: http.StatusUnprocessableEntity,
+			code:   CodeInsufficientFunds,
+		},
+		{
+			name:   "SameAccountTransfer",
+			err:    &pgconn.PgError{Code: "23514", ConstraintName: "transfers_distinct_accounts_check"},
+			status: http.StatusBadRequest,
+			code:   CodeInvalidArgument,
+		},
+		{
+			name:   "UnknownCheckViolation",
+			err:    &pgconn.PgError{Code: "23514", ConstraintName: "unknown_check"},
+			status: http.StatusUnprocessableEntity,
+			code:   CodeFailedPrecondition,
+		},
+		{
+			name:   "SerializationFailure",
+			err:    &pgconn.PgError{Code: "40001"},
+			status: http.StatusConflict,
+			code:   CodeAborted,
+		},
+		{
+			name:   "DeadlockDetected",
+			err:    &pgconn.PgError{Code: "40P01"},
+			status: http.StatusConflict,
+			code:   CodeAborted,
+		},
+		{
+			name:   "UnknownPgError",
+			err:    &pgconn.PgError{Code: "42P01", Message: "relation does not exist"},
+			status: http.StatusInternalServerError,
+			code:   CodeInternal,
+		},
+		{
+			name:   "InsufficientFunds",
+			err:    db.ErrInsufficientFunds,
+			status: http.StatusUnprocessableEntity,
+			code:   CodeInsufficientFunds,
+		},
+		{
+			name:   "CurrencyMismatch",
+			err:    db.ErrCurrencyMismatch,
+			status: http.StatusBadRequest,
+			code:   CodeInvalidArgument,
+		},
+		{
+			name:   "TransferAlreadyReversed",
+			err:    db.ErrTransferAlreadyReversed,
+			status: http.StatusUnprocessableEntity,
+			code:   CodeFailedPrecondition,
+		},
+		{
+			name:   "HoldExpired",
+			err:    fmt.Errorf("wrapped : %w", db.ErrHoldExpired),
+			status: http.StatusUnprocessableEntity,
+			code:   CodeFailedPrecondition,
+		},
+		{
+			name:   "AccountFrozen",
+			err:    db.ErrAccountFrozen,
+			status: http.StatusUnprocessableEntity,
+			code:   CodeFailedPrecondition,
+		},
+		{
+			name:   "DailyLimitExceeded",
+			err:    db.ErrDailyLimitExceeded,
+			status: http.StatusUnprocessableEntity,
+			code:   CodeLimitExceeded,
+		},
+		{
+			name:   "UnknownTier",
+			err:    &pgconn.PgError{Code: "23503", ConstraintName: "users_tier_fkey"},
+			status: http.StatusBadRequest,
+			code:   CodeInvalidArgument,
+		},
+		{
+			name:   "FeeScheduleMaxFee",
+			err:    &pgconn.PgError{Code: "23514", ConstraintName: "fee_schedules_max_fee_check"},
+			status: http.StatusBadRequest,
+			code:   CodeInvalidArgument,
+		},
+		{
+			name:   "InterestAlreadyPosted",
+			err:    &pgconn.PgError{Code: "23505", ConstraintName: "interest_postings_pkey"},
+			status: http.StatusForbidden,
+			code:   CodeAlreadyExists,
+		},
+		{
+			name:   "UnknownEventType",
+			err:    &pgconn.PgError{Code: "23514", ConstraintName: "webhook_subscriptions_event_types_check"},
+			status: http.StatusBadRequest,
+			code:   CodeInvalidArgument,
+		},
+		{
+			name:   "ExpiredToken",
+			err:    token.ErrExpiredToken,
+			status: http.StatusUnauthorized,
+			code:   CodeExpiredToken,
+		},
+		{
+			name:   "WrongPassword",
+			err:    bcrypt.ErrMismatchedHashAndPassword,
+			status: http.StatusUnauthorized,
+			code:   CodeInvalidCredentials,
+		},
+		{
+			name:   "Internal",
+			err:    sql.ErrConnDone,
+			status: http.StatusInternalServerError,
+			code:   CodeInternal,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			apiErr := FromError(tc.err)
+			require.Equal(t, tc.status, apiErr.Status)
+			require.Equal(t, tc.code, apiErr.Code)
+			require.NotEmpty(t, apiErr.Message)
+
+			// internal details are never part of the message
+			if tc.code == CodeInternal {
+				require.NotContains(t, apiErr.Message, tc.err.Error())
+				require.True(t, errors.Is(apiErr, tc.err))
+			}
+		})
+	}
+}
+
+func TestFromBatchTransferError(t *testing.T) {
+	err := &db.BatchTransferError{Leg: 2, Err: db.ErrInsufficientFunds}
+
+	apiErr := FromError(err)
+	require.Equal(t, http.StatusUnprocessableEntity, apiErr.Status)
+	require.Equal(t, CodeInsufficientFunds, apiErr.Code)
+	require.Len(t, apiErr.Details, 1)
+	require.Equal(t, "legs[2]", apiErr.Details[0].Field)
+	require.True(t, errors.Is(apiErr, db.ErrInsufficientFunds))
+
+	// leg errors hiding an internal error are not detailed
+	apiErr = FromError(&db.BatchTransferError{Leg: 0, Err: sql.ErrConnDone})
+	require.Equal(t, http.StatusInternalServerError, apiErr.Status)
+	require.Empty(t, apiErr.Details)
+}
+
+func TestFromBinding(t *testing.T) {
+	type request struct {
+		Username string `json:"username" binding:"required,alphanum"`
+		Amount   int64  `json:"amount" binding:"required,min=1"`
+	}
+
+	req := request{Username: "not valid!"}
+	err := binding.Validator.ValidateStruct(&req)
+	require.Error(t, err)
+
+	apiErr := FromBinding(err)
+	require.Equal(t, http.StatusBadRequest, apiErr.Status)
+	require.Equal(t, CodeInvalidArgument, apiErr.Code)
+	require.Len(t, apiErr.Details, 2)
+
+	require.Equal(t, "alphanum", apiErr.Details[0].Rule)
+	require.Equal(t, "must contain only letters and digits", apiErr.Details[0].Message)
+	require.Equal(t, "required", apiErr.Details[1].Rule)
+	require.Equal(t, "is required", apiErr.Details[1].Message)
+}
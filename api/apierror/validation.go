@@ -0,0 +1,91 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	db "github.com/samirprakash/go-bank/db/sqlc"
+)
+
+// FromBinding converts an error returned while binding a request into a 400 error.
+// Validation errors are reported with one violation per invalid field.
+func FromBinding(err error) *Error {
+	apiErr := Wrap(err, http.StatusBadRequest, CodeInvalidArgument, "invalid request")
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		for _, fieldErr := range validationErrs {
+			apiErr.Details = append(apiErr.Details, FieldViolation{
+				Field:   fieldErr.Field(),
+				Rule:    fieldErr.Tag(),
+				Message: fieldMessage(fieldErr),
+			})
+		}
+		return apiErr
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		apiErr.Details = []FieldViolation{{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Message: fmt.Sprintf("must be a %s", typeErr.Type.Kind()),
+		}}
+		return apiErr
+	}
+
+	var numErr *strconv.NumError
+	if errors.As(err, &numErr) {
+		apiErr.Message = fmt.Sprintf("invalid number %q", numErr.Num)
+		return apiErr
+	}
+
+	apiErr.Message = "malformed request body"
+	return apiErr
+}
+
+// fieldMessage returns a human readable message for a failed validation rule
+func fieldMessage(fieldErr validator.FieldError) string {
+	isString := fieldErr.Kind() == reflect.String
+
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		if isString {
+			return fmt.Sprintf("must be at least %s characters long", fieldErr.Param())
+		}
+		return fmt.Sprintf("must be at least %s", fieldErr.Param())
+	case "max":
+		if isString {
+			return fmt.Sprintf("must be at most %s characters long", fieldErr.Param())
+		}
+		return fmt.Sprintf("must be at most %s", fieldErr.Param())
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", fieldErr.Param())
+	case "email":
+		return "must be a valid email address"
+	case "alphanum":
+		return "must contain only letters and digits"
+	case "currency":
+		return "must be a supported currency"
+	case "schedule":
+		return "must be a cron expression or an @every interval of at least one minute"
+	case "http_url":
+		return "must be an http or https URL"
+	case "unique":
+		return "must not contain duplicates"
+	case "webhook_event":
+		return fmt.Sprintf("must be one of: %s", strings.Join(db.EventTypes, ", "))
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", strings.Join(strings.Fields(fieldErr.Param()), ", "))
+	}
+
+	return fmt.Sprintf("failed on the %q rule", fieldErr.Tag())
+}
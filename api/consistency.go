@@ -0,0 +1,97 @@
+package api
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/samirprakash/go-bank/db/sqlc"
+	"github.com/samirprakash/go-bank/token"
+)
+
+// writeTracker remembers when the users last wrote, so that their following reads see their writes.
+// Writes are forgotten after window, the maximum lag of the replicas serving reads, as every such replica has replayed them.
+// A zero window tracks nothing, when reads are all served by the primary.
+type writeTracker struct {
+	mu         sync.Mutex
+	window     time.Duration
+	lastWrites map[string]time.Time
+	prunedAt   time.Time
+}
+
+func newWriteTracker(window time.Duration) *writeTracker {
+	return &writeTracker{
+		window:     window,
+		lastWrites: make(map[string]time.Time),
+	}
+}
+
+// record remembers that a user wrote at t
+func (tracker *writeTracker) record(username string, t time.Time) {
+	if tracker.window <= 0 {
+		return
+	}
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	tracker.lastWrites[username] = t
+
+	// forget the writes of the users who stopped writing, at most once per window
+	if t.Sub(tracker.prunedAt) < tracker.window {
+		return
+	}
+	for user, lastWrite := range tracker.lastWrites {
+		if t.Sub(lastWrite) >= tracker.window {
+			delete(tracker.lastWrites, user)
+		}
+	}
+	tracker.prunedAt = t
+}
+
+// lastWrite returns when a user last wrote, if it was less than window before now
+func (tracker *writeTracker) lastWrite(username string, now time.Time) (time.Time, bool) {
+	if tracker.window <= 0 {
+		return time.Time{}, false
+	}
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	lastWrite, ok := tracker.lastWrites[username]
+	if !ok || now.Sub(lastWrite) >= tracker.window {
+		return time.Time{}, false
+	}
+	return lastWrite, true
+}
+
+// readYourWritesMiddleware serves the reads of a user who wrote recently from data that includes the write,
+// e.g. the balance of an account right after a transfer, by only reading from the replicas that replayed it.
+// The writes are remembered by this server only, so the requests of a user should stick to one server.
+// It must run after authMiddleware.
+func readYourWritesMiddleware(tracker *writeTracker) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+		if lastWrite, ok := tracker.lastWrite(authPayload.Username, time.Now()); ok {
+			ctx.Request = ctx.Request.WithContext(db.ReadAfter(ctx.Request.Context(), lastWrite))
+		}
+
+		ctx.Next()
+
+		// the handler has returned so its transaction is committed, if any
+		if isWriteMethod(ctx.Request.Method) {
+			tracker.record(authPayload.Username, time.Now())
+		}
+	}
+}
+
+// isWriteMethod tells if requests with the HTTP method may change data
+func isWriteMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
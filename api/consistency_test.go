@@ -0,0 +1,80 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samirprakash/go-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteTracker(t *testing.T) {
+	window := 5 * time.Second
+	tracker := newWriteTracker(window)
+	now := time.Now()
+
+	_, ok := tracker.lastWrite("user1", now)
+	require.False(t, ok)
+
+	tracker.record("user1", now)
+	lastWrite, ok := tracker.lastWrite("user1", now.Add(time.Second))
+	require.True(t, ok)
+	require.Equal(t, now, lastWrite)
+
+	_, ok = tracker.lastWrite("user2", now)
+	require.False(t, ok)
+
+	// replicas serving reads have replayed the writes older than the window
+	_, ok = tracker.lastWrite("user1", now.Add(window))
+	require.False(t, ok)
+
+	// old writes are pruned on the next record
+	tracker.record("user2", now.Add(2*window))
+	require.NotContains(t, tracker.lastWrites, "user1")
+	require.Contains(t, tracker.lastWrites, "user2")
+}
+
+func TestWriteTrackerDisabled(t *testing.T) {
+	tracker := newWriteTracker(0)
+	now := time.Now()
+
+	tracker.record("user1", now)
+	_, ok := tracker.lastWrite("user1", now)
+	require.False(t, ok)
+	require.Empty(t, tracker.lastWrites)
+}
+
+func TestReadYourWritesMiddleware(t *testing.T) {
+	tracker := newWriteTracker(time.Minute)
+	server := newTestServer(t, nil)
+	username := util.RandomOwnerName()
+
+	router := gin.New()
+	router.Use(authMiddleware(server.tokenMaker), readYourWritesMiddleware(tracker))
+	router.Any("/resource", func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+
+	send := func(method string) {
+		request, err := http.NewRequest(method, "/resource", nil)
+		require.NoError(t, err)
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, username, time.Minute)
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		require.Equal(t, http.StatusOK, recorder.Code)
+	}
+
+	send(http.MethodGet)
+	_, ok := tracker.lastWrite(username, time.Now())
+	require.False(t, ok)
+
+	beforeWrite := time.Now()
+	send(http.MethodPost)
+	lastWrite, ok := tracker.lastWrite(username, time.Now())
+	require.True(t, ok)
+	require.False(t, lastWrite.Before(beforeWrite))
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/samirprakash/go-bank/api/apierror"
+	db "github.com/samirprakash/go-bank/db/sqlc"
+)
+
+type feeScheduleResponse struct {
+	ID            int64     `json:"id"`
+	Currency      *string   `json:"currency"`
+	Tier          *string   `json:"tier"`
+	MinAmount     int64     `json:"min_amount"`
+	FlatFee       int64     `json:"flat_fee"`
+	PercentageBps int32     `json:"percentage_bps"`
+	MinFee        int64     `json:"min_fee"`
+	MaxFee        *int64    `json:"max_fee"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func newFeeScheduleResponse(schedule db.FeeSchedule) feeScheduleResponse {
+	return feeScheduleResponse{
+		ID:            schedule.ID,
+		Currency:      nullString(schedule.Currency),
+		Tier:          nullString(schedule.Tier),
+		MinAmount:     schedule.MinAmount,
+		FlatFee:       schedule.FlatFee,
+		PercentageBps: schedule.PercentageBps,
+		MinFee:        schedule.MinFee,
+		MaxFee:        nullInt64(schedule.MaxFee),
+		CreatedAt:     schedule.CreatedAt,
+	}
+}
+
+// nullString returns nil for a null string so that it is rendered as null in responses
+func nullString(s pgtype.Text) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
+
+// toNullString stores an optional string of a request, nil being stored as null
+func toNullString(s *string) pgtype.Text {
+	if s == nil {
+		return pgtype.Text{}
+	}
+	return pgtype.Text{String: *s, Valid: true}
+}
+
+type createFeeScheduleRequest struct {
+	Currency      *string `json:"currency" binding:"omitempty,currency"`
+	Tier          *string `json:"tier" binding:"omitempty,min=1"`
+	MinAmount     int64   `json:"min_amount" binding:"min=0"`
+	FlatFee       int64   `json:"flat_fee" binding:"min=0"`
+	PercentageBps int32   `json:"percentage_bps" binding:"min=0,max=10000"`
+	MinFee        int64   `json:"min_fee" binding:"min=0"`
+	MaxFee        *int64  `json:"max_fee" binding:"omitempty,gtefield=MinFee"`
+}
+
+// createFeeSchedule adds a band of fees for transfers of at least the minimum amount.
+// A schedule without currency or tier applies to all of them unless a more specific schedule matches.
+func (server *Server) createFeeSchedule(ctx *gin.Context) {
+	var req createFeeScheduleRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		abortWithError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	schedule, err := server.store.CreateFeeSchedule(ctx, db.CreateFeeScheduleParams{
+		Currency:      toNullString(req.Currency),
+		Tier:          toNullString(req.Tier),
+		MinAmount:     req.MinAmount,
+		FlatFee:       req.FlatFee,
+		PercentageBps: req.PercentageBps,
+		MinFee:        req.MinFee,
+		MaxFee:        toNullInt64(req.MaxFee),
+	})
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newFeeScheduleResponse(schedule))
+}
+
+type listFeeSchedulesRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+func (server *Server) listFeeSchedules(ctx *gin.Context) {
+	var req listFeeSchedulesRequest
+
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		abortWithError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	schedules, err := server.store.ListFeeSchedules(ctx, db.ListFeeSchedulesParams{
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	rsp := make([]feeScheduleResponse, len(schedules))
+	for i, schedule := range schedules {
+		rsp[i] = newFeeScheduleResponse(schedule)
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type feeScheduleURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// deleteFeeSchedule removes a band of fees, transfers already made keep the fee they were charged
+func (server *Server) deleteFeeSchedule(ctx *gin.Context) {
+	var uri feeScheduleURI
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		abortWithError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	err := server.store.DeleteFeeSchedule(ctx, uri.ID)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
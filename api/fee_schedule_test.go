@@ -0,0 +1,323 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/samirprakash/go-bank/api/apierror"
+	mockdb "github.com/samirprakash/go-bank/db/mock"
+	db "github.com/samirprakash/go-bank/db/sqlc"
+	"github.com/samirprakash/go-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuoteTransferAPI(t *testing.T) {
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+
+	account1 := randomAccount(user1.Username)
+	account2 := randomAccount(user2.Username)
+	account2.Currency = account1.Currency
+
+	fee := db.Fee{ScheduleID: 1, FlatAmount: 1, PercentageAmount: 2, Amount: 3}
+
+	testCases := []struct {
+		name          string
+		username      string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			username: user1.Username,
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          20,
+				"currency":        account1.Currency,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+
+				arg := db.TransferTxParams{
+					FromAccountID: account1.ID,
+					ToAccountID:   account2.ID,
+					Amount:        20,
+				}
+				store.EXPECT().QuoteTransferFee(gomock.Any(), gomock.Eq(arg)).Times(1).Return(fee, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp transferQuoteResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, fee, rsp.Fee)
+				require.Equal(t, int64(23), rsp.Total)
+			},
+		},
+		{
+			name:     "UnauthorizedUser",
+			username: user2.Username,
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          20,
+				"currency":        account1.Currency,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().QuoteTransferFee(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireBodyMatchError(t, recorder, apierror.CodePermissionDenied)
+			},
+		},
+		{
+			name:     "ToAccountNotFound",
+			username: user1.Username,
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          20,
+				"currency":        account1.Currency,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(db.Account{}, db.ErrRecordNotFound)
+				store.EXPECT().QuoteTransferFee(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				requireBodyMatchError(t, recorder, apierror.CodeNotFound)
+			},
+		},
+		{
+			name:     "InvalidAmount",
+			username: user1.Username,
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          -1,
+				"currency":        account1.Currency,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().QuoteTransferFee(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireBodyMatchError(t, recorder, apierror.CodeInvalidArgument)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/transfers/quote", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestFeeSchedulesAPI(t *testing.T) {
+	admin := "admin"
+	user, _ := randomUser(t)
+
+	schedule := db.FeeSchedule{
+		ID:            util.RandomInt(1, 1000),
+		Currency:      pgtype.Text{String: util.USD, Valid: true},
+		PercentageBps: 150,
+		MinFee:        10,
+		MaxFee:        pgtype.Int8{Int64: 500, Valid: true},
+	}
+
+	testCases := []struct {
+		name          string
+		username      string
+		method        string
+		url           string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "Create",
+			username: admin,
+			method:   http.MethodPost,
+			url:      "/fee_schedules",
+			body:     gin.H{"currency": util.USD, "percentage_bps": 150, "min_fee": 10, "max_fee": 500},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.CreateFeeScheduleParams{
+					Currency:      schedule.Currency,
+					PercentageBps: schedule.PercentageBps,
+					MinFee:        schedule.MinFee,
+					MaxFee:        schedule.MaxFee,
+				}
+				store.EXPECT().CreateFeeSchedule(gomock.Any(), gomock.Eq(arg)).Times(1).Return(schedule, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp feeScheduleResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, schedule.ID, rsp.ID)
+				require.Equal(t, util.USD, *rsp.Currency)
+				require.Nil(t, rsp.Tier)
+				require.Equal(t, int64(500), *rsp.MaxFee)
+			},
+		},
+		{
+			name:     "CreateByUser",
+			username: user.Username,
+			method:   http.MethodPost,
+			url:      "/fee_schedules",
+			body:     gin.H{"flat_fee": 1},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateFeeSchedule(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:     "CreateInvalidPercentage",
+			username: admin,
+			method:   http.MethodPost,
+			url:      "/fee_schedules",
+			body:     gin.H{"percentage_bps": 10001},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateFeeSchedule(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireBodyMatchError(t, recorder, apierror.CodeInvalidArgument)
+			},
+		},
+		{
+			name:     "CreateMaxFeeBelowMinFee",
+			username: admin,
+			method:   http.MethodPost,
+			url:      "/fee_schedules",
+			body:     gin.H{"min_fee": 10, "max_fee": 5},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateFeeSchedule(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireBodyMatchError(t, recorder, apierror.CodeInvalidArgument)
+			},
+		},
+		{
+			name:     "CreateDuplicateBand",
+			username: admin,
+			method:   http.MethodPost,
+			url:      "/fee_schedules",
+			body:     gin.H{"flat_fee": 1},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateFeeSchedule(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.FeeSchedule{}, &pgconn.PgError{Code: "23505", ConstraintName: "fee_schedules_band_key"})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireBodyMatchError(t, recorder, apierror.CodeAlreadyExists)
+			},
+		},
+		{
+			name:     "List",
+			username: admin,
+			method:   http.MethodGet,
+			url:      "/fee_schedules?page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListFeeSchedulesParams{Limit: 5, Offset: 0}
+				store.EXPECT().ListFeeSchedules(gomock.Any(), gomock.Eq(arg)).Times(1).Return([]db.FeeSchedule{schedule}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp []feeScheduleResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Len(t, rsp, 1)
+				require.Equal(t, schedule.ID, rsp[0].ID)
+			},
+		},
+		{
+			name:     "Delete",
+			username: admin,
+			method:   http.MethodDelete,
+			url:      "/fee_schedules/1",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().DeleteFeeSchedule(gomock.Any(), gomock.Eq(int64(1))).Times(1).Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			config := util.Config{
+				TokenSymmetricKey:   util.RandomString(32),
+				AccessTokenDuration: time.Minute,
+				AdminUsernames:      []string{admin},
+			}
+
+			server, err := NewServer(config, store, nil, nil)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(tc.method, tc.url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samirprakash/go-bank/db/migration"
+)
+
+// healthCheckTimeout bounds how long a single dependency check may take
+const healthCheckTimeout = 2 * time.Second
+
+const (
+	checkStatusOK   = "ok"
+	checkStatusFail = "fail"
+)
+
+type checkResult struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+}
+
+type healthCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// readinessChecks lists the dependencies the server needs to serve traffic
+func (server *Server) readinessChecks() []healthCheck {
+	checks := []healthCheck{
+		{name: "database", check: server.checkDatabase},
+		{name: "token_maker", check: server.checkTokenMaker},
+	}
+
+	if server.migrator != nil {
+		checks = append(checks, healthCheck{name: "migrations", check: server.checkMigrations})
+	}
+
+	return checks
+}
+
+func (server *Server) checkDatabase(ctx context.Context) error {
+	if server.store == nil {
+		return errors.New("store is not configured")
+	}
+	return server.store.Ping(ctx)
+}
+
+func (server *Server) checkTokenMaker(ctx context.Context) error {
+	if server.tokenMaker == nil {
+		return errors.New("token maker is not configured")
+	}
+
+	// a token that round trips proves the key is usable
+	token, _, err := server.tokenMaker.CreateToken("healthcheck", time.Minute)
+	if err != nil {
+		return err
+	}
+	_, err = server.tokenMaker.VerifyToken(token)
+	return err
+}
+
+func (server *Server) checkMigrations(ctx context.Context) error {
+	status, err := server.migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !status.UpToDate {
+		return fmt.Errorf("schema version %d (dirty: %t) is behind expected version %d", status.Version, status.Dirty, status.Latest)
+	}
+	return nil
+}
+
+// runChecks executes all checks and reports whether all of them passed
+func runChecks(ctx context.Context, checks []healthCheck) ([]checkResult, bool) {
+	results := make([]checkResult, 0, len(checks))
+	healthy := true
+
+	for _, c := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+		start := time.Now()
+		err := c.check(checkCtx)
+		cancel()
+
+		result := checkResult{
+			Name:     c.name,
+			Status:   checkStatusOK,
+			Duration: time.Since(start).String(),
+		}
+		if err != nil {
+			healthy = false
+			result.Status = checkStatusFail
+			result.Error = err.Error()
+		}
+
+		results = append(results, result)
+	}
+
+	return results, healthy
+}
+
+// healthz reports that the process is alive and able to serve HTTP requests
+func (server *Server) healthz(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": checkStatusOK})
+}
+
+// readyz reports whether all dependencies are available.
+// Errors are not returned as the endpoint is not authenticated.
+func (server *Server) readyz(ctx *gin.Context) {
+	results, healthy := runChecks(ctx, server.readinessChecks())
+
+	checks := make(map[string]string, len(results))
+	for _, result := range results {
+		checks[result.Name] = result.Status
+	}
+
+	if !healthy {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"status": checkStatusFail, "checks": checks})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": checkStatusOK, "checks": checks})
+}
+
+type statusResponse struct {
+	Status     string            `json:"status"`
+	StartedAt  time.Time         `json:"started_at"`
+	Uptime     string            `json:"uptime"`
+	Checks     []checkResult     `json:"checks"`
+	Migrations *migration.Status `json:"migrations,omitempty"`
+}
+
+// status reports the detailed state of the server and its dependencies for admins
+func (server *Server) status(ctx *gin.Context) {
+	results, healthy := runChecks(ctx, server.readinessChecks())
+
+	rsp := statusResponse{
+		Status:    checkStatusOK,
+		StartedAt: server.startedAt,
+		Uptime:    time.Since(server.startedAt).Round(time.Second).String(),
+		Checks:    results,
+	}
+	if !healthy {
+		rsp.Status = checkStatusFail
+	}
+
+	if server.migrator != nil {
+		migrationStatus, err := server.migrator.Status(ctx)
+		if err == nil {
+			rsp.Migrations = &migrationStatus
+		}
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
@@ -0,0 +1,293 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samirprakash/go-bank/api/apierror"
+	db "github.com/samirprakash/go-bank/db/sqlc"
+	"github.com/samirprakash/go-bank/metrics"
+	"github.com/samirprakash/go-bank/token"
+)
+
+type holdResponse struct {
+	ID             int64      `json:"id"`
+	AccountID      int64      `json:"account_id"`
+	ToAccountID    int64      `json:"to_account_id"`
+	Amount         int64      `json:"amount"`
+	Fee            int64      `json:"fee"`
+	Status         string     `json:"status"`
+	CapturedAmount int64      `json:"captured_amount"`
+	TransferID     *int64     `json:"transfer_id"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	FinishedAt     *time.Time `json:"finished_at"`
+}
+
+func newHoldResponse(hold db.Hold) holdResponse {
+	rsp := holdResponse{
+		ID:             hold.ID,
+		AccountID:      hold.AccountID,
+		ToAccountID:    hold.ToAccountID,
+		Amount:         hold.Amount,
+		Fee:            hold.Fee,
+		Status:         hold.Status,
+		CapturedAmount: hold.CapturedAmount,
+		ExpiresAt:      hold.ExpiresAt,
+		CreatedAt:      hold.CreatedAt,
+		FinishedAt:     nullTime(hold.FinishedAt),
+	}
+	if hold.TransferID.Valid {
+		rsp.TransferID = &hold.TransferID.Int64
+	}
+	return rsp
+}
+
+type createHoldRequest struct {
+	AccountID   int64      `json:"account_id" binding:"required,min=1"`
+	ToAccountID int64      `json:"to_account_id" binding:"required,min=1"`
+	Amount      int64      `json:"amount" binding:"required,gt=0"`
+	Currency    string     `json:"currency" binding:"required,currency"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+type createHoldResponse struct {
+	Hold    holdResponse `json:"hold"`
+	Account db.Account   `json:"account"`
+}
+
+// createHold reserves funds on an account of the authenticated user for a later capture by the recipient.
+// Holds expire after HOLD_DEFAULT_TTL unless an expiry is given.
+func (server *Server) createHold(ctx *gin.Context) {
+	var req createHoldRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		abortWithError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(server.config.HoldDefaultTTL)
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(now) {
+			apiErr := apierror.InvalidArgument("invalid request")
+			apiErr.Details = []apierror.FieldViolation{{
+				Field:   "expires_at",
+				Rule:    "future",
+				Message: "must be in the future",
+			}}
+			abortWithError(ctx, apiErr)
+			return
+		}
+		expiresAt = *req.ExpiresAt
+	}
+
+	account, err := server.accountInCurrency(ctx, req.AccountID, req.Currency)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if account.Owner != authPayload.Username {
+		abortWithError(ctx, apierror.PermissionDenied("account does not belong to the authenticated user"))
+		return
+	}
+
+	_, err = server.accountInCurrency(ctx, req.ToAccountID, req.Currency)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	arg := db.HoldTxParams{
+		AccountID:   req.AccountID,
+		ToAccountID: req.ToAccountID,
+		Amount:      req.Amount,
+		ExpiresAt:   expiresAt,
+	}
+
+	result, err := server.store.HoldTx(ctx, arg)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, createHoldResponse{
+		Hold:    newHoldResponse(result.Hold),
+		Account: result.Account,
+	})
+}
+
+type holdURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// getHoldForRecipient fetches the hold of the URI and checks that the authenticated user
+// owns the account credited on capture, or is an admin
+func (server *Server) getHoldForRecipient(ctx *gin.Context) (db.Hold, db.Account, bool) {
+	var uri holdURI
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		abortWithError(ctx, apierror.FromBinding(err))
+		return db.Hold{}, db.Account{}, false
+	}
+
+	hold, err := server.store.GetHold(ctx, uri.ID)
+	if err != nil {
+		abortWithError(ctx, err)
+		return hold, db.Account{}, false
+	}
+
+	toAccount, err := server.store.GetAccount(ctx, hold.ToAccountID)
+	if err != nil {
+		abortWithError(ctx, err)
+		return hold, toAccount, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if toAccount.Owner != authPayload.Username && !isAdmin(server.config.AdminUsernames, authPayload.Username) {
+		abortWithError(ctx, apierror.PermissionDenied("only the recipient of the hold can settle it"))
+		return hold, toAccount, false
+	}
+
+	return hold, toAccount, true
+}
+
+// getHold returns a hold to the owner of either account
+func (server *Server) getHold(ctx *gin.Context) {
+	var uri holdURI
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		abortWithError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	hold, err := server.store.GetHold(ctx, uri.ID)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	for _, accountID := range []int64{hold.AccountID, hold.ToAccountID} {
+		account, err := server.store.GetAccount(ctx, accountID)
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+
+		if account.Owner == authPayload.Username {
+			ctx.JSON(http.StatusOK, newHoldResponse(hold))
+			return
+		}
+	}
+
+	abortWithError(ctx, apierror.PermissionDenied("hold does not belong to the authenticated user"))
+}
+
+type listHoldsRequest struct {
+	AccountID int64 `form:"account_id" binding:"required,min=1"`
+	PageID    int32 `form:"page_id" binding:"required,min=1"`
+	PageSize  int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+// listHolds returns the holds placed on an account of the authenticated user, most recent first
+func (server *Server) listHolds(ctx *gin.Context) {
+	var req listHoldsRequest
+
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		abortWithError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	account, err := server.store.GetAccount(ctx, req.AccountID)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if account.Owner != authPayload.Username {
+		abortWithError(ctx, apierror.PermissionDenied("account does not belong to the authenticated user"))
+		return
+	}
+
+	holds, err := server.store.ListAccountHolds(ctx, db.ListAccountHoldsParams{
+		AccountID: account.ID,
+		Limit:     req.PageSize,
+		Offset:    (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	rsp := make([]holdResponse, len(holds))
+	for i, hold := range holds {
+		rsp[i] = newHoldResponse(hold)
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type captureHoldRequest struct {
+	Amount int64 `json:"amount" binding:"omitempty,gt=0"`
+}
+
+type captureHoldResponse struct {
+	db.TransferTxResult
+	Hold holdResponse `json:"hold"`
+}
+
+// captureHold settles all or part of a hold with a transfer to the recipient
+func (server *Server) captureHold(ctx *gin.Context) {
+	var req captureHoldRequest
+
+	hold, toAccount, ok := server.getHoldForRecipient(ctx)
+	if !ok {
+		return
+	}
+
+	// the body is optional : without it the whole hold is captured
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			abortWithError(ctx, apierror.FromBinding(err))
+			return
+		}
+	}
+
+	result, err := server.store.CaptureHoldTx(ctx, db.CaptureHoldTxParams{
+		HoldID: hold.ID,
+		Amount: req.Amount,
+	})
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	metrics.HoldFinished(db.HoldCaptured)
+	metrics.TransferCreated(toAccount.Currency, result.Transfer.Amount)
+	metrics.FeeCharged(toAccount.Currency, result.Fee.Amount)
+	ctx.JSON(http.StatusOK, captureHoldResponse{
+		TransferTxResult: result.TransferTxResult,
+		Hold:             newHoldResponse(result.Hold),
+	})
+}
+
+// voidHold cancels a hold and gives the reserved funds back to the payer
+func (server *Server) voidHold(ctx *gin.Context) {
+	hold, _, ok := server.getHoldForRecipient(ctx)
+	if !ok {
+		return
+	}
+
+	hold, err := server.store.VoidHoldTx(ctx, hold.ID)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	metrics.HoldFinished(db.HoldVoided)
+	ctx.JSON(http.StatusOK, newHoldResponse(hold))
+}
@@ -0,0 +1,312 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samirprakash/go-bank/api/apierror"
+	db "github.com/samirprakash/go-bank/db/sqlc"
+	"github.com/samirprakash/go-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+// integrationClient sends requests to a server backed by an in-memory store, through all its handlers and middlewares
+type integrationClient struct {
+	t           *testing.T
+	server      *Server
+	accessToken string
+}
+
+func (client *integrationClient) send(method string, url string, body gin.H, response interface{}) int {
+	var data []byte
+	if body != nil {
+		var err error
+		data, err = json.Marshal(body)
+		require.NoError(client.t, err)
+	}
+
+	request, err := http.NewRequest(method, url, bytes.NewReader(data))
+	require.NoError(client.t, err)
+	if client.accessToken != "" {
+		request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, client.accessToken))
+	}
+
+	recorder := httptest.NewRecorder()
+	client.server.router.ServeHTTP(recorder, request)
+
+	if response != nil {
+		require.NoError(client.t, json.Unmarshal(recorder.Body.Bytes(), response))
+	}
+	return recorder.Code
+}
+
+// newIntegrationServer creates a server backed by an in-memory store and a client logged in as its admin
+func newIntegrationServer(t *testing.T) (*Server, *integrationClient) {
+	adminUsername := util.RandomOwnerName()
+	config := util.Config{
+		TokenSymmetricKey:   util.RandomString(32),
+		AccessTokenDuration: time.Minute,
+		AdminUsernames:      []string{adminUsername},
+	}
+
+	server, err := NewServer(config, db.NewMemStore(), nil, nil)
+	require.NoError(t, err)
+
+	admin := &integrationClient{t: t, server: server}
+	admin.signUpAs(adminUsername)
+	return server, admin
+}
+
+// signUp creates a user and logs it in
+func (client *integrationClient) signUp() string {
+	return client.signUpAs(util.RandomOwnerName())
+}
+
+// signUpAs creates a user with the username and logs it in
+func (client *integrationClient) signUpAs(username string) string {
+	password := util.RandomString(6)
+
+	code := client.send(http.MethodPost, "/users", gin.H{
+		"username":  username,
+		"password":  password,
+		"full_name": util.RandomOwnerName(),
+		"email":     util.RandomEmail(),
+	}, nil)
+	require.Equal(client.t, http.StatusOK, code)
+
+	var login loginUserResponse
+	code = client.send(http.MethodPost, "/users/login", gin.H{
+		"username": username,
+		"password": password,
+	}, &login)
+	require.Equal(client.t, http.StatusOK, code)
+	require.Equal(client.t, username, login.User.Username)
+
+	client.accessToken = login.AccessToken
+	return username
+}
+
+func TestIntegrationTransfer(t *testing.T) {
+	server := newTestServer(t, db.NewMemStore())
+	sender := &integrationClient{t: t, server: server}
+	recipient := &integrationClient{t: t, server: server}
+	sender.signUp()
+	recipient.signUp()
+
+	var fromAccount, toAccount db.Account
+	code := sender.send(http.MethodPost, "/accounts", gin.H{"currency": util.USD}, &fromAccount)
+	require.Equal(t, http.StatusOK, code)
+	code = recipient.send(http.MethodPost, "/accounts", gin.H{"currency": util.USD}, &toAccount)
+	require.Equal(t, http.StatusOK, code)
+
+	// an account of each type per currency
+	var savings db.Account
+	code = sender.send(http.MethodPost, "/accounts", gin.H{"currency": util.USD, "type": db.AccountSavings}, &savings)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, db.AccountSavings, savings.Type)
+	require.Equal(t, util.USD, savings.Currency)
+	require.NotEqual(t, fromAccount.ID, savings.ID)
+
+	var apiErr apierror.Response
+	code = sender.send(http.MethodPost, "/accounts", gin.H{"currency": util.USD}, &apiErr)
+	require.Equal(t, http.StatusForbidden, code)
+	require.Equal(t, apierror.CodeAlreadyExists, apiErr.Error.Code)
+
+	code = sender.send(http.MethodPost, "/accounts", gin.H{"currency": util.USD, "type": db.AccountSavings}, &apiErr)
+	require.Equal(t, http.StatusForbidden, code)
+	require.Equal(t, apierror.CodeAlreadyExists, apiErr.Error.Code)
+
+	code = sender.send(http.MethodPatch, fmt.Sprintf("/accounts/%d", fromAccount.ID), gin.H{"amount": 500}, nil)
+	require.Equal(t, http.StatusOK, code)
+
+	transfer := gin.H{
+		"from_account_id": fromAccount.ID,
+		"to_account_id":   toAccount.ID,
+		"amount":          200,
+		"currency":        util.USD,
+	}
+	var result db.TransferTxResult
+	code = sender.send(http.MethodPost, "/transfers", transfer, &result)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, int64(300), result.FromAccount.Balance)
+	require.Equal(t, int64(200), result.ToAccount.Balance)
+
+	// the recipient cannot move the money of the sender
+	code = recipient.send(http.MethodPost, "/transfers", transfer, nil)
+	require.Equal(t, http.StatusForbidden, code)
+
+	transfer["amount"] = 301
+	code = sender.send(http.MethodPost, "/transfers", transfer, &apiErr)
+	require.Equal(t, http.StatusUnprocessableEntity, code)
+	require.Equal(t, apierror.CodeInsufficientFunds, apiErr.Error.Code)
+
+	var account db.Account
+	code = recipient.send(http.MethodGet, fmt.Sprintf("/accounts/%d", toAccount.ID), nil, &account)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, int64(200), account.Balance)
+
+	code = recipient.send(http.MethodGet, fmt.Sprintf("/accounts/%d", fromAccount.ID), nil, nil)
+	require.Equal(t, http.StatusForbidden, code)
+}
+
+// openAccount creates an account in the currency and deposits the amount on it
+func (client *integrationClient) openAccount(currency string, amount int64) db.Account {
+	var account db.Account
+	code := client.send(http.MethodPost, "/accounts", gin.H{"currency": currency}, &account)
+	require.Equal(client.t, http.StatusOK, code)
+
+	for amount > 0 {
+		deposit := amount
+		if deposit > 10000 {
+			// deposits are capped by the maximum amount of the standard tier
+			deposit = 10000
+		}
+		code = client.send(http.MethodPatch, fmt.Sprintf("/accounts/%d", account.ID), gin.H{"amount": deposit}, &account)
+		require.Equal(client.t, http.StatusOK, code)
+		amount -= deposit
+	}
+	return account
+}
+
+func TestIntegrationFrozenAccount(t *testing.T) {
+	server, admin := newIntegrationServer(t)
+	sender := &integrationClient{t: t, server: server}
+	recipient := &integrationClient{t: t, server: server}
+	sender.signUp()
+	recipient.signUp()
+
+	fromAccount := sender.openAccount(util.USD, 500)
+	toAccount := recipient.openAccount(util.USD, 100)
+
+	code := admin.send(http.MethodPost, fmt.Sprintf("/accounts/%d/freeze", fromAccount.ID), gin.H{"reason": "suspicious activity"}, nil)
+	require.Equal(t, http.StatusOK, code)
+
+	// a frozen account cannot send money
+	transfer := gin.H{
+		"from_account_id": fromAccount.ID,
+		"to_account_id":   toAccount.ID,
+		"amount":          100,
+		"currency":        util.USD,
+	}
+	var apiErr apierror.Response
+	code = sender.send(http.MethodPost, "/transfers", transfer, &apiErr)
+	require.Equal(t, http.StatusUnprocessableEntity, code)
+	require.Equal(t, apierror.CodeFailedPrecondition, apiErr.Error.Code)
+
+	// nor receive it
+	code = recipient.send(http.MethodPost, "/transfers", gin.H{
+		"from_account_id": toAccount.ID,
+		"to_account_id":   fromAccount.ID,
+		"amount":          100,
+		"currency":        util.USD,
+	}, &apiErr)
+	require.Equal(t, http.StatusUnprocessableEntity, code)
+	require.Equal(t, apierror.CodeFailedPrecondition, apiErr.Error.Code)
+
+	// until it is unfrozen
+	code = admin.send(http.MethodPost, fmt.Sprintf("/accounts/%d/unfreeze", fromAccount.ID), gin.H{"reason": "cleared"}, nil)
+	require.Equal(t, http.StatusOK, code)
+
+	var result db.TransferTxResult
+	code = sender.send(http.MethodPost, "/transfers", transfer, &result)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, int64(400), result.FromAccount.Balance)
+}
+
+func TestIntegrationTransferLimit(t *testing.T) {
+	server := newTestServer(t, db.NewMemStore())
+	sender := &integrationClient{t: t, server: server}
+	recipient := &integrationClient{t: t, server: server}
+	sender.signUp()
+	recipient.signUp()
+
+	fromAccount := sender.openAccount(util.USD, 20000)
+	toAccount := recipient.openAccount(util.USD, 0)
+
+	// the standard tier caps a single transfer at 10000
+	transfer := gin.H{
+		"from_account_id": fromAccount.ID,
+		"to_account_id":   toAccount.ID,
+		"amount":          10001,
+		"currency":        util.USD,
+	}
+	var apiErr apierror.Response
+	code := sender.send(http.MethodPost, "/transfers", transfer, &apiErr)
+	require.Equal(t, http.StatusUnprocessableEntity, code)
+	require.Equal(t, apierror.CodeLimitExceeded, apiErr.Error.Code)
+	require.Equal(t, "amount", apiErr.Error.Details[0].Field)
+
+	// and so does a hold
+	hold := gin.H{
+		"account_id":    fromAccount.ID,
+		"to_account_id": toAccount.ID,
+		"amount":        10001,
+		"currency":      util.USD,
+	}
+	code = sender.send(http.MethodPost, "/holds", hold, &apiErr)
+	require.Equal(t, http.StatusUnprocessableEntity, code)
+	require.Equal(t, apierror.CodeLimitExceeded, apiErr.Error.Code)
+
+	var account db.Account
+	code = sender.send(http.MethodGet, fmt.Sprintf("/accounts/%d", fromAccount.ID), nil, &account)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, int64(20000), account.Balance)
+	require.Equal(t, int64(20000), account.AvailableBalance)
+}
+
+func TestIntegrationHoldCapture(t *testing.T) {
+	server := newTestServer(t, db.NewMemStore())
+	payer := &integrationClient{t: t, server: server}
+	merchant := &integrationClient{t: t, server: server}
+	payer.signUp()
+	merchant.signUp()
+
+	fromAccount := payer.openAccount(util.USD, 500)
+	toAccount := merchant.openAccount(util.USD, 0)
+
+	var created createHoldResponse
+	code := payer.send(http.MethodPost, "/holds", gin.H{
+		"account_id":    fromAccount.ID,
+		"to_account_id": toAccount.ID,
+		"amount":        200,
+		"currency":      util.USD,
+		"expires_at":    time.Now().Add(time.Hour),
+	}, &created)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, db.HoldActive, created.Hold.Status)
+
+	// the funds on hold cannot be spent
+	var apiErr apierror.Response
+	code = payer.send(http.MethodPost, "/transfers", gin.H{
+		"from_account_id": fromAccount.ID,
+		"to_account_id":   toAccount.ID,
+		"amount":          301,
+		"currency":        util.USD,
+	}, &apiErr)
+	require.Equal(t, http.StatusUnprocessableEntity, code)
+	require.Equal(t, apierror.CodeInsufficientFunds, apiErr.Error.Code)
+
+	// only the recipient captures the hold
+	captureURL := fmt.Sprintf("/holds/%d/capture", created.Hold.ID)
+	code = payer.send(http.MethodPost, captureURL, nil, nil)
+	require.Equal(t, http.StatusForbidden, code)
+
+	var captured captureHoldResponse
+	code = merchant.send(http.MethodPost, captureURL, gin.H{"amount": 150}, &captured)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, db.HoldCaptured, captured.Hold.Status)
+	require.Equal(t, int64(150), captured.Hold.CapturedAmount)
+	require.Equal(t, int64(350), captured.FromAccount.Balance)
+	require.Zero(t, captured.FromAccount.HeldAmount)
+	require.Equal(t, int64(150), captured.ToAccount.Balance)
+
+	code = merchant.send(http.MethodPost, captureURL, nil, &apiErr)
+	require.Equal(t, http.StatusUnprocessableEntity, code)
+	require.Equal(t, apierror.CodeFailedPrecondition, apiErr.Error.Code)
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samirprakash/go-bank/api/apierror"
+	db "github.com/samirprakash/go-bank/db/sqlc"
+	"github.com/samirprakash/go-bank/token"
+)
+
+type interestPostingResponse struct {
+	// Month is the first day of the month the interest was accrued in
+	Month      string    `json:"month"`
+	Amount     int64     `json:"amount"`
+	TransferID *int64    `json:"transfer_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func newInterestPostingResponse(posting db.InterestPosting) interestPostingResponse {
+	return interestPostingResponse{
+		Month:      posting.Month.Format("2006-01-02"),
+		Amount:     posting.Amount,
+		TransferID: nullInt64(posting.TransferID),
+		CreatedAt:  posting.CreatedAt,
+	}
+}
+
+type listInterestPostingsURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type listInterestPostingsRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+// listInterestPostings returns the interest paid monthly on a savings account to its owner, most recent first
+func (server *Server) listInterestPostings(ctx *gin.Context) {
+	var uri listInterestPostingsURI
+	var req listInterestPostingsRequest
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		abortWithError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		abortWithError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	account, err := server.store.GetAccount(ctx, uri.ID)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if account.Owner != authPayload.Username {
+		abortWithError(ctx, apierror.PermissionDenied("account does not belong to the authenticated user"))
+		return
+	}
+
+	postings, err := server.store.ListInterestPostings(ctx, db.ListInterestPostingsParams{
+		AccountID: account.ID,
+		Limit:     req.PageSize,
+		Offset:    (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	rsp := make([]interestPostingResponse, len(postings))
+	for i, posting := range postings {
+		rsp[i] = newInterestPostingResponse(posting)
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+func (server *Server) listInterestRates(ctx *gin.Context) {
+	rates, err := server.store.ListInterestRates(ctx)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rates)
+}
+
+type updateInterestRateURI struct {
+	Currency string `uri:"currency" binding:"required,currency"`
+}
+
+type updateInterestRateRequest struct {
+	AnnualRateBps *int32 `json:"annual_rate_bps" binding:"required,min=0,max=10000"`
+	DayCount      string `json:"day_count" binding:"required,oneof=ACT/365 ACT/360 30/360"`
+}
+
+// updateInterestRate sets the annual rate paid on the savings accounts of a currency.
+// Days already accrued keep the rate they were accrued at.
+func (server *Server) updateInterestRate(ctx *gin.Context) {
+	var uri updateInterestRateURI
+	var req updateInterestRateRequest
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		abortWithError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		abortWithError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	rate, err := server.store.UpsertInterestRate(ctx, db.UpsertInterestRateParams{
+		Currency:      uri.Currency,
+		AnnualRateBps: *req.AnnualRateBps,
+		DayCount:      req.DayCount,
+	})
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rate)
+}
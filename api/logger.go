@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/samirprakash/go-bank/token"
+	"github.com/samirprakash/go-bank/util"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	requestIDHeaderKey = "X-Request-ID"
+	requestIDKey       = "request_id"
+)
+
+// validRequestID restricts incoming request IDs to something safe to log and echo back
+var validRequestID = regexp.MustCompile(`^[a-zA-Z0-9._-]{1,128}$`)
+
+// requestIDMiddleware accepts the X-Request-ID header from the client or generates a new ID.
+// The ID is returned in the response and stored in the request context along with a logger
+// that includes it, so that it is available to every layer handling the request.
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requestID := ctx.GetHeader(requestIDHeaderKey)
+		if !validRequestID.MatchString(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		ctx.Set(requestIDKey, requestID)
+		ctx.Header(requestIDHeaderKey, requestID)
+
+		loggerContext := log.With().Str(requestIDKey, requestID)
+		// link the logs of the request to its trace
+		if spanContext := trace.SpanContextFromContext(ctx.Request.Context()); spanContext.HasTraceID() {
+			loggerContext = loggerContext.Str("trace_id", spanContext.TraceID().String())
+		}
+		logger := loggerContext.Logger()
+		reqCtx := util.ContextWithRequestID(ctx.Request.Context(), requestID)
+		ctx.Request = ctx.Request.WithContext(logger.WithContext(reqCtx))
+
+		ctx.Next()
+	}
+}
+
+// loggerMiddleware writes a structured log line for every request once it has been handled
+func loggerMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+		duration := time.Since(start)
+
+		logger := zerolog.Ctx(ctx.Request.Context())
+		status := ctx.Writer.Status()
+
+		event := logger.Info()
+		if status >= 500 {
+			event = logger.Error()
+		}
+		if len(ctx.Errors) > 0 {
+			event = event.Str("errors", ctx.Errors.String())
+		}
+
+		if payload, exists := ctx.Get(authorizationPayloadKey); exists {
+			event = event.Str("username", payload.(*token.Payload).Username)
+		}
+
+		event.
+			Str("protocol", "http").
+			Str("method", ctx.Request.Method).
+			Str("path", ctx.Request.URL.Path).
+			Str("route", ctx.FullPath()).
+			Int("status_code", status).
+			Str("status_text", http.StatusText(status)).
+			Dur("duration", duration).
+			Str("client_ip", ctx.ClientIP()).
+			Msg("received an HTTP request")
+	}
+}
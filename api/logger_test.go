@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samirprakash/go-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	testCases := []struct {
+		name          string
+		requestID     string
+		checkResponse func(t *testing.T, requestID string, gotID string)
+	}{
+		{
+			name:      "Propagated",
+			requestID: "abc-123_DEF.456",
+			checkResponse: func(t *testing.T, requestID string, gotID string) {
+				require.Equal(t, requestID, gotID)
+			},
+		},
+		{
+			name:      "Generated",
+			requestID: "",
+			checkResponse: func(t *testing.T, requestID string, gotID string) {
+				require.NotEmpty(t, gotID)
+			},
+		},
+		{
+			name:      "Invalid",
+			requestID: strings.Repeat("a", 129),
+			checkResponse: func(t *testing.T, requestID string, gotID string) {
+				require.NotEmpty(t, gotID)
+				require.NotEqual(t, requestID, gotID)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			server := newTestServer(t, nil)
+
+			// the handler sees the same request ID through the request context
+			var contextID string
+			path := "/request_id"
+			server.router.GET(path, func(ctx *gin.Context) {
+				contextID = util.RequestIDFromContext(ctx)
+				ctx.JSON(http.StatusOK, gin.H{})
+			})
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, path, nil)
+			require.NoError(t, err)
+			if tc.requestID != "" {
+				request.Header.Set(requestIDHeaderKey, tc.requestID)
+			}
+
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusOK, recorder.Code)
+
+			gotID := recorder.Header().Get(requestIDHeaderKey)
+			require.Equal(t, gotID, contextID)
+			tc.checkResponse(t, tc.requestID, gotID)
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package api
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samirprakash/go-bank/metrics"
+)
+
+// unmatchedRoute labels requests that did not match any route so that random paths do not create new series
+const unmatchedRoute = "unmatched"
+
+// metricsMiddleware records the count and latency of every request by route
+func metricsMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		metrics.ObserveHTTPRequest(ctx.Request.Method, route, ctx.Writer.Status(), time.Since(start))
+	}
+}
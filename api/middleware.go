@@ -1,13 +1,14 @@
 package api
 
 import (
-	"errors"
+	"context"
 	"fmt"
-	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/samirprakash/go-bank/api/apierror"
 	"github.com/samirprakash/go-bank/token"
+	"github.com/samirprakash/go-bank/tracing"
 )
 
 const (
@@ -16,33 +17,41 @@ const (
 	authorizationPayloadKey = "authrorization_payload"
 )
 
+// verifyToken verifies the token in its own span so that its cost shows up in traces
+func verifyToken(ctx context.Context, tokenMaker token.Maker, tokenString string) (*token.Payload, error) {
+	var payload *token.Payload
+	err := tracing.Trace(ctx, "token.VerifyToken", func(context.Context) error {
+		var err error
+		payload, err = tokenMaker.VerifyToken(tokenString)
+		return err
+	})
+	return payload, err
+}
+
 func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authorizationHeader := ctx.GetHeader(authorizationHeaderKey)
 		if len(authorizationHeader) == 0 {
-			err := errors.New("authorization header not provided")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			abortWithError(ctx, apierror.Unauthenticated("authorization header not provided"))
 			return
 		}
 
 		fields := strings.Fields(authorizationHeader)
 		if len(fields) < 2 {
-			err := errors.New("invalid authorization header format")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			abortWithError(ctx, apierror.Unauthenticated("invalid authorization header format"))
 			return
 		}
 
 		authorizationType := strings.ToLower(fields[0])
 		if authorizationType != authorizationTypeBearer {
-			err := fmt.Errorf("unsupported token type %s", authorizationType)
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			abortWithError(ctx, apierror.Unauthenticated(fmt.Sprintf("unsupported token type %s", authorizationType)))
 			return
 		}
 
 		accessToken := fields[1]
-		payload, err := tokenMaker.VerifyToken(accessToken)
+		payload, err := verifyToken(ctx, tokenMaker, accessToken)
 		if err != nil {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			abortWithError(ctx, err)
 			return
 		}
 
@@ -50,3 +59,28 @@ func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
 		ctx.Next()
 	}
 }
+
+// adminMiddleware only lets through authenticated users listed as admins in the config.
+// It must run after authMiddleware.
+func adminMiddleware(admins []string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+		if !isAdmin(admins, authPayload.Username) {
+			abortWithError(ctx, apierror.PermissionDenied("user is not allowed to access this resource"))
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// isAdmin checks if the user is one of the configured admins
+func isAdmin(admins []string, username string) bool {
+	for _, admin := range admins {
+		if admin == username {
+			return true
+		}
+	}
+	return false
+}
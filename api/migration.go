@@ -0,0 +1,23 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samirprakash/go-bank/api/apierror"
+)
+
+func (server *Server) getMigrationStatus(ctx *gin.Context) {
+	if server.migrator == nil {
+		abortWithError(ctx, apierror.Unavailable("database migrations are not managed by this server"))
+		return
+	}
+
+	status, err := server.migrator.Status(ctx)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, status)
+}
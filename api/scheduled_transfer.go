@@ -0,0 +1,337 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/samirprakash/go-bank/api/apierror"
+	db "github.com/samirprakash/go-bank/db/sqlc"
+	"github.com/samirprakash/go-bank/scheduler"
+	"github.com/samirprakash/go-bank/token"
+)
+
+type scheduledTransferResponse struct {
+	ID            int64      `json:"id"`
+	FromAccountID int64      `json:"from_account_id"`
+	ToAccountID   int64      `json:"to_account_id"`
+	Amount        int64      `json:"amount"`
+	Currency      string     `json:"currency"`
+	Schedule      string     `json:"schedule"`
+	StartAt       time.Time  `json:"start_at"`
+	EndAt         *time.Time `json:"end_at"`
+	NextRunAt     *time.Time `json:"next_run_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+func newScheduledTransferResponse(scheduledTransfer db.ScheduledTransfer) scheduledTransferResponse {
+	return scheduledTransferResponse{
+		ID:            scheduledTransfer.ID,
+		FromAccountID: scheduledTransfer.FromAccountID,
+		ToAccountID:   scheduledTransfer.ToAccountID,
+		Amount:        scheduledTransfer.Amount,
+		Currency:      scheduledTransfer.Currency,
+		Schedule:      scheduledTransfer.Schedule,
+		StartAt:       scheduledTransfer.StartAt,
+		EndAt:         nullTime(scheduledTransfer.EndAt),
+		NextRunAt:     nullTime(scheduledTransfer.NextRunAt),
+		CreatedAt:     scheduledTransfer.CreatedAt,
+	}
+}
+
+type scheduledTransferRunResponse struct {
+	ID           int64      `json:"id"`
+	ScheduledFor time.Time  `json:"scheduled_for"`
+	Status       string     `json:"status"`
+	TransferID   *int64     `json:"transfer_id"`
+	Error        string     `json:"error,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	FinishedAt   *time.Time `json:"finished_at"`
+}
+
+func newScheduledTransferRunResponse(run db.ScheduledTransferRun) scheduledTransferRunResponse {
+	rsp := scheduledTransferRunResponse{
+		ID:           run.ID,
+		ScheduledFor: run.ScheduledFor,
+		Status:       run.Status,
+		Error:        run.Error,
+		CreatedAt:    run.CreatedAt,
+		FinishedAt:   nullTime(run.FinishedAt),
+	}
+	if run.TransferID.Valid {
+		rsp.TransferID = &run.TransferID.Int64
+	}
+	return rsp
+}
+
+// nullTime returns nil for a null timestamp so that it is rendered as null in responses
+func nullTime(t pgtype.Timestamptz) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+type createScheduledTransferRequest struct {
+	FromAccountID int64      `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID   int64      `json:"to_account_id" binding:"required,min=1"`
+	Amount        int64      `json:"amount" binding:"required,gt=0"`
+	Currency      string     `json:"currency" binding:"required,currency"`
+	Schedule      string     `json:"schedule" binding:"required,schedule"`
+	StartAt       *time.Time `json:"start_at"`
+	EndAt         *time.Time `json:"end_at"`
+}
+
+func (server *Server) createScheduledTransfer(ctx *gin.Context) {
+	var req createScheduledTransferRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		abortWithError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	startAt := time.Now()
+	if req.StartAt != nil {
+		startAt = *req.StartAt
+	}
+
+	endAt, err := scheduleEnd(startAt, req.EndAt)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	fromAccount, err := server.accountInCurrency(ctx, req.FromAccountID, req.Currency)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if fromAccount.Owner != authPayload.Username {
+		abortWithError(ctx, apierror.PermissionDenied("from account does not belong to the authenticated user"))
+		return
+	}
+
+	_, err = server.accountInCurrency(ctx, req.ToAccountID, req.Currency)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	// the schedule has already been validated by the binding
+	schedule, _ := scheduler.ParseSchedule(req.Schedule)
+
+	arg := db.CreateScheduledTransferParams{
+		Owner:         authPayload.Username,
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        req.Amount,
+		Currency:      req.Currency,
+		Schedule:      req.Schedule,
+		StartAt:       startAt,
+		EndAt:         endAt,
+		NextRunAt:     scheduler.InitialRunAt(schedule, startAt, endAt),
+	}
+
+	scheduledTransfer, err := server.store.CreateScheduledTransfer(ctx, arg)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newScheduledTransferResponse(scheduledTransfer))
+}
+
+// scheduleEnd checks that a schedule does not end before it starts
+func scheduleEnd(startAt time.Time, endAt *time.Time) (pgtype.Timestamptz, error) {
+	if endAt == nil {
+		return pgtype.Timestamptz{}, nil
+	}
+
+	if !endAt.After(startAt) {
+		apiErr := apierror.InvalidArgument("invalid request")
+		apiErr.Details = []apierror.FieldViolation{{
+			Field:   "end_at",
+			Rule:    "gtfield",
+			Message: "must be after start_at",
+		}}
+		return pgtype.Timestamptz{}, apiErr
+	}
+
+	return pgtype.Timestamptz{Time: *endAt, Valid: true}, nil
+}
+
+type scheduledTransferURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// getOwnedScheduledTransfer fetches the scheduled transfer of the URI and checks that it belongs to the authenticated user
+func (server *Server) getOwnedScheduledTransfer(ctx *gin.Context) (db.ScheduledTransfer, bool) {
+	var uri scheduledTransferURI
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		abortWithError(ctx, apierror.FromBinding(err))
+		return db.ScheduledTransfer{}, false
+	}
+
+	scheduledTransfer, err := server.store.GetScheduledTransfer(ctx, uri.ID)
+	if err != nil {
+		abortWithError(ctx, err)
+		return scheduledTransfer, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if scheduledTransfer.Owner != authPayload.Username {
+		abortWithError(ctx, apierror.PermissionDenied("scheduled transfer does not belong to the authenticated user"))
+		return scheduledTransfer, false
+	}
+
+	return scheduledTransfer, true
+}
+
+func (server *Server) getScheduledTransfer(ctx *gin.Context) {
+	scheduledTransfer, ok := server.getOwnedScheduledTransfer(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newScheduledTransferResponse(scheduledTransfer))
+}
+
+type listScheduledTransfersRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+func (server *Server) listScheduledTransfers(ctx *gin.Context) {
+	var req listScheduledTransfersRequest
+
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		abortWithError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	arg := db.ListScheduledTransfersParams{
+		Owner:  authPayload.Username,
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	}
+
+	scheduledTransfers, err := server.store.ListScheduledTransfers(ctx, arg)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	rsp := make([]scheduledTransferResponse, len(scheduledTransfers))
+	for i, scheduledTransfer := range scheduledTransfers {
+		rsp[i] = newScheduledTransferResponse(scheduledTransfer)
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type updateScheduledTransferRequest struct {
+	Amount   int64      `json:"amount" binding:"required,gt=0"`
+	Schedule string     `json:"schedule" binding:"required,schedule"`
+	EndAt    *time.Time `json:"end_at"`
+}
+
+// updateScheduledTransfer replaces the amount, schedule and end of a scheduled transfer.
+// The next run is computed again from the new schedule, which also resumes a schedule that had ended.
+func (server *Server) updateScheduledTransfer(ctx *gin.Context) {
+	var req updateScheduledTransferRequest
+
+	scheduledTransfer, ok := server.getOwnedScheduledTransfer(ctx)
+	if !ok {
+		return
+	}
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		abortWithError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	endAt, err := scheduleEnd(scheduledTransfer.StartAt, req.EndAt)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	scheduledTransfer.Schedule = req.Schedule
+	scheduledTransfer.EndAt = endAt
+	scheduledTransfer.NextRunAt = pgtype.Timestamptz{}
+
+	arg := db.UpdateScheduledTransferParams{
+		ID:        scheduledTransfer.ID,
+		Amount:    req.Amount,
+		Schedule:  req.Schedule,
+		EndAt:     endAt,
+		NextRunAt: scheduler.NextRunAt(scheduledTransfer, time.Now()),
+	}
+
+	scheduledTransfer, err = server.store.UpdateScheduledTransfer(ctx, arg)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newScheduledTransferResponse(scheduledTransfer))
+}
+
+// deleteScheduledTransfer cancels a scheduled transfer along with its run history.
+// Transfers already executed are not affected.
+func (server *Server) deleteScheduledTransfer(ctx *gin.Context) {
+	scheduledTransfer, ok := server.getOwnedScheduledTransfer(ctx)
+	if !ok {
+		return
+	}
+
+	err := server.store.DeleteScheduledTransfer(ctx, scheduledTransfer.ID)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+type listScheduledTransferRunsRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+// listScheduledTransferRuns returns the history of the runs of a scheduled transfer, most recent first
+func (server *Server) listScheduledTransferRuns(ctx *gin.Context) {
+	var req listScheduledTransferRunsRequest
+
+	scheduledTransfer, ok := server.getOwnedScheduledTransfer(ctx)
+	if !ok {
+		return
+	}
+
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		abortWithError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	arg := db.ListScheduledTransferRunsParams{
+		ScheduledTransferID: scheduledTransfer.ID,
+		Limit:               req.PageSize,
+		Offset:              (req.PageID - 1) * req.PageSize,
+	}
+
+	runs, err := server.store.ListScheduledTransferRuns(ctx, arg)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	rsp := make([]scheduledTransferRunResponse, len(runs))
+	for i, run := range runs {
+		rsp[i] = newScheduledTransferRunResponse(run)
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
@@ -1,71 +1,180 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
+	"github.com/samirprakash/go-bank/api/apierror"
+	"github.com/samirprakash/go-bank/db/migration"
 	db "github.com/samirprakash/go-bank/db/sqlc"
+	"github.com/samirprakash/go-bank/metrics"
+	"github.com/samirprakash/go-bank/realtime"
 	"github.com/samirprakash/go-bank/token"
+	"github.com/samirprakash/go-bank/tracing"
 	"github.com/samirprakash/go-bank/util"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 // Server serves HTTP requests for the banking service
 type Server struct {
 	config     util.Config
 	store      db.Store
+	migrator   *migration.Migrator
+	hub        *realtime.Hub
+	writes     *writeTracker
 	tokenMaker token.Maker
 	router     *gin.Engine
+	httpServer *http.Server
+	startedAt  time.Time
 }
 
-// NewServer creates a new HTTP server and sets up routing
-func NewServer(config util.Config, store db.Store) (*Server, error) {
+// NewServer creates a new HTTP server and sets up routing.
+// The migrator is optional and is only used to report the schema migration status.
+// The hub is optional too, account streams are unavailable without it.
+func NewServer(config util.Config, store db.Store, migrator *migration.Migrator, hub *realtime.Hub) (*Server, error) {
 	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create token maker : %w", err)
 	}
 
+	// only replicas can serve reads that miss the previous writes of their user
+	var readYourWritesWindow time.Duration
+	if len(config.DBReplicaSources) > 0 {
+		readYourWritesWindow = config.DBReplicaMaxLag
+	}
+
 	server := &Server{
 		config:     config,
 		store:      store,
+		migrator:   migrator,
+		hub:        hub,
+		writes:     newWriteTracker(readYourWritesWindow),
 		tokenMaker: tokenMaker,
+		startedAt:  time.Now(),
 	}
 
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
-		v.RegisterValidation("customCurrencyValidator", validateCurrency)
+		v.RegisterValidation("currency", validateCurrency)
+		v.RegisterValidation("schedule", validateSchedule)
+		v.RegisterValidation("webhook_event", validateWebhookEvent)
+		v.RegisterTagNameFunc(fieldName)
 	}
 
 	server.setupRouter()
+
+	server.httpServer = &http.Server{
+		Addr:              config.ServerAddress,
+		Handler:           server.router,
+		ReadTimeout:       config.ServerReadTimeout,
+		ReadHeaderTimeout: config.ServerReadTimeout,
+		WriteTimeout:      config.ServerWriteTimeout,
+		IdleTimeout:       config.ServerIdleTimeout,
+	}
+
 	return server, nil
 }
 
 func (server *Server) setupRouter() {
-	router := gin.Default()
+	router := gin.New()
+	// pass the request context to the store through gin.Context so it carries the request ID
+	router.ContextWithFallback = true
+	router.Use(otelgin.Middleware(tracing.ServiceName), requestIDMiddleware(), loggerMiddleware(), metricsMiddleware(), gin.Recovery())
 
 	router.POST("/users", server.createUser)
 	router.POST("/users/login", server.loginUser)
 	router.POST("/tokens/renew_access", server.renewAccessToken)
 
-	authRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker))
+	router.GET("/healthz", server.healthz)
+	router.GET("/readyz", server.readyz)
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	// browsers cannot set the authorization header of event sources and WebSockets
+	router.GET("/accounts/:id/stream", streamTokenMiddleware(), authMiddleware(server.tokenMaker), readYourWritesMiddleware(server.writes), server.streamAccount)
+
+	authRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker), readYourWritesMiddleware(server.writes))
 
 	authRoutes.POST("/accounts", server.createAccount)
 	authRoutes.GET("/accounts/:id", server.getAccount)
 	authRoutes.GET("/accounts", server.listAccounts)
 	authRoutes.PATCH("/accounts/:id", server.updateAccountBalance)
-	authRoutes.DELETE("/accounts/:id", server.deleteAccount)
+	authRoutes.DELETE("/accounts/:id", server.closeAccount)
+	authRoutes.GET("/accounts/:id/limits", server.getAccountLimits)
+	authRoutes.GET("/accounts/:id/interest", server.listInterestPostings)
+	authRoutes.GET("/accounts/:id/statement", server.getStatement)
 
 	authRoutes.POST("/transfers", server.createTransfer)
+	authRoutes.POST("/transfers/batch", server.createBatchTransfer)
+	authRoutes.POST("/transfers/quote", server.quoteTransfer)
+	authRoutes.POST("/transfers/:id/reverse", server.reverseTransfer)
+
+	authRoutes.POST("/holds", server.createHold)
+	authRoutes.GET("/holds/:id", server.getHold)
+	authRoutes.GET("/holds", server.listHolds)
+	authRoutes.POST("/holds/:id/capture", server.captureHold)
+	authRoutes.POST("/holds/:id/void", server.voidHold)
+
+	authRoutes.POST("/scheduled_transfers", server.createScheduledTransfer)
+	authRoutes.GET("/scheduled_transfers/:id", server.getScheduledTransfer)
+	authRoutes.GET("/scheduled_transfers", server.listScheduledTransfers)
+	authRoutes.PUT("/scheduled_transfers/:id", server.updateScheduledTransfer)
+	authRoutes.DELETE("/scheduled_transfers/:id", server.deleteScheduledTransfer)
+	authRoutes.GET("/scheduled_transfers/:id/runs", server.listScheduledTransferRuns)
+
+	authRoutes.POST("/webhooks", server.createWebhook)
+	authRoutes.GET("/webhooks/:id", server.getWebhook)
+	authRoutes.GET("/webhooks", server.listWebhooks)
+	authRoutes.DELETE("/webhooks/:id", server.deleteWebhook)
+	authRoutes.GET("/webhooks/:id/deliveries", server.listWebhookDeliveries)
+
+	adminRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker), readYourWritesMiddleware(server.writes), adminMiddleware(server.config.AdminUsernames))
+
+	adminRoutes.GET("/status", server.status)
+	adminRoutes.GET("/migrations/status", server.getMigrationStatus)
+	adminRoutes.POST("/accounts/:id/freeze", server.freezeAccount)
+	adminRoutes.POST("/accounts/:id/unfreeze", server.unfreezeAccount)
+	adminRoutes.GET("/accounts/:id/status_changes", server.listAccountStatusChanges)
+	adminRoutes.PUT("/accounts/:id/limits", server.updateAccountLimits)
+	adminRoutes.PUT("/users/:username/tier", server.updateUserTier)
+	adminRoutes.POST("/fee_schedules", server.createFeeSchedule)
+	adminRoutes.GET("/fee_schedules", server.listFeeSchedules)
+	adminRoutes.DELETE("/fee_schedules/:id", server.deleteFeeSchedule)
+	adminRoutes.GET("/interest_rates", server.listInterestRates)
+	adminRoutes.PUT("/interest_rates/:currency", server.updateInterestRate)
 
 	server.router = router
 }
 
-// Start starts a new server on the specified address
-func (server *Server) Start(address string) error {
-	return server.router.Run(address)
+// Start starts serving HTTP requests on the configured address.
+// It blocks until the server fails or is stopped with Shutdown, in which case it returns nil.
+func (server *Server) Start() error {
+	err := server.httpServer.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops accepting new connections and waits for in-flight requests to complete
+// until the context is done. Account streams never complete on their own, so they are closed first.
+func (server *Server) Shutdown(ctx context.Context) error {
+	if server.hub != nil {
+		server.hub.Close()
+	}
+	return server.httpServer.Shutdown(ctx)
 }
 
-// returns custom error messages
-func errorResponse(err error) gin.H {
-	return gin.H{"error": err.Error()}
+// abortWithError writes the API error matching err and stops the handler chain.
+// The original error is attached to the context so that it gets logged with the request.
+func abortWithError(ctx *gin.Context, err error) {
+	apiErr := *apierror.FromError(err)
+	apiErr.RequestID = ctx.GetString(requestIDKey)
+
+	_ = ctx.Error(err)
+	ctx.AbortWithStatusJSON(apiErr.Status, apierror.Response{Error: &apiErr})
 }
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/samirprakash/go-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerShutdown(t *testing.T) {
+	config := util.Config{
+		ServerAddress:     "127.0.0.1:0",
+		TokenSymmetricKey: util.RandomString(32),
+	}
+
+	server, err := NewServer(config, nil, nil, nil)
+	require.NoError(t, err)
+
+	errs := make(chan error)
+	go func() {
+		errs <- server.Start()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err = server.Shutdown(ctx)
+	require.NoError(t, err)
+
+	// a graceful shutdown is not reported as an error
+	select {
+	case err := <-errs:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("server did not stop after shutdown")
+	}
+}
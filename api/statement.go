@@ -0,0 +1,120 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samirprakash/go-bank/api/apierror"
+	db "github.com/samirprakash/go-bank/db/sqlc"
+	"github.com/samirprakash/go-bank/statement"
+	"github.com/samirprakash/go-bank/token"
+)
+
+// maxStatementDays bounds the period of a statement
+const maxStatementDays = 366
+
+type statementURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type statementRequest struct {
+	// From and To are the first and last days of the statement in UTC
+	From   time.Time `form:"from" binding:"required" time_format:"2006-01-02" time_utc:"1"`
+	To     time.Time `form:"to" binding:"required" time_format:"2006-01-02" time_utc:"1"`
+	Format string    `form:"format" binding:"omitempty,oneof=csv json pdf camt053 mt940"`
+}
+
+// getStatement streams the statement of an account over a period to its owner or an admin.
+// Once the statement has started, an error can no longer change the response and the statement is cut short.
+func (server *Server) getStatement(ctx *gin.Context) {
+	var uri statementURI
+	var req statementRequest
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		abortWithError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		abortWithError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	if req.Format == "" {
+		req.Format = statement.FormatJSON
+	}
+
+	// the statement includes the whole last day
+	to := req.To.AddDate(0, 0, 1)
+	if !to.After(req.From) || to.Sub(req.From) > maxStatementDays*24*time.Hour {
+		abortWithError(ctx, apierror.InvalidArgument(fmt.Sprintf("to must be after from and the period at most %d days", maxStatementDays)))
+		return
+	}
+
+	// the closing balance of an end-of-day statement is read up front, and only stays true once the day is over
+	endOfDay := statement.EndOfDay(req.Format)
+	if endOfDay && to.After(time.Now().UTC()) {
+		abortWithError(ctx, apierror.InvalidArgument(fmt.Sprintf("%s statements cover completed days only", req.Format)))
+		return
+	}
+
+	account, err := server.store.GetAccount(ctx, uri.ID)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if account.Owner != authPayload.Username && !isAdmin(server.config.AdminUsernames, authPayload.Username) {
+		abortWithError(ctx, apierror.PermissionDenied("account does not belong to the authenticated user"))
+		return
+	}
+
+	openingBalance, err := server.store.GetAccountBalanceAt(ctx, db.GetAccountBalanceAtParams{
+		At:        req.From,
+		AccountID: account.ID,
+	})
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	header := statement.Header{
+		Account:        account,
+		From:           req.From,
+		To:             to,
+		OpeningBalance: openingBalance,
+		GeneratedAt:    time.Now(),
+	}
+
+	if endOfDay {
+		header.ClosingBalance, err = server.store.GetAccountBalanceAt(ctx, db.GetAccountBalanceAtParams{
+			At:        to,
+			AccountID: account.ID,
+		})
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+	}
+
+	writer, err := statement.NewWriter(req.Format, ctx.Writer)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	filename := fmt.Sprintf("statement-%d-%s-%s.%s", account.ID, req.From.Format("20060102"), req.To.Format("20060102"), statement.Extension(req.Format))
+	ctx.Header("Content-Type", statement.ContentType(req.Format))
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	ctx.Status(http.StatusOK)
+
+	err = statement.Generate(ctx, server.store, writer, header, statement.DefaultPageSize)
+	if err != nil {
+		// the error is logged with the request
+		_ = ctx.Error(err)
+		ctx.Abort()
+	}
+}
@@ -0,0 +1,201 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/samirprakash/go-bank/api/apierror"
+	mockdb "github.com/samirprakash/go-bank/db/mock"
+	db "github.com/samirprakash/go-bank/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetStatementAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	other, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	from := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, time.February, 1, 0, 0, 0, 0, time.UTC)
+	entry := db.ListStatementEntriesRow{ID: 1, Amount: 250, CreatedAt: from.Add(time.Hour), Kind: "deposit"}
+
+	buildStatementStubs := func(store *mockdb.MockStore) {
+		store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+
+		balanceArg := db.GetAccountBalanceAtParams{At: from, AccountID: account.ID}
+		store.EXPECT().GetAccountBalanceAt(gomock.Any(), gomock.Eq(balanceArg)).Times(1).Return(int64(1000), nil)
+
+		entriesArg := db.ListStatementEntriesParams{
+			AccountID:  account.ID,
+			FromTime:   from,
+			ToTime:     to,
+			LimitCount: 500,
+		}
+		store.EXPECT().ListStatementEntries(gomock.Any(), gomock.Eq(entriesArg)).Times(1).Return([]db.ListStatementEntriesRow{entry}, nil)
+	}
+
+	testCases := []struct {
+		name          string
+		username      string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:       "CSV",
+			username:   user.Username,
+			query:      "from=2023-01-01&to=2023-01-31&format=csv",
+			buildStubs: buildStatementStubs,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "text/csv; charset=utf-8", recorder.Header().Get("Content-Type"))
+				require.Contains(t, recorder.Header().Get("Content-Disposition"), ".csv")
+
+				records, err := csv.NewReader(recorder.Body).ReadAll()
+				require.NoError(t, err)
+				require.Len(t, records, 4)
+				require.Equal(t, "12.50", records[3][7])
+			},
+		},
+		{
+			name:       "JSONByDefault",
+			username:   user.Username,
+			query:      "from=2023-01-01&to=2023-01-31",
+			buildStubs: buildStatementStubs,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp struct {
+					OpeningBalance int64             `json:"opening_balance"`
+					Entries        []json.RawMessage `json:"entries"`
+					ClosingBalance int64             `json:"closing_balance"`
+				}
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, int64(1000), rsp.OpeningBalance)
+				require.Len(t, rsp.Entries, 1)
+				require.Equal(t, int64(1250), rsp.ClosingBalance)
+			},
+		},
+		{
+			name:     "MT940",
+			username: user.Username,
+			query:    "from=2023-01-01&to=2023-01-31&format=mt940",
+			buildStubs: func(store *mockdb.MockStore) {
+				buildStatementStubs(store)
+
+				closingArg := db.GetAccountBalanceAtParams{At: to, AccountID: account.ID}
+				store.EXPECT().GetAccountBalanceAt(gomock.Any(), gomock.Eq(closingArg)).Times(1).Return(int64(1250), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "text/plain; charset=utf-8", recorder.Header().Get("Content-Type"))
+				require.Contains(t, recorder.Header().Get("Content-Disposition"), "20230131.sta")
+				require.True(t, strings.HasPrefix(recorder.Body.String(), ":20:"))
+				require.Contains(t, recorder.Body.String(), ":62F:C230131"+account.Currency+"12,50\r\n")
+			},
+		},
+		{
+			name:     "CAMT053OpenDay",
+			username: user.Username,
+			query:    "from=" + time.Now().UTC().AddDate(0, 0, -1).Format("2006-01-02") + "&to=" + time.Now().UTC().Format("2006-01-02") + "&format=camt053",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "completed days only")
+			},
+		},
+		{
+			name:     "OtherUser",
+			username: other.Username,
+			query:    "from=2023-01-01&to=2023-01-31",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().GetAccountBalanceAt(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireBodyMatchError(t, recorder, apierror.CodePermissionDenied)
+			},
+		},
+		{
+			name:     "ToBeforeFrom",
+			username: user.Username,
+			query:    "from=2023-01-31&to=2023-01-01",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireBodyMatchError(t, recorder, apierror.CodeInvalidArgument)
+			},
+		},
+		{
+			name:     "PeriodTooLong",
+			username: user.Username,
+			query:    "from=2021-01-01&to=2023-01-01",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireBodyMatchError(t, recorder, apierror.CodeInvalidArgument)
+			},
+		},
+		{
+			name:     "InvalidFormat",
+			username: user.Username,
+			query:    "from=2023-01-01&to=2023-01-31&format=xls",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireBodyMatchError(t, recorder, apierror.CodeInvalidArgument)
+			},
+		},
+		{
+			name:     "InvalidDate",
+			username: user.Username,
+			query:    "from=01/01/2023&to=2023-01-31",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/accounts/%d/statement?%s", account.ID, tc.query)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/samirprakash/go-bank/api/apierror"
+	"github.com/samirprakash/go-bank/realtime"
+	"github.com/samirprakash/go-bank/token"
+)
+
+const (
+	// streamHeartbeat keeps idle streams open through proxies and detects clients that went away
+	streamHeartbeat = 15 * time.Second
+	// streamWriteWait bounds the time to write one message to a client
+	streamWriteWait = 10 * time.Second
+	// accessTokenQueryKey carries the access token of browsers, which cannot set headers on EventSource and WebSocket
+	accessTokenQueryKey = "access_token"
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// streamTokenMiddleware accepts the access token in the query of a streaming request without an authorization header.
+// It must run before authMiddleware. Only the path of requests is logged, so the token is not.
+func streamTokenMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if accessToken := ctx.Query(accessTokenQueryKey); accessToken != "" && ctx.GetHeader(authorizationHeaderKey) == "" {
+			ctx.Request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+		}
+		ctx.Next()
+	}
+}
+
+type streamAccountRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// streamAccount pushes the balance changes and new entries of an account as they are committed,
+// as server-sent events or over a WebSocket when the request asks for an upgrade.
+// The first update is the current balance. A client too slow to keep up is disconnected and should reconnect.
+func (server *Server) streamAccount(ctx *gin.Context) {
+	var req streamAccountRequest
+
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		abortWithError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	if server.hub == nil {
+		abortWithError(ctx, apierror.Unavailable("account streams are not available"))
+		return
+	}
+
+	account, err := server.store.GetAccount(ctx, req.ID)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if account.Owner != authPayload.Username {
+		abortWithError(ctx, apierror.PermissionDenied("account does not belong to the authenticated user"))
+		return
+	}
+
+	// subscribe before reading the balance so that no change is missed in between
+	sub := server.hub.Subscribe(account.ID)
+	defer sub.Close()
+
+	account, err = server.store.GetAccount(ctx, account.ID)
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	balance, err := json.Marshal(gin.H{
+		"balance":           account.Balance,
+		"held_amount":       account.HeldAmount,
+		"available_balance": account.AvailableBalance,
+		"currency":          account.Currency,
+	})
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+	first := realtime.Update{Type: realtime.UpdateBalance, AccountID: account.ID, Data: balance}
+
+	if websocket.IsWebSocketUpgrade(ctx.Request) {
+		server.streamWebSocket(ctx, sub, first)
+		return
+	}
+	server.streamEvents(ctx, sub, first)
+}
+
+// streamEvents writes the updates as server-sent events until the client goes away
+func (server *Server) streamEvents(ctx *gin.Context, sub *realtime.Subscription, first realtime.Update) {
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	// ask reverse proxies not to buffer the stream
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+
+	controller := http.NewResponseController(ctx.Writer)
+	write := func(format string, args ...interface{}) bool {
+		// the write timeout of the server would end the stream, each write gets its own deadline instead
+		_ = controller.SetWriteDeadline(time.Now().Add(streamWriteWait))
+		if _, err := fmt.Fprintf(ctx.Writer, format, args...); err != nil {
+			return false
+		}
+		ctx.Writer.Flush()
+		return true
+	}
+	writeUpdate := func(update realtime.Update) bool {
+		data, err := json.Marshal(update)
+		if err != nil {
+			return false
+		}
+		return write("event: %s\ndata: %s\n\n", update.Type, data)
+	}
+
+	if !writeUpdate(first) {
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case <-sub.Done():
+			if err := sub.Err(); err != nil {
+				data, _ := json.Marshal(gin.H{"message": err.Error()})
+				write("event: error\ndata: %s\n\n", data)
+			}
+			return
+		case update := <-sub.Updates():
+			if !writeUpdate(update) {
+				return
+			}
+		case <-heartbeat.C:
+			if !write(": heartbeat\n\n") {
+				return
+			}
+		}
+	}
+}
+
+// streamWebSocket writes the updates as JSON text messages over a WebSocket until either side closes it.
+// Messages from the client are ignored.
+func (server *Server) streamWebSocket(ctx *gin.Context, sub *realtime.Subscription, first realtime.Update) {
+	conn, err := upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	if err != nil {
+		// the upgrader has already answered with an error
+		_ = ctx.Error(err)
+		return
+	}
+	defer conn.Close()
+
+	// read the connection to answer pings and notice when the client closes it
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		conn.SetReadDeadline(time.Now().Add(2 * streamHeartbeat))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(2 * streamHeartbeat))
+		})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	writeUpdate := func(update realtime.Update) bool {
+		conn.SetWriteDeadline(time.Now().Add(streamWriteWait))
+		return conn.WriteJSON(update) == nil
+	}
+
+	if !writeUpdate(first) {
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-sub.Done():
+			message := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+			switch err := sub.Err(); {
+			case errors.Is(err, realtime.ErrSlowSubscriber):
+				message = websocket.FormatCloseMessage(websocket.CloseTryAgainLater, err.Error())
+			case errors.Is(err, realtime.ErrHubClosed):
+				message = websocket.FormatCloseMessage(websocket.CloseGoingAway, err.Error())
+			}
+			conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(streamWriteWait))
+			return
+		case update := <-sub.Updates():
+			if !writeUpdate(update) {
+				return
+			}
+		case <-heartbeat.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteWait)); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,290 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/websocket"
+	"github.com/samirprakash/go-bank/api/apierror"
+	mockdb "github.com/samirprakash/go-bank/db/mock"
+	db "github.com/samirprakash/go-bank/db/sqlc"
+	"github.com/samirprakash/go-bank/realtime"
+	"github.com/samirprakash/go-bank/token"
+	"github.com/stretchr/testify/require"
+)
+
+func newStreamTestServer(t *testing.T, store db.Store) *Server {
+	server := newTestServer(t, store)
+	server.hub = realtime.NewHub(4)
+	return server
+}
+
+// readEvent reads the next server-sent event, skipping heartbeats
+func readEvent(t *testing.T, reader *bufio.Reader) (string, realtime.Update) {
+	var eventType, data string
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			eventType = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		case line == "" && eventType != "":
+			var update realtime.Update
+			require.NoError(t, json.Unmarshal([]byte(data), &update))
+			return eventType, update
+		}
+	}
+}
+
+func TestStreamAccountAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	other, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	testCases := []struct {
+		name          string
+		accountID     int64
+		noHub         bool
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "OtherUser",
+			accountID: account.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, other.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireBodyMatchError(t, recorder, apierror.CodePermissionDenied)
+			},
+		},
+		{
+			name:      "NotFound",
+			accountID: account.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Account{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				requireBodyMatchError(t, recorder, apierror.CodeNotFound)
+			},
+		},
+		{
+			name:      "NoAuthorization",
+			accountID: account.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireBodyMatchError(t, recorder, apierror.CodeUnauthenticated)
+			},
+		},
+		{
+			name:      "InvalidID",
+			accountID: 0,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireBodyMatchError(t, recorder, apierror.CodeInvalidArgument)
+			},
+		},
+		{
+			name:      "NoHub",
+			accountID: account.ID,
+			noHub:     true,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+				requireBodyMatchError(t, recorder, apierror.CodeUnavailable)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newStreamTestServer(t, store)
+			if tc.noHub {
+				server.hub = nil
+			}
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/accounts/%d/stream", tc.accountID)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+
+			// no subscription is left behind
+			if server.hub != nil {
+				require.Zero(t, server.hub.Subscribers())
+			}
+		})
+	}
+}
+
+func TestStreamAccountOtherUser(t *testing.T) {
+	user, _ := randomUser(t)
+	other, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	server := newStreamTestServer(t, store)
+
+	// the account of another user is never subscribed to, not even until the owner is checked
+	store.EXPECT().
+		GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, id int64) (db.Account, error) {
+			require.Zero(t, server.hub.Subscribers())
+			return account, nil
+		})
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/accounts/%d/stream", account.ID), nil)
+	require.NoError(t, err)
+
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, other.Username, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+	requireBodyMatchError(t, recorder, apierror.CodePermissionDenied)
+	require.Zero(t, server.hub.Subscribers())
+}
+
+func TestStreamAccountEvents(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(2).Return(account, nil)
+
+	server := newStreamTestServer(t, store)
+	httpServer := httptest.NewServer(server.router)
+	defer httpServer.Close()
+
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/accounts/%d/stream", httpServer.URL, account.ID), nil)
+	require.NoError(t, err)
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+	reader := bufio.NewReader(response.Body)
+
+	// the stream starts with the current balance
+	eventType, update := readEvent(t, reader)
+	require.Equal(t, realtime.UpdateBalance, eventType)
+	require.Equal(t, account.ID, update.AccountID)
+
+	var balance struct {
+		Balance  int64  `json:"balance"`
+		Currency string `json:"currency"`
+	}
+	require.NoError(t, json.Unmarshal(update.Data, &balance))
+	require.Equal(t, account.Balance, balance.Balance)
+	require.Equal(t, account.Currency, balance.Currency)
+
+	// then pushes the updates of the account only
+	server.hub.Publish(realtime.Update{Type: realtime.UpdateEntry, AccountID: account.ID + 1, Data: json.RawMessage(`{"id":1}`)})
+	server.hub.Publish(realtime.Update{Type: realtime.UpdateEntry, AccountID: account.ID, Data: json.RawMessage(`{"id":2}`)})
+
+	eventType, update = readEvent(t, reader)
+	require.Equal(t, realtime.UpdateEntry, eventType)
+	require.JSONEq(t, `{"id":2}`, string(update.Data))
+
+	// and ends when the server shuts down
+	server.hub.Close()
+	eventType, _ = readEvent(t, reader)
+	require.Equal(t, "error", eventType)
+}
+
+func TestStreamAccountWebSocket(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(2).Return(account, nil)
+
+	server := newStreamTestServer(t, store)
+	httpServer := httptest.NewServer(server.router)
+	defer httpServer.Close()
+
+	// browsers pass the access token in the query
+	accessToken, _, err := server.tokenMaker.CreateToken(user.Username, time.Minute)
+	require.NoError(t, err)
+
+	url := fmt.Sprintf("ws%s/accounts/%d/stream?%s=%s", strings.TrimPrefix(httpServer.URL, "http"), account.ID, accessTokenQueryKey, accessToken)
+	conn, response, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.Equal(t, http.StatusSwitchingProtocols, response.StatusCode)
+
+	var update realtime.Update
+	require.NoError(t, conn.ReadJSON(&update))
+	require.Equal(t, realtime.UpdateBalance, update.Type)
+	require.Equal(t, account.ID, update.AccountID)
+
+	server.hub.Publish(realtime.Update{Type: realtime.UpdateEntry, AccountID: account.ID, Data: json.RawMessage(`{"id":3}`)})
+	require.NoError(t, conn.ReadJSON(&update))
+	require.Equal(t, realtime.UpdateEntry, update.Type)
+	require.JSONEq(t, `{"id":3}`, string(update.Data))
+
+	server.hub.Close()
+	_, _, err = conn.ReadMessage()
+	require.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway))
+}
@@ -1,13 +1,14 @@
 package api
 
 import (
-	"database/sql"
 	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/samirprakash/go-bank/api/apierror"
 	db "github.com/samirprakash/go-bank/db/sqlc"
+	"github.com/samirprakash/go-bank/metrics"
 	"github.com/samirprakash/go-bank/token"
 )
 
@@ -15,14 +16,15 @@ type transferRequest struct {
 	FromAccountID int64  `json:"from_account_id" binding:"required,min=1"`
 	ToAccountID   int64  `json:"to_account_id" binding:"required,min=1"`
 	Amount        int64  `json:"amount" binding:"required,gt=0"`
-	Currency      string `json:"currency" binding:"required,customCurrencyValidator"`
+	Currency      string `json:"currency" binding:"required,currency"`
 }
 
 func (server *Server) createTransfer(ctx *gin.Context) {
 	var req transferRequest
 
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		metrics.TransferFailed(metrics.TransferFailedInvalidRequest)
+		abortWithError(ctx, apierror.FromBinding(err))
 		return
 	}
 
@@ -34,8 +36,8 @@ func (server *Server) createTransfer(ctx *gin.Context) {
 	// add auth middleware
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 	if fromAccount.Owner != authPayload.Username {
-		err := errors.New("from account does not belong to the authenticated user")
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		metrics.TransferFailed(metrics.TransferFailedUnauthorized)
+		abortWithError(ctx, apierror.PermissionDenied("from account does not belong to the authenticated user"))
 		return
 	}
 
@@ -52,30 +54,188 @@ func (server *Server) createTransfer(ctx *gin.Context) {
 
 	result, err := server.store.TransferTx(ctx, arg)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		metrics.TransferFailed(transferFailureReason(err))
+		abortWithError(ctx, err)
 		return
 	}
 
+	metrics.TransferCreated(req.Currency, req.Amount)
+	metrics.FeeCharged(req.Currency, result.Fee.Amount)
 	ctx.JSON(http.StatusOK, result)
 }
 
-func (server *Server) validAccount(ctx *gin.Context, accountID int64, currency string) (db.Account, bool) {
-	account, err := server.store.GetAccount(ctx, accountID)
+type transferQuoteResponse struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+	Fee      db.Fee `json:"fee"`
+	// Total is the amount taken from the account, fee included
+	Total int64 `json:"total"`
+}
+
+// quoteTransfer previews the fee of a transfer without moving any money.
+// The fee may change if the fee schedules change before the transfer is made.
+func (server *Server) quoteTransfer(ctx *gin.Context) {
+	var req transferRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		abortWithError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	fromAccount, err := server.accountInCurrency(ctx, req.FromAccountID, req.Currency)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
-			return account, false
+		abortWithError(ctx, err)
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if fromAccount.Owner != authPayload.Username {
+		abortWithError(ctx, apierror.PermissionDenied("from account does not belong to the authenticated user"))
+		return
+	}
+
+	if _, err := server.accountInCurrency(ctx, req.ToAccountID, req.Currency); err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	fee, err := server.store.QuoteTransferFee(ctx, db.TransferTxParams{
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        req.Amount,
+	})
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, transferQuoteResponse{
+		Amount:   req.Amount,
+		Currency: req.Currency,
+		Fee:      fee,
+		Total:    req.Amount + fee.Amount,
+	})
+}
+
+type batchTransferLegRequest struct {
+	FromAccountID int64 `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID   int64 `json:"to_account_id" binding:"required,min=1"`
+	Amount        int64 `json:"amount" binding:"required,gt=0"`
+}
+
+type batchTransferRequest struct {
+	Currency string                    `json:"currency" binding:"required,currency"`
+	Legs     []batchTransferLegRequest `json:"legs" binding:"required,min=1,max=100,dive"`
+}
+
+// createBatchTransfer moves money between many accounts in one atomic transaction.
+// Every account money is taken from must belong to the authenticated user.
+func (server *Server) createBatchTransfer(ctx *gin.Context) {
+	var req batchTransferRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		metrics.TransferFailed(metrics.TransferFailedInvalidRequest)
+		abortWithError(ctx, apierror.FromBinding(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	arg := db.BatchTransferTxParams{
+		Currency: req.Currency,
+		Legs:     make([]db.BatchTransferLeg, len(req.Legs)),
+	}
+
+	checked := make(map[int64]bool)
+	for i, leg := range req.Legs {
+		arg.Legs[i] = db.BatchTransferLeg{
+			FromAccountID: leg.FromAccountID,
+			ToAccountID:   leg.ToAccountID,
+			Amount:        leg.Amount,
 		}
 
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-		return account, false
+		if checked[leg.FromAccountID] {
+			continue
+		}
+		checked[leg.FromAccountID] = true
+
+		fromAccount, valid := server.validAccount(ctx, leg.FromAccountID, req.Currency)
+		if !valid {
+			return
+		}
+
+		if fromAccount.Owner != authPayload.Username {
+			metrics.TransferFailed(metrics.TransferFailedUnauthorized)
+			abortWithError(ctx, apierror.PermissionDenied(fmt.Sprintf("account [%d] does not belong to the authenticated user", leg.FromAccountID)))
+			return
+		}
 	}
 
-	if account.Currency != currency {
-		err := fmt.Errorf("account [%d] current mismatch : %s vs %s", accountID, account.Currency, currency)
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+	// destination accounts and balances are checked by the transaction while the accounts are locked
+	result, err := server.store.BatchTransferTx(ctx, arg)
+	if err != nil {
+		metrics.TransferFailed(transferFailureReason(err))
+		abortWithError(ctx, err)
+		return
+	}
+
+	for i, leg := range req.Legs {
+		metrics.TransferCreated(req.Currency, leg.Amount)
+		metrics.FeeCharged(req.Currency, result.Legs[i].Fee.Amount)
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+func (server *Server) validAccount(ctx *gin.Context, accountID int64, currency string) (db.Account, bool) {
+	account, err := server.accountInCurrency(ctx, accountID, currency)
+	if err != nil {
+		metrics.TransferFailed(accountFailureReason(err))
+		abortWithError(ctx, err)
 		return account, false
 	}
 
 	return account, true
 }
+
+// accountInCurrency fetches an account and checks that it holds the currency and can move money
+func (server *Server) accountInCurrency(ctx *gin.Context, accountID int64, currency string) (db.Account, error) {
+	account, err := server.store.GetAccount(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return account, apierror.NotFound(fmt.Sprintf("account [%d] not found", accountID))
+		}
+		return account, err
+	}
+
+	if account.Currency != currency {
+		return account, apierror.InvalidArgument(fmt.Sprintf("account [%d] currency mismatch : %s vs %s", accountID, account.Currency, currency))
+	}
+
+	if err := db.AccountStatusError(account.Status); err != nil {
+		return account, apierror.Wrap(err, http.StatusUnprocessableEntity, apierror.CodeFailedPrecondition, fmt.Sprintf("account [%d] is %s", accountID, account.Status))
+	}
+
+	return account, nil
+}
+
+// accountFailureReason labels a transfer rejected because of one of its accounts
+func accountFailureReason(err error) string {
+	switch apierror.FromError(err).Code {
+	case apierror.CodeNotFound:
+		return metrics.TransferFailedAccountNotFound
+	case apierror.CodeInvalidArgument:
+		return metrics.TransferFailedCurrencyMismatch
+	case apierror.CodeFailedPrecondition:
+		return metrics.TransferFailedAccountInactive
+	}
+	return metrics.TransferFailedInternal
+}
+
+// transferFailureReason labels a failed transfer with the code of the error returned to the client
+func transferFailureReason(err error) string {
+	apiErr := apierror.FromError(err)
+	if apiErr.Status >= http.StatusInternalServerError {
+		return metrics.TransferFailedInternal
+	}
+	return string(apiErr.Code)
+}
@@ -1,14 +1,17 @@
 package api
 
 import (
-	"database/sql"
+	"context"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/lib/pq"
+	"github.com/samirprakash/go-bank/api/apierror"
 	db "github.com/samirprakash/go-bank/db/sqlc"
+	"github.com/samirprakash/go-bank/metrics"
+	"github.com/samirprakash/go-bank/tracing"
 	"github.com/samirprakash/go-bank/util"
 )
 
@@ -25,6 +28,7 @@ type userResponse struct {
 	Email             string    `json:"email"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
+	Tier              string    `json:"tier"`
 }
 
 func newUserResponse(user db.User) userResponse {
@@ -34,6 +38,7 @@ func newUserResponse(user db.User) userResponse {
 		Email:             user.Email,
 		PasswordChangedAt: user.PasswordChangedAt,
 		CreatedAt:         user.CreatedAt,
+		Tier:              user.Tier,
 	}
 }
 
@@ -42,14 +47,19 @@ func (server *Server) createUser(ctx *gin.Context) {
 
 	// validate request params
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		abortWithError(ctx, apierror.FromBinding(err))
 		return
 	}
 
 	// create hashed password
-	hashedPassword, err := util.HashPassword(req.Password)
+	var hashedPassword string
+	err := tracing.Trace(ctx, "util.HashPassword", func(context.Context) error {
+		var err error
+		hashedPassword, err = util.HashPassword(req.Password)
+		return err
+	})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		abortWithError(ctx, err)
 		return
 	}
 
@@ -62,17 +72,9 @@ func (server *Server) createUser(ctx *gin.Context) {
 	}
 
 	// save to db
-	user, err := server.store.CreateUser(ctx, arg)
+	user, err := server.store.CreateUserTx(ctx, arg)
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok {
-			switch pqErr.Code.Name() {
-			case "unique_violation":
-				ctx.JSON(http.StatusForbidden, errorResponse(err))
-				return
-			}
-		}
-
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		abortWithError(ctx, err)
 		return
 	}
 
@@ -100,40 +102,46 @@ func (server *Server) loginUser(ctx *gin.Context) {
 	var req loginUserRequest
 
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		abortWithError(ctx, apierror.FromBinding(err))
 		return
 	}
 
 	user, err := server.store.GetUser(ctx, req.Username)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
-			return
+		if errors.Is(err, db.ErrRecordNotFound) {
+			metrics.Login(metrics.LoginUserNotFound)
+		} else {
+			metrics.Login(metrics.LoginFailed)
 		}
 
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		abortWithError(ctx, err)
 		return
 	}
 
-	err = util.CheckPassword(req.Password, user.HashedPassword)
+	err = tracing.Trace(ctx, "util.CheckPassword", func(context.Context) error {
+		return util.CheckPassword(req.Password, user.HashedPassword)
+	})
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		metrics.Login(metrics.LoginWrongPassword)
+		abortWithError(ctx, err)
 		return
 	}
 
 	accessToken, accessPayload, err := server.tokenMaker.CreateToken(user.Username, server.config.AccessTokenDuration)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		metrics.Login(metrics.LoginFailed)
+		abortWithError(ctx, err)
 		return
 	}
 
 	refreshToken, refreshPayload, err := server.tokenMaker.CreateToken(user.Username, server.config.RefreshTokenDuration)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		metrics.Login(metrics.LoginFailed)
+		abortWithError(ctx, err)
 		return
 	}
 
-	session, err := server.store.CreateSession(ctx, db.CreateSessionParams{
+	session, err := server.store.CreateSessionTx(ctx, db.CreateSessionParams{
 		ID:           refreshPayload.ID,
 		Username:     user.Username,
 		RefreshToken: refreshToken,
@@ -144,10 +152,12 @@ func (server *Server) loginUser(ctx *gin.Context) {
 	})
 
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		metrics.Login(metrics.LoginFailed)
+		abortWithError(ctx, err)
 		return
 	}
 
+	metrics.Login(metrics.LoginSucceeded)
 	rsp := loginUserResponse{
 		SessionID:             session.ID,
 		AccessToken:           accessToken,
@@ -1,7 +1,12 @@
 package api
 
 import (
+	"reflect"
+	"strings"
+
 	"github.com/go-playground/validator/v10"
+	db "github.com/samirprakash/go-bank/db/sqlc"
+	"github.com/samirprakash/go-bank/scheduler"
 	"github.com/samirprakash/go-bank/util"
 )
 
@@ -11,3 +16,33 @@ var validateCurrency validator.Func = func(fieldLevel validator.FieldLevel) bool
 	}
 	return false
 }
+
+var validateSchedule validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if schedule, ok := fieldLevel.Field().Interface().(string); ok {
+		_, err := scheduler.ParseSchedule(schedule)
+		return err == nil
+	}
+	return false
+}
+
+var validateWebhookEvent validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if eventType, ok := fieldLevel.Field().Interface().(string); ok {
+		for _, t := range db.EventTypes {
+			if eventType == t {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fieldName reports validation errors with the name of the field as sent by the client
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "uri", "form"} {
+		name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
@@ -0,0 +1,23 @@
+CREATE TABLE "currencies" (
+  "code" varchar PRIMARY KEY,
+  "name" varchar NOT NULL,
+  "minor_unit" smallint NOT NULL DEFAULT 2
+);
+
+INSERT INTO "currencies" ("code", "name", "minor_unit") VALUES
+  ('USD', 'US Dollar', 2),
+  ('EUR', 'Euro', 2),
+  ('GBP', 'Pound Sterling', 2),
+  ('INR', 'Indian Rupee', 2);
+
+ALTER TABLE "accounts" ADD COLUMN "overdraft_enabled" boolean NOT NULL DEFAULT false;
+
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_currency_fkey" FOREIGN KEY ("currency") REFERENCES "currencies" ("code");
+
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_balance_check" CHECK ("overdraft_enabled" OR "balance" >= 0);
+
+ALTER TABLE "transfers" ADD CONSTRAINT "transfers_amount_check" CHECK ("amount" > 0);
+
+ALTER TABLE "transfers" ADD CONSTRAINT "transfers_distinct_accounts_check" CHECK ("from_account_id" <> "to_account_id");
+
+COMMENT ON COLUMN "accounts"."overdraft_enabled" IS 'allows the balance to go below zero';
@@ -0,0 +1,9 @@
+DROP TRIGGER IF EXISTS "transfer_reversals_immutable" ON "transfer_reversals";
+
+DROP TRIGGER IF EXISTS "entries_immutable" ON "entries";
+
+DROP TRIGGER IF EXISTS "transfers_immutable" ON "transfers";
+
+DROP FUNCTION IF EXISTS "prevent_history_change";
+
+DROP TABLE IF EXISTS "transfer_reversals";
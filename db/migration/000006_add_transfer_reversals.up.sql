@@ -0,0 +1,33 @@
+CREATE TABLE "transfer_reversals" (
+  "transfer_id" bigint PRIMARY KEY,
+  "original_transfer_id" bigint NOT NULL,
+  "reason" varchar NOT NULL DEFAULT '',
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "transfer_reversals" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+ALTER TABLE "transfer_reversals" ADD FOREIGN KEY ("original_transfer_id") REFERENCES "transfers" ("id");
+
+CREATE INDEX ON "transfer_reversals" ("original_transfer_id");
+
+COMMENT ON COLUMN "transfer_reversals"."transfer_id" IS 'compensating transfer';
+
+COMMENT ON COLUMN "transfer_reversals"."original_transfer_id" IS 'transfer being reversed';
+
+-- transfers and entries are the history of the balances : they can only be compensated, never changed
+CREATE FUNCTION "prevent_history_change"() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION '% rows cannot be updated or deleted', TG_TABLE_NAME
+    USING ERRCODE = 'restrict_violation';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "transfers_immutable" BEFORE UPDATE OR DELETE ON "transfers"
+  FOR EACH ROW EXECUTE FUNCTION "prevent_history_change"();
+
+CREATE TRIGGER "entries_immutable" BEFORE UPDATE OR DELETE ON "entries"
+  FOR EACH ROW EXECUTE FUNCTION "prevent_history_change"();
+
+CREATE TRIGGER "transfer_reversals_immutable" BEFORE UPDATE OR DELETE ON "transfer_reversals"
+  FOR EACH ROW EXECUTE FUNCTION "prevent_history_change"();
@@ -0,0 +1,49 @@
+ALTER TABLE "accounts" ADD COLUMN "held_amount" bigint NOT NULL DEFAULT 0;
+
+ALTER TABLE "accounts" ADD COLUMN "available_balance" bigint GENERATED ALWAYS AS ("balance" - "held_amount") STORED;
+
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_held_amount_check" CHECK ("held_amount" >= 0);
+
+-- funds on hold cannot be spent : the balance check now applies to the available balance
+ALTER TABLE "accounts" DROP CONSTRAINT "accounts_balance_check";
+
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_balance_check" CHECK ("overdraft_enabled" OR "balance" - "held_amount" >= 0);
+
+CREATE TABLE "holds" (
+  "id" bigserial PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "to_account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "status" varchar NOT NULL DEFAULT 'active',
+  "captured_amount" bigint NOT NULL DEFAULT 0,
+  "transfer_id" bigint,
+  "expires_at" timestamptz NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "finished_at" timestamptz,
+  CONSTRAINT "holds_amount_check" CHECK ("amount" > 0),
+  CONSTRAINT "holds_distinct_accounts_check" CHECK ("account_id" <> "to_account_id"),
+  CONSTRAINT "holds_status_check" CHECK ("status" IN ('active', 'captured', 'voided', 'expired')),
+  CONSTRAINT "holds_captured_amount_check" CHECK ("captured_amount" >= 0 AND "captured_amount" <= "amount")
+);
+
+ALTER TABLE "holds" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "holds" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "holds" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+CREATE INDEX ON "holds" ("account_id");
+
+CREATE INDEX ON "holds" ("expires_at") WHERE "status" = 'active';
+
+COMMENT ON COLUMN "accounts"."balance" IS 'ledger balance';
+
+COMMENT ON COLUMN "accounts"."held_amount" IS 'sum of the active holds';
+
+COMMENT ON COLUMN "accounts"."available_balance" IS 'ledger balance minus the active holds';
+
+COMMENT ON COLUMN "holds"."account_id" IS 'account the funds are reserved on';
+
+COMMENT ON COLUMN "holds"."to_account_id" IS 'account credited on capture';
+
+COMMENT ON COLUMN "holds"."status" IS 'active, captured, voided or expired';
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS "entries_account_id_created_at_idx";
+
+DROP TABLE IF EXISTS "account_limits";
+
+ALTER TABLE IF EXISTS "users" DROP CONSTRAINT IF EXISTS "users_tier_fkey";
+
+ALTER TABLE IF EXISTS "users" DROP COLUMN IF EXISTS "tier";
+
+DROP TABLE IF EXISTS "limit_tiers";
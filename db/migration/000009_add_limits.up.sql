@@ -0,0 +1,38 @@
+CREATE TABLE "limit_tiers" (
+  "name" varchar PRIMARY KEY,
+  "max_transfer_amount" bigint,
+  "daily_outgoing_limit" bigint,
+  "monthly_outgoing_limit" bigint,
+  CONSTRAINT "limit_tiers_positive_check" CHECK ("max_transfer_amount" > 0 AND "daily_outgoing_limit" > 0 AND "monthly_outgoing_limit" > 0)
+);
+
+INSERT INTO "limit_tiers" ("name", "max_transfer_amount", "daily_outgoing_limit", "monthly_outgoing_limit") VALUES
+  ('standard', 10000, 50000, 500000),
+  ('premium', 100000, 500000, 5000000);
+
+ALTER TABLE "users" ADD COLUMN "tier" varchar NOT NULL DEFAULT 'standard';
+
+ALTER TABLE "users" ADD CONSTRAINT "users_tier_fkey" FOREIGN KEY ("tier") REFERENCES "limit_tiers" ("name");
+
+CREATE TABLE "account_limits" (
+  "account_id" bigint PRIMARY KEY,
+  "max_transfer_amount" bigint,
+  "daily_outgoing_limit" bigint,
+  "monthly_outgoing_limit" bigint,
+  "updated_by" varchar NOT NULL,
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  CONSTRAINT "account_limits_positive_check" CHECK ("max_transfer_amount" > 0 AND "daily_outgoing_limit" > 0 AND "monthly_outgoing_limit" > 0)
+);
+
+ALTER TABLE "account_limits" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "account_limits" ADD FOREIGN KEY ("updated_by") REFERENCES "users" ("username");
+
+-- outgoing totals are summed over the entries of an account since a point in time
+CREATE INDEX ON "entries" ("account_id", "created_at");
+
+COMMENT ON COLUMN "users"."tier" IS 'limit tier applying to the accounts of the user';
+
+COMMENT ON TABLE "account_limits" IS 'overrides of the tier limits, a null limit falls back to the tier';
+
+COMMENT ON COLUMN "limit_tiers"."max_transfer_amount" IS 'largest single transfer or deposit, null for no limit';
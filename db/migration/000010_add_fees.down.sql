@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS "revenue_accounts";
+
+DROP TABLE IF EXISTS "fee_schedules";
+
+-- the revenue accounts are kept if fees were already charged to them
+DELETE FROM "accounts" WHERE "owner" = 'bank'
+  AND NOT EXISTS (SELECT 1 FROM "entries" WHERE "entries"."account_id" = "accounts"."id");
+
+DELETE FROM "users" WHERE "username" = 'bank'
+  AND NOT EXISTS (SELECT 1 FROM "accounts" WHERE "owner" = 'bank');
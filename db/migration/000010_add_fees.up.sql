@@ -0,0 +1,50 @@
+CREATE TABLE "fee_schedules" (
+  "id" bigserial PRIMARY KEY,
+  "currency" varchar,
+  "tier" varchar,
+  "min_amount" bigint NOT NULL DEFAULT 0,
+  "flat_fee" bigint NOT NULL DEFAULT 0,
+  "percentage_bps" integer NOT NULL DEFAULT 0,
+  "min_fee" bigint NOT NULL DEFAULT 0,
+  "max_fee" bigint,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  CONSTRAINT "fee_schedules_amounts_check" CHECK ("min_amount" >= 0 AND "flat_fee" >= 0 AND "min_fee" >= 0),
+  CONSTRAINT "fee_schedules_percentage_check" CHECK ("percentage_bps" BETWEEN 0 AND 10000),
+  CONSTRAINT "fee_schedules_max_fee_check" CHECK ("max_fee" >= "min_fee")
+);
+
+ALTER TABLE "fee_schedules" ADD FOREIGN KEY ("currency") REFERENCES "currencies" ("code");
+
+ALTER TABLE "fee_schedules" ADD FOREIGN KEY ("tier") REFERENCES "limit_tiers" ("name");
+
+-- one schedule per band of amounts for each currency and tier, null meaning any
+CREATE UNIQUE INDEX "fee_schedules_band_key" ON "fee_schedules" (COALESCE("currency", ''), COALESCE("tier", ''), "min_amount");
+
+COMMENT ON COLUMN "fee_schedules"."currency" IS 'currency the schedule applies to, null for any';
+
+COMMENT ON COLUMN "fee_schedules"."tier" IS 'limit tier of the sender the schedule applies to, null for any';
+
+COMMENT ON COLUMN "fee_schedules"."min_amount" IS 'smallest transfer amount of the band';
+
+COMMENT ON COLUMN "fee_schedules"."percentage_bps" IS 'percentage of the amount in basis points';
+
+COMMENT ON COLUMN "fee_schedules"."max_fee" IS 'cap of the fee, null for no cap';
+
+-- fees are paid to an account of the bank in each currency
+INSERT INTO "users" ("username", "hashed_password", "full_name", "email") VALUES
+  ('bank', '!', 'Bank revenue', 'revenue@bank.invalid');
+
+CREATE TABLE "revenue_accounts" (
+  "currency" varchar PRIMARY KEY,
+  "account_id" bigint UNIQUE NOT NULL
+);
+
+ALTER TABLE "revenue_accounts" ADD FOREIGN KEY ("currency") REFERENCES "currencies" ("code");
+
+ALTER TABLE "revenue_accounts" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+INSERT INTO "accounts" ("owner", "balance", "currency")
+  SELECT 'bank', 0, "code" FROM "currencies";
+
+INSERT INTO "revenue_accounts" ("currency", "account_id")
+  SELECT "currency", "id" FROM "accounts" WHERE "owner" = 'bank';
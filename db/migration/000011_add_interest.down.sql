@@ -0,0 +1,16 @@
+UPDATE "accounts" SET "overdraft_enabled" = false
+WHERE "id" IN (SELECT "account_id" FROM "revenue_accounts");
+
+UPDATE "users" SET "tier" = 'standard' WHERE "username" = 'bank';
+
+DELETE FROM "limit_tiers" WHERE "name" = 'system';
+
+DROP TABLE IF EXISTS "interest_postings";
+
+DROP TABLE IF EXISTS "interest_accruals";
+
+DROP TABLE IF EXISTS "interest_rates";
+
+ALTER TABLE IF EXISTS "accounts" DROP CONSTRAINT IF EXISTS "accounts_type_check";
+
+ALTER TABLE IF EXISTS "accounts" DROP COLUMN IF EXISTS "type";
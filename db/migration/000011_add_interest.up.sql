@@ -0,0 +1,72 @@
+ALTER TABLE "accounts" ADD COLUMN "type" varchar NOT NULL DEFAULT 'checking';
+
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_type_check" CHECK ("type" IN ('checking', 'savings'));
+
+CREATE TABLE "interest_rates" (
+  "currency" varchar PRIMARY KEY,
+  "annual_rate_bps" integer NOT NULL,
+  "day_count" varchar NOT NULL,
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  CONSTRAINT "interest_rates_rate_check" CHECK ("annual_rate_bps" BETWEEN 0 AND 10000),
+  CONSTRAINT "interest_rates_day_count_check" CHECK ("day_count" IN ('ACT/365', 'ACT/360', '30/360'))
+);
+
+ALTER TABLE "interest_rates" ADD FOREIGN KEY ("currency") REFERENCES "currencies" ("code");
+
+COMMENT ON COLUMN "interest_rates"."annual_rate_bps" IS 'annual rate paid on savings accounts in basis points';
+
+INSERT INTO "interest_rates" ("currency", "annual_rate_bps", "day_count") VALUES
+  ('USD', 200, 'ACT/360'),
+  ('EUR', 150, 'ACT/360'),
+  ('GBP', 175, 'ACT/365'),
+  ('INR', 400, 'ACT/365');
+
+-- one accrual per account and day makes re-running a day a no-op
+CREATE TABLE "interest_accruals" (
+  "account_id" bigint NOT NULL,
+  "accrual_date" date NOT NULL,
+  "balance" bigint NOT NULL,
+  "annual_rate_bps" integer NOT NULL,
+  "day_count" varchar NOT NULL,
+  "units" bigint NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("account_id", "accrual_date")
+);
+
+ALTER TABLE "interest_accruals" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+CREATE INDEX ON "interest_accruals" ("accrual_date");
+
+COMMENT ON COLUMN "interest_accruals"."balance" IS 'balance at the end of the day';
+
+COMMENT ON COLUMN "interest_accruals"."units" IS 'interest accrued in 1/262800000 of the minor unit';
+
+-- one posting per account and month makes re-running a month a no-op
+CREATE TABLE "interest_postings" (
+  "account_id" bigint NOT NULL,
+  "month" date NOT NULL,
+  "units" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "remainder_units" bigint NOT NULL,
+  "transfer_id" bigint,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("account_id", "month")
+);
+
+ALTER TABLE "interest_postings" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "interest_postings" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+COMMENT ON COLUMN "interest_postings"."month" IS 'first day of the month the interest was accrued in';
+
+COMMENT ON COLUMN "interest_postings"."units" IS 'interest accrued in the month plus the remainder of the previous posting';
+
+COMMENT ON COLUMN "interest_postings"."remainder_units" IS 'fraction of the minor unit carried to the next posting';
+
+-- interest is paid by the revenue accounts of the bank, which are not bound by limits or their balance
+INSERT INTO "limit_tiers" ("name") VALUES ('system');
+
+UPDATE "users" SET "tier" = 'system' WHERE "username" = 'bank';
+
+UPDATE "accounts" SET "overdraft_enabled" = true
+WHERE "id" IN (SELECT "account_id" FROM "revenue_accounts");
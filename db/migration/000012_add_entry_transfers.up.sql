@@ -0,0 +1,29 @@
+ALTER TABLE "entries" ADD COLUMN "transfer_id" bigint;
+
+ALTER TABLE "entries" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+CREATE INDEX ON "entries" ("transfer_id");
+
+COMMENT ON COLUMN "entries"."transfer_id" IS 'transfer the entry records, null for deposits';
+
+-- link the existing entries to their transfer, which was created in the same transaction and so at the same time.
+-- Transfers and their entries were created in the same order, which pairs identical legs of a batch.
+ALTER TABLE "entries" DISABLE TRIGGER "entries_immutable";
+
+WITH "legs" AS (
+  SELECT "id" AS "transfer_id", "from_account_id" AS "account_id", -"amount" AS "amount", "created_at" FROM "transfers"
+  UNION ALL
+  SELECT "id", "to_account_id", "amount", "created_at" FROM "transfers"
+), "numbered_legs" AS (
+  SELECT *, row_number() OVER (PARTITION BY "account_id", "amount", "created_at" ORDER BY "transfer_id") AS "n" FROM "legs"
+), "numbered_entries" AS (
+  SELECT "id", "account_id", "amount", "created_at",
+    row_number() OVER (PARTITION BY "account_id", "amount", "created_at" ORDER BY "id") AS "n"
+  FROM "entries"
+)
+UPDATE "entries" SET "transfer_id" = "numbered_legs"."transfer_id"
+FROM "numbered_entries"
+JOIN "numbered_legs" USING ("account_id", "amount", "created_at", "n")
+WHERE "entries"."id" = "numbered_entries"."id";
+
+ALTER TABLE "entries" ENABLE TRIGGER "entries_immutable";
@@ -0,0 +1,21 @@
+CREATE TABLE "events" (
+  "id" bigserial PRIMARY KEY,
+  "event_id" uuid NOT NULL,
+  "event_type" varchar NOT NULL,
+  "partition_key" varchar NOT NULL,
+  "payload" jsonb NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "published_at" timestamptz
+);
+
+CREATE UNIQUE INDEX ON "events" ("event_id", "partition_key");
+
+CREATE INDEX ON "events" ("id") WHERE "published_at" IS NULL;
+
+COMMENT ON COLUMN "events"."id" IS 'order of the events, increasing with commits within a partition';
+
+COMMENT ON COLUMN "events"."event_id" IS 'same for every partition of an event, for consumers to deduplicate';
+
+COMMENT ON COLUMN "events"."partition_key" IS 'account:<id> or user:<username>, events of a partition are published in order';
+
+COMMENT ON COLUMN "events"."published_at" IS 'null until the event is acknowledged by the publisher';
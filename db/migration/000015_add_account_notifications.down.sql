@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS "entries_notify" ON "entries";
+
+DROP TRIGGER IF EXISTS "accounts_notify_balance" ON "accounts";
+
+DROP FUNCTION IF EXISTS "notify_account_entry";
+
+DROP FUNCTION IF EXISTS "notify_account_balance";
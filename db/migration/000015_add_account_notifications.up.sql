@@ -0,0 +1,36 @@
+-- balance changes and new entries are notified on the account_updates channel,
+-- delivered to the listeners once the transaction commits
+CREATE FUNCTION "notify_account_balance"() RETURNS trigger AS $$
+BEGIN
+  PERFORM pg_notify('account_updates', json_build_object(
+    'type', 'balance',
+    'account_id', NEW."id",
+    'data', json_build_object(
+      'balance', NEW."balance",
+      'held_amount', NEW."held_amount",
+      'available_balance', NEW."available_balance",
+      'currency', NEW."currency"
+    )
+  )::text);
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE FUNCTION "notify_account_entry"() RETURNS trigger AS $$
+BEGIN
+  PERFORM pg_notify('account_updates', json_build_object(
+    'type', 'entry',
+    'account_id', NEW."account_id",
+    'data', row_to_json(NEW)
+  )::text);
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "accounts_notify_balance" AFTER UPDATE OF "balance", "held_amount" ON "accounts"
+  FOR EACH ROW
+  WHEN (OLD."balance" IS DISTINCT FROM NEW."balance" OR OLD."held_amount" IS DISTINCT FROM NEW."held_amount")
+  EXECUTE FUNCTION "notify_account_balance"();
+
+CREATE TRIGGER "entries_notify" AFTER INSERT ON "entries"
+  FOR EACH ROW EXECUTE FUNCTION "notify_account_entry"();
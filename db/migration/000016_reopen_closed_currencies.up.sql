@@ -0,0 +1,4 @@
+-- a closed account no longer holds the currency of its owner, who may open a new account in it
+ALTER TABLE "accounts" DROP CONSTRAINT "owner_currency_key";
+
+CREATE UNIQUE INDEX "owner_currency_key" ON "accounts" ("owner", "currency") WHERE "status" <> 'closed';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS "owner_currency_type_key";
+
+CREATE UNIQUE INDEX "owner_currency_key" ON "accounts" ("owner", "currency") WHERE "status" <> 'closed';
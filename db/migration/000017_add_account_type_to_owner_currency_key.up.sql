@@ -0,0 +1,4 @@
+-- a user may hold a checking and a savings account in the same currency, one open account of each type
+DROP INDEX "owner_currency_key";
+
+CREATE UNIQUE INDEX "owner_currency_type_key" ON "accounts" ("owner", "currency", "type") WHERE "status" <> 'closed';
@@ -0,0 +1,6 @@
+-- the fee of a hold is quoted when it is placed and reserved with its amount
+ALTER TABLE "holds" ADD COLUMN "fee" bigint NOT NULL DEFAULT 0;
+
+ALTER TABLE "holds" ADD CONSTRAINT "holds_fee_check" CHECK ("fee" >= 0);
+
+COMMENT ON COLUMN "holds"."fee" IS 'fee quoted for the whole amount, reserved with it';
@@ -0,0 +1,203 @@
+// Package migration embeds the SQL schema migrations into the binary and applies them at startup.
+package migration
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	pgxmigrate "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	"github.com/golang-migrate/migrate/v4/source"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// FS holds the *.up.sql and *.down.sql files shipped with the binary
+//
+//go:embed *.sql
+var FS embed.FS
+
+// lockTimeout is how long a replica waits for another replica to finish migrating before giving up
+const lockTimeout = time.Minute
+
+// undefinedTable is the postgres error code of a query on a table that does not exist
+const undefinedTable = "42P01"
+
+// Status describes the schema version of the database compared to the latest available migration.
+// A schema migrated by a newer release is up to date, so that the pods of the previous release
+// stay ready during a rolling deploy.
+type Status struct {
+	Version  uint `json:"version"`
+	Dirty    bool `json:"dirty"`
+	Latest   uint `json:"latest"`
+	UpToDate bool `json:"up_to_date"`
+}
+
+// Migrator applies schema migrations to a database.
+// Migrations are read from the embedded FS unless a source URL (e.g. file://db/migration) is provided.
+// golang-migrate holds a postgres advisory lock while migrating so that replicas starting
+// at the same time do not race each other : the first one migrates and the others wait and find no change.
+type Migrator struct {
+	connPool  *pgxpool.Pool
+	sourceURL string
+
+	// the latest version of the source, read once
+	latestOnce sync.Once
+	latest     uint
+	latestErr  error
+}
+
+// NewMigrator creates a new Migrator for the database of the connection pool.
+// Migrations run on a dedicated connection while the status is read with the connections of the pool.
+func NewMigrator(connPool *pgxpool.Pool, sourceURL string) *Migrator {
+	return &Migrator{
+		connPool:  connPool,
+		sourceURL: sourceURL,
+	}
+}
+
+// Up applies all pending migrations
+func (migrator *Migrator) Up(ctx context.Context) error {
+	m, err := migrator.newMigrate(ctx)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	err = m.Up()
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("failed to run migrate up : %w", err)
+	}
+
+	return nil
+}
+
+// Status returns the current migration status of the database.
+// It only reads the version table of golang-migrate, without taking its lock, so it is cheap enough for readiness probes.
+func (migrator *Migrator) Status(ctx context.Context) (Status, error) {
+	latest, err := migrator.latestVersion()
+	if err != nil {
+		return Status{}, err
+	}
+
+	var version int64
+	var dirty bool
+	err = migrator.connPool.QueryRow(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) && !isUndefinedTable(err) {
+		return Status{}, fmt.Errorf("failed to read migration version : %w", err)
+	}
+
+	// a database that was never migrated has no version
+	return newStatus(uint(version), dirty, latest), nil
+}
+
+func newStatus(version uint, dirty bool, latest uint) Status {
+	return Status{
+		Version:  version,
+		Dirty:    dirty,
+		Latest:   latest,
+		UpToDate: !dirty && version >= latest,
+	}
+}
+
+func isUndefinedTable(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == undefinedTable
+}
+
+// latestVersion returns the latest version of the migration source, which does not change while the server runs
+func (migrator *Migrator) latestVersion() (uint, error) {
+	migrator.latestOnce.Do(func() {
+		var src source.Driver
+		src, migrator.latestErr = migrator.openSource()
+		if migrator.latestErr != nil {
+			return
+		}
+		defer src.Close()
+
+		migrator.latest, migrator.latestErr = latestVersion(src)
+	})
+
+	return migrator.latest, migrator.latestErr
+}
+
+// newMigrate creates a migrate instance on a dedicated connection, apart from the pool of the store.
+// Closing the instance closes the connection.
+func (migrator *Migrator) newMigrate(ctx context.Context) (*migrate.Migrate, error) {
+	src, err := migrator.openSource()
+	if err != nil {
+		return nil, err
+	}
+
+	db := stdlib.OpenDB(*migrator.connPool.Config().ConnConfig)
+	err = db.PingContext(ctx)
+	if err != nil {
+		src.Close()
+		db.Close()
+		return nil, fmt.Errorf("cannot get migration connection : %w", err)
+	}
+
+	driver, err := pgxmigrate.WithInstance(db, &pgxmigrate.Config{})
+	if err != nil {
+		src.Close()
+		db.Close()
+		return nil, fmt.Errorf("cannot create migration driver : %w", err)
+	}
+
+	m, err := migrate.NewWithInstance("migrations", src, "pgx5", driver)
+	if err != nil {
+		src.Close()
+		driver.Close()
+		return nil, fmt.Errorf("cannot create migrate instance : %w", err)
+	}
+	m.LockTimeout = lockTimeout
+
+	return m, nil
+}
+
+func (migrator *Migrator) openSource() (source.Driver, error) {
+	if migrator.sourceURL == "" {
+		src, err := iofs.New(FS, ".")
+		if err != nil {
+			return nil, fmt.Errorf("cannot open embedded migrations : %w", err)
+		}
+		return src, nil
+	}
+
+	src, err := source.Open(migrator.sourceURL)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open migrations at %s : %w", migrator.sourceURL, err)
+	}
+	return src, nil
+}
+
+// latestVersion walks the migration source and returns the highest version available
+func latestVersion(src source.Driver) (uint, error) {
+	version, err := src.First()
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("cannot read first migration : %w", err)
+	}
+
+	for {
+		next, err := src.Next(version)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return version, nil
+			}
+			return 0, fmt.Errorf("cannot read migration after %d : %w", version, err)
+		}
+		version = next
+	}
+}
@@ -0,0 +1,41 @@
+package migration
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	files, err := fs.Glob(FS, "*.up.sql")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	// every up migration must ship with its down migration
+	for _, file := range files {
+		down := strings.TrimSuffix(file, ".up.sql") + ".down.sql"
+		_, err := fs.Stat(FS, down)
+		require.NoError(t, err, "missing %s", down)
+	}
+
+	src, err := iofs.New(FS, ".")
+	require.NoError(t, err)
+	defer src.Close()
+
+	latest, err := latestVersion(src)
+	require.NoError(t, err)
+	require.Equal(t, uint(len(files)), latest)
+}
+
+func TestStatusUpToDate(t *testing.T) {
+	require.True(t, newStatus(5, false, 5).UpToDate)
+	require.False(t, newStatus(4, false, 5).UpToDate)
+	require.False(t, newStatus(5, true, 5).UpToDate)
+
+	// the database was migrated by a newer release during a rolling deploy
+	require.True(t, newStatus(6, false, 5).UpToDate)
+	require.False(t, newStatus(6, true, 5).UpToDate)
+}
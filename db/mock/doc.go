@@ -0,0 +1,5 @@
+// Package mockdb is the gomock Store generated by `make mock`, used by the unit tests of the handlers.
+// It is kept alongside the in-memory store of db/sqlc because it can make any call fail,
+// which is how the error paths of the handlers are tested; flows spanning several handlers
+// are tested end to end against the in-memory store instead.
+package mockdb
@@ -2,9 +2,10 @@
 INSERT INTO accounts ( 
   owner, 
   balance, 
-  currency
+  currency,
+  type
 ) VALUES ( 
-  $1, $2, $3 
+  $1, $2, $3, $4
 ) RETURNING *;
 
 -- name: GetAccount :one
@@ -37,4 +38,17 @@ RETURNING *;
 
 -- name: DeleteAccount :exec
 DELETE FROM accounts
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: AddAccountHeldAmount :one
+UPDATE accounts
+SET held_amount = held_amount + sqlc.arg(amount)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: UpdateAccountStatus :one
+UPDATE accounts
+SET status = $2,
+  closed_at = CASE WHEN $2 = 'closed' THEN now() END
+WHERE id = $1
+RETURNING *;
@@ -0,0 +1,38 @@
+-- name: GetAccountLimits :one
+SELECT
+  a.id AS account_id,
+  u.tier,
+  COALESCE(l.max_transfer_amount, t.max_transfer_amount) AS max_transfer_amount,
+  COALESCE(l.daily_outgoing_limit, t.daily_outgoing_limit) AS daily_outgoing_limit,
+  COALESCE(l.monthly_outgoing_limit, t.monthly_outgoing_limit) AS monthly_outgoing_limit
+FROM accounts a
+JOIN users u ON u.username = a.owner
+JOIN limit_tiers t ON t.name = u.tier
+LEFT JOIN account_limits l ON l.account_id = a.id
+WHERE a.id = $1 LIMIT 1;
+
+-- name: UpsertAccountLimits :one
+INSERT INTO account_limits (
+  account_id,
+  max_transfer_amount,
+  daily_outgoing_limit,
+  monthly_outgoing_limit,
+  updated_by
+) VALUES (
+  $1, $2, $3, $4, $5
+) ON CONFLICT (account_id) DO UPDATE
+SET max_transfer_amount = EXCLUDED.max_transfer_amount,
+  daily_outgoing_limit = EXCLUDED.daily_outgoing_limit,
+  monthly_outgoing_limit = EXCLUDED.monthly_outgoing_limit,
+  updated_by = EXCLUDED.updated_by,
+  updated_at = now()
+RETURNING *;
+
+-- name: GetOutgoingTotals :one
+SELECT
+  COALESCE(-SUM(amount) FILTER (WHERE created_at >= sqlc.arg(day_start)), 0)::bigint AS daily_total,
+  COALESCE(-SUM(amount), 0)::bigint AS monthly_total
+FROM entries
+WHERE account_id = sqlc.arg(account_id)
+  AND amount < 0
+  AND created_at >= sqlc.arg(month_start);
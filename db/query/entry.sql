@@ -1,9 +1,10 @@
 -- name: CreateEntry :one
 INSERT INTO entries ( 
   account_id, 
-  amount 
+  amount,
+  transfer_id
 ) VALUES ( 
-  $1, $2
+  $1, $2, $3
 ) RETURNING *;
 
 -- name: GetEntry :one
@@ -14,14 +15,4 @@ WHERE id = $1 LIMIT 1;
 SELECT * FROM entries
 ORDER BY id
 LIMIT $1
-OFFSET $2;
-
--- name: UpdateEntry :one
-Update entries
-SET amount = $2
-WHERE id = $1
-RETURNING *;
-
--- name: DeleteEntry :exec
-DELETE FROM entries
-WHERE id = $1;
\ No newline at end of file
+OFFSET $2;
\ No newline at end of file
@@ -0,0 +1,31 @@
+-- name: LockEventPartition :exec
+SELECT pg_advisory_xact_lock(hashtext('events'), hashtext(sqlc.arg(partition_key)::varchar));
+
+-- name: CreateEvents :exec
+INSERT INTO events (
+  event_id,
+  event_type,
+  partition_key,
+  payload
+)
+SELECT sqlc.arg(event_id)::uuid, sqlc.arg(event_type)::varchar, unnest(sqlc.arg(partition_keys)::varchar[]), sqlc.arg(payload)::jsonb;
+
+-- name: TryLockEventRelay :one
+SELECT pg_try_advisory_xact_lock(hashtext('events_relay')) AS locked;
+
+-- name: ListUnpublishedEvents :many
+SELECT * FROM events
+WHERE published_at IS NULL
+ORDER BY id
+LIMIT $1;
+
+-- name: MarkEventsPublished :exec
+UPDATE events
+SET published_at = now()
+WHERE id = ANY(sqlc.arg(ids)::bigint[]);
+
+-- name: ListEvents :many
+SELECT * FROM events
+WHERE partition_key = $1
+ORDER BY id
+LIMIT $2;
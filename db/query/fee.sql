@@ -0,0 +1,34 @@
+-- name: CreateFeeSchedule :one
+INSERT INTO fee_schedules (
+  currency,
+  tier,
+  min_amount,
+  flat_fee,
+  percentage_bps,
+  min_fee,
+  max_fee
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+) RETURNING *;
+
+-- name: ListFeeSchedules :many
+SELECT * FROM fee_schedules
+ORDER BY id
+LIMIT $1
+OFFSET $2;
+
+-- name: DeleteFeeSchedule :exec
+DELETE FROM fee_schedules
+WHERE id = $1;
+
+-- name: GetFeeSchedule :one
+SELECT * FROM fee_schedules
+WHERE (currency = sqlc.arg(currency) OR currency IS NULL)
+  AND (tier = sqlc.arg(tier) OR tier IS NULL)
+  AND min_amount <= sqlc.arg(amount)
+ORDER BY currency IS NULL, tier IS NULL, min_amount DESC
+LIMIT 1;
+
+-- name: GetRevenueAccountID :one
+SELECT account_id FROM revenue_accounts
+WHERE currency = $1 LIMIT 1;
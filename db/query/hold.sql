@@ -0,0 +1,43 @@
+-- name: CreateHold :one
+INSERT INTO holds (
+  account_id,
+  to_account_id,
+  amount,
+  fee,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: GetHold :one
+SELECT * FROM holds
+WHERE id = $1 LIMIT 1;
+
+-- name: GetHoldForUpdate :one
+SELECT * FROM holds
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: ListAccountHolds :many
+SELECT * FROM holds
+WHERE account_id = $1
+ORDER BY id DESC
+LIMIT $2
+OFFSET $3;
+
+-- name: FinishHold :one
+UPDATE holds
+SET status = $2,
+  captured_amount = $3,
+  transfer_id = $4,
+  finished_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: ListExpiredHoldsForUpdate :many
+SELECT * FROM holds
+WHERE status = 'active'
+  AND expires_at <= sqlc.arg(now)::timestamptz
+ORDER BY expires_at
+LIMIT sqlc.arg(limit_count)
+FOR NO KEY UPDATE SKIP LOCKED;
@@ -0,0 +1,99 @@
+-- name: ListInterestRates :many
+SELECT * FROM interest_rates
+ORDER BY currency;
+
+-- name: UpsertInterestRate :one
+INSERT INTO interest_rates (
+  currency,
+  annual_rate_bps,
+  day_count
+) VALUES (
+  $1, $2, $3
+)
+ON CONFLICT (currency) DO UPDATE
+SET annual_rate_bps = EXCLUDED.annual_rate_bps,
+  day_count = EXCLUDED.day_count,
+  updated_at = now()
+RETURNING *;
+
+-- name: ListUnaccruedInterestBalances :many
+SELECT a.id AS account_id,
+  (a.balance - COALESCE((
+    SELECT SUM(e.amount) FROM entries e
+    WHERE e.account_id = a.id AND e.created_at >= sqlc.arg(end_of_day)
+  ), 0))::bigint AS balance,
+  r.annual_rate_bps,
+  r.day_count
+FROM accounts a
+JOIN interest_rates r ON r.currency = a.currency
+WHERE a.type = 'savings'
+  AND a.created_at < sqlc.arg(end_of_day)
+  AND NOT EXISTS (
+    SELECT 1 FROM interest_accruals i
+    WHERE i.account_id = a.id AND i.accrual_date = sqlc.arg(accrual_date)
+  )
+ORDER BY a.id
+LIMIT sqlc.arg(limit_count);
+
+-- name: CreateInterestAccrual :one
+INSERT INTO interest_accruals (
+  account_id,
+  accrual_date,
+  balance,
+  annual_rate_bps,
+  day_count,
+  units
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+)
+ON CONFLICT (account_id, accrual_date) DO NOTHING
+RETURNING *;
+
+-- name: GetLastInterestAccrualDate :one
+SELECT accrual_date FROM interest_accruals
+ORDER BY accrual_date DESC
+LIMIT 1;
+
+-- name: SumInterestAccruals :one
+SELECT COALESCE(SUM(units), 0)::bigint AS units FROM interest_accruals
+WHERE account_id = sqlc.arg(account_id)
+  AND accrual_date >= sqlc.arg(month)
+  AND accrual_date < (sqlc.arg(month)::date + interval '1 month');
+
+-- name: ListPendingInterestPostings :many
+SELECT DISTINCT i.account_id, date_trunc('month', i.accrual_date)::date AS month
+FROM interest_accruals i
+JOIN accounts a ON a.id = i.account_id
+WHERE a.status = 'active'
+  AND i.accrual_date < sqlc.arg(before)
+  AND NOT EXISTS (
+    SELECT 1 FROM interest_postings p
+    WHERE p.account_id = i.account_id AND p.month = date_trunc('month', i.accrual_date)::date
+  )
+ORDER BY month, i.account_id
+LIMIT sqlc.arg(limit_count);
+
+-- name: GetLastInterestPosting :one
+SELECT * FROM interest_postings
+WHERE account_id = sqlc.arg(account_id) AND month < sqlc.arg(month)
+ORDER BY month DESC
+LIMIT 1;
+
+-- name: CreateInterestPosting :one
+INSERT INTO interest_postings (
+  account_id,
+  month,
+  units,
+  amount,
+  remainder_units,
+  transfer_id
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+) RETURNING *;
+
+-- name: ListInterestPostings :many
+SELECT * FROM interest_postings
+WHERE account_id = $1
+ORDER BY month DESC
+LIMIT $2
+OFFSET $3;
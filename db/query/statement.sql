@@ -0,0 +1,33 @@
+-- name: GetAccountBalanceAt :one
+SELECT (a.balance - COALESCE((
+  SELECT SUM(e.amount) FROM entries e
+  WHERE e.account_id = a.id AND e.created_at >= sqlc.arg(at)
+), 0))::bigint AS balance
+FROM accounts a
+WHERE a.id = sqlc.arg(account_id);
+
+-- name: ListStatementEntries :many
+SELECT e.id,
+  e.amount,
+  e.created_at,
+  e.transfer_id,
+  c.id AS counterparty_account_id,
+  c.owner AS counterparty_owner,
+  (CASE
+    WHEN e.transfer_id IS NULL THEN 'deposit'
+    WHEN r.transfer_id IS NOT NULL THEN 'reversal'
+    WHEN ra.account_id IS NOT NULL AND e.amount < 0 THEN 'fee'
+    WHEN ra.account_id IS NOT NULL THEN 'interest'
+    ELSE 'transfer'
+  END)::varchar AS kind
+FROM entries e
+LEFT JOIN transfers t ON t.id = e.transfer_id
+LEFT JOIN accounts c ON c.id = (CASE WHEN t.from_account_id = e.account_id THEN t.to_account_id ELSE t.from_account_id END)
+LEFT JOIN transfer_reversals r ON r.transfer_id = e.transfer_id
+LEFT JOIN revenue_accounts ra ON ra.account_id = c.id
+WHERE e.account_id = sqlc.arg(account_id)
+  AND e.created_at >= sqlc.arg(from_time)
+  AND e.created_at < sqlc.arg(to_time)
+  AND e.id > sqlc.arg(after_id)
+ORDER BY e.id
+LIMIT sqlc.arg(limit_count);
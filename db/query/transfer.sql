@@ -17,12 +17,7 @@ ORDER BY id
 LIMIT $1
 OFFSET $2;
 
--- name: UpdateTransfer :one
-Update transfers
-SET amount = $2
-WHERE id = $1
-RETURNING *;
-
--- name: DeleteTransfer :exec
-DELETE FROM transfers
-WHERE id = $1;
\ No newline at end of file
+-- name: GetTransferForUpdate :one
+SELECT * FROM transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
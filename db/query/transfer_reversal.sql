@@ -0,0 +1,18 @@
+-- name: CreateTransferReversal :one
+INSERT INTO transfer_reversals (
+  transfer_id,
+  original_transfer_id,
+  reason
+) VALUES (
+  $1, $2, $3
+) RETURNING *;
+
+-- name: GetTransferReversal :one
+SELECT * FROM transfer_reversals
+WHERE transfer_id = $1 LIMIT 1;
+
+-- name: GetReversedAmount :one
+SELECT COALESCE(SUM(t.amount), 0)::bigint AS reversed_amount
+FROM transfer_reversals r
+JOIN transfers t ON t.id = r.transfer_id
+WHERE r.original_transfer_id = $1;
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// CheckTransferAmount checks a single movement of money against the maximum amount allowed on the account
+func CheckTransferAmount(limits GetAccountLimitsRow, amount int64) error {
+	if limits.MaxTransferAmount.Valid && amount > limits.MaxTransferAmount.Int64 {
+		return ErrTransferLimitExceeded
+	}
+	return nil
+}
+
+// checkOutgoingLimits checks a transfer against the limits of the account sending it.
+// It runs once the entry of the transfer is recorded and the account row is locked,
+// so that the totals include the transfer and concurrent transfers cannot both slip under a limit.
+// The funds held on the account are added to the totals, so that holds cannot be used to get around the limits
+// and a hold cannot reserve more than the account is still allowed to send.
+// Days and months start at midnight UTC.
+func checkOutgoingLimits(ctx context.Context, q Querier, accountID int64, amount int64, held int64, now time.Time) error {
+	limits, err := q.GetAccountLimits(ctx, accountID)
+	if err != nil {
+		return err
+	}
+
+	if err := CheckTransferAmount(limits, amount); err != nil {
+		return err
+	}
+
+	if !limits.DailyOutgoingLimit.Valid && !limits.MonthlyOutgoingLimit.Valid {
+		return nil
+	}
+
+	now = now.UTC()
+	totals, err := q.GetOutgoingTotals(ctx, GetOutgoingTotalsParams{
+		DayStart:   time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC),
+		AccountID:  accountID,
+		MonthStart: time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		return err
+	}
+
+	if limits.DailyOutgoingLimit.Valid && totals.DailyTotal+held > limits.DailyOutgoingLimit.Int64 {
+		return ErrDailyLimitExceeded
+	}
+	if limits.MonthlyOutgoingLimit.Valid && totals.MonthlyTotal+held > limits.MonthlyOutgoingLimit.Int64 {
+		return ErrMonthlyLimitExceeded
+	}
+
+	return nil
+}
@@ -0,0 +1,191 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func setAccountLimits(t *testing.T, account Account, arg UpsertAccountLimitsParams) {
+	arg.AccountID = account.ID
+	arg.UpdatedBy = account.Owner
+
+	limits, err := testQueries.UpsertAccountLimits(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, account.ID, limits.AccountID)
+}
+
+func TestGetAccountLimits(t *testing.T) {
+	account := createRandomAccount(t)
+
+	// a new account gets the limits of the standard tier
+	limits, err := testQueries.GetAccountLimits(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, "standard", limits.Tier)
+	require.Equal(t, pgtype.Int8{Int64: 10000, Valid: true}, limits.MaxTransferAmount)
+	require.Equal(t, pgtype.Int8{Int64: 50000, Valid: true}, limits.DailyOutgoingLimit)
+	require.Equal(t, pgtype.Int8{Int64: 500000, Valid: true}, limits.MonthlyOutgoingLimit)
+
+	// an override replaces the tier limit it sets only
+	setAccountLimits(t, account, UpsertAccountLimitsParams{
+		DailyOutgoingLimit: pgtype.Int8{Int64: 100, Valid: true},
+	})
+
+	limits, err = testQueries.GetAccountLimits(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(10000), limits.MaxTransferAmount.Int64)
+	require.Equal(t, int64(100), limits.DailyOutgoingLimit.Int64)
+	require.Equal(t, int64(500000), limits.MonthlyOutgoingLimit.Int64)
+
+	// the tier applies to every account of the user
+	_, err = testQueries.UpdateUserTier(context.Background(), UpdateUserTierParams{
+		Username: account.Owner,
+		Tier:     "premium",
+	})
+	require.NoError(t, err)
+
+	limits, err = testQueries.GetAccountLimits(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, "premium", limits.Tier)
+	require.Equal(t, int64(100000), limits.MaxTransferAmount.Int64)
+	require.Equal(t, int64(100), limits.DailyOutgoingLimit.Int64)
+}
+
+func TestTransferTxLimits(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccountInCurrency(t, account1.Currency)
+
+	setAccountLimits(t, account1, UpsertAccountLimitsParams{
+		MaxTransferAmount:    pgtype.Int8{Int64: 50, Valid: true},
+		DailyOutgoingLimit:   pgtype.Int8{Int64: 80, Valid: true},
+		MonthlyOutgoingLimit: pgtype.Int8{Int64: 1000, Valid: true},
+	})
+
+	transfer := func(amount int64) error {
+		_, err := store.TransferTx(context.Background(), TransferTxParams{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        amount,
+		})
+		return err
+	}
+
+	require.ErrorIs(t, transfer(51), ErrTransferLimitExceeded)
+
+	require.NoError(t, transfer(50))
+	require.NoError(t, transfer(30))
+	require.ErrorIs(t, transfer(1), ErrDailyLimitExceeded)
+
+	// incoming transfers do not count against the limits
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account2.ID,
+		ToAccountID:   account1.ID,
+		Amount:        20,
+	})
+	require.NoError(t, err)
+	require.ErrorIs(t, transfer(1), ErrDailyLimitExceeded)
+
+	// rejected transfers are rolled back
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance-80+20, updatedAccount1.Balance)
+}
+
+func TestTransferTxMonthlyLimit(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccountInCurrency(t, account1.Currency)
+
+	setAccountLimits(t, account1, UpsertAccountLimitsParams{
+		MonthlyOutgoingLimit: pgtype.Int8{Int64: 40, Valid: true},
+	})
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        41,
+	})
+	require.ErrorIs(t, err, ErrMonthlyLimitExceeded)
+}
+
+func TestTransferTxLimitsConcurrent(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccountInCurrency(t, account1.Currency)
+
+	setAccountLimits(t, account1, UpsertAccountLimitsParams{
+		DailyOutgoingLimit: pgtype.Int8{Int64: 50, Valid: true},
+	})
+
+	// concurrent transfers cannot go over the limit together
+	n := 10
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.TransferTx(context.Background(), TransferTxParams{
+				FromAccountID: account1.ID,
+				ToAccountID:   account2.ID,
+				Amount:        10,
+			})
+			errs <- err
+		}()
+	}
+
+	succeeded := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err == nil {
+			succeeded++
+			continue
+		}
+		require.ErrorIs(t, err, ErrDailyLimitExceeded)
+	}
+	require.Equal(t, 5, succeeded)
+}
+
+func TestHoldTxLimits(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccountInCurrency(t, account1.Currency)
+
+	setAccountLimits(t, account1, UpsertAccountLimitsParams{
+		MaxTransferAmount:  pgtype.Int8{Int64: 50, Valid: true},
+		DailyOutgoingLimit: pgtype.Int8{Int64: 80, Valid: true},
+	})
+
+	hold := func(amount int64) error {
+		_, err := store.HoldTx(context.Background(), HoldTxParams{
+			AccountID:   account1.ID,
+			ToAccountID: account2.ID,
+			Amount:      amount,
+			ExpiresAt:   time.Now().Add(time.Hour),
+		})
+		return err
+	}
+
+	require.ErrorIs(t, hold(51), ErrTransferLimitExceeded)
+
+	// outstanding holds count against the limits of holds and transfers
+	require.NoError(t, hold(50))
+	require.ErrorIs(t, hold(31), ErrDailyLimitExceeded)
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        31,
+	})
+	require.ErrorIs(t, err, ErrDailyLimitExceeded)
+
+	// rejected holds are rolled back
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(50), updatedAccount1.HeldAmount)
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+)
+
+// Statuses of an account
+const (
+	AccountActive = "active"
+	AccountFrozen = "frozen"
+	AccountClosed = "closed"
+)
+
+// accountTransitions lists the statuses an account can move to from each status.
+// A closed account stays closed.
+var accountTransitions = map[string][]string{
+	AccountActive: {AccountFrozen, AccountClosed},
+	AccountFrozen: {AccountActive},
+}
+
+// CanChangeAccountStatus tells whether an account can move from a status to another
+func CanChangeAccountStatus(from, to string) bool {
+	for _, status := range accountTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// AccountStatusError returns the error to report when money is moved on an account with the status,
+// or nil when the account is active
+func AccountStatusError(status string) error {
+	switch status {
+	case AccountActive:
+		return nil
+	case AccountFrozen:
+		return ErrAccountFrozen
+	default:
+		return ErrAccountClosed
+	}
+}
+
+// ChangeAccountStatusTxParams contains the input parameters of the status change transaction
+type ChangeAccountStatusTxParams struct {
+	AccountID int64  `json:"account_id"`
+	Status    string `json:"status"`
+	ChangedBy string `json:"changed_by"`
+	Reason    string `json:"reason"`
+}
+
+// ChangeAccountStatusTxResult represents the result of the status change transaction
+type ChangeAccountStatusTxResult struct {
+	Account Account             `json:"account"`
+	Change  AccountStatusChange `json:"change"`
+}
+
+// ChangeAccountStatusTx moves an account to a new status, records the change in the audit trail and the event stream
+// and notifies the webhooks of the owner.
+// Only the transitions of accountTransitions are allowed and an account can only be closed once it is empty.
+func (store *txStore) ChangeAccountStatusTx(ctx context.Context, arg ChangeAccountStatusTxParams) (ChangeAccountStatusTxResult, error) {
+	var result ChangeAccountStatusTxResult
+
+	err := store.execTx(ctx, nil, func(q Querier) error {
+		account, err := q.GetAccountForUpdate(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		if !CanChangeAccountStatus(account.Status, arg.Status) {
+			return ErrInvalidStatusTransition
+		}
+
+		if arg.Status == AccountClosed && (account.Balance != 0 || account.HeldAmount != 0) {
+			return ErrAccountNotEmpty
+		}
+
+		result.Account, err = q.UpdateAccountStatus(ctx, UpdateAccountStatusParams{
+			ID:     account.ID,
+			Status: arg.Status,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Change, err = q.CreateAccountStatusChange(ctx, CreateAccountStatusChangeParams{
+			AccountID:  account.ID,
+			FromStatus: account.Status,
+			ToStatus:   arg.Status,
+			ChangedBy:  arg.ChangedBy,
+			Reason:     arg.Reason,
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordEvent(ctx, q, accountStatusEvent(account.Status, arg.Status),
+			[]string{AccountPartition(account.ID)}, []string{account.Owner}, result.Account)
+	})
+
+	return result, err
+}
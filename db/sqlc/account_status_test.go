@@ -0,0 +1,151 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanChangeAccountStatus(t *testing.T) {
+	require.True(t, CanChangeAccountStatus(AccountActive, AccountFrozen))
+	require.True(t, CanChangeAccountStatus(AccountFrozen, AccountActive))
+	require.True(t, CanChangeAccountStatus(AccountActive, AccountClosed))
+
+	require.False(t, CanChangeAccountStatus(AccountActive, AccountActive))
+	require.False(t, CanChangeAccountStatus(AccountFrozen, AccountClosed))
+	require.False(t, CanChangeAccountStatus(AccountClosed, AccountActive))
+	require.False(t, CanChangeAccountStatus(AccountClosed, AccountFrozen))
+}
+
+func TestChangeAccountStatusTx(t *testing.T) {
+	store := NewStore(testDB)
+	admin := createRandomUser(t)
+	account := createRandomAccount(t)
+	require.Equal(t, AccountActive, account.Status)
+
+	result, err := store.ChangeAccountStatusTx(context.Background(), ChangeAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountFrozen,
+		ChangedBy: admin.Username,
+		Reason:    "compromised",
+	})
+	require.NoError(t, err)
+	require.Equal(t, AccountFrozen, result.Account.Status)
+	require.False(t, result.Account.ClosedAt.Valid)
+
+	change := result.Change
+	require.NotZero(t, change.ID)
+	require.Equal(t, account.ID, change.AccountID)
+	require.Equal(t, AccountActive, change.FromStatus)
+	require.Equal(t, AccountFrozen, change.ToStatus)
+	require.Equal(t, admin.Username, change.ChangedBy)
+	require.Equal(t, "compromised", change.Reason)
+
+	// a frozen account cannot be frozen again nor closed
+	_, err = store.ChangeAccountStatusTx(context.Background(), ChangeAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountClosed,
+		ChangedBy: admin.Username,
+	})
+	require.ErrorIs(t, err, ErrInvalidStatusTransition)
+
+	_, err = store.ChangeAccountStatusTx(context.Background(), ChangeAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountActive,
+		ChangedBy: admin.Username,
+		Reason:    "verified",
+	})
+	require.NoError(t, err)
+
+	changes, err := testQueries.ListAccountStatusChanges(context.Background(), ListAccountStatusChangesParams{
+		AccountID: account.ID,
+		Limit:     5,
+	})
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	require.Equal(t, AccountActive, changes[0].ToStatus)
+	require.Equal(t, AccountFrozen, changes[1].ToStatus)
+}
+
+func TestCloseAccount(t *testing.T) {
+	store := NewStore(testDB)
+	account := createRandomAccount(t)
+
+	// only an empty account can be closed
+	_, err := store.ChangeAccountStatusTx(context.Background(), ChangeAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountClosed,
+		ChangedBy: account.Owner,
+	})
+	require.ErrorIs(t, err, ErrAccountNotEmpty)
+
+	_, err = testQueries.UpdateAccountBalance(context.Background(), UpdateAccountBalanceParams{
+		Amount: -account.Balance,
+		ID:     account.ID,
+	})
+	require.NoError(t, err)
+
+	result, err := store.ChangeAccountStatusTx(context.Background(), ChangeAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountClosed,
+		ChangedBy: account.Owner,
+	})
+	require.NoError(t, err)
+	require.Equal(t, AccountClosed, result.Account.Status)
+	require.True(t, result.Account.ClosedAt.Valid)
+	require.WithinDuration(t, time.Now(), result.Account.ClosedAt.Time, time.Second)
+
+	// a closed account cannot be reopened
+	_, err = store.ChangeAccountStatusTx(context.Background(), ChangeAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountActive,
+		ChangedBy: account.Owner,
+	})
+	require.ErrorIs(t, err, ErrInvalidStatusTransition)
+}
+
+func TestTransferTxInactiveAccount(t *testing.T) {
+	store := NewStore(testDB)
+	admin := createRandomUser(t)
+
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccountInCurrency(t, account1.Currency)
+
+	_, err := store.ChangeAccountStatusTx(context.Background(), ChangeAccountStatusTxParams{
+		AccountID: account2.ID,
+		Status:    AccountFrozen,
+		ChangedBy: admin.Username,
+	})
+	require.NoError(t, err)
+
+	// a frozen account can neither be debited nor credited
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrAccountFrozen)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account2.ID,
+		ToAccountID:   account1.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrAccountFrozen)
+
+	_, err = store.HoldTx(context.Background(), HoldTxParams{
+		AccountID:   account1.ID,
+		ToAccountID: account2.ID,
+		Amount:      10,
+		ExpiresAt:   time.Now().Add(time.Hour),
+	})
+	require.ErrorIs(t, err, ErrAccountFrozen)
+
+	// nothing has been moved
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+	require.Zero(t, updatedAccount1.HeldAmount)
+}
@@ -2,7 +2,6 @@ package db
 
 import (
 	"context"
-	"database/sql"
 	"testing"
 	"time"
 
@@ -12,12 +11,18 @@ import (
 
 // createRandomAccount creates a random account to be used in tests
 func createRandomAccount(t *testing.T) Account {
+	return createRandomAccountInCurrency(t, util.RandomCurrency())
+}
+
+func createRandomAccountInCurrency(t *testing.T, currency string) Account {
 	user := createRandomUser(t)
 
+	// keep enough balance for the transfer tests as balances cannot go below zero
 	arg := CreateAccountParams{
 		Owner:    user.Username,
-		Balance:  util.RandomAmount(),
-		Currency: util.RandomCurrency(),
+		Balance:  util.RandomInt(100, 1000),
+		Currency: currency,
+		Type:     AccountChecking,
 	}
 
 	account, err := testQueries.CreateAccount(context.Background(), arg)
@@ -27,6 +32,7 @@ func createRandomAccount(t *testing.T) Account {
 	require.Equal(t, arg.Owner, account.Owner)
 	require.Equal(t, arg.Balance, account.Balance)
 	require.Equal(t, arg.Currency, account.Currency)
+	require.Equal(t, arg.Type, account.Type)
 
 	require.NotZero(t, account.ID)
 	require.NotZero(t, account.CreatedAt)
@@ -38,6 +44,33 @@ func TestCreateAccount(t *testing.T) {
 	createRandomAccount(t)
 }
 
+func TestCreateSavingsAccount(t *testing.T) {
+	user := createRandomUser(t)
+
+	arg := CreateAccountParams{
+		Owner:    user.Username,
+		Balance:  util.RandomAmount(),
+		Currency: util.RandomCurrency(),
+		Type:     AccountSavings,
+	}
+
+	account, err := testQueries.CreateAccount(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Owner, account.Owner)
+	require.Equal(t, arg.Balance, account.Balance)
+	require.Equal(t, arg.Currency, account.Currency)
+	require.Equal(t, AccountSavings, account.Type)
+
+	// the owner may also hold a checking account in the currency, but one open account of each type only
+	arg.Type = AccountChecking
+	_, err = testQueries.CreateAccount(context.Background(), arg)
+	require.NoError(t, err)
+
+	arg.Type = AccountSavings
+	_, err = testQueries.CreateAccount(context.Background(), arg)
+	requirePgError(t, err, UniqueViolation, "owner_currency_type_key")
+}
+
 func TestGetAccount(t *testing.T) {
 	account1 := createRandomAccount(t)
 
@@ -79,7 +112,7 @@ func TestDeleteAccount(t *testing.T) {
 
 	account2, err := testQueries.GetAccount(context.Background(), account1.ID)
 	require.Error(t, err)
-	require.EqualError(t, err, sql.ErrNoRows.Error())
+	require.EqualError(t, err, ErrRecordNotFound.Error())
 	require.Empty(t, account2)
 }
 
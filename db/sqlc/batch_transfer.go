@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"sort"
+)
+
+// BatchTransferLeg is one movement of money within a batch transfer
+type BatchTransferLeg struct {
+	FromAccountID int64 `json:"from_account_id"`
+	ToAccountID   int64 `json:"to_account_id"`
+	Amount        int64 `json:"amount"`
+}
+
+// BatchTransferTxParams contains the input parameters of the batch transfer transaction
+type BatchTransferTxParams struct {
+	Currency string             `json:"currency"`
+	Legs     []BatchTransferLeg `json:"legs"`
+}
+
+// BatchTransferTxResult represents the result of the batch transfer transaction, one result per leg in the order of the request
+type BatchTransferTxResult struct {
+	Legs []TransferTxResult `json:"legs"`
+}
+
+// BatchTransferTx moves money between many accounts atomically : either every leg is applied or none.
+// All the accounts involved are locked in ascending id order before any balance changes,
+// so concurrent batches and transfers touching the same accounts cannot deadlock each other.
+// A leg that cannot be applied fails the whole batch with a *BatchTransferError.
+func (store *txStore) BatchTransferTx(ctx context.Context, arg BatchTransferTxParams) (BatchTransferTxResult, error) {
+	var result BatchTransferTxResult
+
+	err := store.execTx(ctx, nil, func(q Querier) error {
+		accounts, err := lockBatchAccounts(ctx, q, arg)
+		if err != nil {
+			return err
+		}
+
+		// check the legs and their fees against the locked available balances before writing anything
+		balances := make(map[int64]int64, len(accounts))
+		for id, account := range accounts {
+			balances[id] = account.AvailableBalance
+		}
+
+		fees := make([]Fee, len(arg.Legs))
+		for i, leg := range arg.Legs {
+			from := accounts[leg.FromAccountID]
+
+			fees[i], err = evaluateFee(ctx, q, from, leg.Amount)
+			if err != nil {
+				return &BatchTransferError{Leg: i, Err: err}
+			}
+
+			balances[from.ID] -= leg.Amount + fees[i].Amount
+			balances[leg.ToAccountID] += leg.Amount
+
+			if balances[from.ID] < 0 && !from.OverdraftEnabled {
+				return &BatchTransferError{Leg: i, Err: ErrInsufficientFunds}
+			}
+		}
+
+		result.Legs = make([]TransferTxResult, len(arg.Legs))
+		for i, leg := range arg.Legs {
+			result.Legs[i], err = applyTransfer(ctx, q, TransferTxParams{
+				FromAccountID: leg.FromAccountID,
+				ToAccountID:   leg.ToAccountID,
+				Amount:        leg.Amount,
+			})
+			if err == nil {
+				result.Legs[i], err = chargeFee(ctx, q, result.Legs[i], fees[i])
+			}
+			if err != nil {
+				return &BatchTransferError{Leg: i, Err: err}
+			}
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+// lockBatchAccounts locks every account of the batch in ascending id order and checks their currency
+func lockBatchAccounts(ctx context.Context, q Querier, arg BatchTransferTxParams) (map[int64]Account, error) {
+	// remember the first leg referencing each account to report errors against it
+	firstLeg := make(map[int64]int)
+	for i := len(arg.Legs) - 1; i >= 0; i-- {
+		firstLeg[arg.Legs[i].FromAccountID] = i
+		firstLeg[arg.Legs[i].ToAccountID] = i
+	}
+
+	ids := make([]int64, 0, len(firstLeg))
+	for id := range firstLeg {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	accounts := make(map[int64]Account, len(ids))
+	for _, id := range ids {
+		account, err := q.GetAccountForUpdate(ctx, id)
+		if err != nil {
+			return nil, &BatchTransferError{Leg: firstLeg[id], Err: err}
+		}
+
+		if account.Currency != arg.Currency {
+			return nil, &BatchTransferError{Leg: firstLeg[id], Err: ErrCurrencyMismatch}
+		}
+
+		if err := AccountStatusError(account.Status); err != nil {
+			return nil, &BatchTransferError{Leg: firstLeg[id], Err: err}
+		}
+
+		accounts[id] = account
+	}
+
+	return accounts, nil
+}
@@ -0,0 +1,201 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/samirprakash/go-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	payer := createRandomAccountInCurrency(t, util.USD)
+	payee1 := createRandomAccountInCurrency(t, util.USD)
+	payee2 := createRandomAccountInCurrency(t, util.USD)
+
+	arg := BatchTransferTxParams{
+		Currency: util.USD,
+		Legs: []BatchTransferLeg{
+			{FromAccountID: payer.ID, ToAccountID: payee1.ID, Amount: 10},
+			{FromAccountID: payer.ID, ToAccountID: payee2.ID, Amount: 20},
+		},
+	}
+
+	result, err := store.BatchTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, result.Legs, 2)
+
+	for i, leg := range result.Legs {
+		require.NotZero(t, leg.Transfer.ID)
+		require.Equal(t, arg.Legs[i].FromAccountID, leg.Transfer.FromAccountID)
+		require.Equal(t, arg.Legs[i].ToAccountID, leg.Transfer.ToAccountID)
+		require.Equal(t, arg.Legs[i].Amount, leg.Transfer.Amount)
+		require.Equal(t, -arg.Legs[i].Amount, leg.FromEntry.Amount)
+		require.Equal(t, arg.Legs[i].Amount, leg.ToEntry.Amount)
+	}
+
+	// balances reflect the legs applied so far
+	require.Equal(t, payer.Balance-10, result.Legs[0].FromAccount.Balance)
+	require.Equal(t, payer.Balance-30, result.Legs[1].FromAccount.Balance)
+	require.Equal(t, payee1.Balance+10, result.Legs[0].ToAccount.Balance)
+	require.Equal(t, payee2.Balance+20, result.Legs[1].ToAccount.Balance)
+}
+
+func TestBatchTransferTxAllOrNothing(t *testing.T) {
+	store := NewStore(testDB)
+
+	payer := createRandomAccountInCurrency(t, util.EUR)
+	payee := createRandomAccountInCurrency(t, util.EUR)
+	other := createRandomAccountInCurrency(t, util.USD)
+
+	testCases := []struct {
+		name string
+		legs []BatchTransferLeg
+		leg  int
+		err  error
+	}{
+		{
+			name: "InsufficientFunds",
+			legs: []BatchTransferLeg{
+				{FromAccountID: payer.ID, ToAccountID: payee.ID, Amount: 1},
+				{FromAccountID: payer.ID, ToAccountID: payee.ID, Amount: payer.Balance},
+			},
+			leg: 1,
+			err: ErrInsufficientFunds,
+		},
+		{
+			name: "CurrencyMismatch",
+			legs: []BatchTransferLeg{
+				{FromAccountID: payer.ID, ToAccountID: payee.ID, Amount: 1},
+				{FromAccountID: payer.ID, ToAccountID: other.ID, Amount: 1},
+			},
+			leg: 1,
+			err: ErrCurrencyMismatch,
+		},
+		{
+			name: "AccountNotFound",
+			legs: []BatchTransferLeg{
+				{FromAccountID: payer.ID, ToAccountID: payee.ID, Amount: 1},
+				{FromAccountID: payer.ID, ToAccountID: 0, Amount: 1},
+			},
+			leg: 1,
+			err: ErrRecordNotFound,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := store.BatchTransferTx(context.Background(), BatchTransferTxParams{
+				Currency: util.EUR,
+				Legs:     tc.legs,
+			})
+			require.ErrorIs(t, err, tc.err)
+
+			var legErr *BatchTransferError
+			require.ErrorAs(t, err, &legErr)
+			require.Equal(t, tc.leg, legErr.Leg)
+		})
+	}
+
+	// no leg has been applied
+	updatedPayer, err := store.GetAccount(context.Background(), payer.ID)
+	require.NoError(t, err)
+	require.Equal(t, payer.Balance, updatedPayer.Balance)
+
+	updatedPayee, err := store.GetAccount(context.Background(), payee.ID)
+	require.NoError(t, err)
+	require.Equal(t, payee.Balance, updatedPayee.Balance)
+}
+
+func TestBatchTransferTxFee(t *testing.T) {
+	store := NewStore(testDB)
+
+	payer := createPremiumAccount(t)
+	payee := createRandomAccountInCurrency(t, payer.Currency)
+
+	addFeeSchedule(t, CreateFeeScheduleParams{
+		Currency: pgtype.Text{String: payer.Currency, Valid: true},
+		Tier:     pgtype.Text{String: "premium", Valid: true},
+		FlatFee:  2,
+	})
+
+	// the fees count in the balance checked before any leg is applied
+	_, err := store.BatchTransferTx(context.Background(), BatchTransferTxParams{
+		Currency: payer.Currency,
+		Legs: []BatchTransferLeg{
+			{FromAccountID: payer.ID, ToAccountID: payee.ID, Amount: 1},
+			{FromAccountID: payer.ID, ToAccountID: payee.ID, Amount: payer.Balance - 4},
+		},
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	var legErr *BatchTransferError
+	require.ErrorAs(t, err, &legErr)
+	require.Equal(t, 1, legErr.Leg)
+
+	result, err := store.BatchTransferTx(context.Background(), BatchTransferTxParams{
+		Currency: payer.Currency,
+		Legs: []BatchTransferLeg{
+			{FromAccountID: payer.ID, ToAccountID: payee.ID, Amount: 1},
+			{FromAccountID: payer.ID, ToAccountID: payee.ID, Amount: payer.Balance - 5},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), result.Legs[0].Fee.Amount)
+	require.Equal(t, int64(2), result.Legs[1].Fee.Amount)
+	require.Zero(t, result.Legs[1].FromAccount.Balance)
+}
+
+func TestBatchTransferTxDeadlock(t *testing.T) {
+	store := NewStore(testDB)
+
+	accounts := []Account{
+		createRandomAccountInCurrency(t, util.GBP),
+		createRandomAccountInCurrency(t, util.GBP),
+		createRandomAccountInCurrency(t, util.GBP),
+	}
+
+	// batches touching the same accounts in opposite orders
+	n := 10
+	errs := make(chan error)
+
+	for i := 0; i < n; i++ {
+		legs := []BatchTransferLeg{
+			{FromAccountID: accounts[0].ID, ToAccountID: accounts[1].ID, Amount: 5},
+			{FromAccountID: accounts[1].ID, ToAccountID: accounts[2].ID, Amount: 5},
+			{FromAccountID: accounts[2].ID, ToAccountID: accounts[0].ID, Amount: 5},
+		}
+		if i%2 == 1 {
+			legs = []BatchTransferLeg{
+				{FromAccountID: accounts[2].ID, ToAccountID: accounts[1].ID, Amount: 5},
+				{FromAccountID: accounts[1].ID, ToAccountID: accounts[0].ID, Amount: 5},
+				{FromAccountID: accounts[0].ID, ToAccountID: accounts[2].ID, Amount: 5},
+			}
+		}
+
+		go func() {
+			_, err := store.BatchTransferTx(context.Background(), BatchTransferTxParams{
+				Currency: util.GBP,
+				Legs:     legs,
+			})
+			errs <- err
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		err := <-errs
+		require.NoError(t, err)
+	}
+
+	// every batch is a cycle so the balances are unchanged
+	for _, account := range accounts {
+		updatedAccount, err := store.GetAccount(context.Background(), account.ID)
+		require.NoError(t, err)
+		require.Equal(t, account.Balance, updatedAccount.Balance)
+	}
+}
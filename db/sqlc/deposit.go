@@ -0,0 +1,48 @@
+package db
+
+import "context"
+
+// DepositTxParams contains the input parameters of the deposit transaction
+type DepositTxParams struct {
+	AccountID int64 `json:"account_id"`
+	Amount    int64 `json:"amount"`
+}
+
+// DepositTxResult represents the result of the deposit transaction
+type DepositTxResult struct {
+	Account Account `json:"account"`
+	Entry   Entry   `json:"entry"`
+}
+
+// DepositTx adds money to an account from outside the bank.
+// The deposit is recorded as an entry without transfer so that the entries of an account add up to its balance.
+func (store *txStore) DepositTx(ctx context.Context, arg DepositTxParams) (DepositTxResult, error) {
+	var result DepositTxResult
+
+	err := store.execTx(ctx, nil, func(q Querier) error {
+		var err error
+
+		result.Account, err = q.UpdateAccountBalance(ctx, UpdateAccountBalanceParams{
+			Amount: arg.Amount,
+			ID:     arg.AccountID,
+		})
+		if err != nil {
+			return err
+		}
+		if err := AccountStatusError(result.Account.Status); err != nil {
+			return err
+		}
+
+		result.Entry, err = q.CreateEntry(ctx, CreateEntryParams{
+			AccountID: arg.AccountID,
+			Amount:    arg.Amount,
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordEvent(ctx, q, EventDepositCreated, []string{AccountPartition(arg.AccountID)}, nil, result.Entry)
+	})
+
+	return result, err
+}
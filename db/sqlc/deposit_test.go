@@ -0,0 +1,39 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDepositTx(t *testing.T) {
+	store := NewStore(testDB)
+	account := createRandomAccount(t)
+
+	result, err := store.DepositTx(context.Background(), DepositTxParams{
+		AccountID: account.ID,
+		Amount:    50,
+	})
+	require.NoError(t, err)
+	require.Equal(t, account.Balance+50, result.Account.Balance)
+
+	require.Equal(t, account.ID, result.Entry.AccountID)
+	require.Equal(t, int64(50), result.Entry.Amount)
+	require.False(t, result.Entry.TransferID.Valid)
+
+	// frozen accounts cannot receive deposits
+	_, err = store.ChangeAccountStatusTx(context.Background(), ChangeAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountFrozen,
+		ChangedBy: account.Owner,
+		Reason:    "test",
+	})
+	require.NoError(t, err)
+
+	_, err = store.DepositTx(context.Background(), DepositTxParams{
+		AccountID: account.ID,
+		Amount:    50,
+	})
+	require.ErrorIs(t, err, ErrAccountFrozen)
+}
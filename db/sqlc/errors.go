@@ -0,0 +1,75 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrRecordNotFound is returned by the queries of a single row when no row matches
+var ErrRecordNotFound = pgx.ErrNoRows
+
+// Postgres error codes handled by the store and the API
+const (
+	ForeignKeyViolation  = "23503"
+	UniqueViolation      = "23505"
+	CheckViolation       = "23514"
+	SerializationFailure = "40001"
+	DeadlockDetected     = "40P01"
+)
+
+// Errors returned by the store transactions when a request breaks a business rule
+var (
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrCurrencyMismatch  = errors.New("currency mismatch")
+
+	ErrTransferAlreadyReversed = errors.New("transfer has already been fully reversed")
+	ErrReversalExceedsTransfer = errors.New("reversal exceeds the amount left to reverse")
+	ErrReversalOfReversal      = errors.New("a reversal cannot be reversed")
+
+	ErrHoldNotActive      = errors.New("hold is no longer active")
+	ErrHoldExpired        = errors.New("hold has expired")
+	ErrCaptureExceedsHold = errors.New("capture exceeds the amount held")
+
+	ErrAccountFrozen           = errors.New("account is frozen")
+	ErrAccountClosed           = errors.New("account is closed")
+	ErrAccountNotEmpty         = errors.New("account must have a zero balance and no active holds to be closed")
+	ErrInvalidStatusTransition = errors.New("account status cannot be changed this way")
+
+	ErrTransferLimitExceeded = errors.New("amount exceeds the maximum transfer amount of the account")
+	ErrDailyLimitExceeded    = errors.New("transfer exceeds the daily outgoing limit of the account")
+	ErrMonthlyLimitExceeded  = errors.New("transfer exceeds the monthly outgoing limit of the account")
+)
+
+// ruleErrors are the errors of the business rules enforced by constraints of the schema
+var ruleErrors = map[string]error{
+	"accounts_balance_check": ErrInsufficientFunds,
+}
+
+// ruleError wraps the postgres error of a constraint enforcing a business rule with the error of the rule,
+// so that callers can match it with errors.Is while the postgres error remains available to errors.As
+func ruleError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		if ruleErr, ok := ruleErrors[pgErr.ConstraintName]; ok && !errors.Is(err, ruleErr) {
+			return fmt.Errorf("%w : %w", ruleErr, err)
+		}
+	}
+	return err
+}
+
+// BatchTransferError reports the leg of a batch transfer that could not be applied
+type BatchTransferError struct {
+	Leg int
+	Err error
+}
+
+func (e *BatchTransferError) Error() string {
+	return fmt.Sprintf("leg %d : %v", e.Leg, e.Err)
+}
+
+func (e *BatchTransferError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,231 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Types of the events only published to the event stream
+const (
+	EventUserCreated    = "user.created"
+	EventSessionCreated = "session.created"
+	EventDepositCreated = "deposit.created"
+)
+
+// EventPayload is the body of an event, published to the event stream and posted to webhooks
+type EventPayload struct {
+	ID        uuid.UUID   `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// userEvent is the data of a user.created event, without the password
+type userEvent struct {
+	Username  string    `json:"username"`
+	FullName  string    `json:"full_name"`
+	Email     string    `json:"email"`
+	Tier      string    `json:"tier"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// sessionEvent is the data of a session.created event, without the refresh token
+type sessionEvent struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	UserAgent string    `json:"user_agent"`
+	ClientIp  string    `json:"client_ip"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AccountPartition returns the partition key of the events of an account
+func AccountPartition(accountID int64) string {
+	return "account:" + strconv.FormatInt(accountID, 10)
+}
+
+// UserPartition returns the partition key of the events of a user
+func UserPartition(username string) string {
+	return "user:" + username
+}
+
+// recordEvent writes an event to the outbox once per partition and queues its webhook deliveries to the owners,
+// within the transaction of q.
+// The partitions are locked until commit so that the order of the ids of the events of a partition is the order
+// in which they are committed. Account events are recorded once the account rows are locked,
+// so these locks never wait and cannot add deadlocks.
+func recordEvent(ctx context.Context, q Querier, eventType string, partitions []string, owners []string, data interface{}) error {
+	payload := EventPayload{
+		ID:        uuid.New(),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	// an event is written once per partition
+	partitions = dedupe(partitions)
+
+	sorted := append([]string(nil), partitions...)
+	sort.Strings(sorted)
+	for _, partition := range sorted {
+		if err := q.LockEventPartition(ctx, partition); err != nil {
+			return err
+		}
+	}
+
+	err = q.CreateEvents(ctx, CreateEventsParams{
+		EventID:       payload.ID,
+		EventType:     eventType,
+		PartitionKeys: partitions,
+		Payload:       body,
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(owners) == 0 {
+		return nil
+	}
+	return q.CreateWebhookDeliveries(ctx, CreateWebhookDeliveriesParams{
+		EventID:   payload.ID,
+		EventType: eventType,
+		Payload:   body,
+		Owners:    owners,
+	})
+}
+
+// dedupe returns the values without repetition, in the order they first appear
+func dedupe(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// CreateUserTx creates a user and records the user.created event in the same transaction
+func (store *txStore) CreateUserTx(ctx context.Context, arg CreateUserParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, nil, func(q Querier) error {
+		var err error
+		user, err = q.CreateUser(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		return recordEvent(ctx, q, EventUserCreated, []string{UserPartition(user.Username)}, nil, userEvent{
+			Username:  user.Username,
+			FullName:  user.FullName,
+			Email:     user.Email,
+			Tier:      user.Tier,
+			CreatedAt: user.CreatedAt,
+		})
+	})
+
+	return user, err
+}
+
+// CreateSessionTx creates a login session and records the session.created event in the same transaction
+func (store *txStore) CreateSessionTx(ctx context.Context, arg CreateSessionParams) (Session, error) {
+	var session Session
+
+	err := store.execTx(ctx, nil, func(q Querier) error {
+		var err error
+		session, err = q.CreateSession(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		return recordEvent(ctx, q, EventSessionCreated, []string{UserPartition(session.Username)}, nil, sessionEvent{
+			ID:        session.ID,
+			Username:  session.Username,
+			UserAgent: session.UserAgent,
+			ClientIp:  session.ClientIp,
+			ExpiresAt: session.ExpiresAt,
+			CreatedAt: session.CreatedAt,
+		})
+	})
+
+	return session, err
+}
+
+// PublishEventsTxParams contains the input parameters of publishing events
+type PublishEventsTxParams struct {
+	Limit int32
+	// Publish sends one event, the events of a partition being passed in order.
+	// After an error, the following events of the partition are left for the next call.
+	Publish func(ctx context.Context, event Event) error
+}
+
+// PublishEventsTxResult contains the result of publishing events
+type PublishEventsTxResult struct {
+	// Events read from the outbox, published or not
+	Events    int
+	Published int
+}
+
+// PublishEventsTx publishes the oldest events of the outbox that were not published yet, and marks those
+// the publisher acknowledged, so an event is published at least once.
+// Only one caller at a time publishes, the others return without events, and the transaction is kept open
+// while publishing, so the events of a partition are always published in order.
+func (store *txStore) PublishEventsTx(ctx context.Context, arg PublishEventsTxParams) (PublishEventsTxResult, error) {
+	var result PublishEventsTxResult
+
+	err := store.execTx(ctx, nil, func(q Querier) error {
+		result = PublishEventsTxResult{}
+
+		locked, err := q.TryLockEventRelay(ctx)
+		if err != nil || !locked {
+			return err
+		}
+
+		events, err := q.ListUnpublishedEvents(ctx, arg.Limit)
+		if err != nil {
+			return err
+		}
+		result.Events = len(events)
+
+		published := publishEvents(ctx, events, arg.Publish)
+		result.Published = len(published)
+
+		if len(published) == 0 {
+			return nil
+		}
+		return q.MarkEventsPublished(ctx, published)
+	})
+
+	return result, err
+}
+
+// publishEvents publishes events in order and returns the ids of those acknowledged.
+// After an error, the following events of the partition are skipped so that they stay in order.
+func publishEvents(ctx context.Context, events []Event, publish func(ctx context.Context, event Event) error) []int64 {
+	failed := make(map[string]bool)
+	var published []int64
+	for _, event := range events {
+		if failed[event.PartitionKey] {
+			continue
+		}
+		if err := publish(ctx, event); err != nil {
+			failed[event.PartitionKey] = true
+			continue
+		}
+		published = append(published, event.ID)
+	}
+	return published
+}
@@ -0,0 +1,119 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/samirprakash/go-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func partitionEvents(t *testing.T, partition string) []Event {
+	events, err := testQueries.ListEvents(context.Background(), ListEventsParams{
+		PartitionKey: partition,
+		Limit:        100,
+	})
+	require.NoError(t, err)
+	return events
+}
+
+func TestTransferTxEvents(t *testing.T) {
+	store := NewStore(testDB)
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccountInCurrency(t, account1.Currency)
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	// the transfer is in the stream of both accounts, as the same event
+	events1 := partitionEvents(t, AccountPartition(account1.ID))
+	events2 := partitionEvents(t, AccountPartition(account2.ID))
+	require.Len(t, events1, 1)
+	require.Len(t, events2, 1)
+	require.Equal(t, EventTransferCreated, events1[0].EventType)
+	require.Equal(t, events1[0].EventID, events2[0].EventID)
+	require.False(t, events1[0].PublishedAt.Valid)
+}
+
+func TestCreateUserTxEvents(t *testing.T) {
+	store := NewStore(testDB)
+
+	user, err := store.CreateUserTx(context.Background(), CreateUserParams{
+		Username:       util.RandomOwnerName(),
+		HashedPassword: "hashed",
+		FullName:       util.RandomOwnerName(),
+		Email:          util.RandomEmail(),
+	})
+	require.NoError(t, err)
+
+	events := partitionEvents(t, UserPartition(user.Username))
+	require.Len(t, events, 1)
+	require.Equal(t, EventUserCreated, events[0].EventType)
+	require.NotContains(t, string(events[0].Payload), "hashed")
+}
+
+func TestPublishEventsTx(t *testing.T) {
+	store := NewStore(testDB)
+	account := createRandomAccount(t)
+
+	for i := 0; i < 3; i++ {
+		_, err := store.DepositTx(context.Background(), DepositTxParams{AccountID: account.ID, Amount: 10})
+		require.NoError(t, err)
+	}
+	partition := AccountPartition(account.ID)
+
+	// publish until the deposits are out, other tests adding events concurrently
+	publishAll := func(publish func(ctx context.Context, event Event) error) []Event {
+		var published []Event
+		for {
+			result, err := store.PublishEventsTx(context.Background(), PublishEventsTxParams{
+				Limit: 100,
+				Publish: func(ctx context.Context, event Event) error {
+					if event.PartitionKey != partition {
+						return nil
+					}
+					if err := publish(ctx, event); err != nil {
+						return err
+					}
+					published = append(published, event)
+					return nil
+				},
+			})
+			require.NoError(t, err)
+			if result.Events < 100 {
+				return published
+			}
+		}
+	}
+
+	// a failed event holds back the following events of its partition
+	failing := errors.New("publisher unavailable")
+	published := publishAll(func(ctx context.Context, event Event) error {
+		return failing
+	})
+	require.Empty(t, published)
+
+	events := partitionEvents(t, partition)
+	require.Len(t, events, 3)
+	for _, event := range events {
+		require.False(t, event.PublishedAt.Valid)
+	}
+
+	// then the partition is published in order
+	published = publishAll(func(ctx context.Context, event Event) error {
+		return nil
+	})
+	require.Len(t, published, 3)
+	for i, event := range published {
+		require.Equal(t, events[i].ID, event.ID)
+	}
+
+	for _, event := range partitionEvents(t, partition) {
+		require.True(t, event.PublishedAt.Valid)
+	}
+}
@@ -0,0 +1,135 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"math/bits"
+)
+
+// Fee is the breakdown of the fee charged to the sender of a transfer
+type Fee struct {
+	// ScheduleID is the fee schedule applied, zero when no schedule matches the transfer
+	ScheduleID       int64 `json:"schedule_id,omitempty"`
+	FlatAmount       int64 `json:"flat_amount"`
+	PercentageAmount int64 `json:"percentage_amount"`
+	// Amount is the fee charged once the minimum and maximum of the schedule are applied
+	Amount int64 `json:"amount"`
+}
+
+// ComputeFee applies a fee schedule to the amount of a transfer.
+// The percentage part is rounded half up to the minor unit.
+func ComputeFee(schedule FeeSchedule, amount int64) Fee {
+	fee := Fee{
+		ScheduleID: schedule.ID,
+		FlatAmount: schedule.FlatFee,
+		// split the amount to avoid overflowing on large transfers
+		PercentageAmount: amount/10000*int64(schedule.PercentageBps) + (amount%10000*int64(schedule.PercentageBps)+5000)/10000,
+	}
+
+	fee.Amount = fee.FlatAmount + fee.PercentageAmount
+	if fee.Amount < schedule.MinFee {
+		fee.Amount = schedule.MinFee
+	}
+	if schedule.MaxFee.Valid && fee.Amount > schedule.MaxFee.Int64 {
+		fee.Amount = schedule.MaxFee.Int64
+	}
+
+	return fee
+}
+
+// evaluateFee finds the most specific fee schedule for a transfer from the account and computes its fee.
+// Schedules for the currency and the tier of the owner win over the ones applying to any of them,
+// then the band with the highest minimum amount not above the transfer applies.
+func evaluateFee(ctx context.Context, q Querier, account Account, amount int64) (Fee, error) {
+	owner, err := q.GetUser(ctx, account.Owner)
+	if err != nil {
+		return Fee{}, err
+	}
+
+	schedule, err := q.GetFeeSchedule(ctx, GetFeeScheduleParams{
+		Currency: account.Currency,
+		Tier:     owner.Tier,
+		Amount:   amount,
+	})
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return Fee{}, nil
+		}
+		return Fee{}, err
+	}
+
+	// the bank does not charge itself
+	revenueAccountID, err := q.GetRevenueAccountID(ctx, account.Currency)
+	if err != nil {
+		return Fee{}, err
+	}
+	if revenueAccountID == account.ID {
+		return Fee{}, nil
+	}
+
+	return ComputeFee(schedule, amount), nil
+}
+
+// applyTransferWithFee moves the money of a transfer and charges its fee to the sender
+func applyTransferWithFee(ctx context.Context, q Querier, arg TransferTxParams) (TransferTxResult, error) {
+	result, err := applyTransfer(ctx, q, arg)
+	if err != nil {
+		return result, err
+	}
+
+	fee, err := evaluateFee(ctx, q, result.FromAccount, arg.Amount)
+	if err != nil {
+		return result, err
+	}
+
+	return chargeFee(ctx, q, result, fee)
+}
+
+// chargeFee charges a fee to the sender of a transfer with a second transfer to the revenue account of the currency
+func chargeFee(ctx context.Context, q Querier, result TransferTxResult, fee Fee) (TransferTxResult, error) {
+	if fee.Amount == 0 {
+		return result, nil
+	}
+
+	revenueAccountID, err := q.GetRevenueAccountID(ctx, result.FromAccount.Currency)
+	if err != nil {
+		return result, err
+	}
+
+	feeResult, err := applyTransfer(ctx, q, TransferTxParams{
+		FromAccountID: result.FromAccount.ID,
+		ToAccountID:   revenueAccountID,
+		Amount:        fee.Amount,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.Fee = fee
+	result.FeeTransfer = &feeResult.Transfer
+	result.FromAccount = feeResult.FromAccount
+	return result, nil
+}
+
+// proportionalFee is the share of a fee quoted for whole that is due on part of it, rounded half up
+func proportionalFee(fee int64, part int64, whole int64) int64 {
+	if part >= whole {
+		return fee
+	}
+
+	// the product can overflow an int64, the quotient fits since part < whole
+	hi, lo := bits.Mul64(uint64(fee), uint64(part))
+	lo, carry := bits.Add64(lo, uint64(whole/2), 0)
+	quotient, _ := bits.Div64(hi+carry, lo, uint64(whole))
+	return int64(quotient)
+}
+
+// QuoteTransferFee previews the fee of a transfer without moving any money
+func (store *txStore) QuoteTransferFee(ctx context.Context, arg TransferTxParams) (Fee, error) {
+	account, err := store.database.GetAccount(ctx, arg.FromAccountID)
+	if err != nil {
+		return Fee{}, err
+	}
+
+	return evaluateFee(ctx, store.database, account, arg.Amount)
+}
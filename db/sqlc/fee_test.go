@@ -0,0 +1,200 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeFee(t *testing.T) {
+	testCases := []struct {
+		name     string
+		schedule FeeSchedule
+		amount   int64
+		fee      Fee
+	}{
+		{
+			name:     "Flat",
+			schedule: FeeSchedule{ID: 1, FlatFee: 25},
+			amount:   1000,
+			fee:      Fee{ScheduleID: 1, FlatAmount: 25, Amount: 25},
+		},
+		{
+			name:     "Percentage",
+			schedule: FeeSchedule{ID: 1, PercentageBps: 150},
+			amount:   1000,
+			fee:      Fee{ScheduleID: 1, PercentageAmount: 15, Amount: 15},
+		},
+		{
+			name:     "RoundedHalfUp",
+			schedule: FeeSchedule{ID: 1, PercentageBps: 150},
+			amount:   1034,
+			fee:      Fee{ScheduleID: 1, PercentageAmount: 16, Amount: 16},
+		},
+		{
+			name:     "FlatAndPercentage",
+			schedule: FeeSchedule{ID: 1, FlatFee: 10, PercentageBps: 100},
+			amount:   2000,
+			fee:      Fee{ScheduleID: 1, FlatAmount: 10, PercentageAmount: 20, Amount: 30},
+		},
+		{
+			name:     "MinFee",
+			schedule: FeeSchedule{ID: 1, PercentageBps: 100, MinFee: 5},
+			amount:   100,
+			fee:      Fee{ScheduleID: 1, PercentageAmount: 1, Amount: 5},
+		},
+		{
+			name:     "MaxFee",
+			schedule: FeeSchedule{ID: 1, PercentageBps: 100, MaxFee: pgtype.Int8{Int64: 50, Valid: true}},
+			amount:   100000,
+			fee:      Fee{ScheduleID: 1, PercentageAmount: 1000, Amount: 50},
+		},
+		{
+			name:     "LargeAmount",
+			schedule: FeeSchedule{ID: 1, PercentageBps: 10000},
+			amount:   1 << 62,
+			fee:      Fee{ScheduleID: 1, PercentageAmount: 1 << 62, Amount: 1 << 62},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.fee, ComputeFee(tc.schedule, tc.amount))
+		})
+	}
+}
+
+func TestProportionalFee(t *testing.T) {
+	require.Equal(t, int64(7), proportionalFee(7, 50, 50))
+	require.Equal(t, int64(4), proportionalFee(7, 25, 50))
+	require.Equal(t, int64(2), proportionalFee(7, 17, 50))
+	require.Zero(t, proportionalFee(0, 25, 50))
+	require.Equal(t, int64(1<<61), proportionalFee(1<<62, 1<<61, 1<<62))
+}
+
+func addFeeSchedule(t *testing.T, arg CreateFeeScheduleParams) FeeSchedule {
+	schedule, err := testQueries.CreateFeeSchedule(context.Background(), arg)
+	require.NoError(t, err)
+
+	// schedules apply to every transfer, so they must not outlive the test
+	t.Cleanup(func() {
+		err := testQueries.DeleteFeeSchedule(context.Background(), schedule.ID)
+		require.NoError(t, err)
+	})
+
+	return schedule
+}
+
+// createPremiumAccount creates an account of a premium user, the fee schedules of the tests apply to them only
+func createPremiumAccount(t *testing.T) Account {
+	account := createRandomAccount(t)
+
+	_, err := testQueries.UpdateUserTier(context.Background(), UpdateUserTierParams{
+		Username: account.Owner,
+		Tier:     "premium",
+	})
+	require.NoError(t, err)
+
+	return account
+}
+
+func TestTransferTxFee(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createPremiumAccount(t)
+	account2 := createRandomAccountInCurrency(t, account1.Currency)
+
+	currency := pgtype.Text{String: account1.Currency, Valid: true}
+	premium := pgtype.Text{String: "premium", Valid: true}
+	addFeeSchedule(t, CreateFeeScheduleParams{
+		Currency: currency,
+		Tier:     premium,
+		FlatFee:  2,
+	})
+	band := addFeeSchedule(t, CreateFeeScheduleParams{
+		Currency:      currency,
+		Tier:          premium,
+		MinAmount:     50,
+		FlatFee:       1,
+		PercentageBps: 1000,
+	})
+
+	revenueAccountID, err := store.GetRevenueAccountID(context.Background(), account1.Currency)
+	require.NoError(t, err)
+	revenueAccount, err := store.GetAccount(context.Background(), revenueAccountID)
+	require.NoError(t, err)
+
+	// the quote matches the fee charged by the transfer
+	arg := TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        60,
+	}
+	quote, err := store.QuoteTransferFee(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, Fee{ScheduleID: band.ID, FlatAmount: 1, PercentageAmount: 6, Amount: 7}, quote)
+
+	result, err := store.TransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, quote, result.Fee)
+	require.NotNil(t, result.FeeTransfer)
+	require.Equal(t, account1.ID, result.FeeTransfer.FromAccountID)
+	require.Equal(t, revenueAccountID, result.FeeTransfer.ToAccountID)
+	require.Equal(t, int64(7), result.FeeTransfer.Amount)
+
+	// the sender pays the fee on top of the amount
+	require.Equal(t, account1.Balance-67, result.FromAccount.Balance)
+	require.Equal(t, account2.Balance+60, result.ToAccount.Balance)
+
+	updatedRevenueAccount, err := store.GetAccount(context.Background(), revenueAccountID)
+	require.NoError(t, err)
+	require.Equal(t, revenueAccount.Balance+7, updatedRevenueAccount.Balance)
+
+	// transfers below the band get the fee of the band below
+	result, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), result.Fee.Amount)
+
+	// senders of another tier are not charged
+	result, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account2.ID,
+		ToAccountID:   account1.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+	require.Zero(t, result.Fee)
+	require.Nil(t, result.FeeTransfer)
+}
+
+func TestTransferTxFeeInsufficientFunds(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createPremiumAccount(t)
+	account2 := createRandomAccountInCurrency(t, account1.Currency)
+
+	addFeeSchedule(t, CreateFeeScheduleParams{
+		Currency: pgtype.Text{String: account1.Currency, Valid: true},
+		Tier:     pgtype.Text{String: "premium", Valid: true},
+		FlatFee:  1,
+	})
+
+	// the whole balance can be sent but not the fee on top of it
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        account1.Balance,
+	})
+	require.Error(t, err)
+
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+}
@@ -0,0 +1,255 @@
+package db
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Statuses of a hold
+const (
+	HoldActive   = "active"
+	HoldCaptured = "captured"
+	HoldVoided   = "voided"
+	HoldExpired  = "expired"
+)
+
+// HoldTxParams contains the input parameters of the hold transaction
+type HoldTxParams struct {
+	AccountID   int64     `json:"account_id"`
+	ToAccountID int64     `json:"to_account_id"`
+	Amount      int64     `json:"amount"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// HoldTxResult represents the result of the hold transaction
+type HoldTxResult struct {
+	Hold    Hold    `json:"hold"`
+	Account Account `json:"account"`
+}
+
+// HoldTx reserves funds on an account until the hold is captured, voided or expires.
+// The ledger balance is unchanged but the available balance drops by the amount held and its fee,
+// quoted now and charged on capture, and the accounts_balance_check constraint rejects holds the account cannot cover.
+// A hold is checked against the limits of the account like a transfer of its amount and fee.
+func (store *txStore) HoldTx(ctx context.Context, arg HoldTxParams) (HoldTxResult, error) {
+	var result HoldTxResult
+
+	err := store.execTx(ctx, nil, func(q Querier) error {
+		account, err := q.GetAccount(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		fee, err := evaluateFee(ctx, q, account, arg.Amount)
+		if err != nil {
+			return err
+		}
+
+		result.Hold, err = q.CreateHold(ctx, CreateHoldParams{
+			AccountID:   arg.AccountID,
+			ToAccountID: arg.ToAccountID,
+			Amount:      arg.Amount,
+			Fee:         fee.Amount,
+			ExpiresAt:   arg.ExpiresAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Account, err = q.AddAccountHeldAmount(ctx, AddAccountHeldAmountParams{
+			Amount: arg.Amount + fee.Amount,
+			ID:     arg.AccountID,
+		})
+		if err != nil {
+			return err
+		}
+		if err := AccountStatusError(result.Account.Status); err != nil {
+			return err
+		}
+
+		// the hold has no entry yet, it is counted with its fee in the amount held on the account
+		err = checkOutgoingLimits(ctx, q, arg.AccountID, arg.Amount, result.Account.HeldAmount, time.Now())
+		if err != nil {
+			return err
+		}
+
+		// the recipient must still be able to receive the funds on capture
+		toAccount, err := q.GetAccount(ctx, arg.ToAccountID)
+		if err != nil {
+			return err
+		}
+		return AccountStatusError(toAccount.Status)
+	})
+
+	return result, err
+}
+
+// CaptureHoldTxParams contains the input parameters of the capture transaction
+type CaptureHoldTxParams struct {
+	HoldID int64 `json:"hold_id"`
+	// Amount to settle, zero captures the whole hold
+	Amount int64 `json:"amount"`
+}
+
+// CaptureHoldTxResult represents the result of the capture transaction
+type CaptureHoldTxResult struct {
+	TransferTxResult
+	Hold Hold `json:"hold"`
+}
+
+// CaptureHoldTx settles a hold with a transfer to the account named by the hold.
+// A capture can be lower than the hold, the rest of the reserved funds become available again.
+// The payer is charged the fee quoted when the hold was placed, in proportion to the amount captured,
+// so a later change of the fee schedules cannot exceed the funds reserved.
+func (store *txStore) CaptureHoldTx(ctx context.Context, arg CaptureHoldTxParams) (CaptureHoldTxResult, error) {
+	var result CaptureHoldTxResult
+
+	err := store.execTx(ctx, nil, func(q Querier) error {
+		hold, err := q.GetHoldForUpdate(ctx, arg.HoldID)
+		if err != nil {
+			return err
+		}
+
+		if err := checkHoldCapturable(hold, time.Now()); err != nil {
+			return err
+		}
+
+		amount := arg.Amount
+		if amount == 0 {
+			amount = hold.Amount
+		}
+		if amount > hold.Amount {
+			return ErrCaptureExceedsHold
+		}
+
+		_, err = lockAccounts(ctx, q, hold.AccountID, hold.ToAccountID)
+		if err != nil {
+			return err
+		}
+
+		// release the whole hold and its fee before moving the captured amount
+		_, err = q.AddAccountHeldAmount(ctx, AddAccountHeldAmountParams{
+			Amount: -(hold.Amount + hold.Fee),
+			ID:     hold.AccountID,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.TransferTxResult, err = applyTransfer(ctx, q, TransferTxParams{
+			FromAccountID: hold.AccountID,
+			ToAccountID:   hold.ToAccountID,
+			Amount:        amount,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.TransferTxResult, err = chargeFee(ctx, q, result.TransferTxResult, Fee{
+			Amount: proportionalFee(hold.Fee, amount, hold.Amount),
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Hold, err = q.FinishHold(ctx, FinishHoldParams{
+			ID:             hold.ID,
+			Status:         HoldCaptured,
+			CapturedAmount: amount,
+			TransferID:     pgtype.Int8{Int64: result.Transfer.ID, Valid: true},
+		})
+		return err
+	})
+
+	return result, err
+}
+
+// VoidHoldTx cancels an active hold and makes the reserved funds available again
+func (store *txStore) VoidHoldTx(ctx context.Context, holdID int64) (Hold, error) {
+	var result Hold
+
+	err := store.execTx(ctx, nil, func(q Querier) error {
+		hold, err := q.GetHoldForUpdate(ctx, holdID)
+		if err != nil {
+			return err
+		}
+
+		if hold.Status != HoldActive {
+			return ErrHoldNotActive
+		}
+
+		result, err = releaseHold(ctx, q, hold, HoldVoided)
+		return err
+	})
+
+	return result, err
+}
+
+// ExpireHoldsTxParams contains the input parameters of the expiry transaction
+type ExpireHoldsTxParams struct {
+	Now   time.Time
+	Limit int32
+}
+
+// ExpireHoldsTx releases the active holds that have expired at the given time and returns them.
+// Expired rows are locked with SKIP LOCKED so that a hold being captured or voided is left alone.
+func (store *txStore) ExpireHoldsTx(ctx context.Context, arg ExpireHoldsTxParams) ([]Hold, error) {
+	var result []Hold
+
+	err := store.execTx(ctx, nil, func(q Querier) error {
+		result = []Hold{}
+
+		holds, err := q.ListExpiredHoldsForUpdate(ctx, ListExpiredHoldsForUpdateParams{
+			Now:        arg.Now,
+			LimitCount: arg.Limit,
+		})
+		if err != nil {
+			return err
+		}
+
+		// release in account order like every other transaction updating several accounts
+		sort.Slice(holds, func(i, j int) bool { return holds[i].AccountID < holds[j].AccountID })
+
+		for _, hold := range holds {
+			expired, err := releaseHold(ctx, q, hold, HoldExpired)
+			if err != nil {
+				return err
+			}
+			result = append(result, expired)
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+// checkHoldCapturable checks that a hold is active and has not expired yet
+func checkHoldCapturable(hold Hold, now time.Time) error {
+	if hold.Status != HoldActive {
+		return ErrHoldNotActive
+	}
+	if !hold.ExpiresAt.After(now) {
+		return ErrHoldExpired
+	}
+	return nil
+}
+
+// releaseHold gives the funds of a hold and its fee back to the available balance and closes it with the status
+func releaseHold(ctx context.Context, q Querier, hold Hold, status string) (Hold, error) {
+	_, err := q.AddAccountHeldAmount(ctx, AddAccountHeldAmountParams{
+		Amount: -(hold.Amount + hold.Fee),
+		ID:     hold.AccountID,
+	})
+	if err != nil {
+		return hold, err
+	}
+
+	return q.FinishHold(ctx, FinishHoldParams{
+		ID:     hold.ID,
+		Status: status,
+	})
+}
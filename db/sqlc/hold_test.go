@@ -0,0 +1,273 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomHold(t *testing.T, amount int64, expiresAt time.Time) (HoldTxResult, Account, Account) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccountInCurrency(t, account1.Currency)
+
+	result, err := store.HoldTx(context.Background(), HoldTxParams{
+		AccountID:   account1.ID,
+		ToAccountID: account2.ID,
+		Amount:      amount,
+		ExpiresAt:   expiresAt,
+	})
+	require.NoError(t, err)
+
+	return result, account1, account2
+}
+
+func TestHoldTx(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour)
+	result, account1, account2 := createRandomHold(t, 50, expiresAt)
+
+	hold := result.Hold
+	require.NotZero(t, hold.ID)
+	require.Equal(t, account1.ID, hold.AccountID)
+	require.Equal(t, account2.ID, hold.ToAccountID)
+	require.Equal(t, int64(50), hold.Amount)
+	require.Equal(t, HoldActive, hold.Status)
+	require.Zero(t, hold.CapturedAmount)
+	require.False(t, hold.TransferID.Valid)
+	require.WithinDuration(t, expiresAt, hold.ExpiresAt, time.Second)
+	require.False(t, hold.FinishedAt.Valid)
+
+	// the ledger balance is unchanged but the funds are no longer available
+	require.Equal(t, account1.Balance, result.Account.Balance)
+	require.Equal(t, int64(50), result.Account.HeldAmount)
+	require.Equal(t, account1.Balance-50, result.Account.AvailableBalance)
+}
+
+func TestHoldTxInsufficientFunds(t *testing.T) {
+	store := NewStore(testDB)
+	result, account1, account2 := createRandomHold(t, 50, time.Now().Add(time.Hour))
+
+	// a hold cannot reserve funds that are already held
+	_, err := store.HoldTx(context.Background(), HoldTxParams{
+		AccountID:   account1.ID,
+		ToAccountID: account2.ID,
+		Amount:      result.Account.AvailableBalance + 1,
+		ExpiresAt:   time.Now().Add(time.Hour),
+	})
+	requireBalanceCheckViolation(t, err)
+
+	// nor can a transfer spend them
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        result.Account.AvailableBalance + 1,
+	})
+	requireBalanceCheckViolation(t, err)
+}
+
+func requireBalanceCheckViolation(t *testing.T, err error) {
+	var pgErr *pgconn.PgError
+	require.ErrorAs(t, err, &pgErr)
+	require.Equal(t, CheckViolation, pgErr.Code)
+	require.Equal(t, "accounts_balance_check", pgErr.ConstraintName)
+}
+
+func TestCaptureHoldTx(t *testing.T) {
+	store := NewStore(testDB)
+	hold, account1, account2 := createRandomHold(t, 50, time.Now().Add(time.Hour))
+
+	// a partial capture releases the rest of the hold
+	result, err := store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{
+		HoldID: hold.Hold.ID,
+		Amount: 30,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, HoldCaptured, result.Hold.Status)
+	require.Equal(t, int64(30), result.Hold.CapturedAmount)
+	require.True(t, result.Hold.TransferID.Valid)
+	require.Equal(t, result.Transfer.ID, result.Hold.TransferID.Int64)
+	require.True(t, result.Hold.FinishedAt.Valid)
+
+	require.Equal(t, account1.ID, result.Transfer.FromAccountID)
+	require.Equal(t, account2.ID, result.Transfer.ToAccountID)
+	require.Equal(t, int64(30), result.Transfer.Amount)
+
+	require.Equal(t, account1.Balance-30, result.FromAccount.Balance)
+	require.Zero(t, result.FromAccount.HeldAmount)
+	require.Equal(t, account1.Balance-30, result.FromAccount.AvailableBalance)
+	require.Equal(t, account2.Balance+30, result.ToAccount.Balance)
+
+	// a hold is captured once
+	_, err = store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{HoldID: hold.Hold.ID})
+	require.ErrorIs(t, err, ErrHoldNotActive)
+}
+
+func TestCaptureHoldTxFull(t *testing.T) {
+	store := NewStore(testDB)
+	hold, account1, _ := createRandomHold(t, 50, time.Now().Add(time.Hour))
+
+	result, err := store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{HoldID: hold.Hold.ID})
+	require.NoError(t, err)
+	require.Equal(t, int64(50), result.Hold.CapturedAmount)
+	require.Equal(t, int64(50), result.Transfer.Amount)
+	require.Equal(t, account1.Balance-50, result.FromAccount.Balance)
+	require.Zero(t, result.FromAccount.HeldAmount)
+}
+
+func TestCaptureHoldTxFee(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createPremiumAccount(t)
+	account2 := createRandomAccountInCurrency(t, account1.Currency)
+
+	addFeeSchedule(t, CreateFeeScheduleParams{
+		Currency: pgtype.Text{String: account1.Currency, Valid: true},
+		Tier:     pgtype.Text{String: "premium", Valid: true},
+		FlatFee:  3,
+	})
+
+	revenueAccountID, err := store.GetRevenueAccountID(context.Background(), account1.Currency)
+	require.NoError(t, err)
+	revenueAccount, err := store.GetAccount(context.Background(), revenueAccountID)
+	require.NoError(t, err)
+
+	hold, err := store.HoldTx(context.Background(), HoldTxParams{
+		AccountID:   account1.ID,
+		ToAccountID: account2.ID,
+		Amount:      50,
+		ExpiresAt:   time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	// the payer is charged on capture like on a transfer
+	result, err := store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{HoldID: hold.Hold.ID})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), result.Fee.Amount)
+	require.NotNil(t, result.FeeTransfer)
+	require.Equal(t, revenueAccountID, result.FeeTransfer.ToAccountID)
+	require.Equal(t, account1.Balance-53, result.FromAccount.Balance)
+	require.Zero(t, result.FromAccount.HeldAmount)
+
+	updatedRevenueAccount, err := store.GetAccount(context.Background(), revenueAccountID)
+	require.NoError(t, err)
+	require.Equal(t, revenueAccount.Balance+3, updatedRevenueAccount.Balance)
+}
+
+func TestCaptureHoldTxFullBalanceFee(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createPremiumAccount(t)
+	account2 := createRandomAccountInCurrency(t, account1.Currency)
+
+	schedule := addFeeSchedule(t, CreateFeeScheduleParams{
+		Currency: pgtype.Text{String: account1.Currency, Valid: true},
+		Tier:     pgtype.Text{String: "premium", Valid: true},
+		FlatFee:  3,
+	})
+
+	// the fee is reserved with the amount, the account cannot hold more than its balance minus the fee
+	_, err := store.HoldTx(context.Background(), HoldTxParams{
+		AccountID:   account1.ID,
+		ToAccountID: account2.ID,
+		Amount:      account1.Balance - 2,
+		ExpiresAt:   time.Now().Add(time.Hour),
+	})
+	requireBalanceCheckViolation(t, err)
+
+	hold, err := store.HoldTx(context.Background(), HoldTxParams{
+		AccountID:   account1.ID,
+		ToAccountID: account2.ID,
+		Amount:      account1.Balance - 3,
+		ExpiresAt:   time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), hold.Hold.Fee)
+	require.Equal(t, account1.Balance, hold.Account.HeldAmount)
+	require.Zero(t, hold.Account.AvailableBalance)
+
+	// the fee quoted when the hold was placed is charged, whatever the schedules became
+	err = testQueries.DeleteFeeSchedule(context.Background(), schedule.ID)
+	require.NoError(t, err)
+	addFeeSchedule(t, CreateFeeScheduleParams{
+		Currency: pgtype.Text{String: account1.Currency, Valid: true},
+		Tier:     pgtype.Text{String: "premium", Valid: true},
+		FlatFee:  5,
+	})
+
+	result, err := store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{HoldID: hold.Hold.ID})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), result.Fee.Amount)
+	require.Zero(t, result.FromAccount.Balance)
+	require.Zero(t, result.FromAccount.HeldAmount)
+}
+
+func TestCaptureHoldTxPreconditions(t *testing.T) {
+	store := NewStore(testDB)
+
+	hold, account1, _ := createRandomHold(t, 50, time.Now().Add(time.Hour))
+	_, err := store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{HoldID: hold.Hold.ID, Amount: 51})
+	require.ErrorIs(t, err, ErrCaptureExceedsHold)
+
+	expired, _, _ := createRandomHold(t, 50, time.Now().Add(-time.Second))
+	_, err = store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{HoldID: expired.Hold.ID})
+	require.ErrorIs(t, err, ErrHoldExpired)
+
+	// failed captures leave the hold untouched
+	account, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, account.Balance)
+	require.Equal(t, int64(50), account.HeldAmount)
+}
+
+func TestVoidHoldTx(t *testing.T) {
+	store := NewStore(testDB)
+	hold, account1, _ := createRandomHold(t, 50, time.Now().Add(time.Hour))
+
+	voided, err := store.VoidHoldTx(context.Background(), hold.Hold.ID)
+	require.NoError(t, err)
+	require.Equal(t, HoldVoided, voided.Status)
+	require.Zero(t, voided.CapturedAmount)
+	require.True(t, voided.FinishedAt.Valid)
+
+	account, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, account.Balance)
+	require.Equal(t, account1.Balance, account.AvailableBalance)
+
+	_, err = store.VoidHoldTx(context.Background(), hold.Hold.ID)
+	require.ErrorIs(t, err, ErrHoldNotActive)
+}
+
+func TestExpireHoldsTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	now := time.Now()
+	expired, account1, _ := createRandomHold(t, 50, now.Add(-time.Minute))
+	active, _, _ := createRandomHold(t, 50, now.Add(time.Hour))
+
+	holds, err := store.ExpireHoldsTx(context.Background(), ExpireHoldsTxParams{Now: now, Limit: 1000})
+	require.NoError(t, err)
+
+	ids := make(map[int64]Hold, len(holds))
+	for _, hold := range holds {
+		ids[hold.ID] = hold
+	}
+	require.Contains(t, ids, expired.Hold.ID)
+	require.NotContains(t, ids, active.Hold.ID)
+	require.Equal(t, HoldExpired, ids[expired.Hold.ID].Status)
+
+	account, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Zero(t, account.HeldAmount)
+	require.Equal(t, account1.Balance, account.AvailableBalance)
+
+	hold, err := store.GetHold(context.Background(), active.Hold.ID)
+	require.NoError(t, err)
+	require.Equal(t, HoldActive, hold.Status)
+}
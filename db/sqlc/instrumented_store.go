@@ -0,0 +1,702 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// QueryHook is called before every Store method with the name of the method.
+// It can enrich the context passed to the method and returns a function that is called
+// with the error of the method once it has completed.
+// Hooks are used to log, measure and trace database calls without changing the store itself.
+type QueryHook func(ctx context.Context, method string) (context.Context, func(err error))
+
+// instrumentedStore is a Store decorator that runs a set of hooks around every method call
+type instrumentedStore struct {
+	store Store
+	hooks []QueryHook
+}
+
+// NewInstrumentedStore wraps a store so that every method call goes through the hooks.
+// Hooks are started in order and completed in reverse order.
+func NewInstrumentedStore(store Store, hooks ...QueryHook) Store {
+	return &instrumentedStore{
+		store: store,
+		hooks: hooks,
+	}
+}
+
+func (store *instrumentedStore) start(ctx context.Context, method string) (context.Context, func(err error)) {
+	dones := make([]func(err error), len(store.hooks))
+	for i, hook := range store.hooks {
+		ctx, dones[i] = hook(ctx, method)
+	}
+
+	return ctx, func(err error) {
+		for i := len(dones) - 1; i >= 0; i-- {
+			dones[i](err)
+		}
+	}
+}
+
+// Store methods
+
+func (store *instrumentedStore) AddAccountHeldAmount(ctx context.Context, arg AddAccountHeldAmountParams) (Account, error) {
+	ctx, done := store.start(ctx, "AddAccountHeldAmount")
+	result, err := store.store.AddAccountHeldAmount(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) ClaimWebhookDeliveries(ctx context.Context, arg ClaimWebhookDeliveriesParams) ([]ClaimWebhookDeliveriesRow, error) {
+	ctx, done := store.start(ctx, "ClaimWebhookDeliveries")
+	result, err := store.store.ClaimWebhookDeliveries(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error) {
+	ctx, done := store.start(ctx, "CreateAccount")
+	result, err := store.store.CreateAccount(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) CreateAccountStatusChange(ctx context.Context, arg CreateAccountStatusChangeParams) (AccountStatusChange, error) {
+	ctx, done := store.start(ctx, "CreateAccountStatusChange")
+	result, err := store.store.CreateAccountStatusChange(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error) {
+	ctx, done := store.start(ctx, "CreateEntry")
+	result, err := store.store.CreateEntry(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) CreateEvents(ctx context.Context, arg CreateEventsParams) error {
+	ctx, done := store.start(ctx, "CreateEvents")
+	err := store.store.CreateEvents(ctx, arg)
+	done(err)
+	return err
+}
+
+func (store *instrumentedStore) CreateFeeSchedule(ctx context.Context, arg CreateFeeScheduleParams) (FeeSchedule, error) {
+	ctx, done := store.start(ctx, "CreateFeeSchedule")
+	result, err := store.store.CreateFeeSchedule(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) CreateHold(ctx context.Context, arg CreateHoldParams) (Hold, error) {
+	ctx, done := store.start(ctx, "CreateHold")
+	result, err := store.store.CreateHold(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) CreateInterestAccrual(ctx context.Context, arg CreateInterestAccrualParams) (InterestAccrual, error) {
+	ctx, done := store.start(ctx, "CreateInterestAccrual")
+	result, err := store.store.CreateInterestAccrual(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) CreateInterestPosting(ctx context.Context, arg CreateInterestPostingParams) (InterestPosting, error) {
+	ctx, done := store.start(ctx, "CreateInterestPosting")
+	result, err := store.store.CreateInterestPosting(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) CreateScheduledTransfer(ctx context.Context, arg CreateScheduledTransferParams) (ScheduledTransfer, error) {
+	ctx, done := store.start(ctx, "CreateScheduledTransfer")
+	result, err := store.store.CreateScheduledTransfer(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) CreateScheduledTransferRun(ctx context.Context, arg CreateScheduledTransferRunParams) (ScheduledTransferRun, error) {
+	ctx, done := store.start(ctx, "CreateScheduledTransferRun")
+	result, err := store.store.CreateScheduledTransferRun(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error) {
+	ctx, done := store.start(ctx, "CreateSession")
+	result, err := store.store.CreateSession(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error) {
+	ctx, done := store.start(ctx, "CreateTransfer")
+	result, err := store.store.CreateTransfer(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) CreateTransferReversal(ctx context.Context, arg CreateTransferReversalParams) (TransferReversal, error) {
+	ctx, done := store.start(ctx, "CreateTransferReversal")
+	result, err := store.store.CreateTransferReversal(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
+	ctx, done := store.start(ctx, "CreateUser")
+	result, err := store.store.CreateUser(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) CreateWebhookDeliveries(ctx context.Context, arg CreateWebhookDeliveriesParams) error {
+	ctx, done := store.start(ctx, "CreateWebhookDeliveries")
+	err := store.store.CreateWebhookDeliveries(ctx, arg)
+	done(err)
+	return err
+}
+
+func (store *instrumentedStore) CreateWebhookSubscription(ctx context.Context, arg CreateWebhookSubscriptionParams) (WebhookSubscription, error) {
+	ctx, done := store.start(ctx, "CreateWebhookSubscription")
+	result, err := store.store.CreateWebhookSubscription(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) DeleteAccount(ctx context.Context, id int64) error {
+	ctx, done := store.start(ctx, "DeleteAccount")
+	err := store.store.DeleteAccount(ctx, id)
+	done(err)
+	return err
+}
+
+func (store *instrumentedStore) DeleteFeeSchedule(ctx context.Context, id int64) error {
+	ctx, done := store.start(ctx, "DeleteFeeSchedule")
+	err := store.store.DeleteFeeSchedule(ctx, id)
+	done(err)
+	return err
+}
+
+func (store *instrumentedStore) DeleteScheduledTransfer(ctx context.Context, id int64) error {
+	ctx, done := store.start(ctx, "DeleteScheduledTransfer")
+	err := store.store.DeleteScheduledTransfer(ctx, id)
+	done(err)
+	return err
+}
+
+func (store *instrumentedStore) DeleteWebhookSubscription(ctx context.Context, id int64) error {
+	ctx, done := store.start(ctx, "DeleteWebhookSubscription")
+	err := store.store.DeleteWebhookSubscription(ctx, id)
+	done(err)
+	return err
+}
+
+func (store *instrumentedStore) FinishHold(ctx context.Context, arg FinishHoldParams) (Hold, error) {
+	ctx, done := store.start(ctx, "FinishHold")
+	result, err := store.store.FinishHold(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) FinishScheduledTransferRun(ctx context.Context, arg FinishScheduledTransferRunParams) (ScheduledTransferRun, error) {
+	ctx, done := store.start(ctx, "FinishScheduledTransferRun")
+	result, err := store.store.FinishScheduledTransferRun(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) GetAccount(ctx context.Context, id int64) (Account, error) {
+	ctx, done := store.start(ctx, "GetAccount")
+	result, err := store.store.GetAccount(ctx, id)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) GetAccountBalanceAt(ctx context.Context, arg GetAccountBalanceAtParams) (int64, error) {
+	ctx, done := store.start(ctx, "GetAccountBalanceAt")
+	result, err := store.store.GetAccountBalanceAt(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) GetAccountForUpdate(ctx context.Context, id int64) (Account, error) {
+	ctx, done := store.start(ctx, "GetAccountForUpdate")
+	result, err := store.store.GetAccountForUpdate(ctx, id)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) GetAccountLimits(ctx context.Context, accountID int64) (GetAccountLimitsRow, error) {
+	ctx, done := store.start(ctx, "GetAccountLimits")
+	result, err := store.store.GetAccountLimits(ctx, accountID)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) GetEntry(ctx context.Context, id int64) (Entry, error) {
+	ctx, done := store.start(ctx, "GetEntry")
+	result, err := store.store.GetEntry(ctx, id)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) GetFeeSchedule(ctx context.Context, arg GetFeeScheduleParams) (FeeSchedule, error) {
+	ctx, done := store.start(ctx, "GetFeeSchedule")
+	result, err := store.store.GetFeeSchedule(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) GetHold(ctx context.Context, id int64) (Hold, error) {
+	ctx, done := store.start(ctx, "GetHold")
+	result, err := store.store.GetHold(ctx, id)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) GetHoldForUpdate(ctx context.Context, id int64) (Hold, error) {
+	ctx, done := store.start(ctx, "GetHoldForUpdate")
+	result, err := store.store.GetHoldForUpdate(ctx, id)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) GetLastInterestAccrualDate(ctx context.Context) (time.Time, error) {
+	ctx, done := store.start(ctx, "GetLastInterestAccrualDate")
+	result, err := store.store.GetLastInterestAccrualDate(ctx)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) GetLastInterestPosting(ctx context.Context, arg GetLastInterestPostingParams) (InterestPosting, error) {
+	ctx, done := store.start(ctx, "GetLastInterestPosting")
+	result, err := store.store.GetLastInterestPosting(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) GetOutgoingTotals(ctx context.Context, arg GetOutgoingTotalsParams) (GetOutgoingTotalsRow, error) {
+	ctx, done := store.start(ctx, "GetOutgoingTotals")
+	result, err := store.store.GetOutgoingTotals(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) GetRevenueAccountID(ctx context.Context, currency string) (int64, error) {
+	ctx, done := store.start(ctx, "GetRevenueAccountID")
+	result, err := store.store.GetRevenueAccountID(ctx, currency)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) GetReversedAmount(ctx context.Context, originalTransferID int64) (int64, error) {
+	ctx, done := store.start(ctx, "GetReversedAmount")
+	result, err := store.store.GetReversedAmount(ctx, originalTransferID)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) GetScheduledTransfer(ctx context.Context, id int64) (ScheduledTransfer, error) {
+	ctx, done := store.start(ctx, "GetScheduledTransfer")
+	result, err := store.store.GetScheduledTransfer(ctx, id)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) GetSession(ctx context.Context, id uuid.UUID) (Session, error) {
+	ctx, done := store.start(ctx, "GetSession")
+	result, err := store.store.GetSession(ctx, id)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
+	ctx, done := store.start(ctx, "GetTransfer")
+	result, err := store.store.GetTransfer(ctx, id)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error) {
+	ctx, done := store.start(ctx, "GetTransferForUpdate")
+	result, err := store.store.GetTransferForUpdate(ctx, id)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) GetTransferReversal(ctx context.Context, transferID int64) (TransferReversal, error) {
+	ctx, done := store.start(ctx, "GetTransferReversal")
+	result, err := store.store.GetTransferReversal(ctx, transferID)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) GetUser(ctx context.Context, username string) (User, error) {
+	ctx, done := store.start(ctx, "GetUser")
+	result, err := store.store.GetUser(ctx, username)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) GetWebhookSubscription(ctx context.Context, id int64) (WebhookSubscription, error) {
+	ctx, done := store.start(ctx, "GetWebhookSubscription")
+	result, err := store.store.GetWebhookSubscription(ctx, id)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) ListAccountHolds(ctx context.Context, arg ListAccountHoldsParams) ([]Hold, error) {
+	ctx, done := store.start(ctx, "ListAccountHolds")
+	result, err := store.store.ListAccountHolds(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) ListAccountStatusChanges(ctx context.Context, arg ListAccountStatusChangesParams) ([]AccountStatusChange, error) {
+	ctx, done := store.start(ctx, "ListAccountStatusChanges")
+	result, err := store.store.ListAccountStatusChanges(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error) {
+	ctx, done := store.start(ctx, "ListAccounts")
+	result, err := store.store.ListAccounts(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) ListDueScheduledTransfersForUpdate(ctx context.Context, arg ListDueScheduledTransfersForUpdateParams) ([]ScheduledTransfer, error) {
+	ctx, done := store.start(ctx, "ListDueScheduledTransfersForUpdate")
+	result, err := store.store.ListDueScheduledTransfersForUpdate(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error) {
+	ctx, done := store.start(ctx, "ListEntries")
+	result, err := store.store.ListEntries(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) ListEvents(ctx context.Context, arg ListEventsParams) ([]Event, error) {
+	ctx, done := store.start(ctx, "ListEvents")
+	result, err := store.store.ListEvents(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) ListExpiredHoldsForUpdate(ctx context.Context, arg ListExpiredHoldsForUpdateParams) ([]Hold, error) {
+	ctx, done := store.start(ctx, "ListExpiredHoldsForUpdate")
+	result, err := store.store.ListExpiredHoldsForUpdate(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) ListFeeSchedules(ctx context.Context, arg ListFeeSchedulesParams) ([]FeeSchedule, error) {
+	ctx, done := store.start(ctx, "ListFeeSchedules")
+	result, err := store.store.ListFeeSchedules(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) ListInterestPostings(ctx context.Context, arg ListInterestPostingsParams) ([]InterestPosting, error) {
+	ctx, done := store.start(ctx, "ListInterestPostings")
+	result, err := store.store.ListInterestPostings(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) ListInterestRates(ctx context.Context) ([]InterestRate, error) {
+	ctx, done := store.start(ctx, "ListInterestRates")
+	result, err := store.store.ListInterestRates(ctx)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) ListPendingInterestPostings(ctx context.Context, arg ListPendingInterestPostingsParams) ([]ListPendingInterestPostingsRow, error) {
+	ctx, done := store.start(ctx, "ListPendingInterestPostings")
+	result, err := store.store.ListPendingInterestPostings(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) ListScheduledTransferRuns(ctx context.Context, arg ListScheduledTransferRunsParams) ([]ScheduledTransferRun, error) {
+	ctx, done := store.start(ctx, "ListScheduledTransferRuns")
+	result, err := store.store.ListScheduledTransferRuns(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) ListScheduledTransfers(ctx context.Context, arg ListScheduledTransfersParams) ([]ScheduledTransfer, error) {
+	ctx, done := store.start(ctx, "ListScheduledTransfers")
+	result, err := store.store.ListScheduledTransfers(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) ListStatementEntries(ctx context.Context, arg ListStatementEntriesParams) ([]ListStatementEntriesRow, error) {
+	ctx, done := store.start(ctx, "ListStatementEntries")
+	result, err := store.store.ListStatementEntries(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error) {
+	ctx, done := store.start(ctx, "ListTransfers")
+	result, err := store.store.ListTransfers(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) ListUnaccruedInterestBalances(ctx context.Context, arg ListUnaccruedInterestBalancesParams) ([]ListUnaccruedInterestBalancesRow, error) {
+	ctx, done := store.start(ctx, "ListUnaccruedInterestBalances")
+	result, err := store.store.ListUnaccruedInterestBalances(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) ListUnpublishedEvents(ctx context.Context, limit int32) ([]Event, error) {
+	ctx, done := store.start(ctx, "ListUnpublishedEvents")
+	result, err := store.store.ListUnpublishedEvents(ctx, limit)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error) {
+	ctx, done := store.start(ctx, "ListWebhookDeliveries")
+	result, err := store.store.ListWebhookDeliveries(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) ListWebhookSubscriptions(ctx context.Context, arg ListWebhookSubscriptionsParams) ([]WebhookSubscription, error) {
+	ctx, done := store.start(ctx, "ListWebhookSubscriptions")
+	result, err := store.store.ListWebhookSubscriptions(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) LockEventPartition(ctx context.Context, partitionKey string) error {
+	ctx, done := store.start(ctx, "LockEventPartition")
+	err := store.store.LockEventPartition(ctx, partitionKey)
+	done(err)
+	return err
+}
+
+func (store *instrumentedStore) MarkEventsPublished(ctx context.Context, ids []int64) error {
+	ctx, done := store.start(ctx, "MarkEventsPublished")
+	err := store.store.MarkEventsPublished(ctx, ids)
+	done(err)
+	return err
+}
+
+func (store *instrumentedStore) RecordWebhookDeliveryAttempt(ctx context.Context, arg RecordWebhookDeliveryAttemptParams) (WebhookDelivery, error) {
+	ctx, done := store.start(ctx, "RecordWebhookDeliveryAttempt")
+	result, err := store.store.RecordWebhookDeliveryAttempt(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) SumInterestAccruals(ctx context.Context, arg SumInterestAccrualsParams) (int64, error) {
+	ctx, done := store.start(ctx, "SumInterestAccruals")
+	result, err := store.store.SumInterestAccruals(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) TryLockEventRelay(ctx context.Context) (bool, error) {
+	ctx, done := store.start(ctx, "TryLockEventRelay")
+	result, err := store.store.TryLockEventRelay(ctx)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error) {
+	ctx, done := store.start(ctx, "UpdateAccount")
+	result, err := store.store.UpdateAccount(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) UpdateAccountBalance(ctx context.Context, arg UpdateAccountBalanceParams) (Account, error) {
+	ctx, done := store.start(ctx, "UpdateAccountBalance")
+	result, err := store.store.UpdateAccountBalance(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) UpdateAccountStatus(ctx context.Context, arg UpdateAccountStatusParams) (Account, error) {
+	ctx, done := store.start(ctx, "UpdateAccountStatus")
+	result, err := store.store.UpdateAccountStatus(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) UpdateScheduledTransfer(ctx context.Context, arg UpdateScheduledTransferParams) (ScheduledTransfer, error) {
+	ctx, done := store.start(ctx, "UpdateScheduledTransfer")
+	result, err := store.store.UpdateScheduledTransfer(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) UpdateScheduledTransferNextRun(ctx context.Context, arg UpdateScheduledTransferNextRunParams) (ScheduledTransfer, error) {
+	ctx, done := store.start(ctx, "UpdateScheduledTransferNextRun")
+	result, err := store.store.UpdateScheduledTransferNextRun(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) UpdateUserTier(ctx context.Context, arg UpdateUserTierParams) (User, error) {
+	ctx, done := store.start(ctx, "UpdateUserTier")
+	result, err := store.store.UpdateUserTier(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) UpsertAccountLimits(ctx context.Context, arg UpsertAccountLimitsParams) (AccountLimit, error) {
+	ctx, done := store.start(ctx, "UpsertAccountLimits")
+	result, err := store.store.UpsertAccountLimits(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) UpsertInterestRate(ctx context.Context, arg UpsertInterestRateParams) (InterestRate, error) {
+	ctx, done := store.start(ctx, "UpsertInterestRate")
+	result, err := store.store.UpsertInterestRate(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
+	ctx, done := store.start(ctx, "TransferTx")
+	result, err := store.store.TransferTx(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) CreateAccountTx(ctx context.Context, arg CreateAccountParams) (Account, error) {
+	ctx, done := store.start(ctx, "CreateAccountTx")
+	result, err := store.store.CreateAccountTx(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) CreateUserTx(ctx context.Context, arg CreateUserParams) (User, error) {
+	ctx, done := store.start(ctx, "CreateUserTx")
+	result, err := store.store.CreateUserTx(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) CreateSessionTx(ctx context.Context, arg CreateSessionParams) (Session, error) {
+	ctx, done := store.start(ctx, "CreateSessionTx")
+	result, err := store.store.CreateSessionTx(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) PublishEventsTx(ctx context.Context, arg PublishEventsTxParams) (PublishEventsTxResult, error) {
+	ctx, done := store.start(ctx, "PublishEventsTx")
+	result, err := store.store.PublishEventsTx(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) DepositTx(ctx context.Context, arg DepositTxParams) (DepositTxResult, error) {
+	ctx, done := store.start(ctx, "DepositTx")
+	result, err := store.store.DepositTx(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) QuoteTransferFee(ctx context.Context, arg TransferTxParams) (Fee, error) {
+	ctx, done := store.start(ctx, "QuoteTransferFee")
+	result, err := store.store.QuoteTransferFee(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) BatchTransferTx(ctx context.Context, arg BatchTransferTxParams) (BatchTransferTxResult, error) {
+	ctx, done := store.start(ctx, "BatchTransferTx")
+	result, err := store.store.BatchTransferTx(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) ClaimDueScheduledTransfersTx(ctx context.Context, arg ClaimDueScheduledTransfersTxParams) ([]ClaimedScheduledTransfer, error) {
+	ctx, done := store.start(ctx, "ClaimDueScheduledTransfersTx")
+	result, err := store.store.ClaimDueScheduledTransfersTx(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) ReverseTransferTx(ctx context.Context, arg ReverseTransferTxParams) (ReverseTransferTxResult, error) {
+	ctx, done := store.start(ctx, "ReverseTransferTx")
+	result, err := store.store.ReverseTransferTx(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) ChangeAccountStatusTx(ctx context.Context, arg ChangeAccountStatusTxParams) (ChangeAccountStatusTxResult, error) {
+	ctx, done := store.start(ctx, "ChangeAccountStatusTx")
+	result, err := store.store.ChangeAccountStatusTx(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) HoldTx(ctx context.Context, arg HoldTxParams) (HoldTxResult, error) {
+	ctx, done := store.start(ctx, "HoldTx")
+	result, err := store.store.HoldTx(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) CaptureHoldTx(ctx context.Context, arg CaptureHoldTxParams) (CaptureHoldTxResult, error) {
+	ctx, done := store.start(ctx, "CaptureHoldTx")
+	result, err := store.store.CaptureHoldTx(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) VoidHoldTx(ctx context.Context, holdID int64) (Hold, error) {
+	ctx, done := store.start(ctx, "VoidHoldTx")
+	result, err := store.store.VoidHoldTx(ctx, holdID)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) ExpireHoldsTx(ctx context.Context, arg ExpireHoldsTxParams) ([]Hold, error) {
+	ctx, done := store.start(ctx, "ExpireHoldsTx")
+	result, err := store.store.ExpireHoldsTx(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) AccrueInterestTx(ctx context.Context, arg AccrueInterestTxParams) ([]InterestAccrual, error) {
+	ctx, done := store.start(ctx, "AccrueInterestTx")
+	result, err := store.store.AccrueInterestTx(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) PostInterestTx(ctx context.Context, arg PostInterestTxParams) (PostInterestTxResult, error) {
+	ctx, done := store.start(ctx, "PostInterestTx")
+	result, err := store.store.PostInterestTx(ctx, arg)
+	done(err)
+	return result, err
+}
+
+func (store *instrumentedStore) Ping(ctx context.Context) error {
+	ctx, done := store.start(ctx, "Ping")
+	err := store.store.Ping(ctx)
+	done(err)
+	return err
+}
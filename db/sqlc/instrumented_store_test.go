@@ -0,0 +1,51 @@
+package db_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/samirprakash/go-bank/db/mock"
+	db "github.com/samirprakash/go-bank/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+type ctxKey struct{}
+
+func TestInstrumentedStore(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var calls []string
+	hook := func(name string) db.QueryHook {
+		return func(ctx context.Context, method string) (context.Context, func(err error)) {
+			calls = append(calls, name+" start "+method)
+			ctx = context.WithValue(ctx, ctxKey{}, name)
+			return ctx, func(err error) {
+				require.ErrorIs(t, err, db.ErrRecordNotFound)
+				calls = append(calls, name+" done "+method)
+			}
+		}
+	}
+
+	mockStore := mockdb.NewMockStore(ctrl)
+	mockStore.EXPECT().
+		GetAccount(gomock.Any(), gomock.Eq(int64(1))).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, id int64) (db.Account, error) {
+			// the context enriched by the last hook reaches the store
+			require.Equal(t, "second", ctx.Value(ctxKey{}))
+			return db.Account{}, db.ErrRecordNotFound
+		})
+
+	store := db.NewInstrumentedStore(mockStore, hook("first"), hook("second"))
+	_, err := store.GetAccount(context.Background(), 1)
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+
+	require.Equal(t, []string{
+		"first start GetAccount",
+		"second start GetAccount",
+		"second done GetAccount",
+		"first done GetAccount",
+	}, calls)
+}
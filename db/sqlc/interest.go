@@ -0,0 +1,214 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Types of account, only savings accounts earn interest
+const (
+	AccountChecking = "checking"
+	AccountSavings  = "savings"
+)
+
+// Day-count conventions turning an annual rate into the interest of one day
+const (
+	// DayCountActual365 counts every day as 1/365 of a year
+	DayCountActual365 = "ACT/365"
+	// DayCountActual360 counts every day as 1/360 of a year
+	DayCountActual360 = "ACT/360"
+	// DayCount30360 counts every month as 30 days of a 360 days year
+	DayCount30360 = "30/360"
+)
+
+// InterestUnitsPerMinorUnit is the precision interest is accrued at.
+// A basis point of a day of 1/365 or 1/360 of a year is a whole number of units,
+// so daily accruals are exact and only the monthly posting rounds down to the minor unit.
+const InterestUnitsPerMinorUnit = 10000 * 26280
+
+// Errors returned when computing interest
+var (
+	ErrUnknownDayCount  = errors.New("unknown day-count convention")
+	ErrInterestOverflow = errors.New("interest overflows the accrual precision")
+)
+
+// InterestDays returns the number of days of interest a date earns and the number of days in a year.
+// With 30/360 the 31st earns nothing and the last day of February makes up for the missing days,
+// so every month earns 30 days.
+func InterestDays(dayCount string, date time.Time) (days int64, year int64, err error) {
+	switch dayCount {
+	case DayCountActual365:
+		return 1, 365, nil
+	case DayCountActual360:
+		return 1, 360, nil
+	case DayCount30360:
+		if date.Day() == 31 {
+			return 0, 360, nil
+		}
+		if date.Month() == time.February && date.AddDate(0, 0, 1).Month() == time.March {
+			return int64(30 - date.Day() + 1), 360, nil
+		}
+		return 1, 360, nil
+	}
+	return 0, 0, ErrUnknownDayCount
+}
+
+// InterestUnits computes the interest a balance earns on a date in units of InterestUnitsPerMinorUnit.
+// Balances at or below zero earn nothing.
+func InterestUnits(balance int64, annualRateBps int32, dayCount string, date time.Time) (int64, error) {
+	days, year, err := InterestDays(dayCount, date)
+	if err != nil || balance <= 0 {
+		return 0, err
+	}
+
+	// balance * rate / 10000 * days / year, scaled by InterestUnitsPerMinorUnit
+	units := new(big.Int).SetInt64(balance)
+	units.Mul(units, big.NewInt(int64(annualRateBps)*days*(26280/year)))
+	if !units.IsInt64() {
+		return 0, ErrInterestOverflow
+	}
+
+	return units.Int64(), nil
+}
+
+// AccrueInterestTxParams contains the input parameters of the interest accrual transaction
+type AccrueInterestTxParams struct {
+	// Date is the day the interest is accrued for, on the balance at its end in UTC
+	Date  time.Time `json:"date"`
+	Limit int32     `json:"limit"`
+}
+
+// AccrueInterestTx accrues one day of interest on a batch of savings accounts that were not accrued for that day yet.
+// The balance at the end of the day is rebuilt from the entries made since, so a late run accrues the same interest.
+// It returns the accruals made, fewer than the limit once every account is accrued for the day.
+func (store *txStore) AccrueInterestTx(ctx context.Context, arg AccrueInterestTxParams) ([]InterestAccrual, error) {
+	var result []InterestAccrual
+
+	date := arg.Date.UTC()
+	date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+
+	err := store.execTx(ctx, nil, func(q Querier) error {
+		result = []InterestAccrual{}
+
+		balances, err := q.ListUnaccruedInterestBalances(ctx, ListUnaccruedInterestBalancesParams{
+			EndOfDay:    date.AddDate(0, 0, 1),
+			AccrualDate: date,
+			LimitCount:  arg.Limit,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, balance := range balances {
+			units, err := InterestUnits(balance.Balance, balance.AnnualRateBps, balance.DayCount, date)
+			if err != nil {
+				return err
+			}
+
+			accrual, err := q.CreateInterestAccrual(ctx, CreateInterestAccrualParams{
+				AccountID:     balance.AccountID,
+				AccrualDate:   date,
+				Balance:       balance.Balance,
+				AnnualRateBps: balance.AnnualRateBps,
+				DayCount:      balance.DayCount,
+				Units:         units,
+			})
+			if err != nil {
+				// accrued by a concurrent run in the meantime
+				if errors.Is(err, ErrRecordNotFound) {
+					continue
+				}
+				return err
+			}
+			result = append(result, accrual)
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+// PostInterestTxParams contains the input parameters of the interest posting transaction
+type PostInterestTxParams struct {
+	AccountID int64 `json:"account_id"`
+	// Month is any day of the month to post, it must be over
+	Month time.Time `json:"month"`
+}
+
+// PostInterestTxResult represents the result of the interest posting transaction
+type PostInterestTxResult struct {
+	Posting InterestPosting `json:"posting"`
+	// Transfer is nil when the interest of the month is less than one minor unit
+	Transfer *TransferTxResult `json:"transfer,omitempty"`
+}
+
+// PostInterestTx pays the interest accrued on an account in a month with a transfer from the revenue account of its currency.
+// Whole minor units are paid and the fraction left is carried to the next month.
+// A month is posted once, posting it again fails on the primary key of interest_postings.
+func (store *txStore) PostInterestTx(ctx context.Context, arg PostInterestTxParams) (PostInterestTxResult, error) {
+	var result PostInterestTxResult
+
+	month := arg.Month.UTC()
+	month = time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	err := store.execTx(ctx, nil, func(q Querier) error {
+		account, err := q.GetAccount(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		units, err := q.SumInterestAccruals(ctx, SumInterestAccrualsParams{
+			AccountID: arg.AccountID,
+			Month:     month,
+		})
+		if err != nil {
+			return err
+		}
+
+		previous, err := q.GetLastInterestPosting(ctx, GetLastInterestPostingParams{
+			AccountID: arg.AccountID,
+			Month:     month,
+		})
+		if err != nil && !errors.Is(err, ErrRecordNotFound) {
+			return err
+		}
+		units += previous.RemainderUnits
+
+		posting := CreateInterestPostingParams{
+			AccountID:      arg.AccountID,
+			Month:          month,
+			Units:          units,
+			Amount:         units / InterestUnitsPerMinorUnit,
+			RemainderUnits: units % InterestUnitsPerMinorUnit,
+		}
+
+		if posting.Amount > 0 {
+			revenueAccountID, err := q.GetRevenueAccountID(ctx, account.Currency)
+			if err != nil {
+				return err
+			}
+
+			transfer, err := applyTransfer(ctx, q, TransferTxParams{
+				FromAccountID: revenueAccountID,
+				ToAccountID:   arg.AccountID,
+				Amount:        posting.Amount,
+			})
+			if err != nil {
+				return err
+			}
+
+			result.Transfer = &transfer
+			posting.TransferID = pgtype.Int8{Int64: transfer.Transfer.ID, Valid: true}
+		}
+
+		result.Posting, err = q.CreateInterestPosting(ctx, posting)
+		return err
+	})
+
+	return result, err
+}
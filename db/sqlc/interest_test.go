@@ -0,0 +1,165 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/samirprakash/go-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterestDays(t *testing.T) {
+	testCases := []struct {
+		name     string
+		dayCount string
+		date     time.Time
+		days     int64
+		year     int64
+	}{
+		{"Actual365", DayCountActual365, time.Date(2023, time.January, 31, 0, 0, 0, 0, time.UTC), 1, 365},
+		{"Actual360", DayCountActual360, time.Date(2023, time.January, 31, 0, 0, 0, 0, time.UTC), 1, 360},
+		{"30360", DayCount30360, time.Date(2023, time.January, 15, 0, 0, 0, 0, time.UTC), 1, 360},
+		{"30360Day31", DayCount30360, time.Date(2023, time.January, 31, 0, 0, 0, 0, time.UTC), 0, 360},
+		{"30360EndOfFebruary", DayCount30360, time.Date(2023, time.February, 28, 0, 0, 0, 0, time.UTC), 3, 360},
+		{"30360EndOfLeapFebruary", DayCount30360, time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC), 2, 360},
+		{"30360LeapFebruary28", DayCount30360, time.Date(2024, time.February, 28, 0, 0, 0, 0, time.UTC), 1, 360},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			days, year, err := InterestDays(tc.dayCount, tc.date)
+			require.NoError(t, err)
+			require.Equal(t, tc.days, days)
+			require.Equal(t, tc.year, year)
+		})
+	}
+
+	_, _, err := InterestDays("ACT/ACT", time.Now())
+	require.ErrorIs(t, err, ErrUnknownDayCount)
+}
+
+func TestInterestUnits(t *testing.T) {
+	date := time.Date(2023, time.January, 15, 0, 0, 0, 0, time.UTC)
+
+	// 3.65% of 1000000 over one day of 365 is exactly 100 minor units
+	units, err := InterestUnits(1000000, 365, DayCountActual365, date)
+	require.NoError(t, err)
+	require.Equal(t, int64(100*InterestUnitsPerMinorUnit), units)
+
+	// a month of 30/360 earns the same as a month of 30 days of ACT/360
+	var total30360, totalActual360 int64
+	for day := time.Date(2023, time.February, 1, 0, 0, 0, 0, time.UTC); day.Month() == time.February; day = day.AddDate(0, 0, 1) {
+		units, err := InterestUnits(12345, 250, DayCount30360, day)
+		require.NoError(t, err)
+		total30360 += units
+	}
+	for i := 0; i < 30; i++ {
+		units, err := InterestUnits(12345, 250, DayCountActual360, date)
+		require.NoError(t, err)
+		totalActual360 += units
+	}
+	require.Equal(t, totalActual360, total30360)
+
+	units, err = InterestUnits(-100, 365, DayCountActual365, date)
+	require.NoError(t, err)
+	require.Zero(t, units)
+
+	_, err = InterestUnits(1<<62, 10000, DayCountActual360, date)
+	require.ErrorIs(t, err, ErrInterestOverflow)
+}
+
+func createSavingsAccount(t *testing.T, balance int64) Account {
+	user := createRandomUser(t)
+
+	account, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Balance:  balance,
+		Currency: util.USD,
+		Type:     AccountSavings,
+	})
+	require.NoError(t, err)
+	require.Equal(t, AccountSavings, account.Type)
+
+	return account
+}
+
+// accrueAll accrues a day of interest on every savings account and returns the accrual of the account
+func accrueAll(t *testing.T, store Store, date time.Time, accountID int64) (InterestAccrual, bool) {
+	var accrual InterestAccrual
+	found := false
+
+	for {
+		accruals, err := store.AccrueInterestTx(context.Background(), AccrueInterestTxParams{Date: date, Limit: 100})
+		require.NoError(t, err)
+
+		for _, a := range accruals {
+			if a.AccountID == accountID {
+				accrual, found = a, true
+			}
+		}
+
+		if len(accruals) < 100 {
+			return accrual, found
+		}
+	}
+}
+
+func TestAccrueAndPostInterest(t *testing.T) {
+	store := NewStore(testDB)
+
+	// USD savings earn 2% ACT/360, so 10000000 earns 555.55... minor units a day
+	account := createSavingsAccount(t, 10000000)
+	checking := createRandomAccountInCurrency(t, util.USD)
+	today := time.Now().UTC()
+
+	accrual, found := accrueAll(t, store, today, account.ID)
+	require.True(t, found)
+	require.Equal(t, account.Balance, accrual.Balance)
+	require.Equal(t, int32(200), accrual.AnnualRateBps)
+	require.Equal(t, DayCountActual360, accrual.DayCount)
+	require.Equal(t, int64(10000000*200*73), accrual.Units)
+
+	// the day is accrued once and checking accounts earn nothing
+	_, found = accrueAll(t, store, today, account.ID)
+	require.False(t, found)
+	_, found = accrueAll(t, store, today, checking.ID)
+	require.False(t, found)
+
+	result, err := store.PostInterestTx(context.Background(), PostInterestTxParams{
+		AccountID: account.ID,
+		Month:     today,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(555), result.Posting.Amount)
+	require.Equal(t, accrual.Units-555*InterestUnitsPerMinorUnit, result.Posting.RemainderUnits)
+	require.NotNil(t, result.Transfer)
+	require.Equal(t, account.Balance+555, result.Transfer.ToAccount.Balance)
+	require.Equal(t, result.Transfer.Transfer.ID, result.Posting.TransferID.Int64)
+
+	// posting the month again is rejected and pays nothing
+	_, err = store.PostInterestTx(context.Background(), PostInterestTxParams{
+		AccountID: account.ID,
+		Month:     today,
+	})
+	var pgErr *pgconn.PgError
+	require.ErrorAs(t, err, &pgErr)
+	require.Equal(t, "interest_postings_pkey", pgErr.ConstraintName)
+
+	// the fraction of a minor unit left is carried to the next month
+	next, err := store.PostInterestTx(context.Background(), PostInterestTxParams{
+		AccountID: account.ID,
+		Month:     today.AddDate(0, 1, 0),
+	})
+	require.NoError(t, err)
+	require.Zero(t, next.Posting.Amount)
+	require.Nil(t, next.Transfer)
+	require.Equal(t, result.Posting.RemainderUnits, next.Posting.RemainderUnits)
+
+	updatedAccount, err := store.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance+555, updatedAccount.Balance)
+}
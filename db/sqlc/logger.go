@@ -0,0 +1,30 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// LogQueries is a QueryHook that logs every store call with the logger found in the context.
+// The request scoped logger carries the request ID, so database errors can be traced back to the request.
+func LogQueries(ctx context.Context, method string) (context.Context, func(err error)) {
+	start := time.Now()
+
+	return ctx, func(err error) {
+		logger := zerolog.Ctx(ctx)
+
+		event := logger.Debug()
+		// a missing row is an expected outcome handled by the caller
+		if err != nil && !errors.Is(err, ErrRecordNotFound) {
+			event = logger.Error().Err(err)
+		}
+
+		event.
+			Str("method", method).
+			Dur("duration", time.Since(start)).
+			Msg("database call")
+	}
+}
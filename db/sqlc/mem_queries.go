@@ -0,0 +1,1552 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// memQueries runs the queries of a MemStore on its tables, with the semantics of the SQL queries of db/query
+type memQueries struct {
+	tables *memTables
+	// lock is the lock of the store taken by every query outside of a transaction,
+	// nil within a transaction which already holds it
+	lock *sync.Mutex
+	// txTime is the start of the transaction, the time of all its queries like now() in postgres
+	txTime time.Time
+}
+
+// locked locks the store for a query outside of a transaction and returns the function unlocking it
+func (q *memQueries) locked() func() {
+	if q.lock == nil {
+		return func() {}
+	}
+
+	q.lock.Lock()
+	return q.lock.Unlock
+}
+
+// now returns the time of the current transaction, or the current time outside of a transaction
+func (q *memQueries) now() time.Time {
+	if q.txTime.IsZero() {
+		return memNow()
+	}
+	return q.txTime
+}
+
+// memRows returns the rows of a table matching a filter, sorted by less
+func memRows[K comparable, V any](table map[K]V, match func(row V) bool, less func(a, b V) bool) []V {
+	rows := []V{}
+	for _, row := range table {
+		if match(row) {
+			rows = append(rows, row)
+		}
+	}
+
+	sort.Slice(rows, func(i, j int) bool { return less(rows[i], rows[j]) })
+	return rows
+}
+
+// memPage returns the rows of LIMIT limit OFFSET offset
+func memPage[V any](rows []V, limit int32, offset int32) []V {
+	if offset > 0 {
+		if int(offset) >= len(rows) {
+			return rows[:0]
+		}
+		rows = rows[offset:]
+	}
+	if limit >= 0 && int(limit) < len(rows) {
+		rows = rows[:limit]
+	}
+	return rows
+}
+
+func memAll[V any](V) bool {
+	return true
+}
+
+func memTimestamp(t time.Time) time.Time {
+	return t.Truncate(time.Microsecond)
+}
+
+func memTimestamptz(t pgtype.Timestamptz) pgtype.Timestamptz {
+	t.Time = memTimestamp(t.Time)
+	return t
+}
+
+func memTimestamptzOf(t time.Time) pgtype.Timestamptz {
+	return pgtype.Timestamptz{Time: t, Valid: true}
+}
+
+func oneOf(value string, values ...string) bool {
+	for _, v := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Accounts
+
+// putAccount checks a new or changed account against the constraints of the accounts table and stores it
+func (t *memTables) putAccount(account Account) (Account, error) {
+	account.AvailableBalance = account.Balance - account.HeldAmount
+
+	switch {
+	case !account.OverdraftEnabled && account.AvailableBalance < 0:
+		return Account{}, checkViolation("accounts", "accounts_balance_check")
+	case account.Status == AccountClosed && (account.Balance != 0 || account.HeldAmount != 0):
+		return Account{}, checkViolation("accounts", "accounts_closed_check")
+	case account.HeldAmount < 0:
+		return Account{}, checkViolation("accounts", "accounts_held_amount_check")
+	case !oneOf(account.Status, AccountActive, AccountFrozen, AccountClosed):
+		return Account{}, checkViolation("accounts", "accounts_status_check")
+	case !oneOf(account.Type, AccountChecking, AccountSavings):
+		return Account{}, checkViolation("accounts", "accounts_type_check")
+	}
+
+	// the unique index owner_currency_type_key leaves out the closed accounts,
+	// so it is only checked when an account is created or leaves the closed status
+	if previous, ok := t.accounts[account.ID]; account.Status != AccountClosed && (!ok || previous.Status == AccountClosed) {
+		for _, other := range t.accounts {
+			if other.ID != account.ID && other.Status != AccountClosed &&
+				other.Owner == account.Owner && other.Currency == account.Currency && other.Type == account.Type {
+				return Account{}, uniqueViolation("accounts", "owner_currency_type_key")
+			}
+		}
+	}
+
+	if _, ok := t.currencies[account.Currency]; !ok {
+		return Account{}, foreignKeyViolation("accounts", "accounts_currency_fkey")
+	}
+	if _, ok := t.users[account.Owner]; !ok {
+		return Account{}, foreignKeyViolation("accounts", "accounts_owner_fkey")
+	}
+
+	memSet(t, t.accounts, account.ID, account)
+	return account, nil
+}
+
+// updateAccount changes an account, failing with ErrRecordNotFound if it does not exist
+func (t *memTables) updateAccount(id int64, update func(account *Account)) (Account, error) {
+	account, ok := t.accounts[id]
+	if !ok {
+		return Account{}, ErrRecordNotFound
+	}
+
+	update(&account)
+	return t.putAccount(account)
+}
+
+func (q *memQueries) CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error) {
+	defer q.locked()()
+	t := q.tables
+
+	return t.putAccount(Account{
+		ID:        t.nextID("accounts"),
+		Owner:     arg.Owner,
+		Balance:   arg.Balance,
+		Currency:  arg.Currency,
+		CreatedAt: q.now(),
+		Status:    AccountActive,
+		Type:      arg.Type,
+	})
+}
+
+func (q *memQueries) GetAccount(ctx context.Context, id int64) (Account, error) {
+	defer q.locked()()
+
+	account, ok := q.tables.accounts[id]
+	if !ok {
+		return Account{}, ErrRecordNotFound
+	}
+	return account, nil
+}
+
+// GetAccountForUpdate reads an account like GetAccount, the transaction holding the lock of the whole store
+func (q *memQueries) GetAccountForUpdate(ctx context.Context, id int64) (Account, error) {
+	return q.GetAccount(ctx, id)
+}
+
+func (q *memQueries) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error) {
+	defer q.locked()()
+
+	rows := memRows(q.tables.accounts,
+		func(account Account) bool { return account.Owner == arg.Owner },
+		func(a, b Account) bool { return a.ID < b.ID },
+	)
+	return memPage(rows, arg.Limit, arg.Offset), nil
+}
+
+func (q *memQueries) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error) {
+	defer q.locked()()
+
+	return q.tables.updateAccount(arg.ID, func(account *Account) {
+		account.Balance = arg.Balance
+	})
+}
+
+func (q *memQueries) UpdateAccountBalance(ctx context.Context, arg UpdateAccountBalanceParams) (Account, error) {
+	defer q.locked()()
+
+	return q.tables.updateAccount(arg.ID, func(account *Account) {
+		account.Balance += arg.Amount
+	})
+}
+
+func (q *memQueries) AddAccountHeldAmount(ctx context.Context, arg AddAccountHeldAmountParams) (Account, error) {
+	defer q.locked()()
+
+	return q.tables.updateAccount(arg.ID, func(account *Account) {
+		account.HeldAmount += arg.Amount
+	})
+}
+
+func (q *memQueries) UpdateAccountStatus(ctx context.Context, arg UpdateAccountStatusParams) (Account, error) {
+	defer q.locked()()
+	now := q.now()
+
+	return q.tables.updateAccount(arg.ID, func(account *Account) {
+		account.Status = arg.Status
+		account.ClosedAt = pgtype.Timestamptz{}
+		if arg.Status == AccountClosed {
+			account.ClosedAt = memTimestamptzOf(now)
+		}
+	})
+}
+
+func (q *memQueries) DeleteAccount(ctx context.Context, id int64) error {
+	defer q.locked()()
+	t := q.tables
+
+	if _, ok := t.accounts[id]; !ok {
+		return nil
+	}
+
+	referenced := func(constraint, table string) error {
+		return referencedViolation("accounts", constraint, table)
+	}
+	for _, entry := range t.entries {
+		if entry.AccountID == id {
+			return referenced("entries_account_id_fkey", "entries")
+		}
+	}
+	for _, transfer := range t.transfers {
+		if transfer.FromAccountID == id {
+			return referenced("transfers_from_account_id_fkey", "transfers")
+		}
+		if transfer.ToAccountID == id {
+			return referenced("transfers_to_account_id_fkey", "transfers")
+		}
+	}
+	for _, hold := range t.holds {
+		if hold.AccountID == id {
+			return referenced("holds_account_id_fkey", "holds")
+		}
+		if hold.ToAccountID == id {
+			return referenced("holds_to_account_id_fkey", "holds")
+		}
+	}
+	for _, scheduled := range t.scheduledTransfers {
+		if scheduled.FromAccountID == id {
+			return referenced("scheduled_transfers_from_account_id_fkey", "scheduled_transfers")
+		}
+		if scheduled.ToAccountID == id {
+			return referenced("scheduled_transfers_to_account_id_fkey", "scheduled_transfers")
+		}
+	}
+	for _, change := range t.accountStatusChanges {
+		if change.AccountID == id {
+			return referenced("account_status_changes_account_id_fkey", "account_status_changes")
+		}
+	}
+	if _, ok := t.accountLimits[id]; ok {
+		return referenced("account_limits_account_id_fkey", "account_limits")
+	}
+	for key := range t.interestAccruals {
+		if key.accountID == id {
+			return referenced("interest_accruals_account_id_fkey", "interest_accruals")
+		}
+	}
+	for key := range t.interestPostings {
+		if key.accountID == id {
+			return referenced("interest_postings_account_id_fkey", "interest_postings")
+		}
+	}
+	for _, revenue := range t.revenueAccounts {
+		if revenue.AccountID == id {
+			return referenced("revenue_accounts_account_id_fkey", "revenue_accounts")
+		}
+	}
+
+	memDelete(t, t.accounts, id)
+	return nil
+}
+
+// Account limits
+
+func (q *memQueries) GetAccountLimits(ctx context.Context, accountID int64) (GetAccountLimitsRow, error) {
+	defer q.locked()()
+	t := q.tables
+
+	account, ok := t.accounts[accountID]
+	if !ok {
+		return GetAccountLimitsRow{}, ErrRecordNotFound
+	}
+	user, ok := t.users[account.Owner]
+	if !ok {
+		return GetAccountLimitsRow{}, ErrRecordNotFound
+	}
+	tier, ok := t.limitTiers[user.Tier]
+	if !ok {
+		return GetAccountLimitsRow{}, ErrRecordNotFound
+	}
+
+	coalesce := func(override, fallback pgtype.Int8) pgtype.Int8 {
+		if override.Valid {
+			return override
+		}
+		return fallback
+	}
+	limits := t.accountLimits[accountID]
+
+	return GetAccountLimitsRow{
+		AccountID:            account.ID,
+		Tier:                 user.Tier,
+		MaxTransferAmount:    coalesce(limits.MaxTransferAmount, tier.MaxTransferAmount),
+		DailyOutgoingLimit:   coalesce(limits.DailyOutgoingLimit, tier.DailyOutgoingLimit),
+		MonthlyOutgoingLimit: coalesce(limits.MonthlyOutgoingLimit, tier.MonthlyOutgoingLimit),
+	}, nil
+}
+
+func (q *memQueries) UpsertAccountLimits(ctx context.Context, arg UpsertAccountLimitsParams) (AccountLimit, error) {
+	defer q.locked()()
+	t := q.tables
+
+	for _, limit := range []pgtype.Int8{arg.MaxTransferAmount, arg.DailyOutgoingLimit, arg.MonthlyOutgoingLimit} {
+		if limit.Valid && limit.Int64 <= 0 {
+			return AccountLimit{}, checkViolation("account_limits", "account_limits_positive_check")
+		}
+	}
+	if _, ok := t.accounts[arg.AccountID]; !ok {
+		return AccountLimit{}, foreignKeyViolation("account_limits", "account_limits_account_id_fkey")
+	}
+	if _, ok := t.users[arg.UpdatedBy]; !ok {
+		return AccountLimit{}, foreignKeyViolation("account_limits", "account_limits_updated_by_fkey")
+	}
+
+	limits := AccountLimit{
+		AccountID:            arg.AccountID,
+		MaxTransferAmount:    arg.MaxTransferAmount,
+		DailyOutgoingLimit:   arg.DailyOutgoingLimit,
+		MonthlyOutgoingLimit: arg.MonthlyOutgoingLimit,
+		UpdatedBy:            arg.UpdatedBy,
+		UpdatedAt:            q.now(),
+	}
+	memSet(t, t.accountLimits, arg.AccountID, limits)
+	return limits, nil
+}
+
+func (q *memQueries) GetOutgoingTotals(ctx context.Context, arg GetOutgoingTotalsParams) (GetOutgoingTotalsRow, error) {
+	defer q.locked()()
+
+	var totals GetOutgoingTotalsRow
+	for _, entry := range q.tables.entries {
+		if entry.AccountID != arg.AccountID || entry.Amount >= 0 || entry.CreatedAt.Before(arg.MonthStart) {
+			continue
+		}
+		totals.MonthlyTotal -= entry.Amount
+		if !entry.CreatedAt.Before(arg.DayStart) {
+			totals.DailyTotal -= entry.Amount
+		}
+	}
+	return totals, nil
+}
+
+// Account status changes
+
+func (q *memQueries) CreateAccountStatusChange(ctx context.Context, arg CreateAccountStatusChangeParams) (AccountStatusChange, error) {
+	defer q.locked()()
+	t := q.tables
+
+	if _, ok := t.accounts[arg.AccountID]; !ok {
+		return AccountStatusChange{}, foreignKeyViolation("account_status_changes", "account_status_changes_account_id_fkey")
+	}
+	if _, ok := t.users[arg.ChangedBy]; !ok {
+		return AccountStatusChange{}, foreignKeyViolation("account_status_changes", "account_status_changes_changed_by_fkey")
+	}
+
+	change := AccountStatusChange{
+		ID:         t.nextID("account_status_changes"),
+		AccountID:  arg.AccountID,
+		FromStatus: arg.FromStatus,
+		ToStatus:   arg.ToStatus,
+		ChangedBy:  arg.ChangedBy,
+		Reason:     arg.Reason,
+		CreatedAt:  q.now(),
+	}
+	memSet(t, t.accountStatusChanges, change.ID, change)
+	return change, nil
+}
+
+func (q *memQueries) ListAccountStatusChanges(ctx context.Context, arg ListAccountStatusChangesParams) ([]AccountStatusChange, error) {
+	defer q.locked()()
+
+	rows := memRows(q.tables.accountStatusChanges,
+		func(change AccountStatusChange) bool { return change.AccountID == arg.AccountID },
+		func(a, b AccountStatusChange) bool { return a.ID > b.ID },
+	)
+	return memPage(rows, arg.Limit, arg.Offset), nil
+}
+
+// Entries
+
+func (q *memQueries) CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error) {
+	defer q.locked()()
+	t := q.tables
+
+	if _, ok := t.accounts[arg.AccountID]; !ok {
+		return Entry{}, foreignKeyViolation("entries", "entries_account_id_fkey")
+	}
+	if _, ok := t.transfers[arg.TransferID.Int64]; arg.TransferID.Valid && !ok {
+		return Entry{}, foreignKeyViolation("entries", "entries_transfer_id_fkey")
+	}
+
+	entry := Entry{
+		ID:         t.nextID("entries"),
+		AccountID:  arg.AccountID,
+		Amount:     arg.Amount,
+		CreatedAt:  q.now(),
+		TransferID: arg.TransferID,
+	}
+	memSet(t, t.entries, entry.ID, entry)
+	return entry, nil
+}
+
+func (q *memQueries) GetEntry(ctx context.Context, id int64) (Entry, error) {
+	defer q.locked()()
+
+	entry, ok := q.tables.entries[id]
+	if !ok {
+		return Entry{}, ErrRecordNotFound
+	}
+	return entry, nil
+}
+
+func (q *memQueries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error) {
+	defer q.locked()()
+
+	rows := memRows(q.tables.entries, memAll[Entry], func(a, b Entry) bool { return a.ID < b.ID })
+	return memPage(rows, arg.Limit, arg.Offset), nil
+}
+
+// Events
+
+// LockEventPartition returns right away, the transaction holding the lock of the whole store
+func (q *memQueries) LockEventPartition(ctx context.Context, partitionKey string) error {
+	return nil
+}
+
+// TryLockEventRelay always succeeds, the transaction holding the lock of the whole store
+func (q *memQueries) TryLockEventRelay(ctx context.Context) (bool, error) {
+	return true, nil
+}
+
+func (q *memQueries) CreateEvents(ctx context.Context, arg CreateEventsParams) error {
+	defer q.locked()()
+	t := q.tables
+
+	for _, key := range arg.PartitionKeys {
+		for _, other := range t.events {
+			if other.EventID == arg.EventID && other.PartitionKey == key {
+				return uniqueViolation("events", "events_event_id_partition_key_idx")
+			}
+		}
+
+		event := Event{
+			ID:           t.nextID("events"),
+			EventID:      arg.EventID,
+			EventType:    arg.EventType,
+			PartitionKey: key,
+			Payload:      append(json.RawMessage(nil), arg.Payload...),
+			CreatedAt:    q.now(),
+		}
+		memSet(t, t.events, event.ID, event)
+	}
+	return nil
+}
+
+func (q *memQueries) ListUnpublishedEvents(ctx context.Context, limit int32) ([]Event, error) {
+	defer q.locked()()
+
+	rows := memRows(q.tables.events,
+		func(event Event) bool { return !event.PublishedAt.Valid },
+		func(a, b Event) bool { return a.ID < b.ID },
+	)
+	return memPage(rows, limit, 0), nil
+}
+
+func (q *memQueries) MarkEventsPublished(ctx context.Context, ids []int64) error {
+	defer q.locked()()
+	t := q.tables
+
+	for _, id := range ids {
+		if event, ok := t.events[id]; ok {
+			event.PublishedAt = memTimestamptzOf(q.now())
+			memSet(t, t.events, id, event)
+		}
+	}
+	return nil
+}
+
+func (q *memQueries) ListEvents(ctx context.Context, arg ListEventsParams) ([]Event, error) {
+	defer q.locked()()
+
+	rows := memRows(q.tables.events,
+		func(event Event) bool { return event.PartitionKey == arg.PartitionKey },
+		func(a, b Event) bool { return a.ID < b.ID },
+	)
+	return memPage(rows, arg.Limit, 0), nil
+}
+
+// Fees
+
+func (q *memQueries) CreateFeeSchedule(ctx context.Context, arg CreateFeeScheduleParams) (FeeSchedule, error) {
+	defer q.locked()()
+	t := q.tables
+
+	switch {
+	case arg.MinAmount < 0 || arg.FlatFee < 0 || arg.MinFee < 0:
+		return FeeSchedule{}, checkViolation("fee_schedules", "fee_schedules_amounts_check")
+	case arg.MaxFee.Valid && arg.MaxFee.Int64 < arg.MinFee:
+		return FeeSchedule{}, checkViolation("fee_schedules", "fee_schedules_max_fee_check")
+	case arg.PercentageBps < 0 || arg.PercentageBps > 10000:
+		return FeeSchedule{}, checkViolation("fee_schedules", "fee_schedules_percentage_check")
+	}
+
+	for _, other := range t.feeSchedules {
+		if other.Currency.String == arg.Currency.String && other.Tier.String == arg.Tier.String && other.MinAmount == arg.MinAmount {
+			return FeeSchedule{}, uniqueViolation("fee_schedules", "fee_schedules_band_key")
+		}
+	}
+
+	if _, ok := t.currencies[arg.Currency.String]; arg.Currency.Valid && !ok {
+		return FeeSchedule{}, foreignKeyViolation("fee_schedules", "fee_schedules_currency_fkey")
+	}
+	if _, ok := t.limitTiers[arg.Tier.String]; arg.Tier.Valid && !ok {
+		return FeeSchedule{}, foreignKeyViolation("fee_schedules", "fee_schedules_tier_fkey")
+	}
+
+	schedule := FeeSchedule{
+		ID:            t.nextID("fee_schedules"),
+		Currency:      arg.Currency,
+		Tier:          arg.Tier,
+		MinAmount:     arg.MinAmount,
+		FlatFee:       arg.FlatFee,
+		PercentageBps: arg.PercentageBps,
+		MinFee:        arg.MinFee,
+		MaxFee:        arg.MaxFee,
+		CreatedAt:     q.now(),
+	}
+	memSet(t, t.feeSchedules, schedule.ID, schedule)
+	return schedule, nil
+}
+
+func (q *memQueries) ListFeeSchedules(ctx context.Context, arg ListFeeSchedulesParams) ([]FeeSchedule, error) {
+	defer q.locked()()
+
+	rows := memRows(q.tables.feeSchedules, memAll[FeeSchedule], func(a, b FeeSchedule) bool { return a.ID < b.ID })
+	return memPage(rows, arg.Limit, arg.Offset), nil
+}
+
+func (q *memQueries) DeleteFeeSchedule(ctx context.Context, id int64) error {
+	defer q.locked()()
+
+	memDelete(q.tables, q.tables.feeSchedules, id)
+	return nil
+}
+
+// GetFeeSchedule returns the band of the amount in the most specific schedule,
+// a currency match first then a tier match
+func (q *memQueries) GetFeeSchedule(ctx context.Context, arg GetFeeScheduleParams) (FeeSchedule, error) {
+	defer q.locked()()
+
+	rows := memRows(q.tables.feeSchedules,
+		func(schedule FeeSchedule) bool {
+			return (!schedule.Currency.Valid || schedule.Currency.String == arg.Currency) &&
+				(!schedule.Tier.Valid || schedule.Tier.String == arg.Tier) &&
+				schedule.MinAmount <= arg.Amount
+		},
+		func(a, b FeeSchedule) bool {
+			if a.Currency.Valid != b.Currency.Valid {
+				return a.Currency.Valid
+			}
+			if a.Tier.Valid != b.Tier.Valid {
+				return a.Tier.Valid
+			}
+			if a.MinAmount != b.MinAmount {
+				return a.MinAmount > b.MinAmount
+			}
+			return a.ID < b.ID
+		},
+	)
+	if len(rows) == 0 {
+		return FeeSchedule{}, ErrRecordNotFound
+	}
+	return rows[0], nil
+}
+
+func (q *memQueries) GetRevenueAccountID(ctx context.Context, currency string) (int64, error) {
+	defer q.locked()()
+
+	revenue, ok := q.tables.revenueAccounts[currency]
+	if !ok {
+		return 0, ErrRecordNotFound
+	}
+	return revenue.AccountID, nil
+}
+
+// Holds
+
+// putHold checks a new or changed hold against the constraints of the holds table and stores it
+func (t *memTables) putHold(hold Hold) (Hold, error) {
+	switch {
+	case hold.Amount <= 0:
+		return Hold{}, checkViolation("holds", "holds_amount_check")
+	case hold.CapturedAmount < 0 || hold.CapturedAmount > hold.Amount:
+		return Hold{}, checkViolation("holds", "holds_captured_amount_check")
+	case hold.Fee < 0:
+		return Hold{}, checkViolation("holds", "holds_fee_check")
+	case hold.AccountID == hold.ToAccountID:
+		return Hold{}, checkViolation("holds", "holds_distinct_accounts_check")
+	case !oneOf(hold.Status, HoldActive, HoldCaptured, HoldVoided, HoldExpired):
+		return Hold{}, checkViolation("holds", "holds_status_check")
+	}
+
+	if _, ok := t.accounts[hold.AccountID]; !ok {
+		return Hold{}, foreignKeyViolation("holds", "holds_account_id_fkey")
+	}
+	if _, ok := t.accounts[hold.ToAccountID]; !ok {
+		return Hold{}, foreignKeyViolation("holds", "holds_to_account_id_fkey")
+	}
+	if _, ok := t.transfers[hold.TransferID.Int64]; hold.TransferID.Valid && !ok {
+		return Hold{}, foreignKeyViolation("holds", "holds_transfer_id_fkey")
+	}
+
+	memSet(t, t.holds, hold.ID, hold)
+	return hold, nil
+}
+
+func (q *memQueries) CreateHold(ctx context.Context, arg CreateHoldParams) (Hold, error) {
+	defer q.locked()()
+	t := q.tables
+
+	return t.putHold(Hold{
+		ID:          t.nextID("holds"),
+		AccountID:   arg.AccountID,
+		ToAccountID: arg.ToAccountID,
+		Amount:      arg.Amount,
+		Fee:         arg.Fee,
+		Status:      HoldActive,
+		ExpiresAt:   memTimestamp(arg.ExpiresAt),
+		CreatedAt:   q.now(),
+	})
+}
+
+func (q *memQueries) GetHold(ctx context.Context, id int64) (Hold, error) {
+	defer q.locked()()
+
+	hold, ok := q.tables.holds[id]
+	if !ok {
+		return Hold{}, ErrRecordNotFound
+	}
+	return hold, nil
+}
+
+// GetHoldForUpdate reads a hold like GetHold, the transaction holding the lock of the whole store
+func (q *memQueries) GetHoldForUpdate(ctx context.Context, id int64) (Hold, error) {
+	return q.GetHold(ctx, id)
+}
+
+func (q *memQueries) ListAccountHolds(ctx context.Context, arg ListAccountHoldsParams) ([]Hold, error) {
+	defer q.locked()()
+
+	rows := memRows(q.tables.holds,
+		func(hold Hold) bool { return hold.AccountID == arg.AccountID },
+		func(a, b Hold) bool { return a.ID > b.ID },
+	)
+	return memPage(rows, arg.Limit, arg.Offset), nil
+}
+
+func (q *memQueries) FinishHold(ctx context.Context, arg FinishHoldParams) (Hold, error) {
+	defer q.locked()()
+	t := q.tables
+
+	hold, ok := t.holds[arg.ID]
+	if !ok {
+		return Hold{}, ErrRecordNotFound
+	}
+
+	hold.Status = arg.Status
+	hold.CapturedAmount = arg.CapturedAmount
+	hold.TransferID = arg.TransferID
+	hold.FinishedAt = memTimestamptzOf(q.now())
+	return t.putHold(hold)
+}
+
+// ListExpiredHoldsForUpdate returns the expired holds still active, none being locked by another transaction
+func (q *memQueries) ListExpiredHoldsForUpdate(ctx context.Context, arg ListExpiredHoldsForUpdateParams) ([]Hold, error) {
+	defer q.locked()()
+
+	rows := memRows(q.tables.holds,
+		func(hold Hold) bool { return hold.Status == HoldActive && !hold.ExpiresAt.After(arg.Now) },
+		func(a, b Hold) bool {
+			if !a.ExpiresAt.Equal(b.ExpiresAt) {
+				return a.ExpiresAt.Before(b.ExpiresAt)
+			}
+			return a.ID < b.ID
+		},
+	)
+	return memPage(rows, arg.LimitCount, 0), nil
+}
+
+// Interest
+
+func (q *memQueries) ListInterestRates(ctx context.Context) ([]InterestRate, error) {
+	defer q.locked()()
+
+	rows := memRows(q.tables.interestRates, memAll[InterestRate], func(a, b InterestRate) bool { return a.Currency < b.Currency })
+	return rows, nil
+}
+
+func (q *memQueries) UpsertInterestRate(ctx context.Context, arg UpsertInterestRateParams) (InterestRate, error) {
+	defer q.locked()()
+	t := q.tables
+
+	switch {
+	case arg.AnnualRateBps < 0 || arg.AnnualRateBps > 10000:
+		return InterestRate{}, checkViolation("interest_rates", "interest_rates_rate_check")
+	case !oneOf(arg.DayCount, DayCountActual365, DayCountActual360, DayCount30360):
+		return InterestRate{}, checkViolation("interest_rates", "interest_rates_day_count_check")
+	}
+	if _, ok := t.currencies[arg.Currency]; !ok {
+		return InterestRate{}, foreignKeyViolation("interest_rates", "interest_rates_currency_fkey")
+	}
+
+	rate := InterestRate{
+		Currency:      arg.Currency,
+		AnnualRateBps: arg.AnnualRateBps,
+		DayCount:      arg.DayCount,
+		UpdatedAt:     q.now(),
+	}
+	memSet(t, t.interestRates, rate.Currency, rate)
+	return rate, nil
+}
+
+// ListUnaccruedInterestBalances returns the end of day balances of the savings accounts without an accrual for the day
+func (q *memQueries) ListUnaccruedInterestBalances(ctx context.Context, arg ListUnaccruedInterestBalancesParams) ([]ListUnaccruedInterestBalancesRow, error) {
+	defer q.locked()()
+	t := q.tables
+	accrualDate := memDate(arg.AccrualDate)
+
+	accounts := memRows(t.accounts,
+		func(account Account) bool {
+			_, hasRate := t.interestRates[account.Currency]
+			_, accrued := t.interestAccruals[memAccrualKey{account.ID, accrualDate}]
+			return account.Type == AccountSavings && account.CreatedAt.Before(arg.EndOfDay) && hasRate && !accrued
+		},
+		func(a, b Account) bool { return a.ID < b.ID },
+	)
+	accounts = memPage(accounts, arg.LimitCount, 0)
+
+	rows := []ListUnaccruedInterestBalancesRow{}
+	for _, account := range accounts {
+		rate := t.interestRates[account.Currency]
+		rows = append(rows, ListUnaccruedInterestBalancesRow{
+			AccountID:     account.ID,
+			Balance:       account.Balance - t.sumEntriesSince(account.ID, arg.EndOfDay),
+			AnnualRateBps: rate.AnnualRateBps,
+			DayCount:      rate.DayCount,
+		})
+	}
+	return rows, nil
+}
+
+// sumEntriesSince sums the amounts of the entries of an account created at or after a time
+func (t *memTables) sumEntriesSince(accountID int64, since time.Time) int64 {
+	var sum int64
+	for _, entry := range t.entries {
+		if entry.AccountID == accountID && !entry.CreatedAt.Before(since) {
+			sum += entry.Amount
+		}
+	}
+	return sum
+}
+
+// CreateInterestAccrual records the accrual of an account and day, failing with ErrRecordNotFound if there is one already
+func (q *memQueries) CreateInterestAccrual(ctx context.Context, arg CreateInterestAccrualParams) (InterestAccrual, error) {
+	defer q.locked()()
+	t := q.tables
+
+	key := memAccrualKey{arg.AccountID, memDate(arg.AccrualDate)}
+	if _, ok := t.interestAccruals[key]; ok {
+		return InterestAccrual{}, ErrRecordNotFound
+	}
+	if _, ok := t.accounts[arg.AccountID]; !ok {
+		return InterestAccrual{}, foreignKeyViolation("interest_accruals", "interest_accruals_account_id_fkey")
+	}
+
+	accrual := InterestAccrual{
+		AccountID:     arg.AccountID,
+		AccrualDate:   key.date,
+		Balance:       arg.Balance,
+		AnnualRateBps: arg.AnnualRateBps,
+		DayCount:      arg.DayCount,
+		Units:         arg.Units,
+		CreatedAt:     q.now(),
+	}
+	memSet(t, t.interestAccruals, key, accrual)
+	return accrual, nil
+}
+
+func (q *memQueries) GetLastInterestAccrualDate(ctx context.Context) (time.Time, error) {
+	defer q.locked()()
+
+	var last time.Time
+	for key := range q.tables.interestAccruals {
+		if key.date.After(last) {
+			last = key.date
+		}
+	}
+	if last.IsZero() {
+		return time.Time{}, ErrRecordNotFound
+	}
+	return last, nil
+}
+
+func (q *memQueries) SumInterestAccruals(ctx context.Context, arg SumInterestAccrualsParams) (int64, error) {
+	defer q.locked()()
+
+	month := memDate(arg.Month)
+	nextMonth := month.AddDate(0, 1, 0)
+
+	var units int64
+	for key, accrual := range q.tables.interestAccruals {
+		if key.accountID == arg.AccountID && !key.date.Before(month) && key.date.Before(nextMonth) {
+			units += accrual.Units
+		}
+	}
+	return units, nil
+}
+
+// ListPendingInterestPostings returns the months of accruals before a date that were not posted, for the active accounts
+func (q *memQueries) ListPendingInterestPostings(ctx context.Context, arg ListPendingInterestPostingsParams) ([]ListPendingInterestPostingsRow, error) {
+	defer q.locked()()
+	t := q.tables
+
+	pending := make(map[memAccrualKey]ListPendingInterestPostingsRow)
+	for key := range t.interestAccruals {
+		month := time.Date(key.date.Year(), key.date.Month(), 1, 0, 0, 0, 0, time.UTC)
+		posting := memAccrualKey{key.accountID, month}
+
+		if t.accounts[key.accountID].Status != AccountActive || !key.date.Before(arg.Before) {
+			continue
+		}
+		if _, posted := t.interestPostings[posting]; posted {
+			continue
+		}
+		pending[posting] = ListPendingInterestPostingsRow{AccountID: key.accountID, Month: month}
+	}
+
+	rows := memRows(pending, memAll[ListPendingInterestPostingsRow], func(a, b ListPendingInterestPostingsRow) bool {
+		if !a.Month.Equal(b.Month) {
+			return a.Month.Before(b.Month)
+		}
+		return a.AccountID < b.AccountID
+	})
+	return memPage(rows, arg.LimitCount, 0), nil
+}
+
+func (q *memQueries) GetLastInterestPosting(ctx context.Context, arg GetLastInterestPostingParams) (InterestPosting, error) {
+	defer q.locked()()
+
+	rows := memRows(q.tables.interestPostings,
+		func(posting InterestPosting) bool {
+			return posting.AccountID == arg.AccountID && posting.Month.Before(memDate(arg.Month))
+		},
+		func(a, b InterestPosting) bool { return a.Month.After(b.Month) },
+	)
+	if len(rows) == 0 {
+		return InterestPosting{}, ErrRecordNotFound
+	}
+	return rows[0], nil
+}
+
+func (q *memQueries) CreateInterestPosting(ctx context.Context, arg CreateInterestPostingParams) (InterestPosting, error) {
+	defer q.locked()()
+	t := q.tables
+
+	key := memAccrualKey{arg.AccountID, memDate(arg.Month)}
+	if _, ok := t.interestPostings[key]; ok {
+		return InterestPosting{}, uniqueViolation("interest_postings", "interest_postings_pkey")
+	}
+	if _, ok := t.accounts[arg.AccountID]; !ok {
+		return InterestPosting{}, foreignKeyViolation("interest_postings", "interest_postings_account_id_fkey")
+	}
+	if _, ok := t.transfers[arg.TransferID.Int64]; arg.TransferID.Valid && !ok {
+		return InterestPosting{}, foreignKeyViolation("interest_postings", "interest_postings_transfer_id_fkey")
+	}
+
+	posting := InterestPosting{
+		AccountID:      arg.AccountID,
+		Month:          key.date,
+		Units:          arg.Units,
+		Amount:         arg.Amount,
+		RemainderUnits: arg.RemainderUnits,
+		TransferID:     arg.TransferID,
+		CreatedAt:      q.now(),
+	}
+	memSet(t, t.interestPostings, key, posting)
+	return posting, nil
+}
+
+func (q *memQueries) ListInterestPostings(ctx context.Context, arg ListInterestPostingsParams) ([]InterestPosting, error) {
+	defer q.locked()()
+
+	rows := memRows(q.tables.interestPostings,
+		func(posting InterestPosting) bool { return posting.AccountID == arg.AccountID },
+		func(a, b InterestPosting) bool { return a.Month.After(b.Month) },
+	)
+	return memPage(rows, arg.Limit, arg.Offset), nil
+}
+
+// Scheduled transfers
+
+// putScheduledTransfer checks a new or changed scheduled transfer against the constraints of its table and stores it
+func (t *memTables) putScheduledTransfer(scheduled ScheduledTransfer) (ScheduledTransfer, error) {
+	switch {
+	case scheduled.Amount <= 0:
+		return ScheduledTransfer{}, checkViolation("scheduled_transfers", "scheduled_transfers_amount_check")
+	case scheduled.FromAccountID == scheduled.ToAccountID:
+		return ScheduledTransfer{}, checkViolation("scheduled_transfers", "scheduled_transfers_distinct_accounts_check")
+	}
+
+	if _, ok := t.users[scheduled.Owner]; !ok {
+		return ScheduledTransfer{}, foreignKeyViolation("scheduled_transfers", "scheduled_transfers_owner_fkey")
+	}
+	if _, ok := t.accounts[scheduled.FromAccountID]; !ok {
+		return ScheduledTransfer{}, foreignKeyViolation("scheduled_transfers", "scheduled_transfers_from_account_id_fkey")
+	}
+	if _, ok := t.accounts[scheduled.ToAccountID]; !ok {
+		return ScheduledTransfer{}, foreignKeyViolation("scheduled_transfers", "scheduled_transfers_to_account_id_fkey")
+	}
+	if _, ok := t.currencies[scheduled.Currency]; !ok {
+		return ScheduledTransfer{}, foreignKeyViolation("scheduled_transfers", "scheduled_transfers_currency_fkey")
+	}
+
+	memSet(t, t.scheduledTransfers, scheduled.ID, scheduled)
+	return scheduled, nil
+}
+
+// updateScheduledTransfer changes a scheduled transfer, failing with ErrRecordNotFound if it does not exist
+func (t *memTables) updateScheduledTransfer(id int64, update func(scheduled *ScheduledTransfer)) (ScheduledTransfer, error) {
+	scheduled, ok := t.scheduledTransfers[id]
+	if !ok {
+		return ScheduledTransfer{}, ErrRecordNotFound
+	}
+
+	update(&scheduled)
+	return t.putScheduledTransfer(scheduled)
+}
+
+func (q *memQueries) CreateScheduledTransfer(ctx context.Context, arg CreateScheduledTransferParams) (ScheduledTransfer, error) {
+	defer q.locked()()
+	t := q.tables
+
+	return t.putScheduledTransfer(ScheduledTransfer{
+		ID:            t.nextID("scheduled_transfers"),
+		Owner:         arg.Owner,
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        arg.Amount,
+		Currency:      arg.Currency,
+		Schedule:      arg.Schedule,
+		StartAt:       memTimestamp(arg.StartAt),
+		EndAt:         memTimestamptz(arg.EndAt),
+		NextRunAt:     memTimestamptz(arg.NextRunAt),
+		CreatedAt:     q.now(),
+	})
+}
+
+func (q *memQueries) GetScheduledTransfer(ctx context.Context, id int64) (ScheduledTransfer, error) {
+	defer q.locked()()
+
+	scheduled, ok := q.tables.scheduledTransfers[id]
+	if !ok {
+		return ScheduledTransfer{}, ErrRecordNotFound
+	}
+	return scheduled, nil
+}
+
+func (q *memQueries) ListScheduledTransfers(ctx context.Context, arg ListScheduledTransfersParams) ([]ScheduledTransfer, error) {
+	defer q.locked()()
+
+	rows := memRows(q.tables.scheduledTransfers,
+		func(scheduled ScheduledTransfer) bool { return scheduled.Owner == arg.Owner },
+		func(a, b ScheduledTransfer) bool { return a.ID < b.ID },
+	)
+	return memPage(rows, arg.Limit, arg.Offset), nil
+}
+
+func (q *memQueries) UpdateScheduledTransfer(ctx context.Context, arg UpdateScheduledTransferParams) (ScheduledTransfer, error) {
+	defer q.locked()()
+
+	return q.tables.updateScheduledTransfer(arg.ID, func(scheduled *ScheduledTransfer) {
+		scheduled.Amount = arg.Amount
+		scheduled.Schedule = arg.Schedule
+		scheduled.EndAt = memTimestamptz(arg.EndAt)
+		scheduled.NextRunAt = memTimestamptz(arg.NextRunAt)
+	})
+}
+
+func (q *memQueries) UpdateScheduledTransferNextRun(ctx context.Context, arg UpdateScheduledTransferNextRunParams) (ScheduledTransfer, error) {
+	defer q.locked()()
+
+	return q.tables.updateScheduledTransfer(arg.ID, func(scheduled *ScheduledTransfer) {
+		scheduled.NextRunAt = memTimestamptz(arg.NextRunAt)
+	})
+}
+
+// DeleteScheduledTransfer deletes a scheduled transfer and its runs
+func (q *memQueries) DeleteScheduledTransfer(ctx context.Context, id int64) error {
+	defer q.locked()()
+	t := q.tables
+
+	if _, ok := t.scheduledTransfers[id]; !ok {
+		return nil
+	}
+
+	for runID, run := range t.scheduledTransferRuns {
+		if run.ScheduledTransferID == id {
+			memDelete(t, t.scheduledTransferRuns, runID)
+		}
+	}
+	memDelete(t, t.scheduledTransfers, id)
+	return nil
+}
+
+// ListDueScheduledTransfersForUpdate returns the scheduled transfers due to run, none being locked by another transaction
+func (q *memQueries) ListDueScheduledTransfersForUpdate(ctx context.Context, arg ListDueScheduledTransfersForUpdateParams) ([]ScheduledTransfer, error) {
+	defer q.locked()()
+
+	rows := memRows(q.tables.scheduledTransfers,
+		func(scheduled ScheduledTransfer) bool {
+			return scheduled.NextRunAt.Valid && !scheduled.NextRunAt.Time.After(arg.Now)
+		},
+		func(a, b ScheduledTransfer) bool {
+			if !a.NextRunAt.Time.Equal(b.NextRunAt.Time) {
+				return a.NextRunAt.Time.Before(b.NextRunAt.Time)
+			}
+			return a.ID < b.ID
+		},
+	)
+	return memPage(rows, arg.LimitCount, 0), nil
+}
+
+func (q *memQueries) CreateScheduledTransferRun(ctx context.Context, arg CreateScheduledTransferRunParams) (ScheduledTransferRun, error) {
+	defer q.locked()()
+	t := q.tables
+
+	if _, ok := t.scheduledTransfers[arg.ScheduledTransferID]; !ok {
+		return ScheduledTransferRun{}, foreignKeyViolation("scheduled_transfer_runs", "scheduled_transfer_runs_scheduled_transfer_id_fkey")
+	}
+
+	run := ScheduledTransferRun{
+		ID:                  t.nextID("scheduled_transfer_runs"),
+		ScheduledTransferID: arg.ScheduledTransferID,
+		ScheduledFor:        memTimestamp(arg.ScheduledFor),
+		Status:              ScheduledTransferRunPending,
+		CreatedAt:           q.now(),
+	}
+	memSet(t, t.scheduledTransferRuns, run.ID, run)
+	return run, nil
+}
+
+func (q *memQueries) FinishScheduledTransferRun(ctx context.Context, arg FinishScheduledTransferRunParams) (ScheduledTransferRun, error) {
+	defer q.locked()()
+	t := q.tables
+
+	run, ok := t.scheduledTransferRuns[arg.ID]
+	if !ok {
+		return ScheduledTransferRun{}, ErrRecordNotFound
+	}
+	if !oneOf(arg.Status, ScheduledTransferRunPending, ScheduledTransferRunSucceeded, ScheduledTransferRunFailed) {
+		return ScheduledTransferRun{}, checkViolation("scheduled_transfer_runs", "scheduled_transfer_runs_status_check")
+	}
+	if _, ok := t.transfers[arg.TransferID.Int64]; arg.TransferID.Valid && !ok {
+		return ScheduledTransferRun{}, foreignKeyViolation("scheduled_transfer_runs", "scheduled_transfer_runs_transfer_id_fkey")
+	}
+
+	run.Status = arg.Status
+	run.TransferID = arg.TransferID
+	run.Error = arg.Error
+	run.FinishedAt = memTimestamptzOf(q.now())
+	memSet(t, t.scheduledTransferRuns, run.ID, run)
+	return run, nil
+}
+
+func (q *memQueries) ListScheduledTransferRuns(ctx context.Context, arg ListScheduledTransferRunsParams) ([]ScheduledTransferRun, error) {
+	defer q.locked()()
+
+	rows := memRows(q.tables.scheduledTransferRuns,
+		func(run ScheduledTransferRun) bool { return run.ScheduledTransferID == arg.ScheduledTransferID },
+		func(a, b ScheduledTransferRun) bool { return a.ID > b.ID },
+	)
+	return memPage(rows, arg.Limit, arg.Offset), nil
+}
+
+// Sessions
+
+func (q *memQueries) CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error) {
+	defer q.locked()()
+	t := q.tables
+
+	if _, ok := t.sessions[arg.ID]; ok {
+		return Session{}, uniqueViolation("sessions", "sessions_pkey")
+	}
+	if _, ok := t.users[arg.Username]; !ok {
+		return Session{}, foreignKeyViolation("sessions", "sessions_username_fkey")
+	}
+
+	session := Session{
+		ID:           arg.ID,
+		Username:     arg.Username,
+		RefreshToken: arg.RefreshToken,
+		UserAgent:    arg.UserAgent,
+		ClientIp:     arg.ClientIp,
+		IsBlocked:    arg.IsBlocked,
+		ExpiresAt:    memTimestamp(arg.ExpiresAt),
+		CreatedAt:    q.now(),
+	}
+	memSet(t, t.sessions, session.ID, session)
+	return session, nil
+}
+
+func (q *memQueries) GetSession(ctx context.Context, id uuid.UUID) (Session, error) {
+	defer q.locked()()
+
+	session, ok := q.tables.sessions[id]
+	if !ok {
+		return Session{}, ErrRecordNotFound
+	}
+	return session, nil
+}
+
+// Statements
+
+func (q *memQueries) GetAccountBalanceAt(ctx context.Context, arg GetAccountBalanceAtParams) (int64, error) {
+	defer q.locked()()
+	t := q.tables
+
+	account, ok := t.accounts[arg.AccountID]
+	if !ok {
+		return 0, ErrRecordNotFound
+	}
+	return account.Balance - t.sumEntriesSince(account.ID, arg.At), nil
+}
+
+// ListStatementEntries returns the entries of an account in a period with their counterparty and kind
+func (q *memQueries) ListStatementEntries(ctx context.Context, arg ListStatementEntriesParams) ([]ListStatementEntriesRow, error) {
+	defer q.locked()()
+	t := q.tables
+
+	entries := memRows(t.entries,
+		func(entry Entry) bool {
+			return entry.AccountID == arg.AccountID &&
+				!entry.CreatedAt.Before(arg.FromTime) && entry.CreatedAt.Before(arg.ToTime) &&
+				entry.ID > arg.AfterID
+		},
+		func(a, b Entry) bool { return a.ID < b.ID },
+	)
+	entries = memPage(entries, arg.LimitCount, 0)
+
+	revenueAccounts := make(map[int64]bool, len(t.revenueAccounts))
+	for _, revenue := range t.revenueAccounts {
+		revenueAccounts[revenue.AccountID] = true
+	}
+
+	rows := []ListStatementEntriesRow{}
+	for _, entry := range entries {
+		row := ListStatementEntriesRow{
+			ID:         entry.ID,
+			Amount:     entry.Amount,
+			CreatedAt:  entry.CreatedAt,
+			TransferID: entry.TransferID,
+			Kind:       "deposit",
+		}
+
+		if transfer, ok := t.transfers[entry.TransferID.Int64]; entry.TransferID.Valid && ok {
+			counterpartyID := transfer.FromAccountID
+			if transfer.FromAccountID == entry.AccountID {
+				counterpartyID = transfer.ToAccountID
+			}
+			if counterparty, ok := t.accounts[counterpartyID]; ok {
+				row.CounterpartyAccountID = pgtype.Int8{Int64: counterparty.ID, Valid: true}
+				row.CounterpartyOwner = pgtype.Text{String: counterparty.Owner, Valid: true}
+			}
+
+			_, reversal := t.transferReversals[transfer.ID]
+			switch {
+			case reversal:
+				row.Kind = "reversal"
+			case revenueAccounts[counterpartyID] && entry.Amount < 0:
+				row.Kind = "fee"
+			case revenueAccounts[counterpartyID]:
+				row.Kind = "interest"
+			default:
+				row.Kind = "transfer"
+			}
+		}
+
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// Transfers
+
+func (q *memQueries) CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error) {
+	defer q.locked()()
+	t := q.tables
+
+	switch {
+	case arg.Amount <= 0:
+		return Transfer{}, checkViolation("transfers", "transfers_amount_check")
+	case arg.FromAccountID == arg.ToAccountID:
+		return Transfer{}, checkViolation("transfers", "transfers_distinct_accounts_check")
+	}
+
+	if _, ok := t.accounts[arg.FromAccountID]; !ok {
+		return Transfer{}, foreignKeyViolation("transfers", "transfers_from_account_id_fkey")
+	}
+	if _, ok := t.accounts[arg.ToAccountID]; !ok {
+		return Transfer{}, foreignKeyViolation("transfers", "transfers_to_account_id_fkey")
+	}
+
+	transfer := Transfer{
+		ID:            t.nextID("transfers"),
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        arg.Amount,
+		CreatedAt:     q.now(),
+	}
+	memSet(t, t.transfers, transfer.ID, transfer)
+	return transfer, nil
+}
+
+func (q *memQueries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
+	defer q.locked()()
+
+	transfer, ok := q.tables.transfers[id]
+	if !ok {
+		return Transfer{}, ErrRecordNotFound
+	}
+	return transfer, nil
+}
+
+// GetTransferForUpdate reads a transfer like GetTransfer, the transaction holding the lock of the whole store
+func (q *memQueries) GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error) {
+	return q.GetTransfer(ctx, id)
+}
+
+func (q *memQueries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error) {
+	defer q.locked()()
+
+	rows := memRows(q.tables.transfers, memAll[Transfer], func(a, b Transfer) bool { return a.ID < b.ID })
+	return memPage(rows, arg.Limit, arg.Offset), nil
+}
+
+// Transfer reversals
+
+func (q *memQueries) CreateTransferReversal(ctx context.Context, arg CreateTransferReversalParams) (TransferReversal, error) {
+	defer q.locked()()
+	t := q.tables
+
+	if _, ok := t.transferReversals[arg.TransferID]; ok {
+		return TransferReversal{}, uniqueViolation("transfer_reversals", "transfer_reversals_pkey")
+	}
+	if _, ok := t.transfers[arg.TransferID]; !ok {
+		return TransferReversal{}, foreignKeyViolation("transfer_reversals", "transfer_reversals_transfer_id_fkey")
+	}
+	if _, ok := t.transfers[arg.OriginalTransferID]; !ok {
+		return TransferReversal{}, foreignKeyViolation("transfer_reversals", "transfer_reversals_original_transfer_id_fkey")
+	}
+
+	reversal := TransferReversal{
+		TransferID:         arg.TransferID,
+		OriginalTransferID: arg.OriginalTransferID,
+		Reason:             arg.Reason,
+		CreatedAt:          q.now(),
+	}
+	memSet(t, t.transferReversals, reversal.TransferID, reversal)
+	return reversal, nil
+}
+
+func (q *memQueries) GetTransferReversal(ctx context.Context, transferID int64) (TransferReversal, error) {
+	defer q.locked()()
+
+	reversal, ok := q.tables.transferReversals[transferID]
+	if !ok {
+		return TransferReversal{}, ErrRecordNotFound
+	}
+	return reversal, nil
+}
+
+func (q *memQueries) GetReversedAmount(ctx context.Context, originalTransferID int64) (int64, error) {
+	defer q.locked()()
+	t := q.tables
+
+	var reversed int64
+	for _, reversal := range t.transferReversals {
+		if reversal.OriginalTransferID == originalTransferID {
+			reversed += t.transfers[reversal.TransferID].Amount
+		}
+	}
+	return reversed, nil
+}
+
+// Users
+
+func (q *memQueries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
+	defer q.locked()()
+	t := q.tables
+
+	if _, ok := t.users[arg.Username]; ok {
+		return User{}, uniqueViolation("users", "users_pkey")
+	}
+	for _, other := range t.users {
+		if other.Email == arg.Email {
+			return User{}, uniqueViolation("users", "users_email_key")
+		}
+	}
+
+	user := User{
+		Username:          arg.Username,
+		HashedPassword:    arg.HashedPassword,
+		FullName:          arg.FullName,
+		Email:             arg.Email,
+		PasswordChangedAt: time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC),
+		CreatedAt:         q.now(),
+		Tier:              "standard",
+	}
+	memSet(t, t.users, user.Username, user)
+	return user, nil
+}
+
+func (q *memQueries) GetUser(ctx context.Context, username string) (User, error) {
+	defer q.locked()()
+
+	user, ok := q.tables.users[username]
+	if !ok {
+		return User{}, ErrRecordNotFound
+	}
+	return user, nil
+}
+
+func (q *memQueries) UpdateUserTier(ctx context.Context, arg UpdateUserTierParams) (User, error) {
+	defer q.locked()()
+	t := q.tables
+
+	user, ok := t.users[arg.Username]
+	if !ok {
+		return User{}, ErrRecordNotFound
+	}
+	if _, ok := t.limitTiers[arg.Tier]; !ok {
+		return User{}, foreignKeyViolation("users", "users_tier_fkey")
+	}
+
+	user.Tier = arg.Tier
+	memSet(t, t.users, user.Username, user)
+	return user, nil
+}
+
+// Webhooks
+
+func (q *memQueries) CreateWebhookSubscription(ctx context.Context, arg CreateWebhookSubscriptionParams) (WebhookSubscription, error) {
+	defer q.locked()()
+	t := q.tables
+
+	if len(arg.EventTypes) == 0 {
+		return WebhookSubscription{}, checkViolation("webhook_subscriptions", "webhook_subscriptions_event_types_check")
+	}
+	for _, eventType := range arg.EventTypes {
+		if !oneOf(eventType, EventTypes...) {
+			return WebhookSubscription{}, checkViolation("webhook_subscriptions", "webhook_subscriptions_event_types_check")
+		}
+	}
+	if _, ok := t.users[arg.Owner]; !ok {
+		return WebhookSubscription{}, foreignKeyViolation("webhook_subscriptions", "webhook_subscriptions_owner_fkey")
+	}
+
+	subscription := WebhookSubscription{
+		ID:         t.nextID("webhook_subscriptions"),
+		Owner:      arg.Owner,
+		Url:        arg.Url,
+		Secret:     arg.Secret,
+		EventTypes: append([]string(nil), arg.EventTypes...),
+		CreatedAt:  q.now(),
+	}
+	memSet(t, t.webhookSubscriptions, subscription.ID, subscription)
+	return subscription, nil
+}
+
+func (q *memQueries) GetWebhookSubscription(ctx context.Context, id int64) (WebhookSubscription, error) {
+	defer q.locked()()
+
+	subscription, ok := q.tables.webhookSubscriptions[id]
+	if !ok {
+		return WebhookSubscription{}, ErrRecordNotFound
+	}
+	return subscription, nil
+}
+
+func (q *memQueries) ListWebhookSubscriptions(ctx context.Context, arg ListWebhookSubscriptionsParams) ([]WebhookSubscription, error) {
+	defer q.locked()()
+
+	rows := memRows(q.tables.webhookSubscriptions,
+		func(subscription WebhookSubscription) bool { return subscription.Owner == arg.Owner },
+		func(a, b WebhookSubscription) bool { return a.ID < b.ID },
+	)
+	return memPage(rows, arg.Limit, arg.Offset), nil
+}
+
+// DeleteWebhookSubscription deletes a subscription and its deliveries
+func (q *memQueries) DeleteWebhookSubscription(ctx context.Context, id int64) error {
+	defer q.locked()()
+	t := q.tables
+
+	for deliveryID, delivery := range t.webhookDeliveries {
+		if delivery.SubscriptionID == id {
+			memDelete(t, t.webhookDeliveries, deliveryID)
+		}
+	}
+	memDelete(t, t.webhookSubscriptions, id)
+	return nil
+}
+
+// CreateWebhookDeliveries queues an event for every subscription of the owners to its type
+func (q *memQueries) CreateWebhookDeliveries(ctx context.Context, arg CreateWebhookDeliveriesParams) error {
+	defer q.locked()()
+	t := q.tables
+
+	subscriptions := memRows(t.webhookSubscriptions,
+		func(subscription WebhookSubscription) bool {
+			return oneOf(subscription.Owner, arg.Owners...) && oneOf(arg.EventType, subscription.EventTypes...)
+		},
+		func(a, b WebhookSubscription) bool { return a.ID < b.ID },
+	)
+
+	for _, subscription := range subscriptions {
+		for _, other := range t.webhookDeliveries {
+			if other.SubscriptionID == subscription.ID && other.EventID == arg.EventID {
+				return uniqueViolation("webhook_deliveries", "webhook_deliveries_subscription_id_event_id_idx")
+			}
+		}
+	}
+
+	now := q.now()
+	for _, subscription := range subscriptions {
+		delivery := WebhookDelivery{
+			ID:             t.nextID("webhook_deliveries"),
+			SubscriptionID: subscription.ID,
+			EventID:        arg.EventID,
+			EventType:      arg.EventType,
+			Payload:        append(json.RawMessage(nil), arg.Payload...),
+			Status:         WebhookDeliveryPending,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+		}
+		memSet(t, t.webhookDeliveries, delivery.ID, delivery)
+	}
+	return nil
+}
+
+// ClaimWebhookDeliveries leases the pending deliveries due for an attempt until leaseUntil, oldest first
+func (q *memQueries) ClaimWebhookDeliveries(ctx context.Context, arg ClaimWebhookDeliveriesParams) ([]ClaimWebhookDeliveriesRow, error) {
+	defer q.locked()()
+	t := q.tables
+
+	deliveries := memRows(t.webhookDeliveries,
+		func(delivery WebhookDelivery) bool {
+			return delivery.Status == WebhookDeliveryPending && !delivery.NextAttemptAt.After(arg.Now)
+		},
+		func(a, b WebhookDelivery) bool {
+			if !a.NextAttemptAt.Equal(b.NextAttemptAt) {
+				return a.NextAttemptAt.Before(b.NextAttemptAt)
+			}
+			return a.ID < b.ID
+		},
+	)
+	deliveries = memPage(deliveries, arg.LimitCount, 0)
+
+	rows := []ClaimWebhookDeliveriesRow{}
+	for _, delivery := range deliveries {
+		subscription, ok := t.webhookSubscriptions[delivery.SubscriptionID]
+		if !ok {
+			continue
+		}
+
+		delivery.NextAttemptAt = memTimestamp(arg.LeaseUntil)
+		memSet(t, t.webhookDeliveries, delivery.ID, delivery)
+
+		rows = append(rows, ClaimWebhookDeliveriesRow{
+			ID:             delivery.ID,
+			SubscriptionID: delivery.SubscriptionID,
+			EventID:        delivery.EventID,
+			EventType:      delivery.EventType,
+			Payload:        delivery.Payload,
+			Attempts:       delivery.Attempts,
+			Url:            subscription.Url,
+			Secret:         subscription.Secret,
+		})
+	}
+	return rows, nil
+}
+
+func (q *memQueries) RecordWebhookDeliveryAttempt(ctx context.Context, arg RecordWebhookDeliveryAttemptParams) (WebhookDelivery, error) {
+	defer q.locked()()
+	t := q.tables
+
+	delivery, ok := t.webhookDeliveries[arg.ID]
+	if !ok {
+		return WebhookDelivery{}, ErrRecordNotFound
+	}
+	if !oneOf(arg.Status, WebhookDeliveryPending, WebhookDeliveryDelivered, WebhookDeliveryFailed) {
+		return WebhookDelivery{}, checkViolation("webhook_deliveries", "webhook_deliveries_status_check")
+	}
+
+	delivery.Status = arg.Status
+	delivery.Attempts++
+	delivery.NextAttemptAt = memTimestamp(arg.NextAttemptAt)
+	delivery.LastAttemptAt = memTimestamptzOf(q.now())
+	delivery.ResponseStatus = arg.ResponseStatus
+	delivery.Error = arg.Error
+	memSet(t, t.webhookDeliveries, delivery.ID, delivery)
+	return delivery, nil
+}
+
+func (q *memQueries) ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error) {
+	defer q.locked()()
+
+	rows := memRows(q.tables.webhookDeliveries,
+		func(delivery WebhookDelivery) bool { return delivery.SubscriptionID == arg.SubscriptionID },
+		func(a, b WebhookDelivery) bool { return a.ID > b.ID },
+	)
+	return memPage(rows, arg.Limit, arg.Offset), nil
+}
@@ -0,0 +1,305 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// MemStore is a Store keeping its data in memory, for tests and demos without a database.
+// It enforces the constraints of the schema, failing with the same postgres errors as SQLStore,
+// and starts with the reference data of the migrations: currencies, limit tiers, interest rates and revenue accounts.
+// Transactions run one at a time and queries outside of a transaction wait for the running one,
+// so transactions are serializable and never deadlock. They must not use the store other than through their queries.
+// Queries scan whole tables, so it is meant for the small data sets of tests and demos.
+type MemStore struct {
+	txStore
+	*memQueries
+	lock sync.Mutex
+	// relay is held by the caller publishing events, like the advisory lock of SQLStore
+	relay sync.Mutex
+}
+
+// NewMemStore creates an in-memory store holding the reference data of the migrations
+func NewMemStore() Store {
+	store := &MemStore{}
+	store.memQueries = &memQueries{tables: newMemTables(), lock: &store.lock}
+	store.txStore = txStore{database: store}
+
+	return store
+}
+
+// Ping always succeeds as there is no database to reach
+func (store *MemStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+// execTx executes a function with queries writing to the tables directly, journaling the rows they replace.
+// The journal is undone if the function fails or panics, so a transaction only pays for the rows it writes.
+// The store is locked until the function returns. Transaction options are ignored, every transaction being serializable.
+// Like postgres sequences, the ids taken by a transaction rolled back are not given back.
+func (store *MemStore) execTx(ctx context.Context, opts *pgx.TxOptions, fn func(Querier) error) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	t := store.tables
+	t.journal = []func(){}
+	committed := false
+	defer func() {
+		if !committed {
+			for i := len(t.journal) - 1; i >= 0; i-- {
+				t.journal[i]()
+			}
+		}
+		t.journal = nil
+	}()
+
+	if err := fn(&memQueries{tables: t, txTime: memNow()}); err != nil {
+		return err
+	}
+
+	committed = true
+	return nil
+}
+
+// PublishEventsTx publishes the events of the outbox like the one of SQLStore, except that the store is not locked
+// while publishing : only the other publishers wait, and the publisher may use the store.
+func (store *MemStore) PublishEventsTx(ctx context.Context, arg PublishEventsTxParams) (PublishEventsTxResult, error) {
+	var result PublishEventsTxResult
+
+	if !store.relay.TryLock() {
+		return result, nil
+	}
+	defer store.relay.Unlock()
+
+	events, err := store.ListUnpublishedEvents(ctx, arg.Limit)
+	if err != nil {
+		return result, err
+	}
+	result.Events = len(events)
+
+	published := publishEvents(ctx, events, arg.Publish)
+	result.Published = len(published)
+
+	if len(published) == 0 {
+		return result, nil
+	}
+	return result, store.MarkEventsPublished(ctx, published)
+}
+
+// memTables holds the rows of the tables of a MemStore, by primary key
+type memTables struct {
+	sequences map[string]int64
+
+	currencies            map[string]Currency
+	limitTiers            map[string]LimitTier
+	users                 map[string]User
+	sessions              map[uuid.UUID]Session
+	accounts              map[int64]Account
+	entries               map[int64]Entry
+	transfers             map[int64]Transfer
+	transferReversals     map[int64]TransferReversal
+	holds                 map[int64]Hold
+	accountStatusChanges  map[int64]AccountStatusChange
+	accountLimits         map[int64]AccountLimit
+	feeSchedules          map[int64]FeeSchedule
+	revenueAccounts       map[string]RevenueAccount
+	interestRates         map[string]InterestRate
+	interestAccruals      map[memAccrualKey]InterestAccrual
+	interestPostings      map[memAccrualKey]InterestPosting
+	scheduledTransfers    map[int64]ScheduledTransfer
+	scheduledTransferRuns map[int64]ScheduledTransferRun
+	webhookSubscriptions  map[int64]WebhookSubscription
+	webhookDeliveries     map[int64]WebhookDelivery
+	events                map[int64]Event
+
+	// journal undoes the writes of the running transaction, nil outside of a transaction
+	journal []func()
+}
+
+// memAccrualKey is the primary key of the interest accruals and postings, a day or the first day of a month
+type memAccrualKey struct {
+	accountID int64
+	date      time.Time
+}
+
+// newMemTables returns the tables holding the reference data inserted by the migrations
+func newMemTables() *memTables {
+	t := &memTables{
+		sequences:             make(map[string]int64),
+		currencies:            make(map[string]Currency),
+		limitTiers:            make(map[string]LimitTier),
+		users:                 make(map[string]User),
+		sessions:              make(map[uuid.UUID]Session),
+		accounts:              make(map[int64]Account),
+		entries:               make(map[int64]Entry),
+		transfers:             make(map[int64]Transfer),
+		transferReversals:     make(map[int64]TransferReversal),
+		holds:                 make(map[int64]Hold),
+		accountStatusChanges:  make(map[int64]AccountStatusChange),
+		accountLimits:         make(map[int64]AccountLimit),
+		feeSchedules:          make(map[int64]FeeSchedule),
+		revenueAccounts:       make(map[string]RevenueAccount),
+		interestRates:         make(map[string]InterestRate),
+		interestAccruals:      make(map[memAccrualKey]InterestAccrual),
+		interestPostings:      make(map[memAccrualKey]InterestPosting),
+		scheduledTransfers:    make(map[int64]ScheduledTransfer),
+		scheduledTransferRuns: make(map[int64]ScheduledTransferRun),
+		webhookSubscriptions:  make(map[int64]WebhookSubscription),
+		webhookDeliveries:     make(map[int64]WebhookDelivery),
+		events:                make(map[int64]Event),
+	}
+
+	now := memNow()
+	limit := func(amount int64) pgtype.Int8 { return pgtype.Int8{Int64: amount, Valid: true} }
+
+	for _, currency := range []Currency{
+		{Code: "USD", Name: "US Dollar", MinorUnit: 2},
+		{Code: "EUR", Name: "Euro", MinorUnit: 2},
+		{Code: "GBP", Name: "Pound Sterling", MinorUnit: 2},
+		{Code: "INR", Name: "Indian Rupee", MinorUnit: 2},
+	} {
+		t.currencies[currency.Code] = currency
+	}
+
+	for _, tier := range []LimitTier{
+		{Name: "standard", MaxTransferAmount: limit(10000), DailyOutgoingLimit: limit(50000), MonthlyOutgoingLimit: limit(500000)},
+		{Name: "premium", MaxTransferAmount: limit(100000), DailyOutgoingLimit: limit(500000), MonthlyOutgoingLimit: limit(5000000)},
+		{Name: "system"},
+	} {
+		t.limitTiers[tier.Name] = tier
+	}
+
+	t.users["bank"] = User{
+		Username:          "bank",
+		HashedPassword:    "!",
+		FullName:          "Bank revenue",
+		Email:             "revenue@bank.invalid",
+		PasswordChangedAt: time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC),
+		CreatedAt:         now,
+		Tier:              "system",
+	}
+
+	for _, code := range []string{"USD", "EUR", "GBP", "INR"} {
+		id := t.nextID("accounts")
+		t.accounts[id] = Account{
+			ID:               id,
+			Owner:            "bank",
+			Currency:         code,
+			CreatedAt:        now,
+			OverdraftEnabled: true,
+			Status:           AccountActive,
+			Type:             AccountChecking,
+		}
+		t.revenueAccounts[code] = RevenueAccount{Currency: code, AccountID: id}
+	}
+
+	for _, rate := range []InterestRate{
+		{Currency: "USD", AnnualRateBps: 200, DayCount: DayCountActual360},
+		{Currency: "EUR", AnnualRateBps: 150, DayCount: DayCountActual360},
+		{Currency: "GBP", AnnualRateBps: 175, DayCount: DayCountActual365},
+		{Currency: "INR", AnnualRateBps: 400, DayCount: DayCountActual365},
+	} {
+		rate.UpdatedAt = now
+		t.interestRates[rate.Currency] = rate
+	}
+
+	return t
+}
+
+// nextID returns the next value of the id sequence of a table
+func (t *memTables) nextID(table string) int64 {
+	t.sequences[table]++
+	return t.sequences[table]
+}
+
+// memSet writes a row of a table, journaling the row it replaces within a transaction
+func memSet[K comparable, V any](t *memTables, table map[K]V, key K, row V) {
+	memJournal(t, table, key)
+	table[key] = row
+}
+
+// memDelete deletes a row of a table, journaling it within a transaction
+func memDelete[K comparable, V any](t *memTables, table map[K]V, key K) {
+	memJournal(t, table, key)
+	delete(table, key)
+}
+
+// memJournal records how to restore the row of a key before it is written
+func memJournal[K comparable, V any](t *memTables, table map[K]V, key K) {
+	if t.journal == nil {
+		return
+	}
+
+	previous, ok := table[key]
+	t.journal = append(t.journal, func() {
+		if ok {
+			table[key] = previous
+		} else {
+			delete(table, key)
+		}
+	})
+}
+
+// memNow returns the current time at the precision of a postgres timestamp
+func memNow() time.Time {
+	return time.Now().Truncate(time.Microsecond)
+}
+
+// memDate returns the day of a time, as postgres stores a date
+func memDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Errors of the constraints of the schema, as raised by postgres
+
+func checkViolation(table, constraint string) error {
+	return &pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           CheckViolation,
+		Message:        fmt.Sprintf("new row for relation %q violates check constraint %q", table, constraint),
+		TableName:      table,
+		ConstraintName: constraint,
+	}
+}
+
+func uniqueViolation(table, constraint string) error {
+	return &pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           UniqueViolation,
+		Message:        fmt.Sprintf("duplicate key value violates unique constraint %q", constraint),
+		TableName:      table,
+		ConstraintName: constraint,
+	}
+}
+
+func foreignKeyViolation(table, constraint string) error {
+	return &pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           ForeignKeyViolation,
+		Message:        fmt.Sprintf("insert or update on table %q violates foreign key constraint %q", table, constraint),
+		TableName:      table,
+		ConstraintName: constraint,
+	}
+}
+
+// referencedViolation is the error of deleting a row still referenced by another table
+func referencedViolation(table, constraint, referencingTable string) error {
+	return &pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           ForeignKeyViolation,
+		Message:        fmt.Sprintf("update or delete on table %q violates foreign key constraint %q on table %q", table, constraint, referencingTable),
+		TableName:      referencingTable,
+		ConstraintName: constraint,
+	}
+}
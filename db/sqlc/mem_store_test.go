@@ -0,0 +1,565 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/samirprakash/go-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createMemAccount(t *testing.T, store Store, currency string) Account {
+	user, err := store.CreateUser(context.Background(), CreateUserParams{
+		Username:       util.RandomOwnerName(),
+		HashedPassword: util.RandomString(32),
+		FullName:       util.RandomOwnerName(),
+		Email:          util.RandomEmail(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "standard", user.Tier)
+	require.True(t, user.PasswordChangedAt.IsZero())
+
+	account, err := store.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Balance:  util.RandomInt(100, 1000),
+		Currency: currency,
+		Type:     AccountChecking,
+	})
+	require.NoError(t, err)
+	require.NotZero(t, account.ID)
+	require.NotZero(t, account.CreatedAt)
+	require.Equal(t, AccountActive, account.Status)
+	require.Equal(t, account.Balance, account.AvailableBalance)
+
+	return account
+}
+
+func requirePgError(t *testing.T, err error, code string, constraint string) {
+	var pgErr *pgconn.PgError
+	require.ErrorAs(t, err, &pgErr)
+	require.Equal(t, code, pgErr.Code)
+	require.Equal(t, constraint, pgErr.ConstraintName)
+}
+
+func TestMemStoreTransferTx(t *testing.T) {
+	store := NewMemStore()
+
+	account1 := createMemAccount(t, store, util.USD)
+	account2 := createMemAccount(t, store, util.USD)
+
+	n := 10
+	amount := int64(10)
+	errs := make(chan error)
+
+	for i := 0; i < n; i++ {
+		from, to := account1, account2
+		if i%2 == 1 {
+			from, to = account2, account1
+		}
+
+		go func() {
+			_, err := store.TransferTx(context.Background(), TransferTxParams{
+				FromAccountID: from.ID,
+				ToAccountID:   to.ID,
+				Amount:        amount,
+			})
+
+			errs <- err
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		require.NoError(t, <-errs)
+	}
+
+	// as many transfers went each way
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+
+	updatedAccount2, err := store.GetAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+	require.Equal(t, account2.Balance, updatedAccount2.Balance)
+
+	entries, err := store.ListEntries(context.Background(), ListEntriesParams{Limit: 100})
+	require.NoError(t, err)
+	require.Len(t, entries, 2*n)
+
+	events, err := store.ListEvents(context.Background(), ListEventsParams{
+		PartitionKey: AccountPartition(account1.ID),
+		Limit:        100,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, n)
+}
+
+func TestMemStoreTransferTxConstraints(t *testing.T) {
+	store := NewMemStore()
+
+	account1 := createMemAccount(t, store, util.USD)
+	account2 := createMemAccount(t, store, util.USD)
+
+	testCases := []struct {
+		name       string
+		arg        TransferTxParams
+		constraint string
+	}{
+		{
+			name: "InsufficientFunds",
+			arg: TransferTxParams{
+				FromAccountID: account1.ID,
+				ToAccountID:   account2.ID,
+				Amount:        account1.Balance + 1,
+			},
+			constraint: "accounts_balance_check",
+		},
+		{
+			name: "SameAccount",
+			arg: TransferTxParams{
+				FromAccountID: account1.ID,
+				ToAccountID:   account1.ID,
+				Amount:        10,
+			},
+			constraint: "transfers_distinct_accounts_check",
+		},
+		{
+			name: "NegativeAmount",
+			arg: TransferTxParams{
+				FromAccountID: account1.ID,
+				ToAccountID:   account2.ID,
+				Amount:        -10,
+			},
+			constraint: "transfers_amount_check",
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := store.TransferTx(context.Background(), tc.arg)
+			requirePgError(t, err, CheckViolation, tc.constraint)
+		})
+	}
+
+	// the business rule of the constraint is matched without postgres details
+	_, err := store.TransferTx(context.Background(), testCases[0].arg)
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+	for _, tc := range testCases[1:] {
+		_, err := store.TransferTx(context.Background(), tc.arg)
+		require.NotErrorIs(t, err, ErrInsufficientFunds)
+	}
+
+	// the failed transactions were rolled back
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+
+	transfers, err := store.ListTransfers(context.Background(), ListTransfersParams{Limit: 10})
+	require.NoError(t, err)
+	require.Empty(t, transfers)
+}
+
+func TestMemStoreHoldTxLimits(t *testing.T) {
+	store := NewMemStore()
+
+	account1 := createMemAccount(t, store, util.USD)
+	account2 := createMemAccount(t, store, util.USD)
+
+	_, err := store.UpsertAccountLimits(context.Background(), UpsertAccountLimitsParams{
+		AccountID:          account1.ID,
+		MaxTransferAmount:  pgtype.Int8{Int64: 50, Valid: true},
+		DailyOutgoingLimit: pgtype.Int8{Int64: 80, Valid: true},
+		UpdatedBy:          account1.Owner,
+	})
+	require.NoError(t, err)
+
+	hold := func(amount int64) error {
+		_, err := store.HoldTx(context.Background(), HoldTxParams{
+			AccountID:   account1.ID,
+			ToAccountID: account2.ID,
+			Amount:      amount,
+			ExpiresAt:   time.Now().Add(time.Hour),
+		})
+		return err
+	}
+
+	require.ErrorIs(t, hold(51), ErrTransferLimitExceeded)
+	require.NoError(t, hold(50))
+	require.ErrorIs(t, hold(31), ErrDailyLimitExceeded)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        31,
+	})
+	require.ErrorIs(t, err, ErrDailyLimitExceeded)
+
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(50), updatedAccount1.HeldAmount)
+}
+
+func TestMemStoreCaptureHoldTxFee(t *testing.T) {
+	store := NewMemStore()
+
+	account1 := createMemAccount(t, store, util.USD)
+	account2 := createMemAccount(t, store, util.USD)
+
+	_, err := store.CreateFeeSchedule(context.Background(), CreateFeeScheduleParams{
+		Currency: pgtype.Text{String: util.USD, Valid: true},
+		FlatFee:  3,
+	})
+	require.NoError(t, err)
+
+	revenueAccountID, err := store.GetRevenueAccountID(context.Background(), util.USD)
+	require.NoError(t, err)
+	revenueAccount, err := store.GetAccount(context.Background(), revenueAccountID)
+	require.NoError(t, err)
+
+	hold, err := store.HoldTx(context.Background(), HoldTxParams{
+		AccountID:   account1.ID,
+		ToAccountID: account2.ID,
+		Amount:      50,
+		ExpiresAt:   time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	result, err := store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{HoldID: hold.Hold.ID})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), result.Fee.Amount)
+	require.NotNil(t, result.FeeTransfer)
+	require.Equal(t, account1.Balance-53, result.FromAccount.Balance)
+	require.Equal(t, account2.Balance+50, result.ToAccount.Balance)
+
+	updatedRevenueAccount, err := store.GetAccount(context.Background(), revenueAccountID)
+	require.NoError(t, err)
+	require.Equal(t, revenueAccount.Balance+3, updatedRevenueAccount.Balance)
+}
+
+func TestMemStoreCaptureHoldTxFullBalanceFee(t *testing.T) {
+	store := NewMemStore()
+
+	account1 := createMemAccount(t, store, util.USD)
+	account2 := createMemAccount(t, store, util.USD)
+
+	schedule, err := store.CreateFeeSchedule(context.Background(), CreateFeeScheduleParams{
+		Currency: pgtype.Text{String: util.USD, Valid: true},
+		FlatFee:  4,
+	})
+	require.NoError(t, err)
+
+	hold := func(amount int64) (HoldTxResult, error) {
+		return store.HoldTx(context.Background(), HoldTxParams{
+			AccountID:   account1.ID,
+			ToAccountID: account2.ID,
+			Amount:      amount,
+			ExpiresAt:   time.Now().Add(time.Hour),
+		})
+	}
+
+	// the fee is reserved with the amount
+	_, err = hold(account1.Balance - 3)
+	requirePgError(t, err, CheckViolation, "accounts_balance_check")
+
+	full, err := hold(account1.Balance - 4)
+	require.NoError(t, err)
+	require.Equal(t, int64(4), full.Hold.Fee)
+	require.Equal(t, account1.Balance, full.Account.HeldAmount)
+	require.Zero(t, full.Account.AvailableBalance)
+
+	// a raise of the fee after the hold is not charged on capture
+	err = store.DeleteFeeSchedule(context.Background(), schedule.ID)
+	require.NoError(t, err)
+	_, err = store.CreateFeeSchedule(context.Background(), CreateFeeScheduleParams{
+		Currency: pgtype.Text{String: util.USD, Valid: true},
+		FlatFee:  10,
+	})
+	require.NoError(t, err)
+
+	result, err := store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{HoldID: full.Hold.ID})
+	require.NoError(t, err)
+	require.Equal(t, int64(4), result.Fee.Amount)
+	require.NotNil(t, result.FeeTransfer)
+	require.Zero(t, result.FromAccount.Balance)
+	require.Zero(t, result.FromAccount.HeldAmount)
+}
+
+func TestMemStoreCaptureHoldTxPartialFee(t *testing.T) {
+	store := NewMemStore()
+
+	account1 := createMemAccount(t, store, util.USD)
+	account2 := createMemAccount(t, store, util.USD)
+
+	_, err := store.CreateFeeSchedule(context.Background(), CreateFeeScheduleParams{
+		Currency: pgtype.Text{String: util.USD, Valid: true},
+		FlatFee:  7,
+	})
+	require.NoError(t, err)
+
+	hold, err := store.HoldTx(context.Background(), HoldTxParams{
+		AccountID:   account1.ID,
+		ToAccountID: account2.ID,
+		Amount:      50,
+		ExpiresAt:   time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(57), hold.Account.HeldAmount)
+
+	// the payer is charged the share of the quoted fee for the amount captured, the rest is released
+	result, err := store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{HoldID: hold.Hold.ID, Amount: 25})
+	require.NoError(t, err)
+	require.Equal(t, int64(4), result.Fee.Amount)
+	require.Equal(t, account1.Balance-29, result.FromAccount.Balance)
+	require.Zero(t, result.FromAccount.HeldAmount)
+}
+
+func TestMemStoreBatchTransferTxFee(t *testing.T) {
+	store := NewMemStore()
+
+	payer := createMemAccount(t, store, util.USD)
+	payee := createMemAccount(t, store, util.USD)
+
+	_, err := store.CreateFeeSchedule(context.Background(), CreateFeeScheduleParams{
+		Currency: pgtype.Text{String: util.USD, Valid: true},
+		FlatFee:  2,
+	})
+	require.NoError(t, err)
+
+	batch := func(amount int64) (BatchTransferTxResult, error) {
+		return store.BatchTransferTx(context.Background(), BatchTransferTxParams{
+			Currency: util.USD,
+			Legs: []BatchTransferLeg{
+				{FromAccountID: payer.ID, ToAccountID: payee.ID, Amount: 1},
+				{FromAccountID: payer.ID, ToAccountID: payee.ID, Amount: amount},
+			},
+		})
+	}
+
+	// the fee of every leg counts in the balance checked before any leg is applied
+	_, err = batch(payer.Balance - 4)
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	var legErr *BatchTransferError
+	require.ErrorAs(t, err, &legErr)
+	require.Equal(t, 1, legErr.Leg)
+
+	result, err := batch(payer.Balance - 5)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), result.Legs[0].Fee.Amount)
+	require.Equal(t, int64(2), result.Legs[1].Fee.Amount)
+	require.Zero(t, result.Legs[1].FromAccount.Balance)
+}
+
+func TestMemStoreReverseTransferTx(t *testing.T) {
+	store := NewMemStore()
+
+	customer := createMemAccount(t, store, util.USD)
+	merchant := createMemAccount(t, store, util.USD)
+
+	_, err := store.CreateFeeSchedule(context.Background(), CreateFeeScheduleParams{
+		Currency: pgtype.Text{String: util.USD, Valid: true},
+		FlatFee:  2,
+	})
+	require.NoError(t, err)
+
+	transfer, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: customer.ID,
+		ToAccountID:   merchant.ID,
+		Amount:        50,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), transfer.Fee.Amount)
+
+	// the merchant has exhausted its daily limit but can still refund
+	_, err = store.UpsertAccountLimits(context.Background(), UpsertAccountLimitsParams{
+		AccountID:          merchant.ID,
+		MaxTransferAmount:  pgtype.Int8{Int64: 10, Valid: true},
+		DailyOutgoingLimit: pgtype.Int8{Int64: 1, Valid: true},
+		UpdatedBy:          merchant.Owner,
+	})
+	require.NoError(t, err)
+
+	result, err := store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: transfer.Transfer.ID,
+		Reason:     "refund",
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(50), result.Transfer.Amount)
+	require.Equal(t, merchant.Balance, result.FromAccount.Balance)
+
+	// the fee of the original transfer is kept, a reversal is not charged one either
+	require.Zero(t, result.Fee.Amount)
+	require.Nil(t, result.FeeTransfer)
+	require.Equal(t, customer.Balance-2, result.ToAccount.Balance)
+}
+
+func TestMemStoreConstraints(t *testing.T) {
+	store := NewMemStore()
+	account := createMemAccount(t, store, util.USD)
+
+	// one open account of each type per currency
+	_, err := store.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    account.Owner,
+		Currency: account.Currency,
+		Type:     AccountSavings,
+	})
+	require.NoError(t, err)
+
+	_, err = store.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    account.Owner,
+		Currency: account.Currency,
+		Type:     AccountChecking,
+	})
+	requirePgError(t, err, UniqueViolation, "owner_currency_type_key")
+
+	_, err = store.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    util.RandomOwnerName(),
+		Currency: util.EUR,
+		Type:     AccountChecking,
+	})
+	requirePgError(t, err, ForeignKeyViolation, "accounts_owner_fkey")
+
+	_, err = store.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    account.Owner,
+		Currency: util.EUR,
+		Type:     "current",
+	})
+	requirePgError(t, err, CheckViolation, "accounts_type_check")
+
+	_, err = store.CreateTransfer(context.Background(), CreateTransferParams{
+		FromAccountID: account.ID,
+		ToAccountID:   account.ID + 100,
+		Amount:        10,
+	})
+	requirePgError(t, err, ForeignKeyViolation, "transfers_to_account_id_fkey")
+
+	_, err = store.GetAccount(context.Background(), account.ID+100)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	// the reference data of the migrations is there
+	revenueAccountID, err := store.GetRevenueAccountID(context.Background(), util.USD)
+	require.NoError(t, err)
+
+	err = store.DeleteAccount(context.Background(), revenueAccountID)
+	requirePgError(t, err, ForeignKeyViolation, "revenue_accounts_account_id_fkey")
+}
+
+func TestMemStoreReopenClosedCurrency(t *testing.T) {
+	store := NewMemStore()
+	account := createMemAccount(t, store, util.USD)
+
+	_, err := store.UpdateAccount(context.Background(), UpdateAccountParams{ID: account.ID, Balance: 0})
+	require.NoError(t, err)
+	_, err = store.UpdateAccountStatus(context.Background(), UpdateAccountStatusParams{ID: account.ID, Status: AccountClosed})
+	require.NoError(t, err)
+
+	// the closed account leaves the currency to a new account
+	newAccount, err := store.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    account.Owner,
+		Currency: account.Currency,
+		Type:     AccountChecking,
+	})
+	require.NoError(t, err)
+	require.NotEqual(t, account.ID, newAccount.ID)
+
+	_, err = store.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    account.Owner,
+		Currency: account.Currency,
+		Type:     AccountChecking,
+	})
+	requirePgError(t, err, UniqueViolation, "owner_currency_type_key")
+}
+
+func TestMemStoreRollback(t *testing.T) {
+	store := NewMemStore().(*MemStore)
+	account := createMemAccount(t, store, util.USD)
+	errRollback := errors.New("rollback")
+
+	err := store.execTx(context.Background(), nil, func(q Querier) error {
+		_, err := q.UpdateAccountBalance(context.Background(), UpdateAccountBalanceParams{
+			ID:     account.ID,
+			Amount: 10,
+		})
+		require.NoError(t, err)
+
+		// the transaction sees its own writes
+		updatedAccount, err := q.GetAccount(context.Background(), account.ID)
+		require.NoError(t, err)
+		require.Equal(t, account.Balance+10, updatedAccount.Balance)
+
+		return errRollback
+	})
+	require.ErrorIs(t, err, errRollback)
+
+	updatedAccount, err := store.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance, updatedAccount.Balance)
+
+	// rows inserted and deleted are restored as well, even when the transaction panics
+	var created Account
+	require.Panics(t, func() {
+		store.execTx(context.Background(), nil, func(q Querier) error {
+			var err error
+			created, err = q.CreateAccount(context.Background(), CreateAccountParams{
+				Owner:    account.Owner,
+				Currency: util.EUR,
+				Type:     AccountChecking,
+			})
+			require.NoError(t, err)
+
+			require.NoError(t, q.DeleteAccount(context.Background(), account.ID))
+			panic("rollback")
+		})
+	})
+
+	_, err = store.GetAccount(context.Background(), created.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+	updatedAccount, err = store.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account, updatedAccount)
+}
+
+func TestMemStorePublishEventsTx(t *testing.T) {
+	store := NewMemStore()
+	account := createMemAccount(t, store, util.USD)
+
+	_, err := store.DepositTx(context.Background(), DepositTxParams{AccountID: account.ID, Amount: 10})
+	require.NoError(t, err)
+
+	// the store is not locked while publishing, so the publisher can use it
+	result, err := store.PublishEventsTx(context.Background(), PublishEventsTxParams{
+		Limit: 100,
+		Publish: func(ctx context.Context, event Event) error {
+			_, err := store.GetAccount(ctx, account.ID)
+			return err
+		},
+	})
+	require.NoError(t, err)
+	require.NotZero(t, result.Events)
+	require.Equal(t, result.Events, result.Published)
+
+	events, err := store.ListUnpublishedEvents(context.Background(), 100)
+	require.NoError(t, err)
+	require.Empty(t, events)
+}
+
+func TestMemStoreExecTxCanceled(t *testing.T) {
+	store := NewMemStore()
+	account := createMemAccount(t, store, util.USD)
+	other := createMemAccount(t, store, util.USD)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := store.TransferTx(ctx, TransferTxParams{
+		FromAccountID: account.ID,
+		ToAccountID:   other.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -1,21 +1,61 @@
 // Code generated by sqlc. DO NOT EDIT.
 // versions:
-//   sqlc v1.13.0
+//   sqlc v1.18.0
 
 package db
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Account struct {
-	ID        int64     `json:"id"`
-	Owner     string    `json:"owner"`
+	ID    int64  `json:"id"`
+	Owner string `json:"owner"`
+	// ledger balance
 	Balance   int64     `json:"balance"`
 	Currency  string    `json:"currency"`
 	CreatedAt time.Time `json:"created_at"`
+	// allows the balance to go below zero
+	OverdraftEnabled bool `json:"overdraft_enabled"`
+	// sum of the active holds
+	HeldAmount int64 `json:"held_amount"`
+	// ledger balance minus the active holds
+	AvailableBalance int64 `json:"available_balance"`
+	// active, frozen or closed
+	Status   string             `json:"status"`
+	ClosedAt pgtype.Timestamptz `json:"closed_at"`
+	Type     string             `json:"type"`
+}
+
+// overrides of the tier limits, a null limit falls back to the tier
+type AccountLimit struct {
+	AccountID            int64       `json:"account_id"`
+	MaxTransferAmount    pgtype.Int8 `json:"max_transfer_amount"`
+	DailyOutgoingLimit   pgtype.Int8 `json:"daily_outgoing_limit"`
+	MonthlyOutgoingLimit pgtype.Int8 `json:"monthly_outgoing_limit"`
+	UpdatedBy            string      `json:"updated_by"`
+	UpdatedAt            time.Time   `json:"updated_at"`
+}
+
+type AccountStatusChange struct {
+	ID         int64  `json:"id"`
+	AccountID  int64  `json:"account_id"`
+	FromStatus string `json:"from_status"`
+	ToStatus   string `json:"to_status"`
+	// user who changed the status
+	ChangedBy string    `json:"changed_by"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Currency struct {
+	Code      string `json:"code"`
+	Name      string `json:"name"`
+	MinorUnit int16  `json:"minor_unit"`
 }
 
 type Entry struct {
@@ -24,6 +64,131 @@ type Entry struct {
 	// can be negative or positive
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// transfer the entry records, null for deposits
+	TransferID pgtype.Int8 `json:"transfer_id"`
+}
+
+type Event struct {
+	// order of the events, increasing with commits within a partition
+	ID int64 `json:"id"`
+	// same for every partition of an event, for consumers to deduplicate
+	EventID   uuid.UUID `json:"event_id"`
+	EventType string    `json:"event_type"`
+	// account:<id> or user:<username>, events of a partition are published in order
+	PartitionKey string          `json:"partition_key"`
+	Payload      json.RawMessage `json:"payload"`
+	CreatedAt    time.Time       `json:"created_at"`
+	// null until the event is acknowledged by the publisher
+	PublishedAt pgtype.Timestamptz `json:"published_at"`
+}
+
+type FeeSchedule struct {
+	ID int64 `json:"id"`
+	// currency the schedule applies to, null for any
+	Currency pgtype.Text `json:"currency"`
+	// limit tier of the sender the schedule applies to, null for any
+	Tier pgtype.Text `json:"tier"`
+	// smallest transfer amount of the band
+	MinAmount int64 `json:"min_amount"`
+	FlatFee   int64 `json:"flat_fee"`
+	// percentage of the amount in basis points
+	PercentageBps int32 `json:"percentage_bps"`
+	MinFee        int64 `json:"min_fee"`
+	// cap of the fee, null for no cap
+	MaxFee    pgtype.Int8 `json:"max_fee"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+type Hold struct {
+	ID int64 `json:"id"`
+	// account the funds are reserved on
+	AccountID int64 `json:"account_id"`
+	// account credited on capture
+	ToAccountID int64 `json:"to_account_id"`
+	Amount      int64 `json:"amount"`
+	// active, captured, voided or expired
+	Status         string             `json:"status"`
+	CapturedAmount int64              `json:"captured_amount"`
+	TransferID     pgtype.Int8        `json:"transfer_id"`
+	ExpiresAt      time.Time          `json:"expires_at"`
+	CreatedAt      time.Time          `json:"created_at"`
+	FinishedAt     pgtype.Timestamptz `json:"finished_at"`
+	// fee quoted for the whole amount, reserved with it
+	Fee int64 `json:"fee"`
+}
+
+type InterestAccrual struct {
+	AccountID   int64     `json:"account_id"`
+	AccrualDate time.Time `json:"accrual_date"`
+	// balance at the end of the day
+	Balance       int64  `json:"balance"`
+	AnnualRateBps int32  `json:"annual_rate_bps"`
+	DayCount      string `json:"day_count"`
+	// interest accrued in 1/262800000 of the minor unit
+	Units     int64     `json:"units"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type InterestPosting struct {
+	AccountID int64 `json:"account_id"`
+	// first day of the month the interest was accrued in
+	Month time.Time `json:"month"`
+	// interest accrued in the month plus the remainder of the previous posting
+	Units  int64 `json:"units"`
+	Amount int64 `json:"amount"`
+	// fraction of the minor unit carried to the next posting
+	RemainderUnits int64       `json:"remainder_units"`
+	TransferID     pgtype.Int8 `json:"transfer_id"`
+	CreatedAt      time.Time   `json:"created_at"`
+}
+
+type InterestRate struct {
+	Currency string `json:"currency"`
+	// annual rate paid on savings accounts in basis points
+	AnnualRateBps int32     `json:"annual_rate_bps"`
+	DayCount      string    `json:"day_count"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type LimitTier struct {
+	Name string `json:"name"`
+	// largest single transfer or deposit, null for no limit
+	MaxTransferAmount    pgtype.Int8 `json:"max_transfer_amount"`
+	DailyOutgoingLimit   pgtype.Int8 `json:"daily_outgoing_limit"`
+	MonthlyOutgoingLimit pgtype.Int8 `json:"monthly_outgoing_limit"`
+}
+
+type RevenueAccount struct {
+	Currency  string `json:"currency"`
+	AccountID int64  `json:"account_id"`
+}
+
+type ScheduledTransfer struct {
+	ID            int64  `json:"id"`
+	Owner         string `json:"owner"`
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	Amount        int64  `json:"amount"`
+	Currency      string `json:"currency"`
+	// cron expression or @every interval
+	Schedule string             `json:"schedule"`
+	StartAt  time.Time          `json:"start_at"`
+	EndAt    pgtype.Timestamptz `json:"end_at"`
+	// null once the schedule has ended
+	NextRunAt pgtype.Timestamptz `json:"next_run_at"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+type ScheduledTransferRun struct {
+	ID                  int64     `json:"id"`
+	ScheduledTransferID int64     `json:"scheduled_transfer_id"`
+	ScheduledFor        time.Time `json:"scheduled_for"`
+	// pending, succeeded or failed
+	Status     string             `json:"status"`
+	TransferID pgtype.Int8        `json:"transfer_id"`
+	Error      string             `json:"error"`
+	CreatedAt  time.Time          `json:"created_at"`
+	FinishedAt pgtype.Timestamptz `json:"finished_at"`
 }
 
 type Session struct {
@@ -46,6 +211,15 @@ type Transfer struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+type TransferReversal struct {
+	// compensating transfer
+	TransferID int64 `json:"transfer_id"`
+	// transfer being reversed
+	OriginalTransferID int64     `json:"original_transfer_id"`
+	Reason             string    `json:"reason"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
 type User struct {
 	Username          string    `json:"username"`
 	HashedPassword    string    `json:"hashed_password"`
@@ -53,4 +227,34 @@ type User struct {
 	Email             string    `json:"email"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
+	// limit tier applying to the accounts of the user
+	Tier string `json:"tier"`
+}
+
+type WebhookDelivery struct {
+	ID             int64     `json:"id"`
+	SubscriptionID int64     `json:"subscription_id"`
+	EventID        uuid.UUID `json:"event_id"`
+	EventType      string    `json:"event_type"`
+	// event posted to the subscription, signed as is
+	Payload json.RawMessage `json:"payload"`
+	// pending, delivered or failed once out of attempts
+	Status        string             `json:"status"`
+	Attempts      int32              `json:"attempts"`
+	NextAttemptAt time.Time          `json:"next_attempt_at"`
+	LastAttemptAt pgtype.Timestamptz `json:"last_attempt_at"`
+	// HTTP status of the last attempt, null if no response
+	ResponseStatus pgtype.Int4 `json:"response_status"`
+	Error          string      `json:"error"`
+	CreatedAt      time.Time   `json:"created_at"`
+}
+
+type WebhookSubscription struct {
+	ID    int64  `json:"id"`
+	Owner string `json:"owner"`
+	Url   string `json:"url"`
+	// key of the HMAC-SHA256 signature of the deliveries
+	Secret     string    `json:"secret"`
+	EventTypes []string  `json:"event_types"`
+	CreatedAt  time.Time `json:"created_at"`
 }
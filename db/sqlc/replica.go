@@ -0,0 +1,399 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+// replayedQuery tells if a database has replayed the write-ahead log up to a position of the primary.
+// A database that is not in recovery is the primary itself, or a former replica that was promoted.
+const replayedQuery = `
+SELECT COALESCE(
+  CASE WHEN pg_is_in_recovery() THEN pg_last_wal_replay_lsn() ELSE pg_current_wal_lsn() END >= $1::pg_lsn,
+  false
+)`
+
+// Replicas routes reads to read-only replicas of the primary database.
+// Every interval, each replica is checked to have replayed the write-ahead log written on the primary before the check.
+// A replica serves reads while its last successful check is less than maxLag old,
+// so maxLag must be longer than the interval. Reads fall back on the primary when no replica is fresh enough.
+type Replicas struct {
+	primary  *pgxpool.Pool
+	replicas []*replica
+	interval time.Duration
+	maxLag   time.Duration
+	next     atomic.Uint64
+}
+
+// replica is a read-only copy of the primary database
+type replica struct {
+	pool    *pgxpool.Pool
+	queries *Queries
+	// replayedAt is the unix time in nanoseconds before which every write committed on the primary
+	// has been replayed by the replica, zero until it is checked or while it cannot be reached
+	replayedAt atomic.Int64
+}
+
+// NewReplicas creates the replicas of a primary, checked every interval and serving reads while they are less than maxLag behind.
+// The replicas serve no read until they are checked.
+func NewReplicas(primary *pgxpool.Pool, pools []*pgxpool.Pool, interval time.Duration, maxLag time.Duration) *Replicas {
+	replicas := &Replicas{
+		primary:  primary,
+		interval: interval,
+		maxLag:   maxLag,
+	}
+
+	for _, pool := range pools {
+		replicas.replicas = append(replicas.replicas, &replica{pool: pool, queries: New(pool)})
+	}
+
+	return replicas
+}
+
+// Run checks the replicas every interval until the context is done
+func (replicas *Replicas) Run(ctx context.Context) error {
+	ticker := time.NewTicker(replicas.interval)
+	defer ticker.Stop()
+
+	for {
+		err := replicas.Check(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Ctx(ctx).Warn().Err(err).Msg("cannot check replicas, reads fall back on the primary")
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Ctx(ctx).Info().Msg("replica monitor is stopped")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check records which replicas have replayed the writes committed on the primary so far.
+// A replica that cannot be reached serves no read until it is checked successfully.
+func (replicas *Replicas) Check(ctx context.Context) error {
+	checkedAt := time.Now()
+
+	var lsn string
+	err := replicas.primary.QueryRow(ctx, "SELECT pg_current_wal_lsn()::text").Scan(&lsn)
+	if err != nil {
+		return fmt.Errorf("cannot read the WAL position of the primary : %w", err)
+	}
+
+	var errs []error
+	for i, r := range replicas.replicas {
+		var replayed bool
+		err := r.pool.QueryRow(ctx, replayedQuery, lsn).Scan(&replayed)
+		switch {
+		case err != nil:
+			r.replayedAt.Store(0)
+			errs = append(errs, fmt.Errorf("cannot check replica %d : %w", i, err))
+		case replayed:
+			r.replayedAt.Store(checkedAt.UnixNano())
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// reader returns a replica fresh enough to serve the reads of ctx, nil if there is none.
+// The fresh replicas take turns.
+func (replicas *Replicas) reader(ctx context.Context) *replica {
+	if len(replicas.replicas) == 0 {
+		return nil
+	}
+
+	minReplayedAt := time.Now().Add(-replicas.maxLag)
+	if readAfter, ok := ctx.Value(readAfterKey{}).(time.Time); ok && readAfter.After(minReplayedAt) {
+		minReplayedAt = readAfter
+	}
+
+	n := uint64(len(replicas.replicas))
+	start := replicas.next.Add(1)
+	for i := uint64(0); i < n; i++ {
+		r := replicas.replicas[(start+i)%n]
+		if replayedAt := r.replayedAt.Load(); replayedAt > 0 && replayedAt >= minReplayedAt.UnixNano() {
+			return r
+		}
+	}
+
+	return nil
+}
+
+type readAfterKey struct{}
+
+// ReadAfter returns a context whose reads are only served by the replicas that have replayed
+// the writes committed on the primary before t, and by the primary otherwise.
+// Reading after the time of a write gives read-your-writes consistency.
+func ReadAfter(ctx context.Context, t time.Time) context.Context {
+	if readAfter, ok := ctx.Value(readAfterKey{}).(time.Time); ok && readAfter.After(t) {
+		return ctx
+	}
+	return context.WithValue(ctx, readAfterKey{}, t)
+}
+
+// WithReplicas routes the reads that do not need the primary to the fresh replicas.
+// Transactions, locking reads, the bookkeeping reads of the background workers, sessions and users always use the primary.
+func WithReplicas(replicas *Replicas) Option {
+	return func(store *SQLStore) {
+		store.replicas = replicas
+	}
+}
+
+// read runs a read-only query on a replica fresh enough for ctx, or on the primary.
+// A query failing on a replica is run again on the primary,
+// and a replica that cannot be reached serves no read until it is checked again.
+func (store *SQLStore) read(ctx context.Context, query func(q *Queries) error) error {
+	if store.replicas != nil {
+		if r := store.replicas.reader(ctx); r != nil {
+			err := query(r.queries)
+			if err == nil || errors.Is(err, pgx.ErrNoRows) || ctx.Err() != nil {
+				return err
+			}
+
+			var pgErr *pgconn.PgError
+			if !errors.As(err, &pgErr) {
+				r.replayedAt.Store(0)
+			}
+			log.Ctx(ctx).Warn().Err(err).Msg("read failed on replica, reading from the primary")
+		}
+	}
+
+	return query(store.Queries)
+}
+
+// Reads routed to the replicas
+
+func (store *SQLStore) GetAccount(ctx context.Context, id int64) (Account, error) {
+	var result Account
+	err := store.read(ctx, func(q *Queries) (err error) {
+		result, err = q.GetAccount(ctx, id)
+		return
+	})
+	return result, err
+}
+
+func (store *SQLStore) GetAccountBalanceAt(ctx context.Context, arg GetAccountBalanceAtParams) (int64, error) {
+	var result int64
+	err := store.read(ctx, func(q *Queries) (err error) {
+		result, err = q.GetAccountBalanceAt(ctx, arg)
+		return
+	})
+	return result, err
+}
+
+func (store *SQLStore) GetAccountLimits(ctx context.Context, accountID int64) (GetAccountLimitsRow, error) {
+	var result GetAccountLimitsRow
+	err := store.read(ctx, func(q *Queries) (err error) {
+		result, err = q.GetAccountLimits(ctx, accountID)
+		return
+	})
+	return result, err
+}
+
+func (store *SQLStore) GetEntry(ctx context.Context, id int64) (Entry, error) {
+	var result Entry
+	err := store.read(ctx, func(q *Queries) (err error) {
+		result, err = q.GetEntry(ctx, id)
+		return
+	})
+	return result, err
+}
+
+func (store *SQLStore) GetFeeSchedule(ctx context.Context, arg GetFeeScheduleParams) (FeeSchedule, error) {
+	var result FeeSchedule
+	err := store.read(ctx, func(q *Queries) (err error) {
+		result, err = q.GetFeeSchedule(ctx, arg)
+		return
+	})
+	return result, err
+}
+
+func (store *SQLStore) GetHold(ctx context.Context, id int64) (Hold, error) {
+	var result Hold
+	err := store.read(ctx, func(q *Queries) (err error) {
+		result, err = q.GetHold(ctx, id)
+		return
+	})
+	return result, err
+}
+
+func (store *SQLStore) GetLastInterestPosting(ctx context.Context, arg GetLastInterestPostingParams) (InterestPosting, error) {
+	var result InterestPosting
+	err := store.read(ctx, func(q *Queries) (err error) {
+		result, err = q.GetLastInterestPosting(ctx, arg)
+		return
+	})
+	return result, err
+}
+
+func (store *SQLStore) GetScheduledTransfer(ctx context.Context, id int64) (ScheduledTransfer, error) {
+	var result ScheduledTransfer
+	err := store.read(ctx, func(q *Queries) (err error) {
+		result, err = q.GetScheduledTransfer(ctx, id)
+		return
+	})
+	return result, err
+}
+
+func (store *SQLStore) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
+	var result Transfer
+	err := store.read(ctx, func(q *Queries) (err error) {
+		result, err = q.GetTransfer(ctx, id)
+		return
+	})
+	return result, err
+}
+
+func (store *SQLStore) GetTransferReversal(ctx context.Context, transferID int64) (TransferReversal, error) {
+	var result TransferReversal
+	err := store.read(ctx, func(q *Queries) (err error) {
+		result, err = q.GetTransferReversal(ctx, transferID)
+		return
+	})
+	return result, err
+}
+
+func (store *SQLStore) GetWebhookSubscription(ctx context.Context, id int64) (WebhookSubscription, error) {
+	var result WebhookSubscription
+	err := store.read(ctx, func(q *Queries) (err error) {
+		result, err = q.GetWebhookSubscription(ctx, id)
+		return
+	})
+	return result, err
+}
+
+func (store *SQLStore) ListAccountHolds(ctx context.Context, arg ListAccountHoldsParams) ([]Hold, error) {
+	var result []Hold
+	err := store.read(ctx, func(q *Queries) (err error) {
+		result, err = q.ListAccountHolds(ctx, arg)
+		return
+	})
+	return result, err
+}
+
+func (store *SQLStore) ListAccountStatusChanges(ctx context.Context, arg ListAccountStatusChangesParams) ([]AccountStatusChange, error) {
+	var result []AccountStatusChange
+	err := store.read(ctx, func(q *Queries) (err error) {
+		result, err = q.ListAccountStatusChanges(ctx, arg)
+		return
+	})
+	return result, err
+}
+
+func (store *SQLStore) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error) {
+	var result []Account
+	err := store.read(ctx, func(q *Queries) (err error) {
+		result, err = q.ListAccounts(ctx, arg)
+		return
+	})
+	return result, err
+}
+
+func (store *SQLStore) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error) {
+	var result []Entry
+	err := store.read(ctx, func(q *Queries) (err error) {
+		result, err = q.ListEntries(ctx, arg)
+		return
+	})
+	return result, err
+}
+
+func (store *SQLStore) ListEvents(ctx context.Context, arg ListEventsParams) ([]Event, error) {
+	var result []Event
+	err := store.read(ctx, func(q *Queries) (err error) {
+		result, err = q.ListEvents(ctx, arg)
+		return
+	})
+	return result, err
+}
+
+func (store *SQLStore) ListFeeSchedules(ctx context.Context, arg ListFeeSchedulesParams) ([]FeeSchedule, error) {
+	var result []FeeSchedule
+	err := store.read(ctx, func(q *Queries) (err error) {
+		result, err = q.ListFeeSchedules(ctx, arg)
+		return
+	})
+	return result, err
+}
+
+func (store *SQLStore) ListInterestPostings(ctx context.Context, arg ListInterestPostingsParams) ([]InterestPosting, error) {
+	var result []InterestPosting
+	err := store.read(ctx, func(q *Queries) (err error) {
+		result, err = q.ListInterestPostings(ctx, arg)
+		return
+	})
+	return result, err
+}
+
+func (store *SQLStore) ListInterestRates(ctx context.Context) ([]InterestRate, error) {
+	var result []InterestRate
+	err := store.read(ctx, func(q *Queries) (err error) {
+		result, err = q.ListInterestRates(ctx)
+		return
+	})
+	return result, err
+}
+
+func (store *SQLStore) ListScheduledTransferRuns(ctx context.Context, arg ListScheduledTransferRunsParams) ([]ScheduledTransferRun, error) {
+	var result []ScheduledTransferRun
+	err := store.read(ctx, func(q *Queries) (err error) {
+		result, err = q.ListScheduledTransferRuns(ctx, arg)
+		return
+	})
+	return result, err
+}
+
+func (store *SQLStore) ListScheduledTransfers(ctx context.Context, arg ListScheduledTransfersParams) ([]ScheduledTransfer, error) {
+	var result []ScheduledTransfer
+	err := store.read(ctx, func(q *Queries) (err error) {
+		result, err = q.ListScheduledTransfers(ctx, arg)
+		return
+	})
+	return result, err
+}
+
+func (store *SQLStore) ListStatementEntries(ctx context.Context, arg ListStatementEntriesParams) ([]ListStatementEntriesRow, error) {
+	var result []ListStatementEntriesRow
+	err := store.read(ctx, func(q *Queries) (err error) {
+		result, err = q.ListStatementEntries(ctx, arg)
+		return
+	})
+	return result, err
+}
+
+func (store *SQLStore) ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error) {
+	var result []Transfer
+	err := store.read(ctx, func(q *Queries) (err error) {
+		result, err = q.ListTransfers(ctx, arg)
+		return
+	})
+	return result, err
+}
+
+func (store *SQLStore) ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error) {
+	var result []WebhookDelivery
+	err := store.read(ctx, func(q *Queries) (err error) {
+		result, err = q.ListWebhookDeliveries(ctx, arg)
+		return
+	})
+	return result, err
+}
+
+func (store *SQLStore) ListWebhookSubscriptions(ctx context.Context, arg ListWebhookSubscriptionsParams) ([]WebhookSubscription, error) {
+	var result []WebhookSubscription
+	err := store.read(ctx, func(q *Queries) (err error) {
+		result, err = q.ListWebhookSubscriptions(ctx, arg)
+		return
+	})
+	return result, err
+}
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Default retry policy of transactions
+const (
+	DefaultMaxTxRetries = 3
+	defaultRetryDelay   = 10 * time.Millisecond
+	maxRetryDelay       = 500 * time.Millisecond
+)
+
+// Option configures a SQLStore
+type Option func(store *SQLStore)
+
+// WithMaxTxRetries sets how many times a transaction failing with a serialization failure or a deadlock
+// is retried before the error is returned. Zero disables retries.
+func WithMaxTxRetries(maxRetries int) Option {
+	return func(store *SQLStore) {
+		if maxRetries >= 0 {
+			store.maxTxRetries = maxRetries
+		}
+	}
+}
+
+// WithTxRetryObserver sets a function called before a failed transaction is retried,
+// with the name of the postgres error that caused the retry
+func WithTxRetryObserver(observer func(ctx context.Context, reason string)) Option {
+	return func(store *SQLStore) {
+		store.onTxRetry = observer
+	}
+}
+
+// retryReason returns the name of the postgres error if the transaction can be retried
+func retryReason(err error) (string, bool) {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return "", false
+	}
+
+	switch pgErr.Code {
+	case SerializationFailure:
+		return "serialization_failure", true
+	case DeadlockDetected:
+		return "deadlock_detected", true
+	}
+	return "", false
+}
+
+// retryDelay returns a random delay bounded by an exponential backoff of the attempt.
+// The jitter spreads retries of transactions that conflicted with each other.
+func retryDelay(attempt int) time.Duration {
+	backoff := defaultRetryDelay << attempt
+	if backoff <= 0 || backoff > maxRetryDelay {
+		backoff = maxRetryDelay
+	}
+	return time.Duration(rand.Int63n(int64(backoff)) + 1)
+}
+
+// sleep waits for the delay unless the context is done first
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Statuses of a scheduled transfer run
+const (
+	ScheduledTransferRunPending   = "pending"
+	ScheduledTransferRunSucceeded = "succeeded"
+	ScheduledTransferRunFailed    = "failed"
+)
+
+// ClaimDueScheduledTransfersTxParams contains the input parameters of the claim transaction
+type ClaimDueScheduledTransfersTxParams struct {
+	Now   time.Time
+	Limit int32
+	// NextRunAt computes when a claimed scheduled transfer is due next, or null once its schedule has ended
+	NextRunAt func(scheduledTransfer ScheduledTransfer, now time.Time) pgtype.Timestamptz
+}
+
+// ClaimedScheduledTransfer is a scheduled transfer due for execution along with the run recording it
+type ClaimedScheduledTransfer struct {
+	ScheduledTransfer ScheduledTransfer    `json:"scheduled_transfer"`
+	Run               ScheduledTransferRun `json:"run"`
+}
+
+// ClaimDueScheduledTransfersTx picks the scheduled transfers due at the given time, moves them to their next run and records a pending run for each.
+// Due rows are locked with SKIP LOCKED so that several schedulers never claim the same run,
+// and as the next run is stored before the transfer is executed a run is never executed twice.
+func (store *txStore) ClaimDueScheduledTransfersTx(ctx context.Context, arg ClaimDueScheduledTransfersTxParams) ([]ClaimedScheduledTransfer, error) {
+	var result []ClaimedScheduledTransfer
+
+	err := store.execTx(ctx, nil, func(q Querier) error {
+		result = []ClaimedScheduledTransfer{}
+
+		due, err := q.ListDueScheduledTransfersForUpdate(ctx, ListDueScheduledTransfersForUpdateParams{
+			Now:        arg.Now,
+			LimitCount: arg.Limit,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, scheduledTransfer := range due {
+			run, err := q.CreateScheduledTransferRun(ctx, CreateScheduledTransferRunParams{
+				ScheduledTransferID: scheduledTransfer.ID,
+				ScheduledFor:        scheduledTransfer.NextRunAt.Time,
+			})
+			if err != nil {
+				return err
+			}
+
+			updated, err := q.UpdateScheduledTransferNextRun(ctx, UpdateScheduledTransferNextRunParams{
+				ID:        scheduledTransfer.ID,
+				NextRunAt: arg.NextRunAt(scheduledTransfer, arg.Now),
+			})
+			if err != nil {
+				return err
+			}
+
+			result = append(result, ClaimedScheduledTransfer{
+				ScheduledTransfer: updated,
+				Run:               run,
+			})
+		}
+
+		return nil
+	})
+
+	return result, err
+}
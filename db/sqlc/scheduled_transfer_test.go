@@ -0,0 +1,166 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/samirprakash/go-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomScheduledTransfer(t *testing.T, nextRunAt time.Time) ScheduledTransfer {
+	from := createRandomAccountInCurrency(t, util.USD)
+	to := createRandomAccountInCurrency(t, util.USD)
+
+	arg := CreateScheduledTransferParams{
+		Owner:         from.Owner,
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        util.RandomInt(1, 10),
+		Currency:      util.USD,
+		Schedule:      "@every 1h",
+		StartAt:       nextRunAt,
+		NextRunAt:     pgtype.Timestamptz{Time: nextRunAt, Valid: true},
+	}
+
+	scheduledTransfer, err := testQueries.CreateScheduledTransfer(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, scheduledTransfer.ID)
+	require.Equal(t, arg.Owner, scheduledTransfer.Owner)
+	require.Equal(t, arg.Amount, scheduledTransfer.Amount)
+	require.False(t, scheduledTransfer.EndAt.Valid)
+	require.WithinDuration(t, nextRunAt, scheduledTransfer.NextRunAt.Time, time.Second)
+
+	return scheduledTransfer
+}
+
+func TestCreateScheduledTransfer(t *testing.T) {
+	createRandomScheduledTransfer(t, time.Now())
+}
+
+func TestDeleteScheduledTransfer(t *testing.T) {
+	scheduledTransfer := createRandomScheduledTransfer(t, time.Now())
+
+	_, err := testQueries.CreateScheduledTransferRun(context.Background(), CreateScheduledTransferRunParams{
+		ScheduledTransferID: scheduledTransfer.ID,
+		ScheduledFor:        time.Now(),
+	})
+	require.NoError(t, err)
+
+	// runs are deleted along with their scheduled transfer
+	err = testQueries.DeleteScheduledTransfer(context.Background(), scheduledTransfer.ID)
+	require.NoError(t, err)
+
+	_, err = testQueries.GetScheduledTransfer(context.Background(), scheduledTransfer.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestClaimDueScheduledTransfersTx(t *testing.T) {
+	store := NewStore(testDB)
+	now := time.Now()
+
+	due := createRandomScheduledTransfer(t, now.Add(-time.Minute))
+	notDue := createRandomScheduledTransfer(t, now.Add(time.Hour))
+
+	nextRunAt := pgtype.Timestamptz{Time: now.Add(time.Hour), Valid: true}
+	arg := ClaimDueScheduledTransfersTxParams{
+		Now:   now,
+		Limit: 1000,
+		NextRunAt: func(scheduledTransfer ScheduledTransfer, now time.Time) pgtype.Timestamptz {
+			return nextRunAt
+		},
+	}
+
+	claimed, err := store.ClaimDueScheduledTransfersTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	var found bool
+	for _, c := range claimed {
+		require.NotEqual(t, notDue.ID, c.ScheduledTransfer.ID)
+		if c.ScheduledTransfer.ID != due.ID {
+			continue
+		}
+
+		found = true
+		require.Equal(t, ScheduledTransferRunPending, c.Run.Status)
+		require.WithinDuration(t, due.NextRunAt.Time, c.Run.ScheduledFor, time.Second)
+		require.WithinDuration(t, nextRunAt.Time, c.ScheduledTransfer.NextRunAt.Time, time.Second)
+	}
+	require.True(t, found)
+
+	// a claimed run is not claimed again
+	claimed, err = store.ClaimDueScheduledTransfersTx(context.Background(), arg)
+	require.NoError(t, err)
+	for _, c := range claimed {
+		require.NotEqual(t, due.ID, c.ScheduledTransfer.ID)
+	}
+}
+
+func TestClaimDueScheduledTransfersTxConcurrent(t *testing.T) {
+	store := NewStore(testDB)
+	now := time.Now()
+
+	n := 5
+	for i := 0; i < n; i++ {
+		createRandomScheduledTransfer(t, now.Add(-time.Minute))
+	}
+
+	arg := ClaimDueScheduledTransfersTxParams{
+		Now:   now,
+		Limit: 2,
+		NextRunAt: func(scheduledTransfer ScheduledTransfer, now time.Time) pgtype.Timestamptz {
+			return pgtype.Timestamptz{}
+		},
+	}
+
+	// schedulers claiming at the same time never get the same row
+	results := make(chan []ClaimedScheduledTransfer)
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		go func() {
+			claimed, err := store.ClaimDueScheduledTransfersTx(context.Background(), arg)
+			errs <- err
+			results <- claimed
+		}()
+	}
+
+	seen := make(map[int64]bool)
+	for i := 0; i < n; i++ {
+		require.NoError(t, <-errs)
+		for _, c := range <-results {
+			require.False(t, seen[c.ScheduledTransfer.ID])
+			seen[c.ScheduledTransfer.ID] = true
+		}
+	}
+}
+
+func TestFinishScheduledTransferRun(t *testing.T) {
+	scheduledTransfer := createRandomScheduledTransfer(t, time.Now())
+
+	run, err := testQueries.CreateScheduledTransferRun(context.Background(), CreateScheduledTransferRunParams{
+		ScheduledTransferID: scheduledTransfer.ID,
+		ScheduledFor:        time.Now(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, ScheduledTransferRunPending, run.Status)
+	require.False(t, run.FinishedAt.Valid)
+
+	finished, err := testQueries.FinishScheduledTransferRun(context.Background(), FinishScheduledTransferRunParams{
+		ID:     run.ID,
+		Status: ScheduledTransferRunFailed,
+		Error:  "insufficient funds",
+	})
+	require.NoError(t, err)
+	require.Equal(t, ScheduledTransferRunFailed, finished.Status)
+	require.Equal(t, "insufficient funds", finished.Error)
+	require.True(t, finished.FinishedAt.Valid)
+
+	runs, err := testQueries.ListScheduledTransferRuns(context.Background(), ListScheduledTransferRunsParams{
+		ScheduledTransferID: scheduledTransfer.ID,
+		Limit:               5,
+	})
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+}
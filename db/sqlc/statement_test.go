@@ -0,0 +1,68 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatementEntries(t *testing.T) {
+	store := NewStore(testDB)
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccountInCurrency(t, account1.Currency)
+	from := time.Now().Add(-time.Second)
+
+	deposit, err := store.DepositTx(context.Background(), DepositTxParams{
+		AccountID: account1.ID,
+		Amount:    100,
+	})
+	require.NoError(t, err)
+
+	transfer, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	opening, err := store.GetAccountBalanceAt(context.Background(), GetAccountBalanceAtParams{
+		At:        from,
+		AccountID: account1.ID,
+	})
+	require.NoError(t, err)
+
+	rows, err := store.ListStatementEntries(context.Background(), ListStatementEntriesParams{
+		AccountID:  account1.ID,
+		FromTime:   from,
+		ToTime:     time.Now().Add(time.Second),
+		LimitCount: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+
+	require.Equal(t, deposit.Entry.ID, rows[0].ID)
+	require.Equal(t, "deposit", rows[0].Kind)
+	require.False(t, rows[0].CounterpartyAccountID.Valid)
+
+	require.Equal(t, transfer.FromEntry.ID, rows[1].ID)
+	require.Equal(t, "transfer", rows[1].Kind)
+	require.Equal(t, transfer.Transfer.ID, rows[1].TransferID.Int64)
+	require.Equal(t, account2.ID, rows[1].CounterpartyAccountID.Int64)
+	require.Equal(t, account2.Owner, rows[1].CounterpartyOwner.String)
+
+	require.Equal(t, transfer.FromAccount.Balance, opening+rows[0].Amount+rows[1].Amount)
+
+	// keyset paging resumes after the last entry seen
+	rows, err = store.ListStatementEntries(context.Background(), ListStatementEntriesParams{
+		AccountID:  account1.ID,
+		FromTime:   from,
+		ToTime:     time.Now().Add(time.Second),
+		AfterID:    deposit.Entry.ID,
+		LimitCount: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	require.Equal(t, transfer.FromEntry.ID, rows[0].ID)
+}
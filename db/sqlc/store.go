@@ -2,8 +2,13 @@ package db
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // Store provides all functions to execute db queries and transactions.
@@ -12,30 +17,111 @@ import (
 type Store interface {
 	Querier
 	TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error)
+	CreateAccountTx(ctx context.Context, arg CreateAccountParams) (Account, error)
+	CreateUserTx(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateSessionTx(ctx context.Context, arg CreateSessionParams) (Session, error)
+	PublishEventsTx(ctx context.Context, arg PublishEventsTxParams) (PublishEventsTxResult, error)
+	DepositTx(ctx context.Context, arg DepositTxParams) (DepositTxResult, error)
+	QuoteTransferFee(ctx context.Context, arg TransferTxParams) (Fee, error)
+	BatchTransferTx(ctx context.Context, arg BatchTransferTxParams) (BatchTransferTxResult, error)
+	ClaimDueScheduledTransfersTx(ctx context.Context, arg ClaimDueScheduledTransfersTxParams) ([]ClaimedScheduledTransfer, error)
+	ReverseTransferTx(ctx context.Context, arg ReverseTransferTxParams) (ReverseTransferTxResult, error)
+	ChangeAccountStatusTx(ctx context.Context, arg ChangeAccountStatusTxParams) (ChangeAccountStatusTxResult, error)
+	HoldTx(ctx context.Context, arg HoldTxParams) (HoldTxResult, error)
+	CaptureHoldTx(ctx context.Context, arg CaptureHoldTxParams) (CaptureHoldTxResult, error)
+	VoidHoldTx(ctx context.Context, holdID int64) (Hold, error)
+	ExpireHoldsTx(ctx context.Context, arg ExpireHoldsTxParams) ([]Hold, error)
+	AccrueInterestTx(ctx context.Context, arg AccrueInterestTxParams) ([]InterestAccrual, error)
+	PostInterestTx(ctx context.Context, arg PostInterestTxParams) (PostInterestTxResult, error)
+	Ping(ctx context.Context) error
+}
+
+// txDatabase is a database the transactions of a store run on
+type txDatabase interface {
+	Querier
+	// execTx executes a function within a transaction, committed if the function returns no error and rolled back otherwise
+	execTx(ctx context.Context, opts *pgx.TxOptions, fn func(Querier) error) error
+}
+
+// txStore implements the transactions of the Store interface on top of a database,
+// so that every store applies the same business rules
+type txStore struct {
+	database txDatabase
+}
+
+// execTx executes a function within a transaction of the database.
+// Errors of the constraints enforcing business rules are wrapped with the error of the rule.
+func (store *txStore) execTx(ctx context.Context, opts *pgx.TxOptions, fn func(Querier) error) error {
+	return ruleError(store.database.execTx(ctx, opts, fn))
 }
 
 // SQLStore provides all functions to execute SQL queries and transactions.
 // We need to extend on the exisiting *Queries struct that sqlc provides as it only supports executing queries on one table at a time.
 // In order to execute transactions, we will use store to create a set of quesries to be executed in sequence
 type SQLStore struct {
-	db *sql.DB
+	txStore
+	connPool *pgxpool.Pool
 	*Queries
+	maxTxRetries int
+	onTxRetry    func(ctx context.Context, reason string)
+	replicas     *Replicas
 }
 
-// NewStore creates a new store
-func NewStore(db *sql.DB) Store {
-	return &SQLStore{
-		Queries: New(db),
-		db:      db,
+// NewStore creates a new store on a connection pool
+func NewStore(connPool *pgxpool.Pool, opts ...Option) Store {
+	store := &SQLStore{
+		Queries:      New(connPool),
+		connPool:     connPool,
+		maxTxRetries: DefaultMaxTxRetries,
 	}
+	store.txStore = txStore{database: store}
+
+	for _, opt := range opts {
+		opt(store)
+	}
+
+	return store
+}
+
+// Ping verifies that the database is still reachable
+func (store *SQLStore) Ping(ctx context.Context) error {
+	return store.connPool.Ping(ctx)
 }
 
 // execTx executes a function within a database transaction.
-// This function is added on to the store and it takes context and a callback function as parameters.
+// This function is added on to the store and it takes context, transaction options and a callback function as parameters.
 // When we start a new transaction, we will create a set of queries and then call the callback function with those queries to execute the transaction.
 // If there is a problem, then there is an option to rollback or else the transaction can be committed.
-func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) error {
-	tx, err := store.db.BeginTx(ctx, nil)
+// Transactions aborted by postgres because of a serialization failure or a deadlock are retried from the start
+// with a jittered backoff, so the callback must not have side effects outside of the transaction.
+// Nil options start a read-write transaction at the default isolation level.
+func (store *SQLStore) execTx(ctx context.Context, opts *pgx.TxOptions, fn func(Querier) error) error {
+	for attempt := 0; ; attempt++ {
+		err := store.runTx(ctx, opts, fn)
+
+		reason, retryable := retryReason(err)
+		if !retryable || attempt >= store.maxTxRetries {
+			return err
+		}
+
+		if store.onTxRetry != nil {
+			store.onTxRetry(ctx, reason)
+		}
+
+		if err := sleep(ctx, retryDelay(attempt)); err != nil {
+			return err
+		}
+	}
+}
+
+// runTx executes a function within a single database transaction
+func (store *SQLStore) runTx(ctx context.Context, opts *pgx.TxOptions, fn func(Querier) error) error {
+	var txOptions pgx.TxOptions
+	if opts != nil {
+		txOptions = *opts
+	}
+
+	tx, err := store.connPool.BeginTx(ctx, txOptions)
 	if err != nil {
 		return err
 	}
@@ -44,13 +130,13 @@ func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) erro
 	err = fn(q)
 	if err != nil {
 		// rollback if there is an error
-		if rbErr := tx.Rollback(); rbErr != nil {
-			return fmt.Errorf("transaction error : %v, rollback error: %v", err, rbErr)
+		if rbErr := tx.Rollback(ctx); rbErr != nil {
+			return fmt.Errorf("transaction error : %w, rollback error: %v", err, rbErr)
 		}
 		return err
 	}
 	// commit if everything works
-	return tx.Commit()
+	return tx.Commit(ctx)
 }
 
 // TransferTxParams represents the arguments required to execute the transfer transaction
@@ -67,57 +153,123 @@ type TransferTxResult struct {
 	ToAccount   Account  `json:"to_account,omitempty"`
 	FromEntry   Entry    `json:"from_entry,omitempty"`
 	ToEntry     Entry    `json:"to_entry,omitempty"`
+	// Fee charged to the sender, paid with FeeTransfer to the revenue account when it is not zero
+	Fee         Fee       `json:"fee"`
+	FeeTransfer *Transfer `json:"fee_transfer,omitempty"`
 }
 
 // TransferTx performs a money transfer from one account to another.
 // It executes a database transaction to create a transfer record, update account entries and update the account balance.
-func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
+// The fee of the transfer, if any, is charged to the sender in the same transaction.
+func (store *txStore) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
 	var result TransferTxResult
 
-	err := store.execTx(ctx, func(q *Queries) error {
+	err := store.execTx(ctx, nil, func(q Querier) error {
 		var err error
+		result, err = applyTransferWithFee(ctx, q, arg)
+		return err
+	})
 
-		// create a transfer query and execute it
-		result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
-			FromAccountID: arg.FromAccountID,
-			ToAccountID:   arg.ToAccountID,
-			Amount:        arg.Amount,
-		})
-		if err != nil {
-			return err
-		}
+	return result, err
+}
+
+// applyTransfer records a transfer with its entries and moves the money within the transaction of q,
+// checking it against the outgoing limits of the sender
+func applyTransfer(ctx context.Context, q Querier, arg TransferTxParams) (TransferTxResult, error) {
+	return moveMoney(ctx, q, arg, true)
+}
+
+// moveMoney is applyTransfer, the outgoing limits of the sender being checked only when checkLimits is set
+func moveMoney(ctx context.Context, q Querier, arg TransferTxParams, checkLimits bool) (TransferTxResult, error) {
+	var result TransferTxResult
+	var err error
+
+	// create a transfer query and execute it
+	result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        arg.Amount,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	// create an entry for the account from which money has been transferred
+	result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:  arg.FromAccountID,
+		Amount:     -arg.Amount,
+		TransferID: pgtype.Int8{Int64: result.Transfer.ID, Valid: true},
+	})
+	if err != nil {
+		return result, err
+	}
+
+	// create an entry for the acoount to which the money has been transferred
+	result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:  arg.ToAccountID,
+		Amount:     arg.Amount,
+		TransferID: pgtype.Int8{Int64: result.Transfer.ID, Valid: true},
+	})
+	if err != nil {
+		return result, err
+	}
+
+	//Update account balance
+	if arg.FromAccountID < arg.ToAccountID {
+		result.FromAccount, result.ToAccount, err = updateBalancesToAccounts(ctx, q, arg.FromAccountID, -arg.Amount, arg.ToAccountID, arg.Amount)
+	} else {
+		result.ToAccount, result.FromAccount, err = updateBalancesToAccounts(ctx, q, arg.ToAccountID, arg.Amount, arg.FromAccountID, -arg.Amount)
+	}
+	if err != nil {
+		return result, err
+	}
+
+	// the accounts are locked by the updates so their status cannot change before commit
+	if err = AccountStatusError(result.FromAccount.Status); err != nil {
+		return result, err
+	}
+	if err = AccountStatusError(result.ToAccount.Status); err != nil {
+		return result, err
+	}
 
-		// create an entry for the account from which money has been transferred
-		result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.FromAccountID,
-			Amount:    -arg.Amount,
-		})
+	if checkLimits {
+		err = checkOutgoingLimits(ctx, q, arg.FromAccountID, arg.Amount, result.FromAccount.HeldAmount, time.Now())
 		if err != nil {
-			return err
+			return result, err
 		}
+	}
 
-		// create an entry for the acoount to which the money has been transferred
-		result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.ToAccountID,
-			Amount:    arg.Amount,
-		})
-		if err != nil {
-			return err
+	err = recordEvent(ctx, q, EventTransferCreated,
+		[]string{AccountPartition(arg.FromAccountID), AccountPartition(arg.ToAccountID)},
+		[]string{result.FromAccount.Owner, result.ToAccount.Owner},
+		transferEvent{Transfer: result.Transfer, Currency: result.FromAccount.Currency},
+	)
+	return result, err
+}
+
+// lockAccounts locks the accounts in ascending id order, the order every transaction updates accounts in,
+// so that a transaction touching several accounts cannot deadlock with another one
+func lockAccounts(ctx context.Context, q Querier, ids ...int64) (map[int64]Account, error) {
+	sorted := append([]int64(nil), ids...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	accounts := make(map[int64]Account, len(sorted))
+	for _, id := range sorted {
+		if _, ok := accounts[id]; ok {
+			continue
 		}
 
-		//Update account balance
-		if arg.FromAccountID < arg.ToAccountID {
-			result.FromAccount, result.ToAccount, err = updateBalancesToAccounts(ctx, q, arg.FromAccountID, -arg.Amount, arg.ToAccountID, arg.Amount)
-		} else {
-			result.ToAccount, result.FromAccount, err = updateBalancesToAccounts(ctx, q, arg.ToAccountID, arg.Amount, arg.FromAccountID, -arg.Amount)
+		account, err := q.GetAccountForUpdate(ctx, id)
+		if err != nil {
+			return nil, err
 		}
-		return nil
-	})
+		accounts[id] = account
+	}
 
-	return result, err
+	return accounts, nil
 }
 
-func updateBalancesToAccounts(ctx context.Context, q *Queries, accountID1 int64, amount1 int64, accountID2 int64, amount2 int64) (account1 Account, account2 Account, err error) {
+func updateBalancesToAccounts(ctx context.Context, q Querier, accountID1 int64, amount1 int64, accountID2 int64, amount2 int64) (account1 Account, account2 Account, err error) {
 	account1, err = q.UpdateAccountBalance(ctx, UpdateAccountBalanceParams{
 		Amount: amount1,
 		ID:     accountID1,
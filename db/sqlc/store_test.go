@@ -3,8 +3,12 @@ package db
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/stretchr/testify/require"
 )
 
@@ -66,6 +70,7 @@ func TestTransferTx(t *testing.T) {
 		require.NotEmpty(t, fromEntry)
 		require.Equal(t, account1.ID, fromEntry.AccountID)
 		require.Equal(t, -amount, fromEntry.Amount)
+		require.Equal(t, transfer.ID, fromEntry.TransferID.Int64)
 		require.NotZero(t, fromEntry.ID)
 		require.NotEmpty(t, fromEntry.CreatedAt)
 
@@ -78,6 +83,7 @@ func TestTransferTx(t *testing.T) {
 		require.NotEmpty(t, toEntry)
 		require.Equal(t, account2.ID, toEntry.AccountID)
 		require.Equal(t, amount, toEntry.Amount)
+		require.Equal(t, transfer.ID, toEntry.TransferID.Int64)
 		require.NotZero(t, toEntry.ID)
 		require.NotEmpty(t, toEntry.CreatedAt)
 
@@ -179,3 +185,225 @@ func TestTransferTxDeadlock(t *testing.T) {
 	require.Equal(t, account1.Balance, updatedAccount1.Balance)
 	require.Equal(t, account2.Balance, updatedAccount2.Balance)
 }
+
+func TestTransferTxConstraints(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+
+	testCases := []struct {
+		name       string
+		arg        TransferTxParams
+		constraint string
+	}{
+		{
+			name: "InsufficientFunds",
+			arg: TransferTxParams{
+				FromAccountID: account1.ID,
+				ToAccountID:   account2.ID,
+				Amount:        account1.Balance + 1,
+			},
+			constraint: "accounts_balance_check",
+		},
+		{
+			name: "SameAccount",
+			arg: TransferTxParams{
+				FromAccountID: account1.ID,
+				ToAccountID:   account1.ID,
+				Amount:        10,
+			},
+			constraint: "transfers_distinct_accounts_check",
+		},
+		{
+			name: "NegativeAmount",
+			arg: TransferTxParams{
+				FromAccountID: account1.ID,
+				ToAccountID:   account2.ID,
+				Amount:        -10,
+			},
+			constraint: "transfers_amount_check",
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := store.TransferTx(context.Background(), tc.arg)
+			require.Error(t, err)
+
+			var pgErr *pgconn.PgError
+			require.ErrorAs(t, err, &pgErr)
+			require.Equal(t, CheckViolation, pgErr.Code)
+			require.Equal(t, tc.constraint, pgErr.ConstraintName)
+		})
+	}
+
+	// nothing has been moved
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+}
+
+func TestTransferTxBidirectionalStress(t *testing.T) {
+	store := NewStore(testDB)
+
+	accounts := make([]Account, 4)
+	total := int64(0)
+	for i := range accounts {
+		accounts[i] = createRandomAccount(t)
+		total += accounts[i].Balance
+	}
+
+	// hammer every pair of accounts in both directions
+	n := 100
+	errs := make(chan error)
+
+	for i := 0; i < n; i++ {
+		offset := 1 + (i/len(accounts))%(len(accounts)-1)
+		from := accounts[i%len(accounts)]
+		to := accounts[(i+offset)%len(accounts)]
+
+		go func() {
+			_, err := store.TransferTx(context.Background(), TransferTxParams{
+				FromAccountID: from.ID,
+				ToAccountID:   to.ID,
+				Amount:        1,
+			})
+
+			errs <- err
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		err := <-errs
+		require.NoError(t, err)
+	}
+
+	// money is neither created nor destroyed
+	updatedTotal := int64(0)
+	for _, account := range accounts {
+		updatedAccount, err := store.GetAccount(context.Background(), account.ID)
+		require.NoError(t, err)
+		updatedTotal += updatedAccount.Balance
+	}
+	require.Equal(t, total, updatedTotal)
+}
+
+func TestExecTxRetriesSerializationFailures(t *testing.T) {
+	var retries int64
+	store := NewStore(testDB,
+		WithMaxTxRetries(50),
+		WithTxRetryObserver(func(ctx context.Context, reason string) {
+			require.Contains(t, []string{"serialization_failure", "deadlock_detected"}, reason)
+			atomic.AddInt64(&retries, 1)
+		}),
+	).(*SQLStore)
+
+	account := createRandomAccount(t)
+	opts := &pgx.TxOptions{IsoLevel: pgx.Serializable}
+
+	// concurrent read-modify-write cycles conflict under serializable isolation
+	n := 10
+	errs := make(chan error)
+
+	for i := 0; i < n; i++ {
+		go func() {
+			errs <- store.execTx(context.Background(), opts, func(q Querier) error {
+				acc, err := q.GetAccount(context.Background(), account.ID)
+				if err != nil {
+					return err
+				}
+
+				_, err = q.UpdateAccount(context.Background(), UpdateAccountParams{
+					ID:      acc.ID,
+					Balance: acc.Balance + 1,
+				})
+				return err
+			})
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		err := <-errs
+		require.NoError(t, err)
+	}
+
+	// no update has been lost
+	updatedAccount, err := store.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance+int64(n), updatedAccount.Balance)
+	fmt.Println(">> retries :::", atomic.LoadInt64(&retries))
+}
+
+func TestExecTxRetryLimit(t *testing.T) {
+	testCases := []struct {
+		name          string
+		err           error
+		maxRetries    int
+		expectedCalls int
+	}{
+		{
+			name:          "SerializationFailure",
+			err:           &pgconn.PgError{Code: "40001"},
+			maxRetries:    2,
+			expectedCalls: 3,
+		},
+		{
+			name:          "DeadlockDetected",
+			err:           &pgconn.PgError{Code: "40P01"},
+			maxRetries:    1,
+			expectedCalls: 2,
+		},
+		{
+			name:          "RetriesDisabled",
+			err:           &pgconn.PgError{Code: "40001"},
+			maxRetries:    0,
+			expectedCalls: 1,
+		},
+		{
+			name:          "NotRetryable",
+			err:           &pgconn.PgError{Code: "23514"},
+			maxRetries:    3,
+			expectedCalls: 1,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			store := NewStore(testDB, WithMaxTxRetries(tc.maxRetries)).(*SQLStore)
+
+			calls := 0
+			err := store.execTx(context.Background(), nil, func(q Querier) error {
+				calls++
+				return tc.err
+			})
+			require.ErrorIs(t, err, tc.err)
+			require.Equal(t, tc.expectedCalls, calls)
+		})
+	}
+}
+
+func TestExecTxRetryCanceled(t *testing.T) {
+	store := NewStore(testDB, WithMaxTxRetries(100)).(*SQLStore)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := store.execTx(ctx, nil, func(q Querier) error {
+		return &pgconn.PgError{Code: "40001"}
+	})
+	require.Error(t, err)
+	require.True(t, ctx.Err() != nil)
+}
+
+func TestRetryDelay(t *testing.T) {
+	for attempt := 0; attempt < 100; attempt++ {
+		delay := retryDelay(attempt)
+		require.Greater(t, delay, time.Duration(0))
+		require.LessOrEqual(t, delay, maxRetryDelay)
+	}
+}
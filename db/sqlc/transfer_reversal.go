@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"errors"
+)
+
+// ReverseTransferTxParams contains the input parameters of the reversal transaction
+type ReverseTransferTxParams struct {
+	TransferID int64 `json:"transfer_id"`
+	// Amount to give back, zero reverses whatever is left of the transfer
+	Amount int64  `json:"amount"`
+	Reason string `json:"reason"`
+}
+
+// ReverseTransferTxResult represents the result of the reversal transaction
+type ReverseTransferTxResult struct {
+	TransferTxResult
+	OriginalTransfer Transfer         `json:"original_transfer"`
+	Reversal         TransferReversal `json:"reversal"`
+	// RemainingAmount is what can still be reversed after this reversal
+	RemainingAmount int64 `json:"remaining_amount"`
+}
+
+// ReverseTransferTx compensates a transfer, fully or partially, with a transfer in the opposite direction linked to the original one.
+// Transfers are never updated or deleted so the history of every balance stays intact.
+// The original transfer is locked while the amount already reversed is checked, so concurrent reversals can never give back more than was sent.
+// A reversal is not held to the outgoing limits of the account giving the money back, and only the amount is returned :
+// the fee charged for the original transfer is kept.
+func (store *txStore) ReverseTransferTx(ctx context.Context, arg ReverseTransferTxParams) (ReverseTransferTxResult, error) {
+	var result ReverseTransferTxResult
+
+	err := store.execTx(ctx, nil, func(q Querier) error {
+		var err error
+
+		result.OriginalTransfer, err = q.GetTransferForUpdate(ctx, arg.TransferID)
+		if err != nil {
+			return err
+		}
+		original := result.OriginalTransfer
+
+		_, err = q.GetTransferReversal(ctx, original.ID)
+		if err == nil {
+			return ErrReversalOfReversal
+		}
+		if !errors.Is(err, ErrRecordNotFound) {
+			return err
+		}
+
+		reversed, err := q.GetReversedAmount(ctx, original.ID)
+		if err != nil {
+			return err
+		}
+
+		remaining := original.Amount - reversed
+		amount := arg.Amount
+		if amount == 0 {
+			amount = remaining
+		}
+
+		switch {
+		case remaining <= 0:
+			return ErrTransferAlreadyReversed
+		case amount > remaining:
+			return ErrReversalExceedsTransfer
+		}
+
+		// money goes back from the account that received it
+		result.TransferTxResult, err = moveMoney(ctx, q, TransferTxParams{
+			FromAccountID: original.ToAccountID,
+			ToAccountID:   original.FromAccountID,
+			Amount:        amount,
+		}, false)
+		if err != nil {
+			return err
+		}
+
+		result.Reversal, err = q.CreateTransferReversal(ctx, CreateTransferReversalParams{
+			TransferID:         result.Transfer.ID,
+			OriginalTransferID: original.ID,
+			Reason:             arg.Reason,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.RemainingAmount = remaining - amount
+		return nil
+	})
+
+	return result, err
+}
@@ -0,0 +1,164 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func createTransferToReverse(t *testing.T, amount int64) (TransferTxResult, Account, Account) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccountInCurrency(t, account1.Currency)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        amount,
+	})
+	require.NoError(t, err)
+
+	return result, account1, account2
+}
+
+func TestReverseTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+	transfer, account1, account2 := createTransferToReverse(t, 50)
+
+	// partial refund
+	result, err := store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: transfer.Transfer.ID,
+		Amount:     20,
+		Reason:     "partial refund",
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, transfer.Transfer.ID, result.OriginalTransfer.ID)
+	require.Equal(t, account2.ID, result.Transfer.FromAccountID)
+	require.Equal(t, account1.ID, result.Transfer.ToAccountID)
+	require.Equal(t, int64(20), result.Transfer.Amount)
+	require.Equal(t, result.Transfer.ID, result.Reversal.TransferID)
+	require.Equal(t, transfer.Transfer.ID, result.Reversal.OriginalTransferID)
+	require.Equal(t, "partial refund", result.Reversal.Reason)
+	require.Equal(t, int64(-20), result.FromEntry.Amount)
+	require.Equal(t, int64(20), result.ToEntry.Amount)
+	require.Equal(t, int64(30), result.RemainingAmount)
+
+	require.Equal(t, account1.Balance-30, result.ToAccount.Balance)
+	require.Equal(t, account2.Balance+30, result.FromAccount.Balance)
+
+	// the rest of the transfer
+	result, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: transfer.Transfer.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(30), result.Transfer.Amount)
+	require.Zero(t, result.RemainingAmount)
+
+	require.Equal(t, account1.Balance, result.ToAccount.Balance)
+	require.Equal(t, account2.Balance, result.FromAccount.Balance)
+
+	// nothing left to reverse
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: transfer.Transfer.ID,
+	})
+	require.ErrorIs(t, err, ErrTransferAlreadyReversed)
+
+	// a reversal is final
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: result.Transfer.ID,
+	})
+	require.ErrorIs(t, err, ErrReversalOfReversal)
+}
+
+func TestReverseTransferTxExceedsTransfer(t *testing.T) {
+	store := NewStore(testDB)
+	transfer, _, _ := createTransferToReverse(t, 50)
+
+	_, err := store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: transfer.Transfer.ID,
+		Amount:     51,
+	})
+	require.ErrorIs(t, err, ErrReversalExceedsTransfer)
+}
+
+func TestReverseTransferTxConcurrent(t *testing.T) {
+	store := NewStore(testDB)
+	transfer, account1, account2 := createTransferToReverse(t, 50)
+
+	// concurrent refunds never give back more than the transfer
+	n := 10
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+				TransferID: transfer.Transfer.ID,
+				Amount:     10,
+			})
+			errs <- err
+		}()
+	}
+
+	succeeded := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err == nil {
+			succeeded++
+			continue
+		}
+		require.ErrorIs(t, err, ErrTransferAlreadyReversed)
+	}
+	require.Equal(t, 5, succeeded)
+
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+
+	updatedAccount2, err := store.GetAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+	require.Equal(t, account2.Balance, updatedAccount2.Balance)
+}
+
+func TestReverseTransferTxLimitsAndFee(t *testing.T) {
+	store := NewStore(testDB)
+
+	customer := createPremiumAccount(t)
+	merchant := createRandomAccountInCurrency(t, customer.Currency)
+
+	addFeeSchedule(t, CreateFeeScheduleParams{
+		Currency: pgtype.Text{String: customer.Currency, Valid: true},
+		Tier:     pgtype.Text{String: "premium", Valid: true},
+		FlatFee:  2,
+	})
+
+	transfer, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: customer.ID,
+		ToAccountID:   merchant.ID,
+		Amount:        50,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), transfer.Fee.Amount)
+
+	// limits apply to payments, not to giving money back
+	_, err = store.UpsertAccountLimits(context.Background(), UpsertAccountLimitsParams{
+		AccountID:          merchant.ID,
+		MaxTransferAmount:  pgtype.Int8{Int64: 10, Valid: true},
+		DailyOutgoingLimit: pgtype.Int8{Int64: 1, Valid: true},
+		UpdatedBy:          merchant.Owner,
+	})
+	require.NoError(t, err)
+
+	result, err := store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: transfer.Transfer.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(50), result.Transfer.Amount)
+	require.Equal(t, merchant.Balance, result.FromAccount.Balance)
+
+	// the fee of the original transfer is kept
+	require.Zero(t, result.Fee.Amount)
+	require.Equal(t, customer.Balance-2, result.ToAccount.Balance)
+}
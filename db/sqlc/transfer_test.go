@@ -2,10 +2,10 @@ package db
 
 import (
 	"context"
-	"database/sql"
 	"testing"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/samirprakash/go-bank/util"
 	"github.com/stretchr/testify/require"
 )
@@ -18,7 +18,7 @@ func createRandomTransfer(t *testing.T) Transfer {
 	arg := CreateTransferParams{
 		FromAccountID: account1.ID,
 		ToAccountID:   account2.ID,
-		Amount:        util.RandomAmount(),
+		Amount:        util.RandomInt(1, 1000),
 	}
 
 	transfer, err := testQueries.CreateTransfer(context.Background(), arg)
@@ -51,35 +51,25 @@ func TestGetTransfer(t *testing.T) {
 	require.WithinDuration(t, transfer1.CreatedAt, transfer2.CreatedAt, time.Second)
 }
 
-func TestUpdateTransfer(t *testing.T) {
+func TestTransferImmutable(t *testing.T) {
 	transfer1 := createRandomTransfer(t)
 
-	arg := UpdateTransferParams{
-		ID:     transfer1.ID,
-		Amount: util.RandomAmount(),
-	}
+	// transfers can only be compensated by a reversal
+	_, err := testDB.Exec(context.Background(), "UPDATE transfers SET amount = amount + 1 WHERE id = $1", transfer1.ID)
+	requireRestrictViolation(t, err)
 
-	transfer2, err := testQueries.UpdateTransfer(context.Background(), arg)
-	require.NoError(t, err)
-	require.NotEmpty(t, transfer2)
+	_, err = testDB.Exec(context.Background(), "DELETE FROM transfers WHERE id = $1", transfer1.ID)
+	requireRestrictViolation(t, err)
 
-	require.Equal(t, transfer1.ID, transfer2.ID)
-	require.Equal(t, transfer1.FromAccountID, transfer2.FromAccountID)
-	require.Equal(t, transfer1.ToAccountID, transfer2.ToAccountID)
-	require.Equal(t, arg.Amount, transfer2.Amount)
-	require.WithinDuration(t, transfer1.CreatedAt, transfer2.CreatedAt, time.Second)
-}
-
-func TestDeleteTransfer(t *testing.T) {
-	transfer1 := createRandomTransfer(t)
-
-	err := testQueries.DeleteTransfer(context.Background(), transfer1.ID)
+	transfer2, err := testQueries.GetTransfer(context.Background(), transfer1.ID)
 	require.NoError(t, err)
+	require.Equal(t, transfer1.Amount, transfer2.Amount)
+}
 
-	transfer2, err := testQueries.GetTransfer(context.Background(), transfer1.ID)
-	require.Error(t, err)
-	require.EqualError(t, err, sql.ErrNoRows.Error())
-	require.Empty(t, transfer2)
+func requireRestrictViolation(t *testing.T, err error) {
+	var pgErr *pgconn.PgError
+	require.ErrorAs(t, err, &pgErr)
+	require.Equal(t, "23001", pgErr.Code) // restrict_violation
 }
 
 func TestListTransfers(t *testing.T) {
@@ -0,0 +1,67 @@
+package db
+
+import (
+	"context"
+)
+
+// Types of the events delivered to webhook subscriptions
+const (
+	EventTransferCreated = "transfer.created"
+	EventAccountCreated  = "account.created"
+	EventAccountFrozen   = "account.frozen"
+	EventAccountUnfrozen = "account.unfrozen"
+	EventAccountClosed   = "account.closed"
+)
+
+// EventTypes lists the types of events a webhook can subscribe to
+var EventTypes = []string{
+	EventTransferCreated,
+	EventAccountCreated,
+	EventAccountFrozen,
+	EventAccountUnfrozen,
+	EventAccountClosed,
+}
+
+// Statuses of a webhook delivery
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// transferEvent is the data of a transfer.created event
+type transferEvent struct {
+	Transfer
+	Currency string `json:"currency"`
+}
+
+// accountStatusEvent returns the event type of a change of account status
+func accountStatusEvent(from, to string) string {
+	switch {
+	case to == AccountFrozen:
+		return EventAccountFrozen
+	case to == AccountClosed:
+		return EventAccountClosed
+	case from == AccountFrozen && to == AccountActive:
+		return EventAccountUnfrozen
+	}
+	return ""
+}
+
+// CreateAccountTx creates an account, records the account.created event and notifies the webhooks of its owner
+// in the same transaction
+func (store *txStore) CreateAccountTx(ctx context.Context, arg CreateAccountParams) (Account, error) {
+	var account Account
+
+	err := store.execTx(ctx, nil, func(q Querier) error {
+		var err error
+		account, err = q.CreateAccount(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		return recordEvent(ctx, q, EventAccountCreated, []string{AccountPartition(account.ID)}, []string{account.Owner}, account)
+	})
+
+	return account, err
+}
@@ -0,0 +1,171 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/samirprakash/go-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomWebhook(t *testing.T, owner string, eventTypes ...string) WebhookSubscription {
+	arg := CreateWebhookSubscriptionParams{
+		Owner:      owner,
+		Url:        "https://example.com/" + util.RandomString(6),
+		Secret:     util.RandomString(32),
+		EventTypes: eventTypes,
+	}
+
+	subscription, err := testQueries.CreateWebhookSubscription(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Owner, subscription.Owner)
+	require.Equal(t, arg.Url, subscription.Url)
+	require.Equal(t, arg.EventTypes, subscription.EventTypes)
+	return subscription
+}
+
+func TestCreateWebhookSubscriptionUnknownEvent(t *testing.T) {
+	account := createRandomAccount(t)
+
+	_, err := testQueries.CreateWebhookSubscription(context.Background(), CreateWebhookSubscriptionParams{
+		Owner:      account.Owner,
+		Url:        "https://example.com/hooks",
+		Secret:     util.RandomString(32),
+		EventTypes: []string{EventTransferCreated, "transfer.deleted"},
+	})
+	require.Error(t, err)
+}
+
+func TestTransferTxWebhooks(t *testing.T) {
+	store := NewStore(testDB)
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccountInCurrency(t, account1.Currency)
+
+	sender := createRandomWebhook(t, account1.Owner, EventTransferCreated)
+	recipient := createRandomWebhook(t, account2.Owner, EventTransferCreated, EventAccountFrozen)
+	unsubscribed := createRandomWebhook(t, account1.Owner, EventAccountCreated)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	// both owners are notified of the same event
+	var eventIDs []string
+	for _, subscription := range []WebhookSubscription{sender, recipient} {
+		deliveries, err := testQueries.ListWebhookDeliveries(context.Background(), ListWebhookDeliveriesParams{
+			SubscriptionID: subscription.ID,
+			Limit:          10,
+		})
+		require.NoError(t, err)
+		require.Len(t, deliveries, 1)
+		require.Equal(t, EventTransferCreated, deliveries[0].EventType)
+		require.Equal(t, WebhookDeliveryPending, deliveries[0].Status)
+
+		var event struct {
+			ID   string `json:"id"`
+			Type string `json:"type"`
+			Data struct {
+				ID       int64  `json:"id"`
+				Currency string `json:"currency"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(deliveries[0].Payload, &event))
+		require.Equal(t, deliveries[0].EventID.String(), event.ID)
+		require.Equal(t, result.Transfer.ID, event.Data.ID)
+		require.Equal(t, account1.Currency, event.Data.Currency)
+		eventIDs = append(eventIDs, event.ID)
+	}
+	require.Equal(t, eventIDs[0], eventIDs[1])
+
+	deliveries, err := testQueries.ListWebhookDeliveries(context.Background(), ListWebhookDeliveriesParams{
+		SubscriptionID: unsubscribed.ID,
+		Limit:          10,
+	})
+	require.NoError(t, err)
+	require.Empty(t, deliveries)
+}
+
+func TestCreateAccountTxWebhook(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	subscription := createRandomWebhook(t, user.Username, EventAccountCreated)
+
+	account, err := store.CreateAccountTx(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Currency: util.RandomCurrency(),
+		Type:     AccountChecking,
+	})
+	require.NoError(t, err)
+
+	deliveries, err := testQueries.ListWebhookDeliveries(context.Background(), ListWebhookDeliveriesParams{
+		SubscriptionID: subscription.ID,
+		Limit:          10,
+	})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	require.Equal(t, EventAccountCreated, deliveries[0].EventType)
+	require.Contains(t, string(deliveries[0].Payload), `"owner":"`+account.Owner+`"`)
+}
+
+func TestClaimWebhookDeliveries(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	subscription := createRandomWebhook(t, user.Username, EventAccountCreated)
+
+	_, err := store.CreateAccountTx(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Currency: util.RandomCurrency(),
+		Type:     AccountChecking,
+	})
+	require.NoError(t, err)
+
+	// claim far enough in the future to get the delivery whatever else is pending
+	now := time.Now().Add(time.Hour)
+	var claimed *ClaimWebhookDeliveriesRow
+	for claimed == nil {
+		deliveries, err := testQueries.ClaimWebhookDeliveries(context.Background(), ClaimWebhookDeliveriesParams{
+			LeaseUntil: now.Add(time.Minute),
+			Now:        now,
+			LimitCount: 100,
+		})
+		require.NoError(t, err)
+		require.NotEmpty(t, deliveries)
+
+		for i := range deliveries {
+			if deliveries[i].SubscriptionID == subscription.ID {
+				claimed = &deliveries[i]
+			}
+		}
+	}
+	require.Equal(t, subscription.Url, claimed.Url)
+	require.Equal(t, subscription.Secret, claimed.Secret)
+
+	// a leased delivery is not claimed again before the lease ends
+	deliveries, err := testQueries.ClaimWebhookDeliveries(context.Background(), ClaimWebhookDeliveriesParams{
+		LeaseUntil: now.Add(time.Minute),
+		Now:        now,
+		LimitCount: 100,
+	})
+	require.NoError(t, err)
+	for _, delivery := range deliveries {
+		require.NotEqual(t, claimed.ID, delivery.ID)
+	}
+
+	delivery, err := testQueries.RecordWebhookDeliveryAttempt(context.Background(), RecordWebhookDeliveryAttemptParams{
+		ID:             claimed.ID,
+		Status:         WebhookDeliveryPending,
+		NextAttemptAt:  now.Add(time.Minute),
+		ResponseStatus: pgtype.Int4{Int32: 503, Valid: true},
+		Error:          "unexpected response status 503",
+	})
+	require.NoError(t, err)
+	require.Equal(t, int32(1), delivery.Attempts)
+	require.True(t, delivery.LastAttemptAt.Valid)
+	require.Equal(t, int32(503), delivery.ResponseStatus.Int32)
+}
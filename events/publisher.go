@@ -0,0 +1,165 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/samirprakash/go-bank/db/sqlc"
+)
+
+// Message is an event of the outbox as published to the event stream
+type Message struct {
+	// ID is the same for every partition of an event, consumers deduplicate on it
+	ID uuid.UUID `json:"id"`
+	// Sequence increases with every event of a partition
+	Sequence  int64           `json:"sequence"`
+	Type      string          `json:"type"`
+	Key       string          `json:"key"`
+	CreatedAt time.Time       `json:"created_at"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// NewMessage returns the message of an event of the outbox
+func NewMessage(event db.Event) Message {
+	return Message{
+		ID:        event.EventID,
+		Sequence:  event.ID,
+		Type:      event.EventType,
+		Key:       event.PartitionKey,
+		CreatedAt: event.CreatedAt,
+		Payload:   event.Payload,
+	}
+}
+
+// Publisher sends messages to the event stream.
+// Publish returns once the stream acknowledged the message, and is called with the messages of a key in order,
+// so a publisher must keep that order, e.g. by sending a key to a single Kafka partition or NATS subject.
+// A message can be published more than once.
+type Publisher interface {
+	Publish(ctx context.Context, msg Message) error
+	Close() error
+}
+
+// Handler processes a message published in-process
+type Handler func(ctx context.Context, msg Message) error
+
+// LocalPublisher hands the messages to in-process handlers, in the order they are published.
+// A handler error fails the publish, so the message is published again later.
+type LocalPublisher struct {
+	mu       sync.RWMutex
+	handlers []Handler
+}
+
+// NewLocalPublisher creates a publisher without handlers
+func NewLocalPublisher() *LocalPublisher {
+	return &LocalPublisher{}
+}
+
+// Subscribe adds a handler called with every message published from now on
+func (publisher *LocalPublisher) Subscribe(handler Handler) {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	publisher.handlers = append(publisher.handlers, handler)
+}
+
+func (publisher *LocalPublisher) Publish(ctx context.Context, msg Message) error {
+	publisher.mu.RLock()
+	defer publisher.mu.RUnlock()
+
+	for _, handler := range publisher.handlers {
+		if err := handler(ctx, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (publisher *LocalPublisher) Close() error {
+	return nil
+}
+
+// FilePublisher appends the messages to a file as JSON lines, synced to disk before acknowledging them
+type FilePublisher struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFilePublisher opens or creates the file to append messages to
+func NewFilePublisher(path string) (*FilePublisher, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &FilePublisher{file: file}, nil
+}
+
+func (publisher *FilePublisher) Publish(ctx context.Context, msg Message) error {
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	if _, err := publisher.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return publisher.file.Sync()
+}
+
+func (publisher *FilePublisher) Close() error {
+	return publisher.file.Close()
+}
+
+// Broker is the part of a NATS JetStream or Kafka producer client needed to publish events:
+// a send acknowledged by the broker of a keyed record with headers to a topic.
+// With Kafka the key selects the partition, with NATS the topic and key make the subject.
+type Broker interface {
+	Send(ctx context.Context, topic string, key string, value []byte, headers map[string]string) error
+	Close() error
+}
+
+// Headers of the records sent to a broker
+const (
+	HeaderEventID   = "event-id"
+	HeaderEventType = "event-type"
+	HeaderSequence  = "sequence"
+)
+
+// BrokerPublisher publishes the messages to a topic of a broker, keyed by their partition key.
+// The event id is sent as a header, for the broker or the consumers to deduplicate.
+type BrokerPublisher struct {
+	broker Broker
+	topic  string
+}
+
+// NewBrokerPublisher creates a publisher sending to a topic of a broker
+func NewBrokerPublisher(broker Broker, topic string) *BrokerPublisher {
+	return &BrokerPublisher{broker: broker, topic: topic}
+}
+
+func (publisher *BrokerPublisher) Publish(ctx context.Context, msg Message) error {
+	headers := map[string]string{
+		HeaderEventID:   msg.ID.String(),
+		HeaderEventType: msg.Type,
+		HeaderSequence:  strconv.FormatInt(msg.Sequence, 10),
+	}
+
+	err := publisher.broker.Send(ctx, publisher.topic, msg.Key, msg.Payload, headers)
+	if err != nil {
+		return fmt.Errorf("cannot send event %s : %w", msg.ID, err)
+	}
+	return nil
+}
+
+func (publisher *BrokerPublisher) Close() error {
+	return publisher.broker.Close()
+}
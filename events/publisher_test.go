@@ -0,0 +1,146 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/samirprakash/go-bank/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func randomMessage(key string, sequence int64) Message {
+	return Message{
+		ID:        uuid.New(),
+		Sequence:  sequence,
+		Type:      db.EventTransferCreated,
+		Key:       key,
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+		Payload:   json.RawMessage(`{"type":"transfer.created"}`),
+	}
+}
+
+func TestNewMessage(t *testing.T) {
+	event := db.Event{
+		ID:           7,
+		EventID:      uuid.New(),
+		EventType:    db.EventAccountCreated,
+		PartitionKey: db.AccountPartition(3),
+		Payload:      json.RawMessage(`{}`),
+		CreatedAt:    time.Now(),
+	}
+
+	msg := NewMessage(event)
+	require.Equal(t, event.EventID, msg.ID)
+	require.Equal(t, int64(7), msg.Sequence)
+	require.Equal(t, "account:3", msg.Key)
+	require.Equal(t, event.EventType, msg.Type)
+}
+
+func TestLocalPublisher(t *testing.T) {
+	publisher := NewLocalPublisher()
+
+	var received []Message
+	publisher.Subscribe(func(ctx context.Context, msg Message) error {
+		received = append(received, msg)
+		return nil
+	})
+
+	msg1 := randomMessage("account:1", 1)
+	msg2 := randomMessage("account:1", 2)
+	require.NoError(t, publisher.Publish(context.Background(), msg1))
+	require.NoError(t, publisher.Publish(context.Background(), msg2))
+	require.Equal(t, []Message{msg1, msg2}, received)
+
+	// a failing handler fails the publish so the message is published again
+	errHandler := errors.New("handler failed")
+	publisher.Subscribe(func(ctx context.Context, msg Message) error {
+		return errHandler
+	})
+	require.ErrorIs(t, publisher.Publish(context.Background(), msg1), errHandler)
+	require.NoError(t, publisher.Close())
+}
+
+func TestFilePublisher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+
+	publisher, err := NewFilePublisher(path)
+	require.NoError(t, err)
+
+	msg1 := randomMessage("account:1", 1)
+	msg2 := randomMessage("user:alice", 2)
+	require.NoError(t, publisher.Publish(context.Background(), msg1))
+	require.NoError(t, publisher.Close())
+
+	// messages are appended to an existing file
+	publisher, err = NewFilePublisher(path)
+	require.NoError(t, err)
+	require.NoError(t, publisher.Publish(context.Background(), msg2))
+	require.NoError(t, publisher.Close())
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var messages []Message
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var msg Message
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &msg))
+		messages = append(messages, msg)
+	}
+	require.NoError(t, scanner.Err())
+	require.Len(t, messages, 2)
+	require.Equal(t, msg1.ID, messages[0].ID)
+	require.Equal(t, msg2.Key, messages[1].Key)
+	require.JSONEq(t, string(msg2.Payload), string(messages[1].Payload))
+}
+
+type record struct {
+	topic   string
+	key     string
+	value   []byte
+	headers map[string]string
+}
+
+type fakeBroker struct {
+	records []record
+	err     error
+}
+
+func (broker *fakeBroker) Send(ctx context.Context, topic string, key string, value []byte, headers map[string]string) error {
+	if broker.err != nil {
+		return broker.err
+	}
+	broker.records = append(broker.records, record{topic, key, value, headers})
+	return nil
+}
+
+func (broker *fakeBroker) Close() error {
+	return nil
+}
+
+func TestBrokerPublisher(t *testing.T) {
+	broker := &fakeBroker{}
+	publisher := NewBrokerPublisher(broker, "bank.events")
+
+	msg := randomMessage("account:1", 42)
+	require.NoError(t, publisher.Publish(context.Background(), msg))
+
+	require.Len(t, broker.records, 1)
+	require.Equal(t, "bank.events", broker.records[0].topic)
+	require.Equal(t, "account:1", broker.records[0].key)
+	require.Equal(t, []byte(msg.Payload), broker.records[0].value)
+	require.Equal(t, msg.ID.String(), broker.records[0].headers[HeaderEventID])
+	require.Equal(t, msg.Type, broker.records[0].headers[HeaderEventType])
+	require.Equal(t, "42", broker.records[0].headers[HeaderSequence])
+
+	broker.err = errors.New("broker unavailable")
+	require.ErrorIs(t, publisher.Publish(context.Background(), msg), broker.err)
+}
@@ -0,0 +1,87 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	db "github.com/samirprakash/go-bank/db/sqlc"
+	"github.com/samirprakash/go-bank/metrics"
+)
+
+// Relay publishes the events of the outbox, written in the same transactions as the changes they describe.
+// Events are published at least once and in order within a partition: a failed event holds back
+// the following events of its partition until it is published.
+type Relay struct {
+	store     db.Store
+	publisher Publisher
+	interval  time.Duration
+	batchSize int32
+}
+
+// NewRelay creates a relay publishing the outbox every interval, batchSize events at a time
+func NewRelay(store db.Store, publisher Publisher, interval time.Duration, batchSize int32) *Relay {
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+
+	return &Relay{
+		store:     store,
+		publisher: publisher,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+// Run publishes the outbox every interval until the context is done
+func (relay *Relay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(relay.interval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			n, err := relay.Publish(ctx)
+			if err != nil {
+				log.Ctx(ctx).Error().Err(err).Msg("cannot publish events")
+				break
+			}
+
+			// keep going while there may be more events to publish
+			if n < int(relay.batchSize) {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Ctx(ctx).Info().Msg("event relay is stopped")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Publish publishes one batch of events and returns how many were published
+func (relay *Relay) Publish(ctx context.Context) (int, error) {
+	result, err := relay.store.PublishEventsTx(ctx, db.PublishEventsTxParams{
+		Limit: relay.batchSize,
+		Publish: func(ctx context.Context, event db.Event) error {
+			err := relay.publisher.Publish(ctx, NewMessage(event))
+			if err != nil {
+				log.Ctx(ctx).Warn().Err(err).
+					Int64("event_id", event.ID).
+					Str("partition_key", event.PartitionKey).
+					Msg("cannot publish event, holding back its partition")
+				metrics.EventPublished(metrics.EventPublishFailed)
+				return err
+			}
+			metrics.EventPublished(metrics.EventPublishSucceeded)
+			return nil
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.Published, nil
+}
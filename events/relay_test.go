@@ -0,0 +1,61 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	mockdb "github.com/samirprakash/go-bank/db/mock"
+	db "github.com/samirprakash/go-bank/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelayPublish(t *testing.T) {
+	events := []db.Event{
+		{ID: 1, EventID: uuid.New(), EventType: db.EventAccountCreated, PartitionKey: "account:1", Payload: json.RawMessage(`{}`), CreatedAt: time.Now()},
+		{ID: 2, EventID: uuid.New(), EventType: db.EventTransferCreated, PartitionKey: "account:2", Payload: json.RawMessage(`{}`), CreatedAt: time.Now()},
+		{ID: 3, EventID: uuid.New(), EventType: db.EventDepositCreated, PartitionKey: "account:1", Payload: json.RawMessage(`{}`), CreatedAt: time.Now()},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		PublishEventsTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, arg db.PublishEventsTxParams) (db.PublishEventsTxResult, error) {
+			require.Equal(t, int32(10), arg.Limit)
+
+			result := db.PublishEventsTxResult{Events: len(events)}
+			for _, event := range events {
+				if arg.Publish(ctx, event) == nil {
+					result.Published++
+				}
+			}
+			return result, nil
+		})
+
+	publisher := NewLocalPublisher()
+	var received []Message
+	publisher.Subscribe(func(ctx context.Context, msg Message) error {
+		if msg.Key == "account:2" {
+			return errors.New("unavailable")
+		}
+		received = append(received, msg)
+		return nil
+	})
+
+	relay := NewRelay(store, publisher, time.Second, 10)
+	n, err := relay.Publish(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	require.Len(t, received, 2)
+	require.Equal(t, int64(1), received[0].Sequence)
+	require.Equal(t, int64(3), received[1].Sequence)
+}
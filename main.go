@@ -1,40 +1,443 @@
 package main
 
 import (
-	"database/sql"
-	"log"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
-	_ "github.com/golang/mock/mockgen/model"
-	_ "github.com/lib/pq"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
 	"github.com/samirprakash/go-bank/api"
+	"github.com/samirprakash/go-bank/db/migration"
 	db "github.com/samirprakash/go-bank/db/sqlc"
+	"github.com/samirprakash/go-bank/events"
+	"github.com/samirprakash/go-bank/metrics"
+	"github.com/samirprakash/go-bank/realtime"
+	"github.com/samirprakash/go-bank/scheduler"
+	"github.com/samirprakash/go-bank/tracing"
 	"github.com/samirprakash/go-bank/util"
+	"github.com/samirprakash/go-bank/webhook"
+	"golang.org/x/sync/errgroup"
 )
 
+// interruptSignals are the signals that trigger a graceful shutdown
+var interruptSignals = []os.Signal{
+	os.Interrupt,
+	syscall.SIGTERM,
+}
+
 func main() {
+	demo := flag.Bool("demo", false, "run without a database, keeping the data in memory until the server stops")
+	flag.Parse()
+
 	// load config from file or env vars using viper
 	config, err := util.LoadConfig(".")
 	if err != nil {
-		log.Fatal("Not able to load config", err)
+		log.Fatal().Err(err).Msg("Not able to load config")
+	}
+
+	setupLogger(config)
+
+	// cancel the context when an interrupt signal is received
+	ctx, stop := signal.NotifyContext(context.Background(), interruptSignals...)
+	defer stop()
+
+	// export traces if an exporter is configured
+	shutdownTracing, err := tracing.Setup(ctx, config)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot setup tracing")
+	}
+
+	// keep the data in memory for a demo, or in the database
+	var store db.Store
+	var migrator *migration.Migrator
+	var replicas *db.Replicas
+	closeDB := func() {}
+	if *demo {
+		log.Warn().Msg("demo mode : data is kept in memory and lost on exit")
+		store = db.NewMemStore()
+	} else {
+		store, migrator, replicas, closeDB = openSQLStore(ctx, config)
+	}
+	store = db.NewInstrumentedStore(store, tracing.TraceQueries, db.LogQueries, metrics.ObserveQueries)
+
+	// run the server and background workers until the context is cancelled
+	waitGroup, ctx := errgroup.WithContext(ctx)
+	var hub *realtime.Hub
+	if *demo {
+		// account updates are notified by the database
+		log.Info().Msg("account streams are disabled in demo mode")
+	} else {
+		hub = runAccountListener(ctx, waitGroup, config)
+	}
+	runHTTPServer(ctx, waitGroup, config, store, migrator, hub)
+	runReplicaMonitor(ctx, waitGroup, config, replicas)
+	runScheduler(ctx, waitGroup, config, store)
+	runHoldSweeper(ctx, waitGroup, config, store)
+	runInterestAccruer(ctx, waitGroup, config, store)
+	runWebhookDispatcher(ctx, waitGroup, config, store)
+	runEventRelay(ctx, waitGroup, config, store)
+
+	err = waitGroup.Wait()
+	if err != nil {
+		log.Fatal().Err(err).Msg("error from wait group")
+	}
+
+	// flush the spans that have not been exported yet
+	err = shutdownTracing(context.Background())
+	if err != nil {
+		log.Error().Err(err).Msg("cannot flush traces")
+	}
+
+	// close the database once nothing is using it anymore
+	closeDB()
+}
+
+// openSQLStore connects to the database and its replicas, migrates it if enabled and returns a store on it,
+// the migrator reporting the schema version, the replicas to monitor if any and the function closing the connections.
+func openSQLStore(ctx context.Context, config util.Config) (db.Store, *migration.Migrator, *db.Replicas, func()) {
+	// create the connection pool of the database
+	poolConfig, err := newPoolConfig(config, config.DBSource)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid database configuration")
+	}
+
+	connPool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot connect to database")
+	}
+
+	// the pool connects lazily, so wait until the database actually answers
+	err = waitForDB(ctx, connPool, config.DBConnectTimeout)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot reach database")
 	}
 
-	// connect to the database
-	conn, err := sql.Open(config.DBDriver, config.DBSource)
+	// apply the embedded schema migrations if enabled
+	migrator := migration.NewMigrator(connPool, config.MigrationURL)
+	if config.AutoMigrate {
+		err = migrator.Up(ctx)
+		if err != nil {
+			log.Fatal().Err(err).Msg("cannot migrate database")
+		}
+		log.Info().Msg("db migrated successfully")
+	}
+
+	// expose the connection pool stats to prometheus
+	err = metrics.RegisterPoolStats(connPool, "simple_bank")
 	if err != nil {
-		log.Fatal("cannot connect to database", err)
+		log.Fatal().Err(err).Msg("cannot register database metrics")
 	}
 
 	// create a database store for executing queries
-	store := db.NewStore(conn)
-	// create a server connected to the store
-	server, err := api.NewServer(config, store)
+	storeOptions := []db.Option{
+		db.WithMaxTxRetries(config.DBTxMaxRetries),
+		db.WithTxRetryObserver(metrics.TxRetried),
+	}
+
+	// serve reads from the replicas, if any
+	replicaPools := openReplicaPools(ctx, config)
+	var replicas *db.Replicas
+	if len(replicaPools) > 0 {
+		replicas = db.NewReplicas(connPool, replicaPools, config.DBReplicaInterval, config.DBReplicaMaxLag)
+		storeOptions = append(storeOptions, db.WithReplicas(replicas))
+	}
+
+	closeDB := func() {
+		for _, replicaPool := range replicaPools {
+			replicaPool.Close()
+		}
+		connPool.Close()
+		log.Info().Msg("database connection pool is closed")
+	}
+
+	return db.NewStore(connPool, storeOptions...), migrator, replicas, closeDB
+}
+
+// setupLogger configures the global logger.
+// Logs are written as JSON except in development where they are pretty printed.
+func setupLogger(config util.Config) {
+	level, err := zerolog.ParseLevel(config.LogLevel)
+	if err != nil || config.LogLevel == "" {
+		level = zerolog.InfoLevel
+	}
+	zerolog.SetGlobalLevel(level)
+
+	if config.Environment == "development" {
+		log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	}
+
+	// calls made outside of a request still get a logger from their context
+	zerolog.DefaultContextLogger = &log.Logger
+}
+
+// newPoolConfig returns the connection pool settings of a database source sized and aged with the DB_* settings.
+// Settings left to zero keep the defaults of pgxpool.
+func newPoolConfig(config util.Config, source string) (*pgxpool.Config, error) {
+	poolConfig, err := pgxpool.ParseConfig(source)
 	if err != nil {
-		log.Fatal("cannot create server : ", err)
+		// the source is not part of the error as it holds a password
+		return nil, fmt.Errorf("cannot parse database source : %w", err)
+	}
+
+	if config.DBMaxConns > 0 {
+		poolConfig.MaxConns = config.DBMaxConns
+	}
+	if config.DBMinConns > 0 {
+		poolConfig.MinConns = config.DBMinConns
+	}
+	if poolConfig.MinConns > poolConfig.MaxConns {
+		return nil, fmt.Errorf("DB_MIN_CONNS %d is above DB_MAX_CONNS %d", poolConfig.MinConns, poolConfig.MaxConns)
+	}
+	if config.DBMaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = config.DBMaxConnLifetime
+	}
+	if config.DBMaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = config.DBMaxConnIdleTime
+	}
+	if config.DBHealthCheckPeriod > 0 {
+		poolConfig.HealthCheckPeriod = config.DBHealthCheckPeriod
+	}
+
+	return poolConfig, nil
+}
+
+// openReplicaPools opens a connection pool to each replica of DB_REPLICA_SOURCES, sized like the pool of the primary.
+// The pools connect lazily and the replicas serve no read until they are checked, so a replica may be down at startup.
+func openReplicaPools(ctx context.Context, config util.Config) []*pgxpool.Pool {
+	if len(config.DBReplicaSources) == 0 {
+		return nil
+	}
+
+	if config.DBReplicaInterval <= 0 || config.DBReplicaMaxLag <= config.DBReplicaInterval {
+		log.Fatal().Msgf("DB_REPLICA_MAX_LAG %s must be above DB_REPLICA_CHECK_INTERVAL %s", config.DBReplicaMaxLag, config.DBReplicaInterval)
+	}
+
+	var replicaPools []*pgxpool.Pool
+	for i, source := range config.DBReplicaSources {
+		poolConfig, err := newPoolConfig(config, source)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("invalid configuration of replica %d", i)
+		}
+
+		replicaPool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("cannot connect to replica %d", i)
+		}
+
+		err = metrics.RegisterPoolStats(replicaPool, fmt.Sprintf("simple_bank_replica_%d", i))
+		if err != nil {
+			log.Fatal().Err(err).Msg("cannot register replica metrics")
+		}
+
+		replicaPools = append(replicaPools, replicaPool)
 	}
 
-	// start the server
-	err = server.Start(config.ServerAddress)
+	return replicaPools
+}
+
+// waitForDB pings the database with an exponential backoff until it answers or the timeout expires.
+// This lets the server start before the database container is ready.
+func waitForDB(ctx context.Context, connPool *pgxpool.Pool, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	backoff := 500 * time.Millisecond
+	const maxBackoff = 5 * time.Second
+
+	for {
+		err := connPool.Ping(ctx)
+		if err == nil {
+			return nil
+		}
+
+		log.Warn().Err(err).Msgf("database is not ready, retrying in %s", backoff)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("database not reachable after %s : %w", timeout, err)
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// runHTTPServer starts the HTTP server and shuts it down gracefully once the context is done
+func runHTTPServer(ctx context.Context, waitGroup *errgroup.Group, config util.Config, store db.Store, migrator *migration.Migrator, hub *realtime.Hub) {
+	// create a server connected to the store
+	server, err := api.NewServer(config, store, migrator, hub)
 	if err != nil {
-		log.Fatal("cannot start server", err)
+		log.Fatal().Err(err).Msg("cannot create server")
+	}
+
+	waitGroup.Go(func() error {
+		log.Info().Msgf("start HTTP server at %s", config.ServerAddress)
+		err := server.Start()
+		if err != nil {
+			return fmt.Errorf("cannot start server : %w", err)
+		}
+		return nil
+	})
+
+	waitGroup.Go(func() error {
+		<-ctx.Done()
+		log.Info().Msg("graceful shutdown HTTP server")
+
+		// drain in-flight requests without waiting forever
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+		defer cancel()
+
+		err := server.Shutdown(shutdownCtx)
+		if err != nil {
+			return fmt.Errorf("failed to shutdown HTTP server : %w", err)
+		}
+
+		log.Info().Msg("HTTP server is stopped")
+		return nil
+	})
+}
+
+// runReplicaMonitor checks how far behind the primary the replicas are, to only read from the fresh ones.
+// Without DB_REPLICA_SOURCES, replicas is nil and every read goes to the primary.
+func runReplicaMonitor(ctx context.Context, waitGroup *errgroup.Group, config util.Config, replicas *db.Replicas) {
+	if replicas == nil {
+		log.Info().Msg("read replicas are disabled")
+		return
+	}
+
+	waitGroup.Go(func() error {
+		log.Info().Msgf("start replica monitor of %d replicas every %s", len(config.DBReplicaSources), config.DBReplicaInterval)
+		return replicas.Run(ctx)
+	})
+}
+
+// runAccountListener forwards the account updates notified by the database to a hub streaming them to the clients.
+// Setting STREAM_BUFFER_SIZE to 0 disables account streams, in which case the returned hub is nil.
+func runAccountListener(ctx context.Context, waitGroup *errgroup.Group, config util.Config) *realtime.Hub {
+	if config.StreamBufferSize <= 0 {
+		log.Info().Msg("account streams are disabled")
+		return nil
+	}
+
+	hub := realtime.NewHub(config.StreamBufferSize)
+	listener := realtime.NewListener(config.DBSource, hub)
+
+	waitGroup.Go(func() error {
+		log.Info().Msgf("start account updates listener on channel %s", realtime.Channel)
+		return listener.Run(ctx)
+	})
+
+	return hub
+}
+
+// runScheduler executes the scheduled transfers in the background until the context is done.
+// Setting SCHEDULER_INTERVAL to 0 disables it, e.g. when a dedicated instance runs the scheduler.
+func runScheduler(ctx context.Context, waitGroup *errgroup.Group, config util.Config, store db.Store) {
+	if config.SchedulerInterval <= 0 {
+		log.Info().Msg("scheduler is disabled")
+		return
+	}
+
+	s := scheduler.New(store, config.SchedulerInterval, config.SchedulerBatchSize)
+
+	waitGroup.Go(func() error {
+		log.Info().Msgf("start scheduler every %s", config.SchedulerInterval)
+		return s.Run(ctx)
+	})
+}
+
+// runHoldSweeper expires the holds that were neither captured nor voided in time.
+// Setting HOLD_SWEEP_INTERVAL to 0 disables it.
+func runHoldSweeper(ctx context.Context, waitGroup *errgroup.Group, config util.Config, store db.Store) {
+	if config.HoldSweepInterval <= 0 {
+		log.Info().Msg("hold sweeper is disabled")
+		return
+	}
+
+	sweeper := scheduler.NewHoldSweeper(store, config.HoldSweepInterval, config.SchedulerBatchSize)
+
+	waitGroup.Go(func() error {
+		log.Info().Msgf("start hold sweeper every %s", config.HoldSweepInterval)
+		return sweeper.Run(ctx)
+	})
+}
+
+// runInterestAccruer accrues daily interest on savings accounts and posts it monthly.
+// Setting INTEREST_INTERVAL to 0 disables it.
+func runInterestAccruer(ctx context.Context, waitGroup *errgroup.Group, config util.Config, store db.Store) {
+	if config.InterestInterval <= 0 {
+		log.Info().Msg("interest accruer is disabled")
+		return
 	}
+
+	accruer := scheduler.NewInterestAccruer(store, config.InterestInterval, config.SchedulerBatchSize)
+
+	waitGroup.Go(func() error {
+		log.Info().Msgf("start interest accruer every %s", config.InterestInterval)
+		return accruer.Run(ctx)
+	})
+}
+
+// runWebhookDispatcher delivers the events to the webhook subscriptions, retrying failed deliveries.
+// Setting WEBHOOK_INTERVAL to 0 disables it.
+func runWebhookDispatcher(ctx context.Context, waitGroup *errgroup.Group, config util.Config, store db.Store) {
+	if config.WebhookInterval <= 0 {
+		log.Info().Msg("webhook dispatcher is disabled")
+		return
+	}
+
+	dispatcher := webhook.NewDispatcher(store, config.WebhookInterval, config.SchedulerBatchSize, config.WebhookMaxAttempts, config.WebhookTimeout)
+
+	waitGroup.Go(func() error {
+		log.Info().Msgf("start webhook dispatcher every %s", config.WebhookInterval)
+		return dispatcher.Run(ctx)
+	})
+}
+
+// runEventRelay publishes the events of the outbox to the publisher of EVENT_PUBLISHER :
+// "file" appends them to EVENT_FILE_PATH and "local" logs them in-process.
+// Leaving EVENT_PUBLISHER empty or setting EVENT_RELAY_INTERVAL to 0 disables it, events waiting in the outbox.
+func runEventRelay(ctx context.Context, waitGroup *errgroup.Group, config util.Config, store db.Store) {
+	if config.EventPublisher == "" || config.EventRelayInterval <= 0 {
+		log.Info().Msg("event relay is disabled")
+		return
+	}
+
+	var publisher events.Publisher
+	switch config.EventPublisher {
+	case "file":
+		filePublisher, err := events.NewFilePublisher(config.EventFilePath)
+		if err != nil {
+			log.Fatal().Err(err).Msg("cannot open event file")
+		}
+		publisher = filePublisher
+	case "local":
+		localPublisher := events.NewLocalPublisher()
+		localPublisher.Subscribe(func(ctx context.Context, msg events.Message) error {
+			log.Ctx(ctx).Info().Str("event_type", msg.Type).Str("key", msg.Key).Int64("sequence", msg.Sequence).Msg("event published")
+			return nil
+		})
+		publisher = localPublisher
+	default:
+		log.Fatal().Msgf("unknown event publisher %q", config.EventPublisher)
+	}
+
+	relay := events.NewRelay(store, publisher, config.EventRelayInterval, config.SchedulerBatchSize)
+
+	waitGroup.Go(func() error {
+		log.Info().Msgf("start %s event relay every %s", config.EventPublisher, config.EventRelayInterval)
+		err := relay.Run(ctx)
+		if closeErr := publisher.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	})
 }
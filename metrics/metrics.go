@@ -0,0 +1,227 @@
+// Package metrics defines the Prometheus metrics exposed by the bank on /metrics
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "gobank"
+
+var (
+	httpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of HTTP requests handled by route, method and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	dbCallDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_call_duration_seconds",
+		Help:      "Latency of store calls by method and result.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "result"})
+
+	txRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_tx_retries_total",
+		Help:      "Number of database transactions retried by postgres error.",
+	}, []string{"reason"})
+
+	transfersCreated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transfers_created_total",
+		Help:      "Number of transfers created by currency.",
+	}, []string{"currency"})
+
+	transferredAmount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transferred_amount_total",
+		Help:      "Amount of money transferred in minor units by currency.",
+	}, []string{"currency"})
+
+	transfersReversed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transfers_reversed_total",
+		Help:      "Number of reversals and refunds by currency.",
+	}, []string{"currency"})
+
+	reversedAmount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reversed_amount_total",
+		Help:      "Amount of money given back by reversals in minor units by currency.",
+	}, []string{"currency"})
+
+	feesCharged = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "fees_charged_total",
+		Help:      "Amount of transfer fees charged in minor units by currency.",
+	}, []string{"currency"})
+
+	interestPaid = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "interest_paid_total",
+		Help:      "Amount of interest paid on savings accounts in minor units by currency.",
+	}, []string{"currency"})
+
+	transfersFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transfers_failed_total",
+		Help:      "Number of rejected or failed transfers by reason.",
+	}, []string{"reason"})
+
+	scheduledTransferRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "scheduled_transfer_runs_total",
+		Help:      "Number of scheduled transfer runs by status.",
+	}, []string{"status"})
+
+	holdsFinished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "holds_finished_total",
+		Help:      "Number of holds captured, voided or expired by status.",
+	}, []string{"status"})
+
+	logins = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Number of login attempts by result.",
+	}, []string{"result"})
+	webhookDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_delivery_attempts_total",
+		Help:      "Number of webhook delivery attempts by resulting delivery status.",
+	}, []string{"status"})
+	eventsPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_published_total",
+		Help:      "Number of attempts to publish an event of the outbox by result.",
+	}, []string{"result"})
+)
+
+// Reasons for a failed transfer
+const (
+	TransferFailedInvalidRequest   = "invalid_request"
+	TransferFailedAccountNotFound  = "account_not_found"
+	TransferFailedCurrencyMismatch = "currency_mismatch"
+	TransferFailedAccountInactive  = "account_inactive"
+	TransferFailedUnauthorized     = "unauthorized"
+	TransferFailedInternal         = "internal"
+)
+
+// Results of a login attempt
+const (
+	LoginSucceeded     = "succeeded"
+	LoginUserNotFound  = "user_not_found"
+	LoginWrongPassword = "wrong_password"
+	LoginFailed        = "failed"
+)
+
+// Results of publishing an event
+const (
+	EventPublishSucceeded = "succeeded"
+	EventPublishFailed    = "failed"
+)
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// RegisterPoolStats exposes the stats of the database connection pool
+func RegisterPoolStats(pool *pgxpool.Pool, dbName string) error {
+	return prometheus.Register(newPoolStatsCollector(pool, dbName))
+}
+
+// ObserveHTTPRequest records a handled HTTP request.
+// The route is the path template to keep the number of label values bounded.
+func ObserveHTTPRequest(method, route string, status int, duration time.Duration) {
+	httpRequestsTotal.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	httpRequestDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+// ObserveQueries is a db.QueryHook that times every store call
+func ObserveQueries(ctx context.Context, method string) (context.Context, func(err error)) {
+	start := time.Now()
+
+	return ctx, func(err error) {
+		result := "ok"
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			result = "error"
+		}
+		dbCallDuration.WithLabelValues(method, result).Observe(time.Since(start).Seconds())
+	}
+}
+
+// TxRetried records a database transaction retried after a serialization failure or a deadlock
+func TxRetried(ctx context.Context, reason string) {
+	txRetries.WithLabelValues(reason).Inc()
+}
+
+// TransferCreated records a successful transfer
+func TransferCreated(currency string, amount int64) {
+	transfersCreated.WithLabelValues(currency).Inc()
+	transferredAmount.WithLabelValues(currency).Add(float64(amount))
+}
+
+// TransferReversed records a successful reversal or partial refund
+func TransferReversed(currency string, amount int64) {
+	transfersReversed.WithLabelValues(currency).Inc()
+	reversedAmount.WithLabelValues(currency).Add(float64(amount))
+}
+
+// FeeCharged records the fee charged for a transfer
+func FeeCharged(currency string, amount int64) {
+	feesCharged.WithLabelValues(currency).Add(float64(amount))
+}
+
+// InterestPaid records the monthly interest paid on a savings account
+func InterestPaid(currency string, amount int64) {
+	interestPaid.WithLabelValues(currency).Add(float64(amount))
+}
+
+// TransferFailed records a transfer that was rejected or failed
+func TransferFailed(reason string) {
+	transfersFailed.WithLabelValues(reason).Inc()
+}
+
+// ScheduledTransferRun records the outcome of a scheduled transfer run
+func ScheduledTransferRun(status string) {
+	scheduledTransferRuns.WithLabelValues(status).Inc()
+}
+
+// HoldFinished records a hold leaving the active status
+func HoldFinished(status string) {
+	holdsFinished.WithLabelValues(status).Inc()
+}
+
+// Login records the result of a login attempt
+func Login(result string) {
+	logins.WithLabelValues(result).Inc()
+}
+
+// WebhookDelivery records an attempt to deliver a webhook by the resulting delivery status
+func WebhookDelivery(status string) {
+	webhookDeliveries.WithLabelValues(status).Inc()
+}
+
+// EventPublished records an attempt to publish an event of the outbox
+func EventPublished(result string) {
+	eventsPublished.WithLabelValues(result).Inc()
+}
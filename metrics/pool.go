@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// poolStatsCollector exposes the stats of a pgx connection pool, read on every scrape
+type poolStatsCollector struct {
+	pool *pgxpool.Pool
+
+	maxConns                *prometheus.Desc
+	totalConns              *prometheus.Desc
+	acquiredConns           *prometheus.Desc
+	idleConns               *prometheus.Desc
+	constructingConns       *prometheus.Desc
+	acquireCount            *prometheus.Desc
+	acquireDuration         *prometheus.Desc
+	emptyAcquireCount       *prometheus.Desc
+	canceledAcquireCount    *prometheus.Desc
+	newConnsCount           *prometheus.Desc
+	maxLifetimeDestroyCount *prometheus.Desc
+	maxIdleDestroyCount     *prometheus.Desc
+}
+
+func newPoolStatsCollector(pool *pgxpool.Pool, dbName string) *poolStatsCollector {
+	labels := prometheus.Labels{"db_name": dbName}
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, labels)
+	}
+
+	return &poolStatsCollector{
+		pool:                    pool,
+		maxConns:                desc("max_connections", "Maximum size of the connection pool."),
+		totalConns:              desc("connections", "Number of connections in the pool, acquired, idle or being opened."),
+		acquiredConns:           desc("acquired_connections", "Number of connections in use."),
+		idleConns:               desc("idle_connections", "Number of idle connections."),
+		constructingConns:       desc("constructing_connections", "Number of connections being opened."),
+		acquireCount:            desc("acquires_total", "Number of connections acquired from the pool."),
+		acquireDuration:         desc("acquire_duration_seconds_total", "Total time spent acquiring connections from the pool."),
+		emptyAcquireCount:       desc("empty_acquires_total", "Number of acquires that waited for a connection because the pool was empty."),
+		canceledAcquireCount:    desc("canceled_acquires_total", "Number of acquires canceled by their context."),
+		newConnsCount:           desc("new_connections_total", "Number of connections opened."),
+		maxLifetimeDestroyCount: desc("max_lifetime_closed_total", "Number of connections closed for reaching DB_MAX_CONN_LIFETIME."),
+		maxIdleDestroyCount:     desc("max_idle_closed_total", "Number of connections closed for being idle for DB_MAX_CONN_IDLE_TIME."),
+	}
+}
+
+func (c *poolStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *poolStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+
+	gauge := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value)
+	}
+	counter := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value)
+	}
+
+	gauge(c.maxConns, float64(stat.MaxConns()))
+	gauge(c.totalConns, float64(stat.TotalConns()))
+	gauge(c.acquiredConns, float64(stat.AcquiredConns()))
+	gauge(c.idleConns, float64(stat.IdleConns()))
+	gauge(c.constructingConns, float64(stat.ConstructingConns()))
+	counter(c.acquireCount, float64(stat.AcquireCount()))
+	counter(c.acquireDuration, stat.AcquireDuration().Seconds())
+	counter(c.emptyAcquireCount, float64(stat.EmptyAcquireCount()))
+	counter(c.canceledAcquireCount, float64(stat.CanceledAcquireCount()))
+	counter(c.newConnsCount, float64(stat.NewConnsCount()))
+	counter(c.maxLifetimeDestroyCount, float64(stat.MaxLifetimeDestroyCount()))
+	counter(c.maxIdleDestroyCount, float64(stat.MaxIdleDestroyCount()))
+}
@@ -0,0 +1,188 @@
+package realtime
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+)
+
+// Types of the updates pushed to the streams of an account
+const (
+	UpdateBalance = "balance"
+	UpdateEntry   = "entry"
+	// UpdateResync tells that updates may have been missed, e.g. while reconnecting to the database,
+	// and the client should fetch the account again
+	UpdateResync = "resync"
+)
+
+var (
+	// ErrSlowSubscriber closes a subscription whose buffer filled up, rather than blocking the other subscribers
+	ErrSlowSubscriber = errors.New("subscriber is too slow, updates were dropped")
+	// ErrHubClosed closes the subscriptions of a hub being shut down
+	ErrHubClosed = errors.New("server is shutting down")
+)
+
+// Update is a change of an account pushed to its subscribers
+type Update struct {
+	Type      string          `json:"type"`
+	AccountID int64           `json:"account_id,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+}
+
+// Subscription receives the updates of one account until it is closed
+type Subscription struct {
+	hub       *Hub
+	accountID int64
+	updates   chan Update
+	done      chan struct{}
+	err       error
+}
+
+// Updates returns the channel of the updates of the account
+func (sub *Subscription) Updates() <-chan Update {
+	return sub.updates
+}
+
+// Done is closed when the subscription is closed, by the subscriber or for being too slow
+func (sub *Subscription) Done() <-chan struct{} {
+	return sub.done
+}
+
+// Err returns why the subscription was closed by the hub, nil if it is open or closed by the subscriber
+func (sub *Subscription) Err() error {
+	sub.hub.mu.Lock()
+	defer sub.hub.mu.Unlock()
+
+	return sub.err
+}
+
+// Close stops the subscription
+func (sub *Subscription) Close() {
+	sub.hub.mu.Lock()
+	defer sub.hub.mu.Unlock()
+
+	sub.hub.remove(sub, nil)
+}
+
+// Hub dispatches the updates of the accounts to their subscribers.
+// Every subscription has a buffer of bufferSize updates and is closed with ErrSlowSubscriber when it is full,
+// so a slow client never holds back the others.
+type Hub struct {
+	mu            sync.Mutex
+	bufferSize    int
+	subscriptions map[int64]map[*Subscription]struct{}
+	closed        bool
+}
+
+// NewHub creates a hub buffering bufferSize updates per subscription
+func NewHub(bufferSize int) *Hub {
+	if bufferSize <= 0 {
+		bufferSize = 1
+	}
+
+	return &Hub{
+		bufferSize:    bufferSize,
+		subscriptions: make(map[int64]map[*Subscription]struct{}),
+	}
+}
+
+// Subscribe returns a subscription to the updates of an account.
+// The subscription of a closed hub is closed with ErrHubClosed right away.
+func (hub *Hub) Subscribe(accountID int64) *Subscription {
+	sub := &Subscription{
+		hub:       hub,
+		accountID: accountID,
+		updates:   make(chan Update, hub.bufferSize),
+		done:      make(chan struct{}),
+	}
+
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	if hub.closed {
+		sub.err = ErrHubClosed
+		close(sub.done)
+		return sub
+	}
+
+	if hub.subscriptions[accountID] == nil {
+		hub.subscriptions[accountID] = make(map[*Subscription]struct{})
+	}
+	hub.subscriptions[accountID][sub] = struct{}{}
+
+	return sub
+}
+
+// Publish pushes an update to the subscribers of its account without waiting for them
+func (hub *Hub) Publish(update Update) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	for sub := range hub.subscriptions[update.AccountID] {
+		hub.push(sub, update)
+	}
+}
+
+// Resync tells every subscriber that updates may have been missed
+func (hub *Hub) Resync() {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	for accountID, subs := range hub.subscriptions {
+		for sub := range subs {
+			hub.push(sub, Update{Type: UpdateResync, AccountID: accountID})
+		}
+	}
+}
+
+// Close closes every subscription with ErrHubClosed and the ones made afterwards
+func (hub *Hub) Close() {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	hub.closed = true
+	for _, subs := range hub.subscriptions {
+		for sub := range subs {
+			hub.remove(sub, ErrHubClosed)
+		}
+	}
+}
+
+// Subscribers returns the number of open subscriptions
+func (hub *Hub) Subscribers() int {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	n := 0
+	for _, subs := range hub.subscriptions {
+		n += len(subs)
+	}
+	return n
+}
+
+// push sends an update to a subscription, closing it when its buffer is full. The hub must be locked.
+func (hub *Hub) push(sub *Subscription, update Update) {
+	select {
+	case sub.updates <- update:
+	default:
+		hub.remove(sub, ErrSlowSubscriber)
+	}
+}
+
+// remove closes a subscription once. The hub must be locked.
+func (hub *Hub) remove(sub *Subscription, err error) {
+	subs, ok := hub.subscriptions[sub.accountID]
+	if !ok {
+		return
+	}
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(hub.subscriptions, sub.accountID)
+	}
+	sub.err = err
+	close(sub.done)
+}
@@ -0,0 +1,77 @@
+package realtime
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHubPublish(t *testing.T) {
+	hub := NewHub(2)
+
+	sub := hub.Subscribe(1)
+	other := hub.Subscribe(2)
+	require.Equal(t, 2, hub.Subscribers())
+
+	update := Update{Type: UpdateBalance, AccountID: 1, Data: json.RawMessage(`{"balance":10}`)}
+	hub.Publish(update)
+
+	require.Equal(t, update, <-sub.Updates())
+	require.Empty(t, other.Updates())
+
+	sub.Close()
+	sub.Close()
+	require.NoError(t, sub.Err())
+	require.Equal(t, 1, hub.Subscribers())
+
+	// a closed subscription does not receive updates anymore
+	hub.Publish(update)
+	require.Empty(t, sub.Updates())
+}
+
+func TestHubSlowSubscriber(t *testing.T) {
+	hub := NewHub(1)
+
+	slow := hub.Subscribe(1)
+	fast := hub.Subscribe(1)
+
+	hub.Publish(Update{Type: UpdateEntry, AccountID: 1})
+	<-fast.Updates()
+	hub.Publish(Update{Type: UpdateEntry, AccountID: 1})
+
+	// the full buffer closes the slow subscription without holding back the other one
+	<-slow.Done()
+	require.ErrorIs(t, slow.Err(), ErrSlowSubscriber)
+	require.Len(t, fast.Updates(), 1)
+	require.NoError(t, fast.Err())
+	require.Equal(t, 1, hub.Subscribers())
+}
+
+func TestHubResync(t *testing.T) {
+	hub := NewHub(1)
+
+	sub1 := hub.Subscribe(1)
+	sub2 := hub.Subscribe(2)
+
+	hub.Resync()
+
+	require.Equal(t, Update{Type: UpdateResync, AccountID: 1}, <-sub1.Updates())
+	require.Equal(t, Update{Type: UpdateResync, AccountID: 2}, <-sub2.Updates())
+}
+
+func TestHubClose(t *testing.T) {
+	hub := NewHub(1)
+
+	sub := hub.Subscribe(1)
+	hub.Close()
+
+	<-sub.Done()
+	require.ErrorIs(t, sub.Err(), ErrHubClosed)
+	require.Zero(t, hub.Subscribers())
+
+	late := hub.Subscribe(1)
+	<-late.Done()
+	require.ErrorIs(t, late.Err(), ErrHubClosed)
+	require.Zero(t, hub.Subscribers())
+}
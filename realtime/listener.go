@@ -0,0 +1,114 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rs/zerolog/log"
+)
+
+// Channel is the Postgres channel the triggers notify the account updates on
+const Channel = "account_updates"
+
+// Reconnection and health check intervals of the listening connection
+const (
+	minReconnectInterval = time.Second
+	maxReconnectInterval = time.Minute
+	pingInterval         = 90 * time.Second
+)
+
+// Listener forwards the account updates notified by Postgres to a hub.
+// Notifications are only sent once the transaction of the change commits.
+type Listener struct {
+	dsn string
+	hub *Hub
+}
+
+// NewListener creates a listener on a dedicated connection to the database of dsn
+func NewListener(dsn string, hub *Hub) *Listener {
+	return &Listener{dsn: dsn, hub: hub}
+}
+
+// Run listens to the account updates until the context is done.
+// The connection is reopened when lost, and the subscribers are told to resync as updates may have been missed.
+func (listener *Listener) Run(ctx context.Context) error {
+	backoff := minReconnectInterval
+	listening := false
+
+	for {
+		err := listener.listen(ctx, func() {
+			if listening {
+				listener.hub.Resync()
+			}
+			listening = true
+			backoff = minReconnectInterval
+		})
+		if ctx.Err() != nil {
+			log.Ctx(ctx).Info().Msg("account updates listener is stopped")
+			return nil
+		}
+		log.Ctx(ctx).Warn().Err(err).Msgf("account updates listener connection lost, reconnecting in %s", backoff)
+
+		select {
+		case <-ctx.Done():
+			log.Ctx(ctx).Info().Msg("account updates listener is stopped")
+			return nil
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > maxReconnectInterval {
+			backoff = maxReconnectInterval
+		}
+	}
+}
+
+// listen opens a connection, listens to the channel and dispatches the notifications until the connection fails.
+// onListen is called once the connection listens.
+func (listener *Listener) listen(ctx context.Context, onListen func()) error {
+	conn, err := pgx.Connect(ctx, listener.dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	_, err = conn.Exec(ctx, "LISTEN "+pgx.Identifier{Channel}.Sanitize())
+	if err != nil {
+		return err
+	}
+	onListen()
+
+	for {
+		waitCtx, cancel := context.WithTimeout(ctx, pingInterval)
+		notification, err := conn.WaitForNotification(waitCtx)
+		cancel()
+
+		if err == nil {
+			listener.dispatch(ctx, notification.Payload)
+			continue
+		}
+		if ctx.Err() != nil || !pgconn.Timeout(err) {
+			return err
+		}
+
+		// detect a connection dropped silently
+		err = conn.Ping(ctx)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// dispatch publishes the update of a notification payload
+func (listener *Listener) dispatch(ctx context.Context, payload string) {
+	var update Update
+	if err := json.Unmarshal([]byte(payload), &update); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("invalid account update notification")
+		return
+	}
+
+	listener.hub.Publish(update)
+}
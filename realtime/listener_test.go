@@ -0,0 +1,35 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenerDispatch(t *testing.T) {
+	hub := NewHub(1)
+	listener := NewListener("", hub)
+	sub := hub.Subscribe(7)
+
+	// payload as built by the notify_account_balance trigger
+	listener.dispatch(context.Background(), `{"type":"balance","account_id":7,"data":{"balance":100,"held_amount":20,"available_balance":80,"currency":"EUR"}}`)
+
+	update := <-sub.Updates()
+	require.Equal(t, UpdateBalance, update.Type)
+	require.Equal(t, int64(7), update.AccountID)
+
+	var data struct {
+		Balance          int64 `json:"balance"`
+		AvailableBalance int64 `json:"available_balance"`
+	}
+	require.NoError(t, json.Unmarshal(update.Data, &data))
+	require.Equal(t, int64(100), data.Balance)
+	require.Equal(t, int64(80), data.AvailableBalance)
+
+	// an invalid payload is dropped
+	listener.dispatch(context.Background(), `not json`)
+	require.Empty(t, sub.Updates())
+	require.NoError(t, sub.Err())
+}
@@ -0,0 +1,78 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	db "github.com/samirprakash/go-bank/db/sqlc"
+	"github.com/samirprakash/go-bank/metrics"
+)
+
+// HoldSweeper releases the funds of the holds that expired without being captured or voided
+type HoldSweeper struct {
+	store     db.Store
+	interval  time.Duration
+	batchSize int32
+	now       func() time.Time
+}
+
+// NewHoldSweeper creates a sweeper expiring holds every interval, batchSize at a time
+func NewHoldSweeper(store db.Store, interval time.Duration, batchSize int32) *HoldSweeper {
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+
+	return &HoldSweeper{
+		store:     store,
+		interval:  interval,
+		batchSize: batchSize,
+		now:       time.Now,
+	}
+}
+
+// Run expires holds every interval until the context is done
+func (sweeper *HoldSweeper) Run(ctx context.Context) error {
+	ticker := time.NewTicker(sweeper.interval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			n, err := sweeper.Sweep(ctx)
+			if err != nil {
+				log.Ctx(ctx).Error().Err(err).Msg("cannot expire holds")
+				break
+			}
+
+			// keep going while there may be more expired holds
+			if n < int(sweeper.batchSize) {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Ctx(ctx).Info().Msg("hold sweeper is stopped")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep expires one batch of holds and returns how many were expired
+func (sweeper *HoldSweeper) Sweep(ctx context.Context) (int, error) {
+	holds, err := sweeper.store.ExpireHoldsTx(ctx, db.ExpireHoldsTxParams{
+		Now:   sweeper.now(),
+		Limit: sweeper.batchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, hold := range holds {
+		log.Ctx(ctx).Info().Int64("hold_id", hold.ID).Int64("account_id", hold.AccountID).Msg("hold expired")
+		metrics.HoldFinished(db.HoldExpired)
+	}
+
+	return len(holds), nil
+}
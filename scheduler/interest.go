@@ -0,0 +1,136 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	db "github.com/samirprakash/go-bank/db/sqlc"
+	"github.com/samirprakash/go-bank/metrics"
+)
+
+// InterestAccruer accrues daily interest on savings accounts and pays it once a month is over.
+// Accruals and postings are recorded per account and day or month, so running it again,
+// or on several instances at once, never credits an account twice.
+type InterestAccruer struct {
+	store     db.Store
+	interval  time.Duration
+	batchSize int32
+	now       func() time.Time
+}
+
+// NewInterestAccruer creates an accruer catching up on interest every interval, batchSize accounts at a time
+func NewInterestAccruer(store db.Store, interval time.Duration, batchSize int32) *InterestAccruer {
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+
+	return &InterestAccruer{
+		store:     store,
+		interval:  interval,
+		batchSize: batchSize,
+		now:       time.Now,
+	}
+}
+
+// Run accrues and posts interest every interval until the context is done
+func (accruer *InterestAccruer) Run(ctx context.Context) error {
+	ticker := time.NewTicker(accruer.interval)
+	defer ticker.Stop()
+
+	for {
+		// a month is only posted once all of its days are accrued
+		if _, err := accruer.Accrue(ctx); err != nil {
+			log.Ctx(ctx).Error().Err(err).Msg("cannot accrue interest")
+		} else if _, err := accruer.Post(ctx); err != nil {
+			log.Ctx(ctx).Error().Err(err).Msg("cannot post interest")
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Ctx(ctx).Info().Msg("interest accruer is stopped")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Accrue accrues interest for every day from the last accrued one up to yesterday in UTC
+// and returns the number of accruals made.
+// The last accrued day is accrued again for the accounts a previous run did not get to.
+func (accruer *InterestAccruer) Accrue(ctx context.Context) (int, error) {
+	now := accruer.now().UTC()
+	yesterday := time.Date(now.Year(), now.Month(), now.Day()-1, 0, 0, 0, 0, time.UTC)
+
+	day := yesterday
+	last, err := accruer.store.GetLastInterestAccrualDate(ctx)
+	if err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+		return 0, err
+	}
+	if err == nil && last.Before(yesterday) {
+		day = last.UTC()
+	}
+
+	n := 0
+	for ; !day.After(yesterday); day = day.AddDate(0, 0, 1) {
+		for ctx.Err() == nil {
+			accruals, err := accruer.store.AccrueInterestTx(ctx, db.AccrueInterestTxParams{
+				Date:  day,
+				Limit: accruer.batchSize,
+			})
+			if err != nil {
+				return n, err
+			}
+			n += len(accruals)
+
+			// keep going while there may be more accounts to accrue for the day
+			if len(accruals) < int(accruer.batchSize) {
+				break
+			}
+		}
+	}
+
+	return n, ctx.Err()
+}
+
+// Post pays the interest of the months that are over and not posted yet, and returns the number of postings made.
+// It stops at the first posting that fails, which is tried again on the next run.
+func (accruer *InterestAccruer) Post(ctx context.Context) (int, error) {
+	now := accruer.now().UTC()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	n := 0
+	for ctx.Err() == nil {
+		pending, err := accruer.store.ListPendingInterestPostings(ctx, db.ListPendingInterestPostingsParams{
+			Before:     monthStart,
+			LimitCount: accruer.batchSize,
+		})
+		if err != nil {
+			return n, err
+		}
+
+		for _, posting := range pending {
+			result, err := accruer.store.PostInterestTx(ctx, db.PostInterestTxParams{
+				AccountID: posting.AccountID,
+				Month:     posting.Month,
+			})
+			if err != nil {
+				log.Ctx(ctx).Error().Err(err).Int64("account_id", posting.AccountID).Time("month", posting.Month).Msg("cannot post interest")
+				return n, err
+			}
+			n++
+
+			if result.Transfer != nil {
+				metrics.InterestPaid(result.Transfer.ToAccount.Currency, result.Posting.Amount)
+			}
+			log.Ctx(ctx).Info().Int64("account_id", posting.AccountID).Time("month", posting.Month).Int64("amount", result.Posting.Amount).Msg("interest posted")
+		}
+
+		if len(pending) < int(accruer.batchSize) {
+			break
+		}
+	}
+
+	return n, ctx.Err()
+}
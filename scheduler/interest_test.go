@@ -0,0 +1,100 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/samirprakash/go-bank/db/mock"
+	db "github.com/samirprakash/go-bank/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccrueInterest(t *testing.T) {
+	now := time.Date(2023, time.March, 1, 9, 0, 0, 0, time.UTC)
+	feb27 := time.Date(2023, time.February, 27, 0, 0, 0, 0, time.UTC)
+	feb28 := time.Date(2023, time.February, 28, 0, 0, 0, 0, time.UTC)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetLastInterestAccrualDate(gomock.Any()).Times(1).Return(feb27, nil)
+
+	// the last accrued day is accrued again, then every day up to yesterday batch by batch
+	gomock.InOrder(
+		store.EXPECT().
+			AccrueInterestTx(gomock.Any(), gomock.Eq(db.AccrueInterestTxParams{Date: feb27, Limit: 2})).
+			Times(1).
+			Return([]db.InterestAccrual{{AccountID: 1}}, nil),
+		store.EXPECT().
+			AccrueInterestTx(gomock.Any(), gomock.Eq(db.AccrueInterestTxParams{Date: feb28, Limit: 2})).
+			Times(1).
+			Return([]db.InterestAccrual{{AccountID: 1}, {AccountID: 2}}, nil),
+		store.EXPECT().
+			AccrueInterestTx(gomock.Any(), gomock.Eq(db.AccrueInterestTxParams{Date: feb28, Limit: 2})).
+			Times(1).
+			Return([]db.InterestAccrual{}, nil),
+	)
+
+	accruer := NewInterestAccruer(store, time.Hour, 2)
+	accruer.now = func() time.Time { return now }
+
+	n, err := accruer.Accrue(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+}
+
+func TestAccrueInterestFirstRun(t *testing.T) {
+	now := time.Date(2023, time.March, 1, 9, 0, 0, 0, time.UTC)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetLastInterestAccrualDate(gomock.Any()).Times(1).Return(time.Time{}, db.ErrRecordNotFound)
+	store.EXPECT().
+		AccrueInterestTx(gomock.Any(), gomock.Eq(db.AccrueInterestTxParams{Date: time.Date(2023, time.February, 28, 0, 0, 0, 0, time.UTC), Limit: 2})).
+		Times(1).
+		Return([]db.InterestAccrual{}, nil)
+
+	accruer := NewInterestAccruer(store, time.Hour, 2)
+	accruer.now = func() time.Time { return now }
+
+	n, err := accruer.Accrue(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
+
+func TestPostInterest(t *testing.T) {
+	now := time.Date(2023, time.March, 1, 9, 0, 0, 0, time.UTC)
+	february := time.Date(2023, time.February, 1, 0, 0, 0, 0, time.UTC)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListPendingInterestPostings(gomock.Any(), gomock.Eq(db.ListPendingInterestPostingsParams{Before: time.Date(2023, time.March, 1, 0, 0, 0, 0, time.UTC), LimitCount: 10})).
+		Times(1).
+		Return([]db.ListPendingInterestPostingsRow{{AccountID: 1, Month: february}, {AccountID: 2, Month: february}}, nil)
+	store.EXPECT().
+		PostInterestTx(gomock.Any(), gomock.Eq(db.PostInterestTxParams{AccountID: 1, Month: february})).
+		Times(1).
+		Return(db.PostInterestTxResult{
+			Posting:  db.InterestPosting{AccountID: 1, Month: february, Amount: 5},
+			Transfer: &db.TransferTxResult{ToAccount: db.Account{ID: 1, Currency: "USD"}},
+		}, nil)
+	store.EXPECT().
+		PostInterestTx(gomock.Any(), gomock.Eq(db.PostInterestTxParams{AccountID: 2, Month: february})).
+		Times(1).
+		Return(db.PostInterestTxResult{Posting: db.InterestPosting{AccountID: 2, Month: february}}, nil)
+
+	accruer := NewInterestAccruer(store, time.Hour, 10)
+	accruer.now = func() time.Time { return now }
+
+	n, err := accruer.Post(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+}
@@ -0,0 +1,83 @@
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/robfig/cron/v3"
+	db "github.com/samirprakash/go-bank/db/sqlc"
+)
+
+// MinInterval is the shortest interval allowed between two runs of a scheduled transfer
+const MinInterval = time.Minute
+
+// ParseSchedule parses a standard 5 field cron expression (e.g. "0 9 1 * *" for 9am on the 1st of every month),
+// a descriptor such as "@monthly" or an interval such as "@every 24h".
+// Cron expressions are evaluated in UTC unless prefixed with CRON_TZ=<zone>.
+func ParseSchedule(spec string) (cron.Schedule, error) {
+	expr := spec
+	if !strings.HasPrefix(expr, "CRON_TZ=") && !strings.HasPrefix(expr, "TZ=") {
+		expr = "CRON_TZ=UTC " + expr
+	}
+
+	schedule, err := cron.ParseStandard(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule %q : %w", spec, err)
+	}
+
+	if interval, ok := schedule.(cron.ConstantDelaySchedule); ok && interval.Delay < MinInterval {
+		return nil, fmt.Errorf("invalid schedule %q : runs must be at least %s apart", spec, MinInterval)
+	}
+
+	// an expression matching no date, e.g. "0 0 30 2 *", has no next run
+	if schedule.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("invalid schedule %q : it never runs", spec)
+	}
+
+	return schedule, nil
+}
+
+// FirstRun returns the first time the schedule is due at or after start.
+// An interval schedule runs at start and then every interval.
+// The time is zero when the schedule never runs after start.
+func FirstRun(schedule cron.Schedule, start time.Time) time.Time {
+	if _, ok := schedule.(cron.ConstantDelaySchedule); ok {
+		return start
+	}
+	return schedule.Next(start.Add(-time.Second))
+}
+
+// NextRunAt returns the first run of a scheduled transfer strictly after now, or null once its schedule has ended.
+// Runs missed while the scheduler was down are skipped rather than executed one after the other.
+func NextRunAt(scheduledTransfer db.ScheduledTransfer, now time.Time) pgtype.Timestamptz {
+	schedule, err := ParseSchedule(scheduledTransfer.Schedule)
+	if err != nil {
+		return pgtype.Timestamptz{}
+	}
+
+	next := FirstRun(schedule, scheduledTransfer.StartAt)
+	if scheduledTransfer.NextRunAt.Valid {
+		next = scheduledTransfer.NextRunAt.Time
+	}
+	// a cron schedule returns a zero time once it has no run left, which ends the scheduled transfer
+	for !next.IsZero() && !next.After(now) {
+		next = schedule.Next(next)
+	}
+
+	return activeRun(next, scheduledTransfer.EndAt)
+}
+
+// activeRun returns the run unless there is none or it is after the end of the schedule
+func activeRun(run time.Time, endAt pgtype.Timestamptz) pgtype.Timestamptz {
+	if run.IsZero() || endAt.Valid && run.After(endAt.Time) {
+		return pgtype.Timestamptz{}
+	}
+	return pgtype.Timestamptz{Time: run, Valid: true}
+}
+
+// InitialRunAt returns the first run of a new schedule, or null if it ends before its first run
+func InitialRunAt(schedule cron.Schedule, startAt time.Time, endAt pgtype.Timestamptz) pgtype.Timestamptz {
+	return activeRun(FirstRun(schedule, startAt), endAt)
+}
@@ -0,0 +1,113 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/robfig/cron/v3"
+	db "github.com/samirprakash/go-bank/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSchedule(t *testing.T) {
+	testCases := []struct {
+		spec  string
+		valid bool
+	}{
+		{spec: "0 9 1 * *", valid: true},
+		{spec: "@monthly", valid: true},
+		{spec: "@every 24h", valid: true},
+		{spec: "CRON_TZ=Europe/Paris 0 9 1 * *", valid: true},
+		{spec: "@every 1s", valid: false},
+		{spec: "every day", valid: false},
+		{spec: "0 9 32 * *", valid: false},
+		{spec: "0 0 30 2 *", valid: false},
+		{spec: "", valid: false},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.spec, func(t *testing.T) {
+			_, err := ParseSchedule(tc.spec)
+			if tc.valid {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestFirstRun(t *testing.T) {
+	start := time.Date(2023, time.March, 15, 10, 30, 0, 0, time.UTC)
+
+	monthly, err := ParseSchedule("0 9 1 * *")
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2023, time.April, 1, 9, 0, 0, 0, time.UTC), FirstRun(monthly, start))
+
+	// a cron schedule matching the start runs at the start
+	everyMinute, err := ParseSchedule("* * * * *")
+	require.NoError(t, err)
+	require.Equal(t, start, FirstRun(everyMinute, start))
+
+	daily, err := ParseSchedule("@every 24h")
+	require.NoError(t, err)
+	require.Equal(t, start, FirstRun(daily, start))
+}
+
+func TestNextRunAt(t *testing.T) {
+	start := time.Date(2023, time.January, 1, 9, 0, 0, 0, time.UTC)
+
+	scheduledTransfer := db.ScheduledTransfer{
+		Schedule:  "0 9 1 * *",
+		StartAt:   start,
+		NextRunAt: pgtype.Timestamptz{Time: start, Valid: true},
+	}
+
+	// the run after the one being executed
+	next := NextRunAt(scheduledTransfer, start)
+	require.True(t, next.Valid)
+	require.Equal(t, time.Date(2023, time.February, 1, 9, 0, 0, 0, time.UTC), next.Time)
+
+	// missed runs are skipped
+	next = NextRunAt(scheduledTransfer, time.Date(2023, time.May, 10, 0, 0, 0, 0, time.UTC))
+	require.True(t, next.Valid)
+	require.Equal(t, time.Date(2023, time.June, 1, 9, 0, 0, 0, time.UTC), next.Time)
+
+	// no run after the end of the schedule
+	scheduledTransfer.EndAt = pgtype.Timestamptz{Time: time.Date(2023, time.January, 31, 0, 0, 0, 0, time.UTC), Valid: true}
+	next = NextRunAt(scheduledTransfer, start)
+	require.False(t, next.Valid)
+
+	// intervals keep their alignment with the start
+	scheduledTransfer = db.ScheduledTransfer{
+		Schedule:  "@every 1h",
+		StartAt:   start,
+		NextRunAt: pgtype.Timestamptz{Time: start, Valid: true},
+	}
+	next = NextRunAt(scheduledTransfer, start.Add(90*time.Minute))
+	require.True(t, next.Valid)
+	require.Equal(t, start.Add(2*time.Hour), next.Time)
+}
+
+func TestNextRunAtNeverRuns(t *testing.T) {
+	start := time.Date(2023, time.January, 1, 9, 0, 0, 0, time.UTC)
+
+	// parsed without the checks of ParseSchedule, as a schedule stored before them could be
+	never, err := cron.ParseStandard("CRON_TZ=UTC 0 0 30 2 *")
+	require.NoError(t, err)
+	require.True(t, FirstRun(never, start).IsZero())
+	require.False(t, InitialRunAt(never, start, pgtype.Timestamptz{}).Valid)
+
+	// the scheduled transfer ends instead of being due forever
+	scheduledTransfer := db.ScheduledTransfer{
+		Schedule: "0 0 30 2 *",
+		StartAt:  start,
+	}
+	require.False(t, NextRunAt(scheduledTransfer, start).Valid)
+
+	scheduledTransfer.NextRunAt = pgtype.Timestamptz{Time: start, Valid: true}
+	require.False(t, NextRunAt(scheduledTransfer, start).Valid)
+}
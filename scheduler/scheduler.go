@@ -0,0 +1,143 @@
+// Package scheduler runs the time based jobs of the bank :
+// scheduled and recurring transfers when they are due and the expiry of holds.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+	db "github.com/samirprakash/go-bank/db/sqlc"
+	"github.com/samirprakash/go-bank/metrics"
+)
+
+// Scheduler polls the store for due scheduled transfers and executes them through TransferTx.
+// Several schedulers can run against the same database as due rows are claimed with SKIP LOCKED.
+type Scheduler struct {
+	store     db.Store
+	interval  time.Duration
+	batchSize int32
+	now       func() time.Time
+}
+
+// New creates a scheduler checking for due transfers every interval, batchSize at a time
+func New(store db.Store, interval time.Duration, batchSize int32) *Scheduler {
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+
+	return &Scheduler{
+		store:     store,
+		interval:  interval,
+		batchSize: batchSize,
+		now:       time.Now,
+	}
+}
+
+// Run executes due transfers every interval until the context is done.
+// A batch that has been claimed is always executed to the end so that no run is left pending on shutdown.
+func (scheduler *Scheduler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(scheduler.interval)
+	defer ticker.Stop()
+
+	// claimed runs are executed even if the context is cancelled in the middle of a batch
+	workCtx := log.Ctx(ctx).WithContext(context.Background())
+
+	for {
+		for ctx.Err() == nil {
+			n, err := scheduler.RunDue(workCtx)
+			if err != nil {
+				log.Ctx(ctx).Error().Err(err).Msg("cannot run scheduled transfers")
+				break
+			}
+
+			// keep going while there may be more due transfers
+			if n < int(scheduler.batchSize) {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Ctx(ctx).Info().Msg("scheduler is stopped")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue claims one batch of due transfers, executes them and returns how many were claimed
+func (scheduler *Scheduler) RunDue(ctx context.Context) (int, error) {
+	claimed, err := scheduler.store.ClaimDueScheduledTransfersTx(ctx, db.ClaimDueScheduledTransfersTxParams{
+		Now:       scheduler.now(),
+		Limit:     scheduler.batchSize,
+		NextRunAt: NextRunAt,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, c := range claimed {
+		scheduler.execute(ctx, c)
+	}
+
+	return len(claimed), nil
+}
+
+// execute runs the transfer of a claimed run and records its outcome.
+// Failed runs are not retried : the scheduled transfer simply waits for its next run.
+func (scheduler *Scheduler) execute(ctx context.Context, claimed db.ClaimedScheduledTransfer) {
+	scheduledTransfer := claimed.ScheduledTransfer
+	logger := log.Ctx(ctx).With().
+		Int64("scheduled_transfer_id", scheduledTransfer.ID).
+		Int64("run_id", claimed.Run.ID).
+		Logger()
+
+	arg := db.FinishScheduledTransferRunParams{
+		ID:     claimed.Run.ID,
+		Status: db.ScheduledTransferRunSucceeded,
+	}
+
+	result, err := scheduler.store.TransferTx(ctx, db.TransferTxParams{
+		FromAccountID: scheduledTransfer.FromAccountID,
+		ToAccountID:   scheduledTransfer.ToAccountID,
+		Amount:        scheduledTransfer.Amount,
+	})
+	if err != nil {
+		logger.Warn().Err(err).Msg("scheduled transfer failed")
+		arg.Status = db.ScheduledTransferRunFailed
+		arg.Error = runError(err)
+	} else {
+		arg.TransferID = pgtype.Int8{Int64: result.Transfer.ID, Valid: true}
+		metrics.TransferCreated(scheduledTransfer.Currency, scheduledTransfer.Amount)
+		metrics.FeeCharged(scheduledTransfer.Currency, result.Fee.Amount)
+	}
+	metrics.ScheduledTransferRun(arg.Status)
+
+	_, err = scheduler.store.FinishScheduledTransferRun(ctx, arg)
+	if err != nil {
+		logger.Error().Err(err).Str("status", arg.Status).Msg("cannot record scheduled transfer run")
+	}
+}
+
+// runErrors are the errors of the business rules a scheduled transfer may break, whose message is safe to show to its owner
+var runErrors = []error{
+	db.ErrInsufficientFunds,
+	db.ErrAccountFrozen,
+	db.ErrAccountClosed,
+	db.ErrTransferLimitExceeded,
+	db.ErrDailyLimitExceeded,
+	db.ErrMonthlyLimitExceeded,
+}
+
+// runError describes why a run failed to the owner of the scheduled transfer, without the details of internal errors
+func runError(err error) string {
+	for _, runErr := range runErrors {
+		if errors.Is(err, runErr) {
+			return runErr.Error()
+		}
+	}
+	return "internal error"
+}
@@ -0,0 +1,194 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	mockdb "github.com/samirprakash/go-bank/db/mock"
+	db "github.com/samirprakash/go-bank/db/sqlc"
+	"github.com/samirprakash/go-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunDue(t *testing.T) {
+	now := time.Date(2023, time.February, 1, 9, 0, 0, 0, time.UTC)
+
+	succeeding := db.ClaimedScheduledTransfer{
+		ScheduledTransfer: db.ScheduledTransfer{ID: 1, FromAccountID: 10, ToAccountID: 20, Amount: 100, Currency: "USD"},
+		Run:               db.ScheduledTransferRun{ID: 11, Status: db.ScheduledTransferRunPending},
+	}
+	failing := db.ClaimedScheduledTransfer{
+		ScheduledTransfer: db.ScheduledTransfer{ID: 2, FromAccountID: 30, ToAccountID: 20, Amount: 100, Currency: "USD"},
+		Run:               db.ScheduledTransferRun{ID: 12, Status: db.ScheduledTransferRunPending},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+
+	store.EXPECT().
+		ClaimDueScheduledTransfersTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, arg db.ClaimDueScheduledTransfersTxParams) ([]db.ClaimedScheduledTransfer, error) {
+			require.Equal(t, now, arg.Now)
+			require.Equal(t, int32(10), arg.Limit)
+			require.NotNil(t, arg.NextRunAt)
+			return []db.ClaimedScheduledTransfer{succeeding, failing}, nil
+		})
+
+	store.EXPECT().
+		TransferTx(gomock.Any(), gomock.Eq(db.TransferTxParams{FromAccountID: 10, ToAccountID: 20, Amount: 100})).
+		Times(1).
+		Return(db.TransferTxResult{Transfer: db.Transfer{ID: 99}}, nil)
+	store.EXPECT().
+		FinishScheduledTransferRun(gomock.Any(), gomock.Eq(db.FinishScheduledTransferRunParams{
+			ID:         11,
+			Status:     db.ScheduledTransferRunSucceeded,
+			TransferID: pgtype.Int8{Int64: 99, Valid: true},
+		})).
+		Times(1)
+
+	// a failed run is recorded with a message safe to show to the owner
+	store.EXPECT().
+		TransferTx(gomock.Any(), gomock.Eq(db.TransferTxParams{FromAccountID: 30, ToAccountID: 20, Amount: 100})).
+		Times(1).
+		Return(db.TransferTxResult{}, fmt.Errorf("%w : %w", db.ErrInsufficientFunds, &pgconn.PgError{Code: "23514", ConstraintName: "accounts_balance_check"}))
+	store.EXPECT().
+		FinishScheduledTransferRun(gomock.Any(), gomock.Eq(db.FinishScheduledTransferRunParams{
+			ID:     12,
+			Status: db.ScheduledTransferRunFailed,
+			Error:  "insufficient funds",
+		})).
+		Times(1)
+
+	scheduler := New(store, time.Minute, 10)
+	scheduler.now = func() time.Time { return now }
+
+	n, err := scheduler.RunDue(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+}
+
+func TestRunError(t *testing.T) {
+	require.Equal(t, db.ErrDailyLimitExceeded.Error(), runError(fmt.Errorf("transfer : %w", db.ErrDailyLimitExceeded)))
+	require.Equal(t, db.ErrAccountFrozen.Error(), runError(db.ErrAccountFrozen))
+
+	// the details of internal errors are not shown to the owner
+	require.Equal(t, "internal error", runError(errors.New("connection reset by peer")))
+}
+
+func TestRunStopsWithContext(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ClaimDueScheduledTransfersTx(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return([]db.ClaimedScheduledTransfer{}, nil)
+
+	scheduler := New(store, 10*time.Millisecond, 10)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	done := make(chan error)
+	go func() {
+		done <- scheduler.Run(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("scheduler did not stop")
+	}
+}
+
+func TestSweepHolds(t *testing.T) {
+	now := time.Date(2023, time.February, 1, 9, 0, 0, 0, time.UTC)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ExpireHoldsTx(gomock.Any(), gomock.Eq(db.ExpireHoldsTxParams{Now: now, Limit: 10})).
+		Times(1).
+		Return([]db.Hold{{ID: 1, Status: db.HoldExpired}, {ID: 2, Status: db.HoldExpired}}, nil)
+
+	sweeper := NewHoldSweeper(store, time.Minute, 10)
+	sweeper.now = func() time.Time { return now }
+
+	n, err := sweeper.Sweep(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+}
+
+func TestRunDueNeverRunningSchedule(t *testing.T) {
+	store := db.NewMemStore()
+	now := time.Now()
+
+	user, err := store.CreateUser(context.Background(), db.CreateUserParams{
+		Username:       util.RandomOwnerName(),
+		HashedPassword: util.RandomString(32),
+		FullName:       util.RandomOwnerName(),
+		Email:          util.RandomEmail(),
+	})
+	require.NoError(t, err)
+
+	var accounts []db.Account
+	for _, accountType := range []string{db.AccountChecking, db.AccountSavings} {
+		account, err := store.CreateAccount(context.Background(), db.CreateAccountParams{
+			Owner:    user.Username,
+			Balance:  1000,
+			Currency: util.USD,
+			Type:     accountType,
+		})
+		require.NoError(t, err)
+		accounts = append(accounts, account)
+	}
+
+	// a schedule matching no date, stored before schedules were checked for one
+	scheduledTransfer, err := store.CreateScheduledTransfer(context.Background(), db.CreateScheduledTransferParams{
+		Owner:         user.Username,
+		FromAccountID: accounts[0].ID,
+		ToAccountID:   accounts[1].ID,
+		Amount:        10,
+		Currency:      util.USD,
+		Schedule:      "0 0 30 2 *",
+		StartAt:       now.Add(-time.Hour),
+		NextRunAt:     pgtype.Timestamptz{Time: now.Add(-time.Minute), Valid: true},
+	})
+	require.NoError(t, err)
+
+	scheduler := New(store, time.Minute, 10)
+	scheduler.now = func() time.Time { return now }
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		// the due run is executed and the schedule ends rather than computing its next run forever
+		n, err := scheduler.RunDue(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, 1, n)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("claiming a schedule that never runs does not return")
+	}
+
+	scheduledTransfer, err = store.GetScheduledTransfer(context.Background(), scheduledTransfer.ID)
+	require.NoError(t, err)
+	require.False(t, scheduledTransfer.NextRunAt.Valid)
+}
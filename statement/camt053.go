@@ -0,0 +1,293 @@
+package statement
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// camt053Namespace is the version of camt.053 accepted by most accounting software
+const camt053Namespace = "urn:iso:std:iso:20022:tech:xsd:camt.053.001.02"
+
+// Balance types and credit debit indicators of camt.053
+const (
+	camtOpeningBalance = "OPBD"
+	camtClosingBalance = "CLBD"
+	camtCredit         = "CRDT"
+	camtDebit          = "DBIT"
+	camtBooked         = "BOOK"
+)
+
+// camt053Writer renders an end-of-day statement as a camt.053 document holding a single statement.
+// Both balances come before the entries in camt.053, so the closing balance is taken from the header
+// and checked against the entries once they are all written.
+type camt053Writer struct {
+	out    io.Writer
+	enc    *xml.Encoder
+	header Header
+}
+
+type camtAmount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type camtGroupHeader struct {
+	XMLName   xml.Name `xml:"GrpHdr"`
+	MessageID string   `xml:"MsgId"`
+	CreatedAt string   `xml:"CreDtTm"`
+}
+
+type camtPeriod struct {
+	XMLName xml.Name `xml:"FrToDt"`
+	From    string   `xml:"FrDtTm"`
+	To      string   `xml:"ToDtTm"`
+}
+
+type camtAccount struct {
+	XMLName  xml.Name `xml:"Acct"`
+	ID       string   `xml:"Id>Othr>Id"`
+	Currency string   `xml:"Ccy"`
+	Owner    string   `xml:"Ownr>Nm"`
+}
+
+type camtBalance struct {
+	XMLName              xml.Name   `xml:"Bal"`
+	Type                 string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amount               camtAmount `xml:"Amt"`
+	CreditDebitIndicator string     `xml:"CdtDbtInd"`
+	Date                 string     `xml:"Dt>Dt"`
+}
+
+type camtEntry struct {
+	XMLName              xml.Name     `xml:"Ntry"`
+	Reference            string       `xml:"NtryRef"`
+	Amount               camtAmount   `xml:"Amt"`
+	CreditDebitIndicator string       `xml:"CdtDbtInd"`
+	Reversal             bool         `xml:"RvslInd,omitempty"`
+	Status               string       `xml:"Sts"`
+	BookingDate          string       `xml:"BookgDt>DtTm"`
+	ValueDate            string       `xml:"ValDt>Dt"`
+	ServicerReference    string       `xml:"AcctSvcrRef"`
+	Domain               string       `xml:"BkTxCd>Domn>Cd"`
+	Family               string       `xml:"BkTxCd>Domn>Fmly>Cd"`
+	SubFamily            string       `xml:"BkTxCd>Domn>Fmly>SubFmlyCd"`
+	Details              *camtDetails `xml:"NtryDtls>TxDtls,omitempty"`
+	Information          string       `xml:"AddtlNtryInf"`
+}
+
+// camtDetails names the counterparty of an entry, the debtor of a credit or the creditor of a debit
+type camtDetails struct {
+	TransferID string              `xml:"Refs>TxId"`
+	Parties    *camtRelatedParties `xml:"RltdPties,omitempty"`
+}
+
+// camtRelatedParties holds pointers as encoding/xml keeps the parents of empty fields
+type camtRelatedParties struct {
+	Debtor          *camtParty     `xml:"Dbtr,omitempty"`
+	DebtorAccount   *camtAccountID `xml:"DbtrAcct,omitempty"`
+	Creditor        *camtParty     `xml:"Cdtr,omitempty"`
+	CreditorAccount *camtAccountID `xml:"CdtrAcct,omitempty"`
+}
+
+type camtParty struct {
+	Name string `xml:"Nm"`
+}
+
+type camtAccountID struct {
+	ID string `xml:"Id>Othr>Id"`
+}
+
+func newCAMT053Writer(w io.Writer) *camt053Writer {
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return &camt053Writer{out: w, enc: enc}
+}
+
+func (w *camt053Writer) WriteHeader(header Header) error {
+	w.header = header
+
+	id := statementID(header)
+	createdAt := header.GeneratedAt.UTC().Format(time.RFC3339)
+
+	if _, err := io.WriteString(w.out, xml.Header); err != nil {
+		return err
+	}
+
+	err := w.enc.EncodeToken(xml.StartElement{
+		Name: xml.Name{Local: "Document"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: camt053Namespace}},
+	})
+	if err != nil {
+		return err
+	}
+	if err := w.enc.EncodeToken(xml.StartElement{Name: xml.Name{Local: "BkToCstmrStmt"}}); err != nil {
+		return err
+	}
+	if err := w.enc.Encode(camtGroupHeader{MessageID: id, CreatedAt: createdAt}); err != nil {
+		return err
+	}
+
+	if err := w.enc.EncodeToken(xml.StartElement{Name: xml.Name{Local: "Stmt"}}); err != nil {
+		return err
+	}
+	if err := w.element("Id", id); err != nil {
+		return err
+	}
+	if err := w.element("CreDtTm", createdAt); err != nil {
+		return err
+	}
+	err = w.enc.Encode(camtPeriod{
+		From: header.From.UTC().Format(time.RFC3339),
+		To:   lastDay(header.To).UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+	err = w.enc.Encode(camtAccount{
+		ID:       strconv.FormatInt(header.Account.ID, 10),
+		Currency: header.Account.Currency,
+		Owner:    header.Account.Owner,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := w.enc.Encode(w.balance(camtOpeningBalance, header.OpeningBalance, header.From)); err != nil {
+		return err
+	}
+	return w.enc.Encode(w.balance(camtClosingBalance, header.ClosingBalance, lastDay(header.To)))
+}
+
+func (w *camt053Writer) WriteEntry(entry Entry) error {
+	credit := entry.Amount >= 0
+	domain, family, subFamily := camtTransactionCode(entry.Kind, credit)
+
+	ntry := camtEntry{
+		Reference:            strconv.FormatInt(entry.ID, 10),
+		Amount:               w.amount(entry.Amount),
+		CreditDebitIndicator: creditDebitIndicator(entry.Amount),
+		Reversal:             entry.Kind == "reversal",
+		Status:               camtBooked,
+		BookingDate:          entry.CreatedAt.UTC().Format(time.RFC3339),
+		ValueDate:            entry.CreatedAt.UTC().Format("2006-01-02"),
+		ServicerReference:    strconv.FormatInt(entry.ID, 10),
+		Domain:               domain,
+		Family:               family,
+		SubFamily:            subFamily,
+		Information:          entry.Kind,
+	}
+
+	if entry.TransferID != nil {
+		var party *camtParty
+		if entry.CounterpartyOwner != "" {
+			party = &camtParty{Name: entry.CounterpartyOwner}
+		}
+		var account *camtAccountID
+		if entry.CounterpartyAccountID != nil {
+			account = &camtAccountID{ID: strconv.FormatInt(*entry.CounterpartyAccountID, 10)}
+		}
+
+		ntry.Details = &camtDetails{TransferID: strconv.FormatInt(*entry.TransferID, 10)}
+		switch {
+		case party == nil && account == nil:
+		case credit:
+			ntry.Details.Parties = &camtRelatedParties{Debtor: party, DebtorAccount: account}
+		default:
+			ntry.Details.Parties = &camtRelatedParties{Creditor: party, CreditorAccount: account}
+		}
+	}
+
+	return w.enc.Encode(ntry)
+}
+
+func (w *camt053Writer) WriteFooter(footer Footer) error {
+	if footer.ClosingBalance != w.header.ClosingBalance {
+		return ErrClosingBalance
+	}
+
+	for _, name := range []string{"Stmt", "BkToCstmrStmt", "Document"} {
+		if err := w.enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: name}}); err != nil {
+			return err
+		}
+	}
+	if err := w.enc.Flush(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w.out, "\n")
+	return err
+}
+
+func (w *camt053Writer) Flush() error {
+	if err := w.enc.Flush(); err != nil {
+		return err
+	}
+	flushOutput(w.out)
+	return nil
+}
+
+func (w *camt053Writer) element(name string, value string) error {
+	return w.enc.EncodeElement(value, xml.StartElement{Name: xml.Name{Local: name}})
+}
+
+func (w *camt053Writer) amount(amount int64) camtAmount {
+	if amount < 0 {
+		amount = -amount
+	}
+	return camtAmount{Currency: w.header.Account.Currency, Value: formatAmount(amount)}
+}
+
+func (w *camt053Writer) balance(balanceType string, amount int64, date time.Time) camtBalance {
+	return camtBalance{
+		Type:                 balanceType,
+		Amount:               w.amount(amount),
+		CreditDebitIndicator: creditDebitIndicator(amount),
+		Date:                 date.UTC().Format("2006-01-02"),
+	}
+}
+
+// creditDebitIndicator returns the side of an amount, camt.053 amounts being unsigned
+func creditDebitIndicator(amount int64) string {
+	if amount < 0 {
+		return camtDebit
+	}
+	return camtCredit
+}
+
+// camtTransactionCode returns the ISO 20022 bank transaction code of an entry:
+// its domain, family and sub-family
+func camtTransactionCode(kind string, credit bool) (string, string, string) {
+	switch kind {
+	case "deposit":
+		if credit {
+			return "PMNT", "CNTR", "CDPT"
+		}
+		return "PMNT", "CNTR", "CWDL"
+	case "fee":
+		if credit {
+			return "ACMT", "MCOP", "CHRG"
+		}
+		return "ACMT", "MDOP", "CHRG"
+	case "interest":
+		if credit {
+			return "ACMT", "MCOP", "INTR"
+		}
+		return "ACMT", "MDOP", "INTR"
+	case "reversal":
+		if credit {
+			return "PMNT", "RCDT", "RRTN"
+		}
+		return "PMNT", "ICDT", "RRTN"
+	}
+	if credit {
+		return "PMNT", "RCDT", "DMCT"
+	}
+	return "PMNT", "ICDT", "DMCT"
+}
+
+// statementID identifies the statement of an account over a period in at most 35 characters
+func statementID(header Header) string {
+	return fmt.Sprintf("%d-%s-%s", header.Account.ID, header.From.Format("060102"), lastDay(header.To).Format("060102"))
+}
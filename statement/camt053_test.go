@@ -0,0 +1,402 @@
+package statement
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/samirprakash/go-bank/db/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// xmlNode is an element of a parsed XML document
+type xmlNode struct {
+	Name     xml.Name
+	Attrs    []xml.Attr
+	Children []*xmlNode
+	Text     string
+}
+
+func (n *xmlNode) child(name string) *xmlNode {
+	for _, child := range n.Children {
+		if child.Name.Local == name {
+			return child
+		}
+	}
+	return nil
+}
+
+func (n *xmlNode) children(name string) []*xmlNode {
+	var nodes []*xmlNode
+	for _, child := range n.Children {
+		if child.Name.Local == name {
+			nodes = append(nodes, child)
+		}
+	}
+	return nodes
+}
+
+// path returns the text of the descendant at the path, or an empty string
+func (n *xmlNode) path(names ...string) string {
+	node := n
+	for _, name := range names {
+		if node = node.child(name); node == nil {
+			return ""
+		}
+	}
+	return node.Text
+}
+
+func parseXML(r io.Reader) (*xmlNode, error) {
+	dec := xml.NewDecoder(r)
+	var stack []*xmlNode
+	var root *xmlNode
+	for {
+		token, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{Name: token.Name, Attrs: token.Attr}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, node)
+			} else {
+				root = node
+			}
+			stack = append(stack, node)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].Text += strings.TrimSpace(string(token))
+			}
+		}
+	}
+	if root == nil {
+		return nil, errors.New("empty document")
+	}
+	return root, nil
+}
+
+// camtChild is an element of the sequence of children of a camt.053 element, occurring min to max times
+type camtChild struct {
+	name     string
+	min, max int
+}
+
+const unbounded = -1
+
+// camt053Sequences are the children of the elements of camt.053.001.02 checked by the validator, in schema order.
+// The children of other elements are not checked, and none of these names is used for different elements.
+var camt053Sequences = map[string][]camtChild{
+	"Document":      {{"BkToCstmrStmt", 1, 1}},
+	"BkToCstmrStmt": {{"GrpHdr", 1, 1}, {"Stmt", 1, unbounded}, {"SplmtryData", 0, unbounded}},
+	"GrpHdr":        {{"MsgId", 1, 1}, {"CreDtTm", 1, 1}, {"MsgRcpt", 0, 1}, {"MsgPgntn", 0, 1}, {"AddtlInf", 0, 1}},
+	"Stmt": {
+		{"Id", 1, 1}, {"ElctrncSeqNb", 0, 1}, {"LglSeqNb", 0, 1}, {"CreDtTm", 1, 1}, {"FrToDt", 0, 1},
+		{"CpyDplctInd", 0, 1}, {"RptgSrc", 0, 1}, {"Acct", 1, 1}, {"RltdAcct", 0, 1}, {"Intrst", 0, unbounded},
+		{"Bal", 1, unbounded}, {"TxsSummry", 0, 1}, {"Ntry", 0, unbounded}, {"AddtlStmtInf", 0, 1},
+	},
+	"FrToDt": {{"FrDtTm", 1, 1}, {"ToDtTm", 1, 1}},
+	"Acct":   {{"Id", 1, 1}, {"Tp", 0, 1}, {"Ccy", 0, 1}, {"Nm", 0, 1}, {"Ownr", 0, 1}, {"Svcr", 0, 1}},
+	"Bal":    {{"Tp", 1, 1}, {"CdtLine", 0, 1}, {"Amt", 1, 1}, {"CdtDbtInd", 1, 1}, {"Dt", 1, 1}, {"Avlbty", 0, unbounded}},
+	"Ntry": {
+		{"NtryRef", 0, 1}, {"Amt", 1, 1}, {"CdtDbtInd", 1, 1}, {"RvslInd", 0, 1}, {"Sts", 1, 1},
+		{"BookgDt", 0, 1}, {"ValDt", 0, 1}, {"AcctSvcrRef", 0, 1}, {"Avlbty", 0, unbounded}, {"BkTxCd", 1, 1},
+		{"ComssnWvrInd", 0, 1}, {"AddtlInfInd", 0, 1}, {"AmtDtls", 0, 1}, {"Chrgs", 0, 1}, {"TechInptChanl", 0, 1},
+		{"Intrst", 0, 1}, {"NtryDtls", 0, unbounded}, {"AddtlNtryInf", 0, 1},
+	},
+	"BkTxCd": {{"Domn", 0, 1}, {"Prtry", 0, 1}},
+	"Othr":   {{"Id", 1, 1}, {"SchmeNm", 0, 1}, {"Issr", 0, 1}},
+	"Domn":   {{"Cd", 1, 1}, {"Fmly", 1, 1}},
+	"Fmly":   {{"Cd", 1, 1}, {"SubFmlyCd", 1, 1}},
+	"TxDtls": {
+		{"Refs", 0, 1}, {"AmtDtls", 0, 1}, {"Avlbty", 0, unbounded}, {"BkTxCd", 0, 1}, {"Chrgs", 0, 1},
+		{"Intrst", 0, 1}, {"RltdPties", 0, 1}, {"RltdAgts", 0, 1}, {"Purp", 0, 1}, {"RltdRmtInf", 0, 10},
+		{"RmtInf", 0, 1}, {"RltdDts", 0, 1}, {"RltdPric", 0, 1}, {"RltdQties", 0, unbounded}, {"FinInstrmId", 0, 1},
+		{"Tax", 0, 1}, {"RtrInf", 0, 1}, {"CorpActn", 0, 1}, {"SfkpgAcct", 0, 1}, {"CshDpst", 0, unbounded},
+		{"CardTx", 0, 1}, {"AddtlTxInf", 0, 1}, {"SplmtryData", 0, unbounded},
+	},
+	"RltdPties": {
+		{"InitgPty", 0, 1}, {"Dbtr", 0, 1}, {"DbtrAcct", 0, 1}, {"UltmtDbtr", 0, 1},
+		{"Cdtr", 0, 1}, {"CdtrAcct", 0, 1}, {"UltmtCdtr", 0, 1}, {"TradgPty", 0, 1}, {"Prtry", 0, unbounded},
+	},
+}
+
+// checkSequence checks that the children of a node follow the sequence of the schema, recursively.
+// No element of camt.053 may be empty.
+func checkSequence(node *xmlNode) error {
+	name := node.Name.Local
+	if len(node.Children) == 0 && node.Text == "" {
+		return fmt.Errorf("empty %s", name)
+	}
+	if sequence, ok := camt053Sequences[name]; ok {
+		i := 0
+		for _, child := range sequence {
+			count := 0
+			for i < len(node.Children) && node.Children[i].Name.Local == child.name {
+				count++
+				i++
+			}
+			if count < child.min || (child.max != unbounded && count > child.max) {
+				return fmt.Errorf("%s: %d %s, want %d to %d", name, count, child.name, child.min, child.max)
+			}
+		}
+		if i < len(node.Children) {
+			return fmt.Errorf("%s: unexpected %s", name, node.Children[i].Name.Local)
+		}
+	}
+
+	for _, child := range node.Children {
+		if err := checkSequence(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	camtAmountPattern   = regexp.MustCompile(`^\d{1,13}(\.\d{1,5})?$`)
+	camtCurrencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+// parseCAMTAmount parses the amount of a balance or an entry in minor units, its sign given by the credit debit indicator of the node
+func parseCAMTAmount(node *xmlNode, currency string) (int64, error) {
+	amt := node.child("Amt")
+	if amt == nil {
+		return 0, errors.New("missing Amt")
+	}
+	if !camtAmountPattern.MatchString(amt.Text) {
+		return 0, fmt.Errorf("invalid amount %q", amt.Text)
+	}
+
+	ccy := ""
+	for _, attr := range amt.Attrs {
+		if attr.Name.Local == "Ccy" {
+			ccy = attr.Value
+		}
+	}
+	if !camtCurrencyPattern.MatchString(ccy) || ccy != currency {
+		return 0, fmt.Errorf("amount in %q, want %q", ccy, currency)
+	}
+
+	units, cents, _ := strings.Cut(amt.Text, ".")
+	if len(cents) > 2 {
+		return 0, fmt.Errorf("amount %q has more than two decimals", amt.Text)
+	}
+	amount, err := strconv.ParseInt(units+(cents + "00")[:2], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	switch node.path("CdtDbtInd") {
+	case "CRDT":
+		return amount, nil
+	case "DBIT":
+		return -amount, nil
+	}
+	return 0, fmt.Errorf("invalid credit debit indicator %q", node.path("CdtDbtInd"))
+}
+
+// parseISODateTime parses an ISO 8601 date and time, banks leaving out the time zone at times
+func parseISODateTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02T15:04:05", value)
+}
+
+func checkMaxLength(value string, n int, what string) error {
+	if value == "" || len(value) > n {
+		return fmt.Errorf("%s %q must have 1 to %d characters", what, value, n)
+	}
+	return nil
+}
+
+// validateCAMT053 checks a camt.053.001.02 document: the order and occurrences of its elements,
+// the format of its values, and that the booked entries of every statement add up to its closing balance
+func validateCAMT053(r io.Reader) error {
+	root, err := parseXML(r)
+	if err != nil {
+		return err
+	}
+	if root.Name.Local != "Document" || root.Name.Space != camt053Namespace {
+		return fmt.Errorf("root element %s in %q", root.Name.Local, root.Name.Space)
+	}
+	if err := checkSequence(root); err != nil {
+		return err
+	}
+
+	groupHeader := root.child("BkToCstmrStmt").child("GrpHdr")
+	if err := checkMaxLength(groupHeader.path("MsgId"), 35, "message id"); err != nil {
+		return err
+	}
+	if _, err := parseISODateTime(groupHeader.path("CreDtTm")); err != nil {
+		return err
+	}
+
+	for _, stmt := range root.child("BkToCstmrStmt").children("Stmt") {
+		if err := checkMaxLength(stmt.path("Id"), 35, "statement id"); err != nil {
+			return err
+		}
+
+		currency := stmt.path("Acct", "Ccy")
+		if !camtCurrencyPattern.MatchString(currency) {
+			return fmt.Errorf("statement %s: invalid currency %q", stmt.path("Id"), currency)
+		}
+
+		balances := map[string]int64{}
+		for _, bal := range stmt.children("Bal") {
+			amount, err := parseCAMTAmount(bal, currency)
+			if err != nil {
+				return fmt.Errorf("balance: %w", err)
+			}
+			if _, err := time.Parse("2006-01-02", bal.path("Dt", "Dt")); err != nil {
+				return fmt.Errorf("balance: %w", err)
+			}
+			balances[bal.path("Tp", "CdOrPrtry", "Cd")] = amount
+		}
+
+		opening, ok := balances["OPBD"]
+		if !ok {
+			opening, ok = balances["PRCD"]
+		}
+		closing, ok2 := balances["CLBD"]
+		if !ok || !ok2 {
+			return fmt.Errorf("statement %s: missing opening or closing booked balance", stmt.path("Id"))
+		}
+
+		balance := opening
+		for _, ntry := range stmt.children("Ntry") {
+			amount, err := parseCAMTAmount(ntry, currency)
+			if err != nil {
+				return fmt.Errorf("entry %s: %w", ntry.path("NtryRef"), err)
+			}
+			if ref := ntry.path("NtryRef"); ref != "" {
+				if err := checkMaxLength(ref, 35, "entry reference"); err != nil {
+					return err
+				}
+			}
+			if info := ntry.path("AddtlNtryInf"); len(info) > 500 {
+				return fmt.Errorf("entry %s: additional information too long", ntry.path("NtryRef"))
+			}
+			if bookingDate := ntry.child("BookgDt"); bookingDate != nil {
+				if _, err := parseISODateTime(bookingDate.path("DtTm")); err != nil && bookingDate.path("Dt") == "" {
+					return fmt.Errorf("entry %s: invalid booking date", ntry.path("NtryRef"))
+				}
+			}
+
+			switch ntry.path("Sts") {
+			case "BOOK":
+				balance += amount
+			case "PDNG", "INFO":
+			default:
+				return fmt.Errorf("entry %s: invalid status %q", ntry.path("NtryRef"), ntry.path("Sts"))
+			}
+		}
+
+		if balance != closing {
+			return fmt.Errorf("statement %s: entries add up to %d, closing balance is %d", stmt.path("Id"), balance, closing)
+		}
+	}
+	return nil
+}
+
+func TestCAMT053Samples(t *testing.T) {
+	testCases := []struct {
+		file    string
+		wantErr string
+	}{
+		{file: "sample.xml"},
+		{file: "sample_multiple.xml"},
+		{file: "invalid_balance.xml", wantErr: "closing balance"},
+		{file: "invalid_order.xml", wantErr: "Stmt: 0 Bal"},
+		{file: "invalid_amount.xml", wantErr: "invalid amount"},
+		{file: "invalid_namespace.xml", wantErr: "root element"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.file, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", "camt053", tc.file))
+			require.NoError(t, err)
+			defer f.Close()
+
+			err = validateCAMT053(f)
+			if tc.wantErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestCAMT053(t *testing.T) {
+	out := generate(t, FormatCAMT053, bankRows())
+
+	require.NoError(t, validateCAMT053(bytes.NewReader(out)))
+
+	golden, err := os.ReadFile(filepath.Join("testdata", "camt053", "statement.xml"))
+	require.NoError(t, err)
+	require.Equal(t, string(golden), string(out))
+}
+
+func TestCAMT053WithoutEntries(t *testing.T) {
+	out := generate(t, FormatCAMT053, nil)
+	require.NoError(t, validateCAMT053(bytes.NewReader(out)))
+}
+
+func TestCAMT053TransactionCode(t *testing.T) {
+	for _, kind := range []string{"deposit", "transfer", "reversal", "fee", "interest"} {
+		for _, credit := range []bool{true, false} {
+			domain, family, subFamily := camtTransactionCode(kind, credit)
+			require.Len(t, domain, 4)
+			require.Len(t, family, 4)
+			require.Len(t, subFamily, 4)
+		}
+	}
+
+	_, family, _ := camtTransactionCode("transfer", true)
+	require.Equal(t, "RCDT", family)
+	_, family, _ = camtTransactionCode("transfer", false)
+	require.Equal(t, "ICDT", family)
+}
+
+func TestEndOfDayClosingBalance(t *testing.T) {
+	for _, format := range []string{FormatCAMT053, FormatMT940} {
+		t.Run(format, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().ListStatementEntries(gomock.Any(), gomock.Any()).Times(1).Return(testRows(1), nil)
+
+			w, err := NewWriter(format, &bytes.Buffer{})
+			require.NoError(t, err)
+
+			// the closing balance of the header misses the entry
+			header := testHeader()
+			header.ClosingBalance = header.OpeningBalance
+
+			err = Generate(context.Background(), store, w, header, 2)
+			require.ErrorIs(t, err, ErrClosingBalance)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package statement
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// csvWriter renders a statement as CSV with amounts in major units.
+// The opening and closing balances are rows of their own so that every row has the same columns.
+type csvWriter struct {
+	out io.Writer
+	csv *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{out: w, csv: csv.NewWriter(w)}
+}
+
+func (w *csvWriter) WriteHeader(header Header) error {
+	err := w.csv.Write([]string{"id", "date", "kind", "transfer_id", "counterparty_account_id", "counterparty_owner", "amount", "balance"})
+	if err != nil {
+		return err
+	}
+	return w.csv.Write([]string{"", header.From.Format(time.RFC3339), "opening_balance", "", "", "", "", formatAmount(header.OpeningBalance)})
+}
+
+func (w *csvWriter) WriteEntry(entry Entry) error {
+	return w.csv.Write([]string{
+		strconv.FormatInt(entry.ID, 10),
+		entry.CreatedAt.UTC().Format(time.RFC3339),
+		entry.Kind,
+		formatID(entry.TransferID),
+		formatID(entry.CounterpartyAccountID),
+		entry.CounterpartyOwner,
+		formatAmount(entry.Amount),
+		formatAmount(entry.Balance),
+	})
+}
+
+func (w *csvWriter) WriteFooter(footer Footer) error {
+	return w.csv.Write([]string{"", "", "closing_balance", "", "", "", "", formatAmount(footer.ClosingBalance)})
+}
+
+func (w *csvWriter) Flush() error {
+	w.csv.Flush()
+	if err := w.csv.Error(); err != nil {
+		return err
+	}
+	flushOutput(w.out)
+	return nil
+}
+
+func formatID(id *int64) string {
+	if id == nil {
+		return ""
+	}
+	return strconv.FormatInt(*id, 10)
+}
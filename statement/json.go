@@ -0,0 +1,83 @@
+package statement
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// jsonWriter renders a statement as a JSON document with amounts in minor units like the rest of the API.
+// The document is written piece by piece, entries being marshalled one at a time.
+type jsonWriter struct {
+	out     io.Writer
+	buf     *bufio.Writer
+	entries int
+}
+
+type jsonHeader struct {
+	AccountID      int64     `json:"account_id"`
+	Owner          string    `json:"owner"`
+	Currency       string    `json:"currency"`
+	From           time.Time `json:"from"`
+	To             time.Time `json:"to"`
+	OpeningBalance int64     `json:"opening_balance"`
+}
+
+func newJSONWriter(w io.Writer) *jsonWriter {
+	return &jsonWriter{out: w, buf: bufio.NewWriter(w)}
+}
+
+func (w *jsonWriter) WriteHeader(header Header) error {
+	data, err := json.Marshal(jsonHeader{
+		AccountID:      header.Account.ID,
+		Owner:          header.Account.Owner,
+		Currency:       header.Account.Currency,
+		From:           header.From,
+		To:             header.To,
+		OpeningBalance: header.OpeningBalance,
+	})
+	if err != nil {
+		return err
+	}
+
+	// reopen the header object to append the entries to it
+	w.buf.Write(data[:len(data)-1])
+	_, err = w.buf.WriteString(`,"entries":[`)
+	return err
+}
+
+func (w *jsonWriter) WriteEntry(entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	if w.entries > 0 {
+		w.buf.WriteByte(',')
+	}
+	w.entries++
+
+	_, err = w.buf.Write(data)
+	return err
+}
+
+func (w *jsonWriter) WriteFooter(footer Footer) error {
+	data, err := json.Marshal(footer.ClosingBalance)
+	if err != nil {
+		return err
+	}
+
+	w.buf.WriteString(`],"closing_balance":`)
+	w.buf.Write(data)
+	_, err = w.buf.WriteString("}\n")
+	return err
+}
+
+func (w *jsonWriter) Flush() error {
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	flushOutput(w.out)
+	return nil
+}
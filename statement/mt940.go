@@ -0,0 +1,180 @@
+package statement
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Limits of the fields of an MT940 message
+const (
+	mt940LineLength       = 65
+	mt940InformationLines = 6
+	mt940ReferenceLength  = 16
+)
+
+// mt940Writer renders an end-of-day statement as an MT940 message without the SWIFT envelope,
+// as imported by accounting software. Lines end with CRLF and only use the SWIFT character set.
+type mt940Writer struct {
+	out    io.Writer
+	buf    *bufio.Writer
+	header Header
+}
+
+func newMT940Writer(w io.Writer) *mt940Writer {
+	return &mt940Writer{out: w, buf: bufio.NewWriter(w)}
+}
+
+// field writes a tag and its value, one line after another
+func (w *mt940Writer) field(tag string, lines ...string) {
+	for i, line := range lines {
+		if i == 0 {
+			w.buf.WriteString(":" + tag + ":")
+		}
+		w.buf.WriteString(line)
+		w.buf.WriteString("\r\n")
+	}
+}
+
+func (w *mt940Writer) WriteHeader(header Header) error {
+	w.header = header
+	last := lastDay(header.To)
+
+	w.field("20", truncate(last.Format("060102")+"-"+strconv.FormatInt(header.Account.ID, 10), mt940ReferenceLength))
+	w.field("25", strconv.FormatInt(header.Account.ID, 10))
+	// the statement number is the year and the day of the year of the last day, statements being daily
+	w.field("28C", fmt.Sprintf("%s%03d/1", last.Format("06"), last.YearDay()))
+	w.field("60F", w.balance(header.OpeningBalance, header.From.Format("060102")))
+	return nil
+}
+
+func (w *mt940Writer) WriteEntry(entry Entry) error {
+	date := entry.CreatedAt.UTC()
+
+	reference := "NONREF"
+	if entry.TransferID != nil {
+		reference = truncate(strconv.FormatInt(*entry.TransferID, 10), mt940ReferenceLength)
+	}
+
+	w.field("61", fmt.Sprintf("%s%s%s%sN%s%s//%d",
+		date.Format("060102"),
+		date.Format("0102"),
+		mt940Mark(entry.Kind, entry.Amount),
+		mt940Amount(entry.Amount),
+		mt940TransactionType(entry.Kind),
+		reference,
+		entry.ID,
+	))
+
+	information := entry.Kind
+	if entry.CounterpartyAccountID != nil {
+		information += fmt.Sprintf(" /ACCT/%d", *entry.CounterpartyAccountID)
+	}
+	if entry.CounterpartyOwner != "" {
+		information += " /NAME/" + entry.CounterpartyOwner
+	}
+	w.field("86", mt940Lines(information)...)
+
+	return nil
+}
+
+func (w *mt940Writer) WriteFooter(footer Footer) error {
+	if footer.ClosingBalance != w.header.ClosingBalance {
+		return ErrClosingBalance
+	}
+
+	w.field("62F", w.balance(footer.ClosingBalance, lastDay(w.header.To).Format("060102")))
+	_, err := w.buf.WriteString("-\r\n")
+	return err
+}
+
+func (w *mt940Writer) Flush() error {
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	flushOutput(w.out)
+	return nil
+}
+
+// balance formats a balance field: its side, date, currency and amount
+func (w *mt940Writer) balance(amount int64, date string) string {
+	mark := "C"
+	if amount < 0 {
+		mark = "D"
+	}
+	return mark + date + w.header.Account.Currency + mt940Amount(amount)
+}
+
+// mt940Mark returns the side of an entry, a reversal being marked as the reversal of the opposite side
+func mt940Mark(kind string, amount int64) string {
+	switch {
+	case kind == "reversal" && amount < 0:
+		return "RC"
+	case kind == "reversal":
+		return "RD"
+	case amount < 0:
+		return "D"
+	}
+	return "C"
+}
+
+// mt940Amount formats an unsigned amount with a decimal comma, e.g. -1050 as 10,50
+func mt940Amount(amount int64) string {
+	if amount < 0 {
+		amount = -amount
+	}
+	return strings.Replace(formatAmount(amount), ".", ",", 1)
+}
+
+// mt940TransactionType returns the SWIFT transaction type of an entry
+func mt940TransactionType(kind string) string {
+	switch kind {
+	case "deposit":
+		return "MSC"
+	case "fee":
+		return "CHG"
+	case "interest":
+		return "INT"
+	case "reversal":
+		return "RTI"
+	}
+	return "TRF"
+}
+
+// mt940Lines splits free text into the lines of a field, after replacing the characters
+// outside the SWIFT character set, and drops what does not fit
+func mt940Lines(text string) []string {
+	text = strings.Map(func(r rune) rune {
+		if mt940Allowed(r) {
+			return r
+		}
+		return '.'
+	}, text)
+
+	var lines []string
+	for len(text) > 0 && len(lines) < mt940InformationLines {
+		n := mt940LineLength
+		if len(lines) == 0 {
+			// the first line starts with the tag
+			n -= len(":86:")
+		}
+		if n > len(text) {
+			n = len(text)
+		}
+		lines = append(lines, text[:n])
+		text = text[n:]
+	}
+	return lines
+}
+
+// mt940Allowed reports whether a character of free text belongs to the SWIFT X character set.
+// Colons and hyphens are left out too, as a line starting with them would open a tag or end the message.
+func mt940Allowed(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	}
+	return strings.ContainsRune("/?().,'+ ", r)
+}
@@ -0,0 +1,287 @@
+package statement
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// mt940Field is a tag of an MT940 message and its value, continuation lines joined with newlines
+type mt940Field struct {
+	tag   string
+	value string
+}
+
+var (
+	mt940CharacterSet = regexp.MustCompile(`^[a-zA-Z0-9/\-?:().,'+ ]*$`)
+	mt940Tag          = regexp.MustCompile(`^:(\d{2}[A-Z]?):`)
+	mt940Reference    = regexp.MustCompile(`^[^/](?:[^/]|/[^/]){0,14}[^/]?$`)
+	mt940StatementNo  = regexp.MustCompile(`^\d{1,5}(/\d{1,5})?$`)
+	mt940Balance      = regexp.MustCompile(`^([CD])(\d{6})([A-Z]{3})(\d{1,12},\d{0,2})$`)
+	mt940Line61       = regexp.MustCompile(`^(\d{6})(\d{4})?(RC|RD|C|D)[A-Z]?(\d{1,12},\d{0,2})([NSF][A-Z0-9]{3})(.{1,16}?)(//.{1,16})?(\n.{1,34})?$`)
+)
+
+// mt940Sequence is the order of the fields of a message, fields matching the pattern occurring min to max times.
+// The 86 following a 61 belongs to the entry and is left out of the sequence.
+var mt940Sequence = []struct {
+	pattern  *regexp.Regexp
+	min, max int
+}{
+	{regexp.MustCompile(`^20$`), 1, 1},
+	{regexp.MustCompile(`^21$`), 0, 1},
+	{regexp.MustCompile(`^25P?$`), 1, 1},
+	{regexp.MustCompile(`^28C$`), 1, 1},
+	{regexp.MustCompile(`^60[FM]$`), 1, 1},
+	{regexp.MustCompile(`^61$`), 0, unbounded},
+	{regexp.MustCompile(`^62[FM]$`), 1, 1},
+	{regexp.MustCompile(`^64$`), 0, 1},
+	{regexp.MustCompile(`^65$`), 0, unbounded},
+	{regexp.MustCompile(`^86$`), 0, 1},
+}
+
+// parseMT940 splits MT940 text into messages, each a list of fields.
+// Lines must end with CRLF, be at most 65 characters long and use the SWIFT character set.
+func parseMT940(r io.Reader) ([][]mt940Field, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasSuffix(data, []byte("\r\n")) || bytes.Count(data, []byte("\n")) != bytes.Count(data, []byte("\r\n")) {
+		return nil, fmt.Errorf("lines must end with CRLF")
+	}
+
+	var messages [][]mt940Field
+	var fields []mt940Field
+	lines := strings.Split(strings.TrimSuffix(string(data), "\r\n"), "\r\n")
+	for i, line := range lines {
+		n := i + 1
+		if len(line) > mt940LineLength {
+			return nil, fmt.Errorf("line %d is longer than %d characters", n, mt940LineLength)
+		}
+		if !mt940CharacterSet.MatchString(line) {
+			return nil, fmt.Errorf("line %d is outside the SWIFT character set", n)
+		}
+
+		switch m := mt940Tag.FindStringSubmatch(line); {
+		case line == "-":
+			if len(fields) == 0 {
+				return nil, fmt.Errorf("line %d: empty message", n)
+			}
+			messages = append(messages, fields)
+			fields = nil
+		case m != nil:
+			fields = append(fields, mt940Field{tag: m[1], value: line[len(m[0]):]})
+		case len(fields) > 0:
+			fields[len(fields)-1].value += "\n" + line
+		default:
+			return nil, fmt.Errorf("line %d is outside a field", n)
+		}
+	}
+	if len(fields) > 0 {
+		return nil, fmt.Errorf("the last message does not end with -")
+	}
+	return messages, nil
+}
+
+// parseMT940Amount parses an amount with a decimal comma in minor units
+func parseMT940Amount(value string) (int64, error) {
+	units, cents, _ := strings.Cut(value, ",")
+	if len(cents) > 2 {
+		return 0, fmt.Errorf("amount %q has more than two decimals", value)
+	}
+	return strconv.ParseInt(units+(cents + "00")[:2], 10, 64)
+}
+
+// parseMT940Balance parses a balance field, returning its currency and signed amount
+func parseMT940Balance(field mt940Field) (string, int64, error) {
+	m := mt940Balance.FindStringSubmatch(field.value)
+	if m == nil {
+		return "", 0, fmt.Errorf(":%s: invalid balance %q", field.tag, field.value)
+	}
+	if _, err := time.Parse("060102", m[2]); err != nil {
+		return "", 0, fmt.Errorf(":%s: %w", field.tag, err)
+	}
+	amount, err := parseMT940Amount(m[4])
+	if err != nil {
+		return "", 0, err
+	}
+	if m[1] == "D" {
+		amount = -amount
+	}
+	return m[3], amount, nil
+}
+
+// validateMT940 checks MT940 messages: the character set and line length, the order of the fields
+// and their formats, and that the entries of every message add up to its closing balance
+func validateMT940(r io.Reader) error {
+	messages, err := parseMT940(r)
+	if err != nil {
+		return err
+	}
+	if len(messages) == 0 {
+		return fmt.Errorf("no message")
+	}
+
+	for _, fields := range messages {
+		var tags []string
+		for i, field := range fields {
+			if field.tag != "86" || i == 0 || fields[i-1].tag != "61" {
+				tags = append(tags, field.tag)
+			}
+		}
+
+		i := 0
+		for _, field := range mt940Sequence {
+			count := 0
+			for i < len(tags) && field.pattern.MatchString(tags[i]) {
+				count++
+				i++
+			}
+			if count < field.min || (field.max != unbounded && count > field.max) {
+				return fmt.Errorf("%d fields matching %s, want %d to %d", count, field.pattern, field.min, field.max)
+			}
+		}
+		if i < len(tags) {
+			return fmt.Errorf("unexpected :%s:", tags[i])
+		}
+
+		var currency string
+		var balance, closing int64
+		for _, field := range fields {
+			switch field.tag {
+			case "20":
+				if !mt940Reference.MatchString(field.value) {
+					return fmt.Errorf(":20: invalid reference %q", field.value)
+				}
+			case "25", "25P":
+				if len(field.value) == 0 || len(field.value) > 35 {
+					return fmt.Errorf(":25: invalid account %q", field.value)
+				}
+			case "28C":
+				if !mt940StatementNo.MatchString(field.value) {
+					return fmt.Errorf(":28C: invalid statement number %q", field.value)
+				}
+			case "60F", "60M":
+				if currency, balance, err = parseMT940Balance(field); err != nil {
+					return err
+				}
+			case "61":
+				m := mt940Line61.FindStringSubmatch(field.value)
+				if m == nil {
+					return fmt.Errorf(":61: invalid entry %q", field.value)
+				}
+				if _, err := time.Parse("060102", m[1]); err != nil {
+					return fmt.Errorf(":61: %w", err)
+				}
+				amount, err := parseMT940Amount(m[4])
+				if err != nil {
+					return err
+				}
+				// a reversal of a credit is a debit and a reversal of a debit a credit
+				if m[3] == "D" || m[3] == "RC" {
+					amount = -amount
+				}
+				balance += amount
+			case "86":
+				if strings.Count(field.value, "\n") >= mt940InformationLines {
+					return fmt.Errorf(":86: more than %d lines", mt940InformationLines)
+				}
+			case "62F", "62M":
+				var closingCurrency string
+				if closingCurrency, closing, err = parseMT940Balance(field); err != nil {
+					return err
+				}
+				if closingCurrency != currency {
+					return fmt.Errorf(":62F: currency %s, opening balance in %s", closingCurrency, currency)
+				}
+			case "64", "65":
+				if _, _, err := parseMT940Balance(field); err != nil {
+					return err
+				}
+			}
+		}
+
+		if balance != closing {
+			return fmt.Errorf("entries add up to %d, closing balance is %d", balance, closing)
+		}
+	}
+	return nil
+}
+
+func TestMT940Samples(t *testing.T) {
+	testCases := []struct {
+		file    string
+		wantErr string
+	}{
+		{file: "sample.sta"},
+		{file: "sample_multiple.sta"},
+		{file: "invalid_balance.sta", wantErr: "closing balance"},
+		{file: "invalid_line_length.sta", wantErr: "longer than 65"},
+		{file: "invalid_character.sta", wantErr: "character set"},
+		{file: "invalid_order.sta", wantErr: "0 fields matching ^60[FM]$"},
+		{file: "invalid_entry.sta", wantErr: "invalid entry"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.file, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", "mt940", tc.file))
+			require.NoError(t, err)
+			defer f.Close()
+
+			err = validateMT940(f)
+			if tc.wantErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestMT940(t *testing.T) {
+	out := generate(t, FormatMT940, bankRows())
+
+	require.NoError(t, validateMT940(bytes.NewReader(out)))
+
+	golden, err := os.ReadFile(filepath.Join("testdata", "mt940", "statement.sta"))
+	require.NoError(t, err)
+	require.Equal(t, string(golden), string(out))
+}
+
+func TestMT940WithoutEntries(t *testing.T) {
+	out := generate(t, FormatMT940, nil)
+	require.NoError(t, validateMT940(bytes.NewReader(out)))
+}
+
+func TestMT940Lines(t *testing.T) {
+	require.Equal(t, []string{"transfer /NAME/bob."}, mt940Lines("transfer /NAME/bob_"))
+
+	lines := mt940Lines(strings.Repeat("a", 1000))
+	require.Len(t, lines, mt940InformationLines)
+	require.Len(t, ":86:"+lines[0], mt940LineLength)
+	for _, line := range lines[1:] {
+		require.Len(t, line, mt940LineLength)
+	}
+
+	// continuation lines never start a tag or end the message
+	for _, line := range mt940Lines(strings.Repeat("x-:", 100)) {
+		require.NotContains(t, line, ":")
+		require.NotContains(t, line, "-")
+	}
+}
+
+func TestMT940Mark(t *testing.T) {
+	require.Equal(t, "C", mt940Mark("transfer", 10))
+	require.Equal(t, "D", mt940Mark("transfer", -10))
+	require.Equal(t, "RD", mt940Mark("reversal", 10))
+	require.Equal(t, "RC", mt940Mark("reversal", -10))
+}
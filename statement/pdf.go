@@ -0,0 +1,229 @@
+package statement
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Layout of the pages of a PDF statement, in points
+const (
+	pdfPageWidth  = 595 // A4
+	pdfPageHeight = 842
+	pdfMargin     = 50
+	pdfFontSize   = 9
+	pdfLeading    = 12
+)
+
+// Objects written up front, the catalog and the page tree being written last once the pages are known
+const (
+	pdfCatalogObject   = 1
+	pdfPagesObject     = 2
+	pdfFontObject      = 3
+	pdfBoldFontObject  = 4
+	pdfFirstFreeObject = 5
+)
+
+// pdfColumns lays out the entries in a fixed-width font so that amounts line up without measuring text
+const pdfColumns = "%-20s %-8s %-9s %-18s %14s %14s"
+
+// pdfWriter renders a statement as a PDF document without any dependency.
+// Pages are written as soon as they are full, only the offsets of the objects are kept
+// to write the cross-reference table at the end.
+type pdfWriter struct {
+	out     io.Writer
+	buf     *bufio.Writer
+	written int64
+	err     error
+
+	// offsets of the objects by number, 0 for the free object
+	offsets []int64
+	pages   []int
+
+	header  Header
+	content bytes.Buffer
+	y       int
+}
+
+func newPDFWriter(w io.Writer) *pdfWriter {
+	return &pdfWriter{
+		out:     w,
+		buf:     bufio.NewWriter(w),
+		offsets: make([]int64, pdfFirstFreeObject),
+	}
+}
+
+// printf writes to the document and keeps track of its length
+func (w *pdfWriter) printf(format string, args ...interface{}) {
+	if w.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(w.buf, format, args...)
+	w.written += int64(n)
+	w.err = err
+}
+
+// beginObject records the offset of an object and opens it
+func (w *pdfWriter) beginObject(number int) {
+	w.offsets[number] = w.written
+	w.printf("%d 0 obj\n", number)
+}
+
+// newObject reserves the number of an object
+func (w *pdfWriter) newObject() int {
+	w.offsets = append(w.offsets, 0)
+	return len(w.offsets) - 1
+}
+
+func (w *pdfWriter) WriteHeader(header Header) error {
+	w.header = header
+
+	// the comment of binary characters tells transfer programs the file is not text
+	w.printf("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+
+	w.beginObject(pdfFontObject)
+	w.printf("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>\nendobj\n")
+	w.beginObject(pdfBoldFontObject)
+	w.printf("<< /Type /Font /Subtype /Type1 /BaseFont /Courier-Bold /Encoding /WinAnsiEncoding >>\nendobj\n")
+
+	w.newPage()
+	w.line(true, fmt.Sprintf("Statement of account %d (%s)", header.Account.ID, header.Account.Currency))
+	w.line(false, "Owner: "+header.Account.Owner)
+	w.line(false, fmt.Sprintf("Period: %s to %s", header.From.Format("2006-01-02"), lastDay(header.To).Format("2006-01-02")))
+	w.line(false, "Opening balance: "+formatAmount(header.OpeningBalance))
+	w.line(false, "")
+	w.columnHeader()
+
+	return w.err
+}
+
+func (w *pdfWriter) WriteEntry(entry Entry) error {
+	if w.y < pdfMargin+pdfLeading {
+		w.endPage()
+		w.newPage()
+		w.columnHeader()
+	}
+
+	counterparty := entry.CounterpartyOwner
+	if entry.CounterpartyAccountID != nil {
+		counterparty = fmt.Sprintf("%d %s", *entry.CounterpartyAccountID, entry.CounterpartyOwner)
+	}
+
+	w.line(false, fmt.Sprintf(pdfColumns,
+		entry.CreatedAt.UTC().Format("2006-01-02 15:04:05"),
+		strconv.FormatInt(entry.ID, 10),
+		entry.Kind,
+		truncate(counterparty, 18),
+		formatAmount(entry.Amount),
+		formatAmount(entry.Balance),
+	))
+
+	return w.err
+}
+
+func (w *pdfWriter) WriteFooter(footer Footer) error {
+	if w.y < pdfMargin+2*pdfLeading {
+		w.endPage()
+		w.newPage()
+	}
+	w.line(false, "")
+	w.line(true, "Closing balance: "+formatAmount(footer.ClosingBalance))
+	w.endPage()
+
+	w.beginObject(pdfPagesObject)
+	w.printf("<< /Type /Pages /Kids [")
+	for _, page := range w.pages {
+		w.printf("%d 0 R ", page)
+	}
+	w.printf("] /Count %d >>\nendobj\n", len(w.pages))
+
+	w.beginObject(pdfCatalogObject)
+	w.printf("<< /Type /Catalog /Pages %d 0 R >>\nendobj\n", pdfPagesObject)
+
+	// every entry of the cross-reference table is exactly 20 bytes long
+	xref := w.written
+	w.printf("xref\n0 %d\n", len(w.offsets))
+	w.printf("%010d 65535 f \n", 0)
+	for _, offset := range w.offsets[1:] {
+		w.printf("%010d 00000 n \n", offset)
+	}
+	w.printf("trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(w.offsets), pdfCatalogObject, xref)
+
+	return w.err
+}
+
+func (w *pdfWriter) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	flushOutput(w.out)
+	return nil
+}
+
+func (w *pdfWriter) newPage() {
+	w.content.Reset()
+	w.y = pdfPageHeight - pdfMargin
+}
+
+// endPage writes the content of the current page followed by the page itself
+func (w *pdfWriter) endPage() {
+	// pages are numbered at the bottom as the total is unknown until the end
+	w.y = pdfMargin - pdfLeading
+	w.line(false, fmt.Sprintf("Page %d", len(w.pages)+1))
+
+	content := w.newObject()
+	w.beginObject(content)
+	w.printf("<< /Length %d >>\nstream\n", w.content.Len())
+	w.printf("%s", w.content.Bytes())
+	w.printf("\nendstream\nendobj\n")
+
+	page := w.newObject()
+	w.beginObject(page)
+	w.printf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> >> /Contents %d 0 R >>\nendobj\n",
+		pdfPagesObject, pdfPageWidth, pdfPageHeight, pdfFontObject, pdfBoldFontObject, content)
+	w.pages = append(w.pages, page)
+}
+
+func (w *pdfWriter) columnHeader() {
+	w.line(true, fmt.Sprintf(pdfColumns, "Date", "Entry", "Kind", "Counterparty", "Amount", "Balance"))
+}
+
+// line adds a line of text to the current page
+func (w *pdfWriter) line(bold bool, text string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(&w.content, "BT /%s %d Tf %d %d Td (%s) Tj ET\n", font, pdfFontSize, pdfMargin, w.y, escapePDF(text))
+	w.y -= pdfLeading
+}
+
+// escapePDF escapes a PDF string and replaces the characters the standard fonts cannot show
+func escapePDF(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r > 0x7e:
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func truncate(text string, n int) string {
+	if len(text) <= n {
+		return text
+	}
+	return text[:n]
+}
@@ -0,0 +1,221 @@
+// Package statement renders account statements as CSV, JSON or PDF, and as end-of-day bank statements
+// in the ISO 20022 camt.053 and SWIFT MT940 formats imported by accounting software.
+// Statements are written entry by entry as they are read from the store, so that a statement
+// of any length is streamed to the client without being held in memory.
+package statement
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	db "github.com/samirprakash/go-bank/db/sqlc"
+)
+
+// Formats of a statement
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+	FormatPDF  = "pdf"
+	// FormatCAMT053 is the ISO 20022 bank to customer statement, camt.053.001.02
+	FormatCAMT053 = "camt053"
+	// FormatMT940 is the SWIFT customer statement message
+	FormatMT940 = "mt940"
+)
+
+// DefaultPageSize is the number of entries read from the store at a time
+const DefaultPageSize = 500
+
+// ErrUnknownFormat is returned for a format that is not supported
+var ErrUnknownFormat = errors.New("unknown statement format")
+
+// ErrClosingBalance is returned when the entries of an end-of-day statement do not add up to its closing balance
+var ErrClosingBalance = errors.New("entries do not add up to the closing balance")
+
+// Header opens a statement
+type Header struct {
+	Account db.Account
+	From    time.Time
+	// To is excluded from the statement
+	To             time.Time
+	OpeningBalance int64
+	// ClosingBalance is only required by end-of-day statements, which give it before the entries
+	ClosingBalance int64
+	// GeneratedAt is the creation time written in end-of-day statements
+	GeneratedAt time.Time
+}
+
+// Entry is a line of a statement
+type Entry struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	// Kind is deposit, transfer, reversal, fee or interest
+	Kind                  string `json:"kind"`
+	TransferID            *int64 `json:"transfer_id"`
+	CounterpartyAccountID *int64 `json:"counterparty_account_id"`
+	CounterpartyOwner     string `json:"counterparty_owner,omitempty"`
+	Amount                int64  `json:"amount"`
+	// Balance is the balance of the account once the entry is applied
+	Balance int64 `json:"balance"`
+}
+
+// Footer closes a statement
+type Footer struct {
+	ClosingBalance int64
+}
+
+// Writer renders a statement in one format.
+// WriteHeader is called first, then WriteEntry for every entry, then WriteFooter which completes the document.
+type Writer interface {
+	WriteHeader(header Header) error
+	WriteEntry(entry Entry) error
+	WriteFooter(footer Footer) error
+	// Flush sends what was written so far to the underlying writer
+	Flush() error
+}
+
+// NewWriter creates a writer rendering a statement in the format to w
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w), nil
+	case FormatJSON:
+		return newJSONWriter(w), nil
+	case FormatPDF:
+		return newPDFWriter(w), nil
+	case FormatCAMT053:
+		return newCAMT053Writer(w), nil
+	case FormatMT940:
+		return newMT940Writer(w), nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+// ContentType returns the MIME type of a format
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatPDF:
+		return "application/pdf"
+	case FormatCAMT053:
+		return "application/xml; charset=utf-8"
+	case FormatMT940:
+		return "text/plain; charset=utf-8"
+	}
+	return "application/json; charset=utf-8"
+}
+
+// Extension returns the file extension of a format
+func Extension(format string) string {
+	switch format {
+	case FormatCAMT053:
+		return "xml"
+	case FormatMT940:
+		return "sta"
+	}
+	return format
+}
+
+// EndOfDay reports whether a format is an end-of-day bank statement.
+// Those statements cover completed days only, as their closing balance must be known before the entries are read.
+func EndOfDay(format string) bool {
+	return format == FormatCAMT053 || format == FormatMT940
+}
+
+// Source reads the entries of a statement, it is implemented by db.Store
+type Source interface {
+	ListStatementEntries(ctx context.Context, arg db.ListStatementEntriesParams) ([]db.ListStatementEntriesRow, error)
+}
+
+// Generate writes the statement opened by the header, reading its entries pageSize at a time
+// and flushing the writer after every page.
+func Generate(ctx context.Context, source Source, w Writer, header Header, pageSize int32) error {
+	if err := w.WriteHeader(header); err != nil {
+		return err
+	}
+
+	balance := header.OpeningBalance
+	afterID := int64(0)
+	for {
+		rows, err := source.ListStatementEntries(ctx, db.ListStatementEntriesParams{
+			AccountID:  header.Account.ID,
+			FromTime:   header.From,
+			ToTime:     header.To,
+			AfterID:    afterID,
+			LimitCount: pageSize,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			balance += row.Amount
+			if err := w.WriteEntry(newEntry(row, balance)); err != nil {
+				return err
+			}
+			afterID = row.ID
+		}
+
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		if len(rows) < int(pageSize) {
+			break
+		}
+	}
+
+	if err := w.WriteFooter(Footer{ClosingBalance: balance}); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+func newEntry(row db.ListStatementEntriesRow, balance int64) Entry {
+	entry := Entry{
+		ID:                row.ID,
+		CreatedAt:         row.CreatedAt,
+		Kind:              row.Kind,
+		CounterpartyOwner: row.CounterpartyOwner.String,
+		Amount:            row.Amount,
+		Balance:           balance,
+	}
+	if row.TransferID.Valid {
+		entry.TransferID = &row.TransferID.Int64
+	}
+	if row.CounterpartyAccountID.Valid {
+		entry.CounterpartyAccountID = &row.CounterpartyAccountID.Int64
+	}
+	return entry
+}
+
+// formatAmount renders an amount in minor units with two decimals, e.g. -1050 as -10.50
+func formatAmount(amount int64) string {
+	sign := ""
+	units := uint64(amount)
+	if amount < 0 {
+		sign = "-"
+		units = uint64(-amount)
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, units/100, units%100)
+}
+
+// lastDay returns the last day of a period ending before to
+func lastDay(to time.Time) time.Time {
+	return to.Add(-time.Nanosecond)
+}
+
+// flusher is implemented by writers buffering their output, such as HTTP responses
+type flusher interface {
+	Flush()
+}
+
+// flushOutput passes a flush on to the underlying writer when it buffers its output
+func flushOutput(w io.Writer) {
+	if f, ok := w.(flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,226 @@
+package statement
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgtype"
+	mockdb "github.com/samirprakash/go-bank/db/mock"
+	db "github.com/samirprakash/go-bank/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testFrom = time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	testTo   = time.Date(2023, time.February, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func testHeader() Header {
+	return Header{
+		Account:        db.Account{ID: 7, Owner: "alice", Currency: "USD"},
+		From:           testFrom,
+		To:             testTo,
+		OpeningBalance: 1000,
+		GeneratedAt:    testTo.Add(6 * time.Hour),
+	}
+}
+
+func testRows(n int) []db.ListStatementEntriesRow {
+	rows := make([]db.ListStatementEntriesRow, n)
+	for i := range rows {
+		rows[i] = db.ListStatementEntriesRow{
+			ID:                    int64(i + 1),
+			Amount:                -25,
+			CreatedAt:             testFrom.Add(time.Duration(i) * time.Hour),
+			TransferID:            pgtype.Int8{Int64: int64(100 + i), Valid: true},
+			CounterpartyAccountID: pgtype.Int8{Int64: 8, Valid: true},
+			CounterpartyOwner:     pgtype.Text{String: "bob", Valid: true},
+			Kind:                  "transfer",
+		}
+	}
+	rows[0].Amount = 50
+	rows[0].TransferID = pgtype.Int8{}
+	rows[0].CounterpartyAccountID = pgtype.Int8{}
+	rows[0].CounterpartyOwner = pgtype.Text{}
+	rows[0].Kind = "deposit"
+	return rows
+}
+
+// bankRows returns entries of every kind, both credits and debits
+func bankRows() []db.ListStatementEntriesRow {
+	rows := testRows(6)
+
+	rows[2].Kind = "fee"
+	rows[2].Amount = -3
+	rows[2].CounterpartyAccountID = pgtype.Int8{Int64: 1, Valid: true}
+	rows[2].CounterpartyOwner = pgtype.Text{String: "bank", Valid: true}
+
+	rows[3].Kind = "reversal"
+	rows[3].Amount = 10
+
+	rows[4].Kind = "interest"
+	rows[4].Amount = 7
+	rows[4].CounterpartyAccountID = rows[2].CounterpartyAccountID
+	rows[4].CounterpartyOwner = rows[2].CounterpartyOwner
+
+	rows[5].Amount = 200
+	return rows
+}
+
+// generate renders a statement of the rows in the format, reading them two at a time
+func generate(t *testing.T, format string, rows []db.ListStatementEntriesRow) []byte {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	afterID := int64(0)
+	for i := 0; i <= len(rows); i += 2 {
+		end := i + 2
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		arg := db.ListStatementEntriesParams{
+			AccountID:  7,
+			FromTime:   testFrom,
+			ToTime:     testTo,
+			AfterID:    afterID,
+			LimitCount: 2,
+		}
+		store.EXPECT().ListStatementEntries(gomock.Any(), gomock.Eq(arg)).Times(1).Return(rows[i:end], nil)
+
+		if end > i {
+			afterID = rows[end-1].ID
+		}
+	}
+
+	header := testHeader()
+	header.ClosingBalance = header.OpeningBalance
+	for _, row := range rows {
+		header.ClosingBalance += row.Amount
+	}
+
+	var out bytes.Buffer
+	w, err := NewWriter(format, &out)
+	require.NoError(t, err)
+
+	err = Generate(context.Background(), store, w, header, 2)
+	require.NoError(t, err)
+
+	return out.Bytes()
+}
+
+func TestCSV(t *testing.T) {
+	out := generate(t, FormatCSV, testRows(3))
+
+	records, err := csv.NewReader(bytes.NewReader(out)).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 6)
+
+	require.Equal(t, "id", records[0][0])
+	require.Equal(t, []string{"", "2023-01-01T00:00:00Z", "opening_balance", "", "", "", "", "10.00"}, records[1])
+	require.Equal(t, []string{"1", "2023-01-01T00:00:00Z", "deposit", "", "", "", "0.50", "10.50"}, records[2])
+	require.Equal(t, []string{"2", "2023-01-01T01:00:00Z", "transfer", "101", "8", "bob", "-0.25", "10.25"}, records[3])
+	require.Equal(t, []string{"", "", "closing_balance", "", "", "", "", "10.00"}, records[5])
+}
+
+func TestJSON(t *testing.T) {
+	out := generate(t, FormatJSON, testRows(3))
+
+	var statement struct {
+		AccountID      int64   `json:"account_id"`
+		Currency       string  `json:"currency"`
+		OpeningBalance int64   `json:"opening_balance"`
+		Entries        []Entry `json:"entries"`
+		ClosingBalance int64   `json:"closing_balance"`
+	}
+	err := json.Unmarshal(out, &statement)
+	require.NoError(t, err)
+
+	require.Equal(t, int64(7), statement.AccountID)
+	require.Equal(t, "USD", statement.Currency)
+	require.Equal(t, int64(1000), statement.OpeningBalance)
+	require.Len(t, statement.Entries, 3)
+	require.Nil(t, statement.Entries[0].TransferID)
+	require.Equal(t, int64(101), *statement.Entries[1].TransferID)
+	require.Equal(t, int64(1025), statement.Entries[1].Balance)
+	require.Equal(t, int64(1000), statement.ClosingBalance)
+}
+
+func TestJSONWithoutEntries(t *testing.T) {
+	out := generate(t, FormatJSON, nil)
+
+	var statement map[string]interface{}
+	err := json.Unmarshal(out, &statement)
+	require.NoError(t, err)
+	require.Empty(t, statement["entries"])
+	require.Equal(t, float64(1000), statement["closing_balance"])
+}
+
+func TestPDF(t *testing.T) {
+	// enough entries to fill several pages
+	out := generate(t, FormatPDF, testRows(150))
+
+	require.True(t, bytes.HasPrefix(out, []byte("%PDF-1.4\n")))
+	require.True(t, bytes.HasSuffix(out, []byte("%%EOF\n")))
+
+	// the cross-reference table points at every object of the document
+	m := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(out)
+	require.NotNil(t, m)
+	xref, err := strconv.Atoi(string(m[1]))
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(out[xref:], []byte("xref\n0 ")))
+
+	lines := strings.Split(string(out[xref:]), "\n")
+	size, err := strconv.Atoi(strings.Fields(lines[1])[1])
+	require.NoError(t, err)
+	for number := 1; number < size; number++ {
+		entry := lines[2+number]
+		require.Len(t, entry+"\n", 20)
+
+		offset, err := strconv.Atoi(entry[:10])
+		require.NoError(t, err)
+		require.True(t, bytes.HasPrefix(out[offset:], []byte(fmt.Sprintf("%d 0 obj\n", number))), "object %d", number)
+	}
+
+	// the streams are as long as they claim to be
+	streams := regexp.MustCompile(`(?s)<< /Length (\d+) >>\nstream\n`).FindAllSubmatchIndex(out, -1)
+	for _, s := range streams {
+		length, err := strconv.Atoi(string(out[s[2]:s[3]]))
+		require.NoError(t, err)
+		require.True(t, bytes.HasPrefix(out[s[1]+length:], []byte("\nendstream\n")))
+	}
+
+	pages := regexp.MustCompile(`/Count (\d+)`).FindSubmatch(out)
+	require.NotNil(t, pages)
+	require.Equal(t, "3", string(pages[1]))
+	require.Len(t, streams, 3)
+
+	require.Contains(t, string(out), "(Opening balance: 10.00)")
+	require.Contains(t, string(out), "(Closing balance: -26.75)")
+}
+
+func TestUnknownFormat(t *testing.T) {
+	_, err := NewWriter("xls", &bytes.Buffer{})
+	require.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestFormatAmount(t *testing.T) {
+	require.Equal(t, "0.00", formatAmount(0))
+	require.Equal(t, "0.05", formatAmount(5))
+	require.Equal(t, "-10.50", formatAmount(-1050))
+	require.Equal(t, "123456.78", formatAmount(12345678))
+}
+
+func TestEscapePDF(t *testing.T) {
+	require.Equal(t, `a\(b\)c\\d?`, escapePDF(`a(b)c\dé`))
+}
@@ -0,0 +1,126 @@
+// Package tracing sets up OpenTelemetry tracing for the bank
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/samirprakash/go-bank/util"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ServiceName identifies the bank in traces
+const ServiceName = "go-bank"
+
+// Supported trace exporters
+const (
+	ExporterNone   = ""
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// Tracer creates the spans of the bank
+func Tracer() trace.Tracer {
+	return otel.Tracer(ServiceName)
+}
+
+// otlpOptions returns the options of the OTLP exporter.
+// Spans carry request data so they are sent over TLS, unless TRACE_OTLP_INSECURE allows plaintext e.g. for a local collector.
+func otlpOptions(config util.Config) []otlptracegrpc.Option {
+	options := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(config.TraceOTLPEndpoint)}
+	if config.TraceOTLPInsecure {
+		options = append(options, otlptracegrpc.WithInsecure())
+	}
+	return options
+}
+
+// Setup installs the global tracer provider and the W3C trace context propagator.
+// It returns a function that flushes the pending spans and must be called before exiting.
+// Without an exporter, spans are not recorded but trace context is still propagated.
+func Setup(ctx context.Context, config util.Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+
+	switch config.TraceExporter {
+	case ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOTLP:
+		exporter, err = otlptracegrpc.New(ctx, otlpOptions(config)...)
+	default:
+		return nil, fmt.Errorf("unsupported trace exporter %q", config.TraceExporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot create %s trace exporter : %w", config.TraceExporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("cannot create trace resource : %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.TraceSampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// TraceQueries is a db.QueryHook that creates a child span for every store call
+func TraceQueries(ctx context.Context, method string) (context.Context, func(err error)) {
+	ctx, span := Tracer().Start(ctx, "db."+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			attribute.String("db.operation", method),
+		),
+	)
+
+	return ctx, func(err error) {
+		// a missing row is an expected outcome handled by the caller
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = nil
+		}
+		endSpan(span, err)
+	}
+}
+
+// Trace runs fn inside a child span of the context and records the error it returns
+func Trace(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	ctx, span := Tracer().Start(ctx, name)
+	err := fn(ctx)
+	endSpan(span, err)
+	return err
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,40 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTraceQueries(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	ctx, parent := Tracer().Start(context.Background(), "parent")
+
+	_, done := TraceQueries(ctx, "GetAccount")
+	done(pgx.ErrNoRows)
+
+	_, done = TraceQueries(ctx, "TransferTx")
+	done(sql.ErrConnDone)
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+
+	// a missing row is not an error
+	require.Equal(t, "db.GetAccount", spans[0].Name())
+	require.Equal(t, codes.Unset, spans[0].Status().Code)
+	require.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
+
+	require.Equal(t, "db.TransferTx", spans[1].Name())
+	require.Equal(t, codes.Error, spans[1].Status().Code)
+	require.Equal(t, parent.SpanContext().TraceID(), spans[1].SpanContext().TraceID())
+}
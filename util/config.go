@@ -9,12 +9,46 @@ import (
 // Config stores the confifguration for the app
 // Values are read by Viper from a config file or from an environment varible
 type Config struct {
-	DBDriver             string        `mapstructure:"DB_DRIVER"`
+	Environment          string        `mapstructure:"ENVIRONMENT"`
+	LogLevel             string        `mapstructure:"LOG_LEVEL"`
+	TraceExporter        string        `mapstructure:"TRACE_EXPORTER"`
+	TraceOTLPEndpoint    string        `mapstructure:"TRACE_OTLP_ENDPOINT"`
+	TraceOTLPInsecure    bool          `mapstructure:"TRACE_OTLP_INSECURE"`
+	TraceSampleRatio     float64       `mapstructure:"TRACE_SAMPLE_RATIO"`
 	DBSource             string        `mapstructure:"DB_SOURCE"`
+	DBConnectTimeout     time.Duration `mapstructure:"DB_CONNECT_TIMEOUT"`
+	DBMaxConns           int32         `mapstructure:"DB_MAX_CONNS"`
+	DBMinConns           int32         `mapstructure:"DB_MIN_CONNS"`
+	DBMaxConnLifetime    time.Duration `mapstructure:"DB_MAX_CONN_LIFETIME"`
+	DBMaxConnIdleTime    time.Duration `mapstructure:"DB_MAX_CONN_IDLE_TIME"`
+	DBHealthCheckPeriod  time.Duration `mapstructure:"DB_HEALTH_CHECK_PERIOD"`
+	DBTxMaxRetries       int           `mapstructure:"DB_TX_MAX_RETRIES"`
+	DBReplicaSources     []string      `mapstructure:"DB_REPLICA_SOURCES"`
+	DBReplicaMaxLag      time.Duration `mapstructure:"DB_REPLICA_MAX_LAG"`
+	DBReplicaInterval    time.Duration `mapstructure:"DB_REPLICA_CHECK_INTERVAL"`
+	MigrationURL         string        `mapstructure:"MIGRATION_URL"`
+	AutoMigrate          bool          `mapstructure:"AUTO_MIGRATE"`
 	ServerAddress        string        `mapstructure:"SERVER_ADDRESS"`
+	ServerReadTimeout    time.Duration `mapstructure:"SERVER_READ_TIMEOUT"`
+	ServerWriteTimeout   time.Duration `mapstructure:"SERVER_WRITE_TIMEOUT"`
+	ServerIdleTimeout    time.Duration `mapstructure:"SERVER_IDLE_TIMEOUT"`
+	ShutdownTimeout      time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"`
+	SchedulerInterval    time.Duration `mapstructure:"SCHEDULER_INTERVAL"`
+	SchedulerBatchSize   int32         `mapstructure:"SCHEDULER_BATCH_SIZE"`
+	HoldSweepInterval    time.Duration `mapstructure:"HOLD_SWEEP_INTERVAL"`
+	HoldDefaultTTL       time.Duration `mapstructure:"HOLD_DEFAULT_TTL"`
+	InterestInterval     time.Duration `mapstructure:"INTEREST_INTERVAL"`
+	WebhookInterval      time.Duration `mapstructure:"WEBHOOK_INTERVAL"`
+	WebhookMaxAttempts   int32         `mapstructure:"WEBHOOK_MAX_ATTEMPTS"`
+	WebhookTimeout       time.Duration `mapstructure:"WEBHOOK_TIMEOUT"`
+	EventPublisher       string        `mapstructure:"EVENT_PUBLISHER"`
+	EventFilePath        string        `mapstructure:"EVENT_FILE_PATH"`
+	EventRelayInterval   time.Duration `mapstructure:"EVENT_RELAY_INTERVAL"`
+	StreamBufferSize     int           `mapstructure:"STREAM_BUFFER_SIZE"`
 	TokenSymmetricKey    string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
 	AccessTokenDuration  time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
 	RefreshTokenDuration time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	AdminUsernames       []string      `mapstructure:"ADMIN_USERNAMES"`
 }
 
 // LoadConfig loads the configuration from an config file or from environment vars
@@ -0,0 +1,212 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+	db "github.com/samirprakash/go-bank/db/sqlc"
+	"github.com/samirprakash/go-bank/metrics"
+)
+
+// Delays between the attempts of a delivery, doubling from minBackoff up to maxBackoff
+const (
+	minBackoff = 30 * time.Second
+	maxBackoff = 6 * time.Hour
+)
+
+// maxErrorLength bounds the error recorded for a failed attempt
+const maxErrorLength = 500
+
+// Dispatcher posts the pending webhook deliveries to their subscriptions.
+// A delivery is retried with exponential backoff until it is acknowledged with a 2xx response
+// or maxAttempts attempts failed, so receivers must expect the same event more than once.
+type Dispatcher struct {
+	store       db.Store
+	client      *http.Client
+	interval    time.Duration
+	batchSize   int32
+	maxAttempts int32
+	now         func() time.Time
+}
+
+// NewDispatcher creates a dispatcher sending the due deliveries every interval, batchSize at a time,
+// waiting at most timeout for a subscription to respond.
+// Deliveries are only sent to public addresses and redirects are not followed.
+func NewDispatcher(store db.Store, interval time.Duration, batchSize int32, maxAttempts int32, timeout time.Duration) *Dispatcher {
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+
+	return &Dispatcher{
+		store:       store,
+		client:      newClient(timeout, publicOnly),
+		interval:    interval,
+		batchSize:   batchSize,
+		maxAttempts: maxAttempts,
+		now:         time.Now,
+	}
+}
+
+// Run sends the due deliveries every interval until the context is done
+func (dispatcher *Dispatcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(dispatcher.interval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			n, err := dispatcher.Dispatch(ctx)
+			if err != nil {
+				log.Ctx(ctx).Error().Err(err).Msg("cannot dispatch webhooks")
+				break
+			}
+
+			// keep going while there may be more due deliveries
+			if n < int(dispatcher.batchSize) {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Ctx(ctx).Info().Msg("webhook dispatcher is stopped")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Dispatch sends one batch of due deliveries concurrently and returns how many were attempted.
+// The deliveries are leased for twice the timeout, so another dispatcher retries them
+// if this one stops before recording the attempts.
+func (dispatcher *Dispatcher) Dispatch(ctx context.Context) (int, error) {
+	now := dispatcher.now()
+	lease := 2 * dispatcher.client.Timeout
+	if lease <= 0 {
+		lease = minBackoff
+	}
+
+	deliveries, err := dispatcher.store.ClaimWebhookDeliveries(ctx, db.ClaimWebhookDeliveriesParams{
+		LeaseUntil: now.Add(lease),
+		Now:        now,
+		LimitCount: dispatcher.batchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func(delivery db.ClaimWebhookDeliveriesRow) {
+			defer wg.Done()
+			dispatcher.deliver(ctx, delivery)
+		}(delivery)
+	}
+	wg.Wait()
+
+	return len(deliveries), nil
+}
+
+// deliver makes one attempt of a delivery and records its outcome
+func (dispatcher *Dispatcher) deliver(ctx context.Context, delivery db.ClaimWebhookDeliveriesRow) {
+	logger := log.Ctx(ctx).With().
+		Int64("delivery_id", delivery.ID).
+		Int64("subscription_id", delivery.SubscriptionID).
+		Str("event_type", delivery.EventType).
+		Logger()
+
+	status, err := dispatcher.post(ctx, delivery)
+	if ctx.Err() != nil {
+		// stopping: the lease expires and the delivery is attempted again
+		return
+	}
+
+	arg := db.RecordWebhookDeliveryAttemptParams{
+		ID:            delivery.ID,
+		Status:        db.WebhookDeliveryDelivered,
+		NextAttemptAt: dispatcher.now(),
+	}
+	if status != 0 {
+		arg.ResponseStatus = pgtype.Int4{Int32: int32(status), Valid: true}
+	}
+	if err != nil {
+		attempts := delivery.Attempts + 1
+		arg.Error = truncate(err.Error(), maxErrorLength)
+		if attempts >= dispatcher.maxAttempts {
+			arg.Status = db.WebhookDeliveryFailed
+		} else {
+			arg.Status = db.WebhookDeliveryPending
+			arg.NextAttemptAt = arg.NextAttemptAt.Add(Backoff(attempts))
+		}
+	}
+
+	_, recordErr := dispatcher.store.RecordWebhookDeliveryAttempt(ctx, arg)
+	if recordErr != nil {
+		logger.Error().Err(recordErr).Msg("cannot record webhook delivery attempt")
+		return
+	}
+
+	metrics.WebhookDelivery(arg.Status)
+	if err != nil {
+		logger.Warn().Err(err).Str("status", arg.Status).Msg("webhook delivery attempt failed")
+		return
+	}
+	logger.Info().Msg("webhook delivered")
+}
+
+// post sends a signed delivery and returns the response status, an error unless it is 2xx
+func (dispatcher *Dispatcher) post(ctx context.Context, delivery db.ClaimWebhookDeliveriesRow) (int, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "go-bank-webhooks")
+	request.Header.Set(EventHeader, delivery.EventType)
+	request.Header.Set(DeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	request.Header.Set(SignatureHeader, Sign(delivery.Secret, dispatcher.now(), delivery.Payload))
+
+	response, err := dispatcher.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	// drain a bounded part of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("unexpected response status %d", response.StatusCode)
+	}
+	return response.StatusCode, nil
+}
+
+// Backoff returns the delay before the next attempt of a delivery that failed attempts times
+func Backoff(attempts int32) time.Duration {
+	backoff := minBackoff
+	for i := int32(1); i < attempts && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return backoff
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
@@ -0,0 +1,229 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	mockdb "github.com/samirprakash/go-bank/db/mock"
+	db "github.com/samirprakash/go-bank/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDispatch(t *testing.T) {
+	now := time.Date(2023, time.February, 1, 9, 0, 0, 0, time.UTC)
+	payload := []byte(`{"type":"transfer.created"}`)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Equal(t, payload, body)
+		require.Equal(t, db.EventTransferCreated, r.Header.Get(EventHeader))
+		require.NoError(t, Verify("secret", r.Header.Get(SignatureHeader), body, now, time.Minute))
+
+		if r.URL.Path != "/ok" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	delivery := func(id int64, path string, attempts int32) db.ClaimWebhookDeliveriesRow {
+		return db.ClaimWebhookDeliveriesRow{
+			ID:             id,
+			SubscriptionID: 1,
+			EventID:        uuid.New(),
+			EventType:      db.EventTransferCreated,
+			Payload:        payload,
+			Attempts:       attempts,
+			Url:            server.URL + path,
+			Secret:         "secret",
+		}
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+
+	store.EXPECT().
+		ClaimWebhookDeliveries(gomock.Any(), gomock.Eq(db.ClaimWebhookDeliveriesParams{
+			LeaseUntil: now.Add(20 * time.Second),
+			Now:        now,
+			LimitCount: 10,
+		})).
+		Times(1).
+		Return([]db.ClaimWebhookDeliveriesRow{
+			delivery(1, "/ok", 0),
+			delivery(2, "/down", 2),
+			delivery(3, "/down", 4),
+		}, nil)
+
+	store.EXPECT().
+		RecordWebhookDeliveryAttempt(gomock.Any(), gomock.Eq(db.RecordWebhookDeliveryAttemptParams{
+			ID:             1,
+			Status:         db.WebhookDeliveryDelivered,
+			NextAttemptAt:  now,
+			ResponseStatus: pgtype.Int4{Int32: http.StatusOK, Valid: true},
+		})).
+		Times(1)
+
+	// a failed attempt is retried after a backoff doubling with every attempt
+	store.EXPECT().
+		RecordWebhookDeliveryAttempt(gomock.Any(), gomock.Eq(db.RecordWebhookDeliveryAttemptParams{
+			ID:             2,
+			Status:         db.WebhookDeliveryPending,
+			NextAttemptAt:  now.Add(2 * time.Minute),
+			ResponseStatus: pgtype.Int4{Int32: http.StatusServiceUnavailable, Valid: true},
+			Error:          "unexpected response status 503",
+		})).
+		Times(1)
+
+	// until it is out of attempts
+	store.EXPECT().
+		RecordWebhookDeliveryAttempt(gomock.Any(), gomock.Eq(db.RecordWebhookDeliveryAttemptParams{
+			ID:             3,
+			Status:         db.WebhookDeliveryFailed,
+			NextAttemptAt:  now,
+			ResponseStatus: pgtype.Int4{Int32: http.StatusServiceUnavailable, Valid: true},
+			Error:          "unexpected response status 503",
+		})).
+		Times(1)
+
+	dispatcher := NewDispatcher(store, time.Minute, 10, 5, 10*time.Second)
+	dispatcher.now = func() time.Time { return now }
+	// the test server listens on the loopback address
+	dispatcher.client = newClient(10*time.Second, nil)
+
+	n, err := dispatcher.Dispatch(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+}
+
+func TestDispatchUnreachable(t *testing.T) {
+	now := time.Date(2023, time.February, 1, 9, 0, 0, 0, time.UTC)
+
+	// a closed server refuses connections
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+
+	store.EXPECT().
+		ClaimWebhookDeliveries(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return([]db.ClaimWebhookDeliveriesRow{{ID: 1, Url: server.URL, Secret: "secret", Payload: []byte(`{}`)}}, nil)
+
+	store.EXPECT().
+		RecordWebhookDeliveryAttempt(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, arg db.RecordWebhookDeliveryAttemptParams) (db.WebhookDelivery, error) {
+			require.Equal(t, db.WebhookDeliveryPending, arg.Status)
+			require.Equal(t, now.Add(minBackoff), arg.NextAttemptAt)
+			require.False(t, arg.ResponseStatus.Valid)
+			require.NotEmpty(t, arg.Error)
+			return db.WebhookDelivery{}, nil
+		})
+
+	dispatcher := NewDispatcher(store, time.Minute, 10, 5, time.Second)
+	dispatcher.now = func() time.Time { return now }
+	dispatcher.client = newClient(time.Second, nil)
+
+	n, err := dispatcher.Dispatch(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+}
+
+func TestDispatchPrivateAddress(t *testing.T) {
+	now := time.Date(2023, time.February, 1, 9, 0, 0, 0, time.UTC)
+
+	// the server is up but the dispatcher must not connect to the loopback address
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the dispatcher reached a private address")
+	}))
+	defer server.Close()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+
+	store.EXPECT().
+		ClaimWebhookDeliveries(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return([]db.ClaimWebhookDeliveriesRow{{ID: 1, Url: server.URL, Secret: "secret", Payload: []byte(`{}`)}}, nil)
+
+	store.EXPECT().
+		RecordWebhookDeliveryAttempt(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, arg db.RecordWebhookDeliveryAttemptParams) (db.WebhookDelivery, error) {
+			require.Equal(t, db.WebhookDeliveryPending, arg.Status)
+			require.False(t, arg.ResponseStatus.Valid)
+			require.Contains(t, arg.Error, ErrPrivateAddress.Error())
+			return db.WebhookDelivery{}, nil
+		})
+
+	dispatcher := NewDispatcher(store, time.Minute, 10, 5, time.Second)
+	dispatcher.now = func() time.Time { return now }
+
+	n, err := dispatcher.Dispatch(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+}
+
+func TestDispatchRedirect(t *testing.T) {
+	now := time.Date(2023, time.February, 1, 9, 0, 0, 0, time.UTC)
+
+	// a redirect is not followed, it would lead the dispatcher to a URL that was never checked
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/hooks" {
+			t.Error("the dispatcher followed a redirect")
+		}
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data", http.StatusTemporaryRedirect)
+	}))
+	defer server.Close()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+
+	store.EXPECT().
+		ClaimWebhookDeliveries(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return([]db.ClaimWebhookDeliveriesRow{{ID: 1, Url: server.URL + "/hooks", Secret: "secret", Payload: []byte(`{}`)}}, nil)
+
+	store.EXPECT().
+		RecordWebhookDeliveryAttempt(gomock.Any(), gomock.Eq(db.RecordWebhookDeliveryAttemptParams{
+			ID:             1,
+			Status:         db.WebhookDeliveryPending,
+			NextAttemptAt:  now.Add(minBackoff),
+			ResponseStatus: pgtype.Int4{Int32: http.StatusTemporaryRedirect, Valid: true},
+			Error:          "unexpected response status 307",
+		})).
+		Times(1)
+
+	dispatcher := NewDispatcher(store, time.Minute, 10, 5, time.Second)
+	dispatcher.now = func() time.Time { return now }
+	dispatcher.client = newClient(time.Second, nil)
+
+	n, err := dispatcher.Dispatch(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+}
+
+func TestBackoff(t *testing.T) {
+	require.Equal(t, 30*time.Second, Backoff(1))
+	require.Equal(t, time.Minute, Backoff(2))
+	require.Equal(t, 4*time.Minute, Backoff(4))
+	require.Equal(t, maxBackoff, Backoff(20))
+	require.Equal(t, maxBackoff, Backoff(1000))
+}
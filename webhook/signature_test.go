@@ -0,0 +1,43 @@
+package webhook
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSign(t *testing.T) {
+	timestamp := time.Unix(1700000000, 0)
+	payload := []byte(`{"type":"transfer.created"}`)
+
+	// HMAC-SHA256 of "1700000000." followed by the payload, keyed with the secret
+	header := Sign("secret", timestamp, payload)
+	require.Equal(t, "t=1700000000,v1=2574ad6d3c5fe064af138e480d5eae4459a5d29707662aed19c618ac86cbd2dd", header)
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Now()
+	payload := []byte(`{"type":"account.created"}`)
+	header := Sign("secret", now, payload)
+
+	require.NoError(t, Verify("secret", header, payload, now, 5*time.Minute))
+
+	require.ErrorIs(t, Verify("other", header, payload, now, 5*time.Minute), ErrInvalidSignature)
+	require.ErrorIs(t, Verify("secret", header, []byte(`{}`), now, 5*time.Minute), ErrInvalidSignature)
+	require.ErrorIs(t, Verify("secret", "v1=abc", payload, now, 5*time.Minute), ErrInvalidSignature)
+	require.ErrorIs(t, Verify("secret", header, payload, now.Add(time.Hour), 5*time.Minute), ErrExpiredSignature)
+	require.NoError(t, Verify("secret", header, payload, now.Add(time.Hour), 0))
+}
+
+func TestNewSecret(t *testing.T) {
+	secret1, err := NewSecret()
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(secret1, secretPrefix))
+	require.Len(t, secret1, len(secretPrefix)+64)
+
+	secret2, err := NewSecret()
+	require.NoError(t, err)
+	require.NotEqual(t, secret1, secret2)
+}
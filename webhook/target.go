@@ -0,0 +1,101 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Errors of a subscription URL the dispatcher will not deliver to
+var (
+	ErrInsecureURL    = errors.New("webhook url must use https")
+	ErrPrivateAddress = errors.New("webhook url must resolve to a public address")
+	ErrUnsupportedURL = errors.New("webhook url must be an absolute http or https url")
+)
+
+// Ranges that are not routable on the internet but not covered by the methods of netip.Addr
+var (
+	sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+	thisNetwork        = netip.MustParsePrefix("0.0.0.0/8")
+)
+
+// ValidateURL checks the URL of a subscription when it is created : it must use https unless allowHTTP is set,
+// and its host cannot be a non-public address literal nor localhost.
+// Host names are only resolved when the dispatcher connects, where the address is checked again.
+func ValidateURL(rawURL string, allowHTTP bool) error {
+	target, err := url.Parse(rawURL)
+	if err != nil || target.Hostname() == "" {
+		return ErrUnsupportedURL
+	}
+
+	switch target.Scheme {
+	case "https":
+	case "http":
+		if !allowHTTP {
+			return ErrInsecureURL
+		}
+	default:
+		return ErrUnsupportedURL
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(target.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrPrivateAddress
+	}
+	if ip, err := netip.ParseAddr(host); err == nil && !isPublicAddr(ip) {
+		return ErrPrivateAddress
+	}
+	return nil
+}
+
+// isPublicAddr reports whether an address is routable on the internet.
+// Loopback, private, link-local (with the 169.254.169.254 metadata endpoint of cloud providers),
+// shared, multicast and unspecified addresses are not.
+func isPublicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() &&
+		!sharedAddressSpace.Contains(ip) && !thisNetwork.Contains(ip)
+}
+
+// publicOnly is the control function of the dialer of the dispatcher.
+// It runs once the host name is resolved, so a name pointing to an internal address is refused as well.
+func publicOnly(network string, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !isPublicAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, addrPort.Addr())
+	}
+	return nil
+}
+
+// newClient returns the HTTP client of the dispatcher, waiting at most timeout for a response.
+// It does not follow redirects, which would lead it to a URL that was never checked,
+// and only connects to the addresses accepted by control.
+func newClient(timeout time.Duration, control func(network string, address string, c syscall.RawConn) error) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   control,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// a proxy would connect to the subscription on behalf of the dispatcher, out of reach of control
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,61 @@
+package webhook
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateURL(t *testing.T) {
+	require.NoError(t, ValidateURL("https://example.com/hooks", false))
+	require.NoError(t, ValidateURL("https://8.8.8.8/hooks", false))
+	require.NoError(t, ValidateURL("http://example.com/hooks", true))
+
+	require.ErrorIs(t, ValidateURL("http://example.com/hooks", false), ErrInsecureURL)
+	require.ErrorIs(t, ValidateURL("ftp://example.com/hooks", true), ErrUnsupportedURL)
+	require.ErrorIs(t, ValidateURL("/hooks", true), ErrUnsupportedURL)
+
+	for _, url := range []string{
+		"https://127.0.0.1/hooks",
+		"https://localhost:8080/hooks",
+		"https://api.localhost./hooks",
+		"https://10.0.0.1/hooks",
+		"https://169.254.169.254/latest/meta-data",
+		"https://[::1]/hooks",
+		"https://[::ffff:192.168.1.1]/hooks",
+	} {
+		require.ErrorIs(t, ValidateURL(url, true), ErrPrivateAddress, url)
+	}
+}
+
+func TestIsPublicAddr(t *testing.T) {
+	for _, addr := range []string{"8.8.8.8", "93.184.216.34", "2606:4700:4700::1111"} {
+		require.True(t, isPublicAddr(netip.MustParseAddr(addr)), addr)
+	}
+
+	for _, addr := range []string{
+		"127.0.0.1",
+		"10.1.2.3",
+		"172.16.0.1",
+		"192.168.1.1",
+		"169.254.169.254",
+		"100.64.0.1",
+		"0.0.0.0",
+		"0.1.2.3",
+		"224.0.0.1",
+		"::1",
+		"::",
+		"fc00::1",
+		"fe80::1",
+		"::ffff:127.0.0.1",
+	} {
+		require.False(t, isPublicAddr(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+func TestPublicOnly(t *testing.T) {
+	require.NoError(t, publicOnly("tcp4", "8.8.8.8:443", nil))
+	require.ErrorIs(t, publicOnly("tcp4", "127.0.0.1:443", nil), ErrPrivateAddress)
+	require.ErrorIs(t, publicOnly("tcp6", "[fe80::1%eth0]:443", nil), ErrPrivateAddress)
+}